@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS url_tags;
+DROP TABLE IF EXISTS tags;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS description;
+ALTER TABLE urls DROP COLUMN IF EXISTS title;
+ALTER TABLE urls DROP COLUMN IF EXISTS id;
@@ -0,0 +1,18 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS id bigint GENERATED ALWAYS AS IDENTITY UNIQUE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title varchar;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS description text;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id int PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_email varchar NOT NULL REFERENCES users(email),
+    name varchar NOT NULL,
+    UNIQUE (user_email, name)
+);
+
+CREATE TABLE IF NOT EXISTS url_tags (
+    url_id bigint NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    tag_id int NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (url_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS url_tags_tag_id_idx ON url_tags(tag_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS urls_user_email_short_url_idx;
+DROP INDEX IF EXISTS urls_user_email_times_visited_idx;
+DROP INDEX IF EXISTS urls_user_email_expires_at_idx;
+DROP INDEX IF EXISTS urls_user_email_created_at_idx;
+
+ALTER TABLE urls ALTER COLUMN times_visited DROP NOT NULL;
+ALTER TABLE urls ALTER COLUMN times_visited DROP DEFAULT;
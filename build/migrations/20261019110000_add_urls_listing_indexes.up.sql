@@ -0,0 +1,9 @@
+UPDATE urls SET times_visited = 0 WHERE times_visited IS NULL;
+
+ALTER TABLE urls ALTER COLUMN times_visited SET DEFAULT 0;
+ALTER TABLE urls ALTER COLUMN times_visited SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS urls_user_email_created_at_idx ON urls(user_email, created_at, short_url);
+CREATE INDEX IF NOT EXISTS urls_user_email_expires_at_idx ON urls(user_email, expires_at, short_url);
+CREATE INDEX IF NOT EXISTS urls_user_email_times_visited_idx ON urls(user_email, times_visited, short_url);
+CREATE INDEX IF NOT EXISTS urls_user_email_short_url_idx ON urls(user_email, short_url);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    event_type varchar NOT NULL,
+    short_url varchar NOT NULL,
+    payload jsonb NOT NULL DEFAULT '{}',
+    attempts int NOT NULL DEFAULT 0,
+    last_error text,
+    available_at timestamp NOT NULL DEFAULT timezone('utc', now()),
+    created_at timestamp NOT NULL DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX IF NOT EXISTS outbox_available_at_idx ON outbox(available_at, id);
+CREATE INDEX IF NOT EXISTS outbox_short_url_idx ON outbox(short_url, id);
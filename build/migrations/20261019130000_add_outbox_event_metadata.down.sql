@@ -0,0 +1,5 @@
+ALTER TABLE outbox DROP COLUMN event_version;
+ALTER TABLE outbox DROP COLUMN event_id;
+
+ALTER INDEX outbox_event_key_idx RENAME TO outbox_short_url_idx;
+ALTER TABLE outbox RENAME COLUMN event_key TO short_url;
@@ -0,0 +1,5 @@
+ALTER TABLE outbox RENAME COLUMN short_url TO event_key;
+ALTER INDEX outbox_short_url_idx RENAME TO outbox_event_key_idx;
+
+ALTER TABLE outbox ADD COLUMN event_id uuid NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE outbox ADD COLUMN event_version int NOT NULL DEFAULT 1;
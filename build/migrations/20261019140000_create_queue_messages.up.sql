@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS queue_messages (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    topic varchar NOT NULL,
+    message_key varchar NOT NULL DEFAULT '',
+    payload bytea NOT NULL,
+    attempts int NOT NULL DEFAULT 0,
+    available_at timestamp NOT NULL DEFAULT timezone('utc', now()),
+    created_at timestamp NOT NULL DEFAULT timezone('utc', now()),
+    original_topic varchar,
+    last_error text
+);
+
+CREATE INDEX IF NOT EXISTS queue_messages_topic_available_at_idx ON queue_messages(topic, available_at, id);
@@ -0,0 +1,34 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS link_search (
+    short_url varchar PRIMARY KEY,
+    user_email varchar NOT NULL,
+    long_url varchar NOT NULL,
+    domain varchar NOT NULL DEFAULT '',
+    title varchar NOT NULL DEFAULT '',
+    tags varchar[] NOT NULL DEFAULT '{}',
+    created_at timestamp NOT NULL,
+    document text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS link_search_document_trgm_idx ON link_search USING gin (document gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS link_search_short_url_pattern_idx ON link_search (short_url varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS link_search_user_email_idx ON link_search (user_email);
+
+INSERT INTO link_search (short_url, user_email, long_url, domain, title, tags, created_at, document)
+SELECT l.short_url,
+       l.user_email,
+       l.long_url,
+       d.domain,
+       coalesce(l.title, ''),
+       t.tags,
+       l.created_at,
+       lower(concat_ws(' ', l.short_url, l.title, array_to_string(t.tags, ' '), d.domain, l.long_url))
+FROM urls l
+CROSS JOIN LATERAL (
+    SELECT coalesce(substring(lower(l.long_url) FROM '^[a-z][a-z0-9+.-]*://(?:www\.)?([^/:?#]+)'), '') AS domain
+) d
+CROSS JOIN LATERAL (
+    SELECT ARRAY(SELECT tg.name FROM url_tags ut JOIN tags tg ON tg.id = ut.tag_id WHERE ut.url_id = l.id ORDER BY tg.name)::varchar[] AS tags
+) t
+ON CONFLICT (short_url) DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE queue_messages ADD COLUMN headers jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_name_key;
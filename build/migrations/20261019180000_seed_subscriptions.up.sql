@@ -0,0 +1,7 @@
+-- раньше тарифы добавлялись при каждом запуске сервиса, оставляем по одному тарифу каждого названия
+DELETE FROM subscriptions s USING subscriptions d WHERE s.name = d.name AND s.id > d.id;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_name_key UNIQUE (name);
+
+INSERT INTO subscriptions (name, total_urls) VALUES ('Bronze', 1000), ('Silver', 5000), ('Gold', 10000)
+ON CONFLICT (name) DO NOTHING;
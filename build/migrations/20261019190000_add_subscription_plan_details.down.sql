@@ -0,0 +1,10 @@
+ALTER TABLE subscriptions
+    DROP COLUMN price,
+    DROP COLUMN currency,
+    DROP COLUMN billing_period,
+    DROP COLUMN max_expiry_days,
+    DROP COLUMN custom_domains,
+    DROP COLUMN analytics_retention_days,
+    DROP COLUMN api_rate_limit,
+    DROP COLUMN active,
+    DROP COLUMN display_order;
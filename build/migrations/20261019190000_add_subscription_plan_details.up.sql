@@ -0,0 +1,15 @@
+ALTER TABLE subscriptions
+    ADD COLUMN price bigint NOT NULL DEFAULT 0 CHECK (price >= 0),
+    ADD COLUMN currency text NOT NULL DEFAULT 'RUB',
+    ADD COLUMN billing_period text NOT NULL DEFAULT 'quarter' CHECK (billing_period IN ('month', 'quarter', 'year')),
+    ADD COLUMN max_expiry_days int NOT NULL DEFAULT 90,
+    ADD COLUMN custom_domains int NOT NULL DEFAULT 0,
+    ADD COLUMN analytics_retention_days int NOT NULL DEFAULT 30,
+    ADD COLUMN api_rate_limit int NOT NULL DEFAULT 0,
+    ADD COLUMN active boolean NOT NULL DEFAULT true,
+    ADD COLUMN display_order int NOT NULL DEFAULT 0;
+
+-- цены в копейках
+UPDATE subscriptions SET price = 19900, max_expiry_days = 180, analytics_retention_days = 30, api_rate_limit = 60, display_order = 1 WHERE name = 'Bronze';
+UPDATE subscriptions SET price = 49900, max_expiry_days = 365, custom_domains = 1, analytics_retention_days = 90, api_rate_limit = 300, display_order = 2 WHERE name = 'Silver';
+UPDATE subscriptions SET price = 99900, max_expiry_days = 730, custom_domains = 5, analytics_retention_days = 365, api_rate_limit = 1000, display_order = 3 WHERE name = 'Gold';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user_subscriptions;
+
+DELETE FROM subscriptions WHERE name = 'Free';
+
+ALTER TABLE subscriptions
+    DROP COLUMN trial_days,
+    DROP COLUMN quota_policy;
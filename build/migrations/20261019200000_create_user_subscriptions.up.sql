@@ -0,0 +1,28 @@
+ALTER TABLE subscriptions
+    ADD COLUMN trial_days int NOT NULL DEFAULT 0 CHECK (trial_days >= 0),
+    ADD COLUMN quota_policy text NOT NULL DEFAULT 'reset' CHECK (quota_policy IN ('reset', 'top_up'));
+
+-- бесплатный тариф: его получают новые пользователи и на него переводятся пользователи без оплаченного периода
+INSERT INTO subscriptions (name, total_urls, price, billing_period, max_expiry_days, analytics_retention_days, display_order)
+VALUES ('Free', 10, 0, 'month', 90, 7, 0)
+ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS user_subscriptions (
+    user_email varchar PRIMARY KEY REFERENCES users(email) ON DELETE CASCADE,
+    plan_id int NOT NULL REFERENCES subscriptions(id),
+    status text NOT NULL CHECK (status IN ('trial', 'active', 'past_due', 'cancelled')),
+    period_start timestamp NOT NULL,
+    period_end timestamp NOT NULL,
+    prepaid_periods int NOT NULL DEFAULT 0 CHECK (prepaid_periods >= 0),
+    trial_used boolean NOT NULL DEFAULT false,
+    updated_at timestamp NOT NULL DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX IF NOT EXISTS user_subscriptions_period_end_idx ON user_subscriptions(period_end);
+
+-- квоты существующих пользователей не меняются до первого продления
+INSERT INTO user_subscriptions (user_email, plan_id, status, period_start, period_end)
+SELECT u.email, s.id, 'active', timezone('utc', now()), timezone('utc', now()) + interval '1 month'
+FROM users u, subscriptions s
+WHERE s.name = 'Free'
+ON CONFLICT (user_email) DO NOTHING;
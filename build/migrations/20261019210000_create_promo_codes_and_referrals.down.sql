@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS referrals;
+
+DROP TABLE IF EXISTS email_verifications;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email_verified,
+    DROP COLUMN IF EXISTS referral_code;
+
+DROP TABLE IF EXISTS promo_redemptions;
+
+DROP TABLE IF EXISTS promo_codes;
+
+DROP TABLE IF EXISTS link_credits;
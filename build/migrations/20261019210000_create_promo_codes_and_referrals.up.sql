@@ -0,0 +1,52 @@
+-- журнал начислений ссылок: urls_left меняется только вместе с записью в нём
+CREATE TABLE IF NOT EXISTS link_credits (
+    id bigserial PRIMARY KEY,
+    user_email varchar NOT NULL REFERENCES users(email) ON DELETE CASCADE,
+    amount int NOT NULL,
+    reason text NOT NULL CHECK (reason IN ('admin', 'promo', 'referral')),
+    reference text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX IF NOT EXISTS link_credits_user_email_idx ON link_credits(user_email, id);
+
+CREATE TABLE IF NOT EXISTS promo_codes (
+    code varchar PRIMARY KEY,
+    kind text NOT NULL CHECK (kind IN ('link_credits', 'percent_off')),
+    link_credits int NOT NULL DEFAULT 0 CHECK (link_credits >= 0),
+    percent_off int NOT NULL DEFAULT 0 CHECK (percent_off BETWEEN 0 AND 100),
+    plan_id int REFERENCES subscriptions(id) ON DELETE CASCADE,
+    max_redemptions int NOT NULL DEFAULT 0 CHECK (max_redemptions >= 0),
+    redemptions int NOT NULL DEFAULT 0,
+    expires_at timestamp,
+    created_at timestamp NOT NULL DEFAULT timezone('utc', now())
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    code varchar NOT NULL REFERENCES promo_codes(code) ON DELETE CASCADE,
+    user_email varchar NOT NULL REFERENCES users(email) ON DELETE CASCADE,
+    redeemed_at timestamp NOT NULL DEFAULT timezone('utc', now()),
+    PRIMARY KEY (code, user_email)
+);
+
+-- пользователи, зарегистрированные до подтверждения почты, считаются подтверждёнными
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS referral_code varchar UNIQUE;
+
+UPDATE users SET email_verified = true;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    user_email varchar PRIMARY KEY REFERENCES users(email) ON DELETE CASCADE,
+    token_hash varchar NOT NULL UNIQUE,
+    expires_at timestamp NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS referrals (
+    invitee_email varchar PRIMARY KEY REFERENCES users(email) ON DELETE CASCADE,
+    referrer_email varchar NOT NULL REFERENCES users(email) ON DELETE CASCADE,
+    created_at timestamp NOT NULL DEFAULT timezone('utc', now()),
+    credited_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS referrals_referrer_email_idx ON referrals(referrer_email);
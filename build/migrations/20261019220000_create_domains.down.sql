@@ -0,0 +1,8 @@
+-- ключи ссылок своих доменов не имеют смысла без домена
+DELETE FROM link_search WHERE short_url IN (SELECT short_url FROM urls WHERE domain IS NOT NULL);
+
+DELETE FROM urls WHERE domain IS NOT NULL;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS domain;
+
+DROP TABLE IF EXISTS domains;
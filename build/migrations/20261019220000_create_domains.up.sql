@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS domains (
+    host varchar PRIMARY KEY,
+    user_email varchar NOT NULL REFERENCES users(email) ON DELETE CASCADE,
+    verification_token varchar NOT NULL,
+    verified_at timestamp,
+    -- куда перенаправлять корень домена и неизвестные ссылки, пустой - ответить 404
+    fallback_url varchar NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX IF NOT EXISTS domains_user_email_idx ON domains(user_email);
+
+-- ссылка на своём домене хранится под ключом host/alias, поэтому алиасы уникальны в пределах домена,
+-- а ссылки основного хоста остаются под алиасом
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS domain varchar REFERENCES domains(host);
+
+CREATE INDEX IF NOT EXISTS urls_domain_idx ON urls(domain);
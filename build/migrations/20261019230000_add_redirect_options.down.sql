@@ -0,0 +1,4 @@
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS forward_path,
+    DROP COLUMN IF EXISTS query_conflict,
+    DROP COLUMN IF EXISTS forward_query;
@@ -0,0 +1,7 @@
+-- forward_query передаёт параметры запроса посетителя в длинную ссылку, query_conflict решает,
+-- чьё значение остаётся при совпадении имён: keep - ссылки, override - посетителя, append - оба.
+-- forward_path превращает алиас в префикс: остаток пути дописывается к длинной ссылке
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS forward_query boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS query_conflict varchar NOT NULL DEFAULT 'keep',
+    ADD COLUMN IF NOT EXISTS forward_path boolean NOT NULL DEFAULT false;
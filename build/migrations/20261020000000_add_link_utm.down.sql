@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS urls_user_email_utm_campaign_idx;
+
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS utm_content,
+    DROP COLUMN IF EXISTS utm_term,
+    DROP COLUMN IF EXISTS utm_campaign,
+    DROP COLUMN IF EXISTS utm_medium,
+    DROP COLUMN IF EXISTS utm_source;
@@ -0,0 +1,10 @@
+-- метки кампании дописываются к длинной ссылке параметрами utm_* при переходе,
+-- по utm_campaign переходы группируются в отчёте по кампаниям
+ALTER TABLE urls
+    ADD COLUMN IF NOT EXISTS utm_source varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS utm_medium varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS utm_campaign varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS utm_term varchar NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS utm_content varchar NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS urls_user_email_utm_campaign_idx ON urls(user_email, utm_campaign) WHERE utm_campaign <> '';
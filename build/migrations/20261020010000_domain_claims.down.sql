@@ -0,0 +1,25 @@
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_domain_fkey;
+
+DROP INDEX IF EXISTS domains_verified_host_idx;
+
+-- у хоста остаётся одна заявка: подтверждённая, а без неё самая ранняя
+DELETE FROM domains d
+WHERE EXISTS (
+    SELECT 1 FROM domains o
+    WHERE o.host = d.host
+      AND (o.verified_at IS NOT NULL, d.created_at, d.user_email) > (d.verified_at IS NOT NULL, o.created_at, o.user_email)
+);
+
+DELETE FROM link_search WHERE short_url IN (
+    SELECT short_url FROM urls u
+    WHERE u.domain IS NOT NULL AND NOT EXISTS (SELECT 1 FROM domains d WHERE d.host = u.domain AND d.user_email = u.user_email)
+);
+
+DELETE FROM urls u
+WHERE u.domain IS NOT NULL AND NOT EXISTS (SELECT 1 FROM domains d WHERE d.host = u.domain AND d.user_email = u.user_email);
+
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_pkey;
+
+ALTER TABLE domains ADD PRIMARY KEY (host);
+
+ALTER TABLE urls ADD CONSTRAINT urls_domain_fkey FOREIGN KEY (domain) REFERENCES domains(host);
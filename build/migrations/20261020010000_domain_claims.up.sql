@@ -0,0 +1,12 @@
+-- неподтверждённый домен - только заявка: заявок на один хост может быть несколько, уникален лишь подтверждённый домен.
+-- При подтверждении чужие неподтверждённые заявки на хост удаляются
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_domain_fkey;
+
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_pkey;
+
+ALTER TABLE domains ADD PRIMARY KEY (host, user_email);
+
+CREATE UNIQUE INDEX IF NOT EXISTS domains_verified_host_idx ON domains(host) WHERE verified_at IS NOT NULL;
+
+-- ссылки на домене создаёт только его владелец
+ALTER TABLE urls ADD CONSTRAINT urls_domain_fkey FOREIGN KEY (domain, user_email) REFERENCES domains(host, user_email);
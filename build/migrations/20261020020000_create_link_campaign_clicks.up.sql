@@ -0,0 +1,8 @@
+-- переходы учитываются под кампанией, которая была у ссылки в момент перехода, поэтому смена метки
+-- не переносит прежние переходы в новую кампанию. Переходы до этой миграции по кампаниям не распределить
+CREATE TABLE IF NOT EXISTS link_campaign_clicks (
+    short_url varchar NOT NULL REFERENCES urls(short_url) ON DELETE CASCADE,
+    campaign varchar NOT NULL,
+    clicks bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (short_url, campaign)
+);
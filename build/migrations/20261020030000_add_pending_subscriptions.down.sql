@@ -0,0 +1,7 @@
+-- неоплаченные подписки ждут оплаты так же, как просроченные
+UPDATE user_subscriptions SET status = 'past_due' WHERE status = 'pending';
+
+ALTER TABLE user_subscriptions DROP CONSTRAINT IF EXISTS user_subscriptions_status_check;
+
+ALTER TABLE user_subscriptions ADD CONSTRAINT user_subscriptions_status_check
+    CHECK (status IN ('trial', 'active', 'past_due', 'cancelled'));
@@ -0,0 +1,5 @@
+-- pending - подписка на платный тариф, первый период которой ещё не оплачен
+ALTER TABLE user_subscriptions DROP CONSTRAINT IF EXISTS user_subscriptions_status_check;
+
+ALTER TABLE user_subscriptions ADD CONSTRAINT user_subscriptions_status_check
+    CHECK (status IN ('pending', 'trial', 'active', 'past_due', 'cancelled'));
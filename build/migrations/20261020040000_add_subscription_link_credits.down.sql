@@ -0,0 +1,6 @@
+DELETE FROM link_credits WHERE reason = 'subscription';
+
+ALTER TABLE link_credits DROP CONSTRAINT IF EXISTS link_credits_reason_check;
+
+ALTER TABLE link_credits ADD CONSTRAINT link_credits_reason_check
+    CHECK (reason IN ('admin', 'promo', 'referral'));
@@ -0,0 +1,5 @@
+-- квота тарифа тоже начисляется через журнал
+ALTER TABLE link_credits DROP CONSTRAINT IF EXISTS link_credits_reason_check;
+
+ALTER TABLE link_credits ADD CONSTRAINT link_credits_reason_check
+    CHECK (reason IN ('admin', 'promo', 'referral', 'subscription'));
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"urleater/internal/config"
+)
+
+// runConfig работает с конфигурацией сервиса.
+//
+//	urleater config print                         показать действующую конфигурацию с замаскированными секретами
+//	urleater config print -file config.yaml       то же с файлом вместо CONFIG_FILE
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: urleater config print [-file path]")
+	}
+
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+
+	file := flags.String("file", os.Getenv(config.ConfigFileEnv), "YAML or TOML config file, environment variables take precedence")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*file)
+
+	if err != nil {
+		return err
+	}
+
+	return cfg.Dump(os.Stdout)
+}
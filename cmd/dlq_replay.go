@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"urleater/internal/config"
+	kafkaProducerConsumer "urleater/internal/repository/kafka"
+	"urleater/internal/repository/pgqueue"
+)
+
+// runDLQReplay переотправляет сообщения из очереди недоставленных в исходные топики.
+//
+//	urleater dlq-replay             переотправить все сообщения
+//	urleater dlq-replay -limit 10   переотправить первые 10
+//	urleater dlq-replay -dry-run    только показать сообщения и причины ошибок
+func runDLQReplay(args []string) error {
+	flags := flag.NewFlagSet("dlq-replay", flag.ExitOnError)
+
+	limit := flags.Int("limit", 0, "number of messages to replay, 0 replays everything")
+	dryRun := flags.Bool("dry-run", false, "print messages without replaying or committing them")
+	idleTimeout := flags.Duration("idle-timeout", 5*time.Second, "stop after this long without new messages")
+
+	err := flags.Parse(args)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	cfg := config.ProvideConfig()
+
+	deadLetterTopic := cfg.Kafka.Consumer.DeadLetterTopic
+
+	var replayed int
+
+	switch cfg.Queue.Backend {
+	case config.QueueBackendMemory:
+		return errors.New("the memory queue keeps dead letters only inside the running process")
+
+	case config.QueueBackendPostgres:
+		postgresPool := providePool(ctx, cfg.PostgresURL(), cfg.Startup.Timeout)
+
+		defer postgresPool.Close()
+
+		replayed, err = pgqueue.New(postgresPool, nil).ReplayDeadLetters(ctx, deadLetterTopic, pgqueue.ReplayOptions{
+			Limit:  *limit,
+			DryRun: *dryRun,
+			Out:    os.Stdout,
+		})
+
+	default:
+		kafkaConfig := provideKafkaConfig(cfg)
+
+		var producer *kafkaProducerConsumer.Producer
+
+		producer, err = kafkaProducerConsumer.NewProducer(kafkaConfig)
+
+		if err != nil {
+			return err
+		}
+
+		defer producer.Close()
+
+		replayed, err = kafkaProducerConsumer.ReplayDeadLetters(ctx, kafkaConfig, deadLetterTopic, producer, kafkaProducerConsumer.ReplayOptions{
+			Limit:       *limit,
+			DryRun:      *dryRun,
+			IdleTimeout: *idleTimeout,
+			Out:         os.Stdout,
+		})
+	}
+
+	if *dryRun {
+		fmt.Printf("%d messages in %s\n", replayed, deadLetterTopic)
+	} else {
+		fmt.Printf("%d messages replayed from %s\n", replayed, deadLetterTopic)
+	}
+
+	return err
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"urleater/internal/config"
+	"urleater/internal/health"
+	"urleater/internal/service"
+)
+
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// provideHealthChecker собирает проверки для /readyz. Критичен только Postgres: без Redis переходы идут мимо кэша,
+// без Elasticsearch не работает поиск, без Kafka теряются только счётчики переходов, а outbox дошлёт события позже.
+// Поиск и очередь в Postgres или в памяти отдельно не проверяются.
+func provideHealthChecker(cfg *config.Config, pool *pgxpool.Pool, redisClient *redis.Client, searcher service.ElasticSearcher, producer service.Producer) *health.Checker {
+	checks := []health.Check{
+		{Name: "postgres", Critical: true, Ping: pool.Ping},
+	}
+
+	// кеш в памяти проверять не нужно
+	if redisClient != nil {
+		checks = append(checks, health.Check{Name: "redis", Ping: func(ctx context.Context) error { return redisClient.Ping(ctx).Err() }})
+	}
+
+	if searcher, ok := searcher.(pinger); ok && cfg.Search.Backend == config.SearchBackendElastic {
+		checks = append(checks, health.Check{Name: "elastic", Ping: searcher.Ping})
+	}
+
+	if producer, ok := producer.(pinger); ok && cfg.Queue.Backend == config.QueueBackendKafka {
+		checks = append(checks, health.Check{Name: "kafka", Ping: producer.Ping})
+	}
+
+	return health.NewChecker(cfg.Health.Timeout, checks...)
+}
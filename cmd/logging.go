@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"urleater/internal/config"
+	"urleater/internal/logging"
+)
+
+// provideLogger создаёт логгер из LOG_* и делает его стандартным, чтобы в него попадали и записи через пакет log.
+func provideLogger(cfg config.LogConfig) *slog.Logger {
+	logger, err := logging.New(logging.Config{
+		Level:     cfg.Level,
+		Format:    cfg.Format,
+		RedactPII: cfg.RedactPII,
+	}, os.Stdout)
+
+	if err != nil {
+		fatal("invalid log config", "error", err)
+	}
+
+	slog.SetDefault(logger)
+
+	return logger
+}
+
+// fatal пишет ошибку в стандартный логгер и завершает процесс, как log.Fatal.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+
+	os.Exit(1)
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/antonlindstrom/pgstore"
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"github.com/olivere/elastic/v7"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
-	"log"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -18,180 +21,304 @@ import (
 	"urleater/dto"
 	"urleater/internal/config"
 	"urleater/internal/handlers"
-	"urleater/internal/repository/elastic_searcher"
-	kafkaProducerConsumer "urleater/internal/repository/kafka"
+	"urleater/internal/mailer"
+	"urleater/internal/metrics"
+	"urleater/internal/repository/memstorage"
 	"urleater/internal/repository/postgresDB"
 	"urleater/internal/repository/redisDB"
 	"urleater/internal/service"
+	"urleater/internal/tracing"
 	"urleater/internal/validator"
 )
 
-const port = ":8080"
+func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
 
-func init() {
-	time.Sleep(10 * time.Second)
-}
+		return
+	}
 
-func main() {
 	serverCtx, serverCancel := context.WithCancel(context.Background())
 
 	cfg := config.ProvideConfig()
 
-	postgresPool := providePool(serverCtx, cfg.PostgresURL(), true)
-
-	// storage layer
-	postgresStorage := postgresDB.NewStorage(postgresPool)
+	logger := provideLogger(cfg.Log)
 
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.Redis.Host + ":" + cfg.Redis.Port,
+	shutdownTracing, err := tracing.Setup(serverCtx, tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
 	})
 
-	redisStorage := redisDB.NewStorage(redisClient)
-
-	elasticClient, err := elastic.NewClient(
-		elastic.SetURL(cfg.Elastic.Host),
-		elastic.SetSniff(false), // Отключаем Sniffing (важно!)
-	)
-
 	if err != nil {
-		log.Fatal(err)
+		fatal("error setting up tracing", "error", err)
 	}
 
-	elasticSearcher := elastic_searcher.NewSearcher(elasticClient, cfg.Elastic.Host)
+	postgresPool := providePool(serverCtx, cfg.PostgresURL(), cfg.Startup.Timeout)
 
-	configMap := &kafka.ConfigMap{
-		"bootstrap.servers":     cfg.Kafka.Address,
-		"group.id":              cfg.Kafka.Consumer.GroupId,
-		"auto.offset.reset":     "earliest",
-		"session.timeout.ms":    10000, // 10 секунд
-		"heartbeat.interval.ms": 3000,  // 3 секунды
+	if cfg.Migrations.OnStartup {
+		if err = migrateUp(serverCtx, postgresPool, logger); err != nil {
+			fatal("error applying migrations", "error", err)
+		}
 	}
 
-	kafkaConfig := kafkaProducerConsumer.KafkaConfig{
-		KafkaConfig:  configMap,
-		KafkaTopics:  []string{cfg.Kafka.Consumer.Topic},
-		KafkaGroupId: cfg.Kafka.Consumer.GroupId,
-		KafkaServer:  cfg.Kafka.Address,
-	}
+	// storage layer
+	postgresStorage := postgresDB.NewStorage(postgresPool).WithLinkTTL(cfg.Links.TTL)
+
+	redisClient, cache := provideCache(serverCtx, cfg)
+
+	searcher := provideSearcher(serverCtx, cfg, postgresPool)
+
+	workerChannel := make(chan dto.ConsumerMessage, 100000)
+
+	metrics.RegisterWorkerChannel(workerChannel)
+
+	producer, consumers, closeQueue := provideQueue(serverCtx, cfg, postgresPool, workerChannel, logger)
 
-	admin, err := kafka.NewAdminClient(configMap)
+	// service layer
+	srv := service.New(postgresStorage, cache, producer, consumers, searcher, cfg.Kafka.Producer.Topic, logger, service.LinkRules{
+		AliasMinLength: cfg.Links.AliasMinLength,
+		AliasMaxLength: cfg.Links.AliasMaxLength,
+		ReservedNames:  cfg.Links.ReservedNames,
+		MaxPageSize:    cfg.Links.MaxPageSize,
+		SearchLimit:    cfg.Search.ResultsLimit,
+	}).WithBilling(service.BillingRules{
+		FreePlan:      cfg.Billing.FreePlan,
+		GracePeriod:   cfg.Billing.GracePeriod,
+		CheckInterval: cfg.Billing.CheckInterval,
+	}).WithCredits(service.CreditRules{
+		ReferralCredits: cfg.Credits.ReferralCredits,
+		VerificationTTL: cfg.Credits.VerificationTTL,
+		Mailer:          provideMailer(cfg.Mail, logger),
+		BaseUrl:         cfg.Mail.BaseURL,
+	}).WithDomains(service.DomainRules{
+		PrimaryHosts: cfg.Domains.PrimaryHosts,
+		Resolver:     provideDNSResolver(cfg.Domains.DNSServer),
+	}).WithQR(service.QRRules{
+		DefaultSize: cfg.QR.DefaultSize,
+		MaxSize:     cfg.QR.MaxSize,
+		CacheTTL:    cfg.QR.CacheTTL,
+		Logo:        provideQRLogo(cfg.QR.LogoPath),
+	}).WithAdmins(service.AdminRules{
+		Emails: cfg.Admin.Emails,
+	})
+
+	store, err := pgstore.NewPGStore(cfg.PostgresURL(), []byte(cfg.Session.Secret))
+
+	sessionStore := handlers.NewPostgresSessionStore(store)
 
 	if err != nil {
-		log.Fatalf("Failed to create Admin client: %v", err)
+		fatal("error creating session store", "error", err)
 	}
 
-	defer admin.Close()
+	cleanupQuit, cleanupDone := store.Cleanup(time.Minute * 5)
 
-	// Задаем спецификацию топика, который нужно создать.
-	topicSpec := kafka.TopicSpecification{
-		Topic:             cfg.Kafka.Consumer.Topic,
-		NumPartitions:     cfg.Kafka.NumberOfPartitions,
-		ReplicationFactor: cfg.Kafka.ReplicationFactor,
-	}
+	// handlers layer
+	healthChecker := provideHealthChecker(cfg, postgresPool, redisClient, searcher, producer)
+
+	e := handlers.GetRoutes(&handlers.Handlers{Service: srv, Store: sessionStore, Health: healthChecker, Logger: logger})
+
+	srv.StartConsumers(serverCtx)
+
+	srv.StartConsumingWorkers(serverCtx, service.WorkersConfig{
+		Number:          cfg.ConsumingWorkersNumber,
+		MaxAttempts:     cfg.Kafka.Consumer.MaxProcessAttempts,
+		DeadLetterTopic: cfg.Kafka.Consumer.DeadLetterTopic,
+	}, workerChannel)
+
+	srv.StartOutboxRelay(serverCtx)
 
-	fmt.Println(cfg.Kafka.Consumer.Topic, cfg.Kafka.Producer.Topic)
+	srv.StartSubscriptionScheduler(serverCtx)
 
-	// Создаем топик. Если топик уже существует, ошибка будет проигнорирована.
-	results, err := admin.CreateTopics(serverCtx, []kafka.TopicSpecification{topicSpec})
+	httpValidator, err := validator.NewValidator()
 
 	if err != nil {
-		log.Fatalf("Error creating topics: %v", err)
+		panic(err)
 	}
 
-	for _, result := range results {
-		if result.Error.Code() != kafka.ErrNoError && result.Error.Code() != kafka.ErrTopicAlreadyExists {
-			log.Fatalf("Failed to create topic %s: %v", result.Topic, result.Error)
-		} else {
-			log.Printf("Topic %s created or already exists", result.Topic)
+	e.Validator = httpValidator
+
+	go func() {
+		if err := e.Start(cfg.HTTP.Address); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("could not start server", "error", err)
 		}
-	}
+	}()
+
+	quit := make(chan os.Signal, 1)
+
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+
+	<-quit
+
+	logger.Info("shutting down server")
 
-	workerChannel := make(chan dto.ConsumerData, 100000)
+	shutdown(cfg.Shutdown, e.Shutdown, srv.Shutdown)
 
-	consumers := make([]service.Consumer, 0, cfg.Kafka.Consumer.NumberOfConsumers)
+	// дописываем в очередь сообщения, которые ещё не доставлены, в том числе отправленные воркерами в DLQ
+	closeQueue()
 
-	for i := 0; i < cfg.Kafka.Consumer.NumberOfConsumers; i++ {
-		consumer, err := kafkaProducerConsumer.NewConsumer(kafkaConfig, workerChannel)
+	store.StopCleanup(cleanupQuit, cleanupDone)
+	store.Close()
 
-		if err != nil {
-			log.Fatalf("Failed to create consumer: %v", err)
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			logger.Error("error closing redis client", "error", err)
 		}
+	}
 
-		consumers = append(consumers, consumer)
+	postgresPool.Close()
+
+	// дописываем накопленные спаны, в том числе спаны остановки
+	tracingCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.QueueTimeout)
+
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logger.Error("error flushing spans", "error", err)
 	}
 
-	// Создаем продюсера.
-	producer, err := kafkaProducerConsumer.NewProducer(kafkaConfig)
+	cancel()
 
-	if err != nil {
-		log.Fatalf("Failed to create producer: %v", err)
+	serverCancel()
+}
+
+// shutdown перестаёт принимать HTTP-запросы, дожидается уже принятых, а затем останавливает фоновую обработку.
+func shutdown(cfg config.ShutdownConfig, stopHTTP, stopService func(ctx context.Context) error) {
+	httpCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPTimeout)
+
+	if err := stopHTTP(httpCtx); err != nil {
+		slog.Error("error shutting down HTTP server", "error", err)
 	}
 
-	// service layer
-	srv := service.New(postgresStorage, redisStorage, producer, consumers, elasticSearcher, cfg.Kafka.Producer.Topic)
+	cancel()
 
-	store, err := pgstore.NewPGStore(cfg.PostgresURL(), []byte("secret-key")) // TODO make env for secret key
+	workersCtx, cancel := context.WithTimeout(context.Background(), cfg.WorkersTimeout)
 
-	sessionStore := handlers.NewPostgresSessionStore(store)
+	defer cancel()
 
+	if err := stopService(workersCtx); err != nil {
+		slog.Error("error stopping background processing", "error", err)
+	}
+}
+
+// runCommand выполняет административную команду вместо запуска сервера.
+func runCommand(name string, args []string) {
+	var err error
+
+	switch name {
+	case "reindex":
+		err = runReindex(args)
+	case "dlq-replay":
+		err = runDLQReplay(args)
+	case "config":
+		err = runConfig(args)
+	case "migrate":
+		err = runMigrate(args)
+	default:
+		err = fmt.Errorf("unknown command %q, available commands: reindex, dlq-replay, config, migrate", name)
+	}
+
+	// ошибки команд адресованы человеку, поэтому печатаются как есть, без формата логов
 	if err != nil {
-		log.Fatal(err.Error())
+		fmt.Fprintln(os.Stderr, err)
+
+		os.Exit(1)
 	}
+}
 
-	defer store.Close()
+// provideCache подключается к Redis или создаёт кеш в памяти. Клиент Redis возвращается для проверки
+// готовности и закрытия при остановке, для кеша в памяти он nil.
+func provideCache(ctx context.Context, cfg *config.Config) (*redis.Client, service.RedisStorage) {
+	if cfg.Cache.Backend == config.CacheBackendMemory {
+		return nil, memstorage.NewCache()
+	}
 
-	defer store.StopCleanup(store.Cleanup(time.Minute * 5))
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: cfg.Redis.Host + ":" + cfg.Redis.Port,
+	})
 
-	// handlers layer
-	e := handlers.GetRoutes(&handlers.Handlers{Service: srv, Store: sessionStore})
+	if err := redisotel.InstrumentTracing(redisClient); err != nil {
+		fatal("error instrumenting redis client", "error", err)
+	}
 
-	err = srv.CreateSubscriptions(serverCtx)
+	err := connectWithRetry(ctx, "redis", cfg.Startup.Timeout, func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
 
 	if err != nil {
-		log.Println(err.Error())
+		fatal("redis is unavailable", "error", err)
 	}
 
-	srv.StartConsumers(serverCtx)
+	return redisClient, redisDB.NewStorage(redisClient)
+}
 
-	srv.StartConsumingWorkers(serverCtx, cfg.ConsumingWorkersNumber, workerChannel)
+// provideDNSResolver возвращает резолвер для проверки TXT-записей своих доменов: системный
+// или обращающийся только к DNS-серверу server.
+func provideDNSResolver(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
 
-	httpValidator, err := validator.NewValidator()
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
 
-	if err != nil {
-		panic(err)
+			return dialer.DialContext(ctx, network, server)
+		},
 	}
+}
 
-	e.Validator = httpValidator
+// provideMailer возвращает отправку писем через SMTP. Без адреса сервера писем нет.
+func provideMailer(cfg config.MailConfig, logger *slog.Logger) service.Mailer {
+	if cfg.SMTPAddress == "" {
+		logger.Warn("SMTP_ADDRESS is not set: verification emails are not sent and referrals credit no links")
 
-	go func() {
-		if err := e.Start(port); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("could not start server: %v", err)
-		}
-	}()
+		return nil
+	}
 
-	quit := make(chan os.Signal, 1)
+	return mailer.NewSMTP(cfg.SMTPAddress, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+}
 
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+// provideQRLogo читает логотип для QR-кодов. Без пути логотипа нет.
+func provideQRLogo(path string) image.Image {
+	if path == "" {
+		return nil
+	}
 
-	<-quit
+	file, err := os.Open(path)
+
+	if err != nil {
+		fatal("error opening qr logo", "error", err)
+	}
 
-	fmt.Println("Shutting down server...")
+	defer file.Close()
 
-	serverCancel()
+	logo, _, err := image.Decode(file)
+
+	if err != nil {
+		fatal("error decoding qr logo", "path", path, "error", err)
+	}
+
+	return logo
 }
 
-func providePool(ctx context.Context, url string, lazy bool) *pgxpool.Pool {
+// providePool создаёт пул без подключения и ждёт, пока Postgres начнёт отвечать.
+func providePool(ctx context.Context, url string, timeout time.Duration) *pgxpool.Pool {
 	poolConfig, err := pgxpool.ParseConfig(url)
 
 	if err != nil {
-		log.Fatal("Unable to parse DB config because " + err.Error())
+		fatal("unable to parse postgres config", "error", err)
 	}
 
-	poolConfig.LazyConnect = lazy
+	poolConfig.LazyConnect = true
 
 	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
 	if err != nil {
-		log.Fatal("Unable to establish connection to " + url + " because " + err.Error())
+		fatal("unable to create postgres pool", "error", err)
+	}
+
+	if err = connectWithRetry(ctx, "postgres", timeout, pool.Ping); err != nil {
+		fatal("postgres is unavailable", "error", err)
 	}
 
 	return pool
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"urleater/build/migrations"
+	"urleater/internal/config"
+	"urleater/internal/migrate"
+)
+
+// runMigrate применяет встроенные миграции.
+//
+//	urleater migrate up              применить все новые миграции
+//	urleater migrate down -steps 1   откатить последние миграции
+//	urleater migrate status          показать применённые и ожидающие миграции
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: urleater migrate up|down|status")
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	cfg := config.ProvideConfig()
+
+	postgresPool := providePool(ctx, cfg.PostgresURL(), cfg.Startup.Timeout)
+
+	defer postgresPool.Close()
+
+	migrator, err := migrate.New(postgresPool, migrations.FS, nil)
+
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d migrations applied\n", applied)
+
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("-steps must be positive")
+		}
+
+		reverted, err := migrator.Down(ctx, *steps)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d migrations reverted\n", reverted)
+
+	case "status":
+		status, err := migrator.Status(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, m := range status.Migrations {
+			state := "pending"
+
+			if m.Applied {
+				state = "applied"
+			}
+
+			fmt.Printf("%-8s %d_%s\n", state, m.Version, m.Name)
+		}
+
+		fmt.Printf("version %d, %d pending", status.Version, status.Pending())
+
+		if status.Dirty {
+			fmt.Print(", dirty")
+		}
+
+		fmt.Println()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q, use up, down or status", args[0])
+	}
+
+	return nil
+}
+
+// migrateUp применяет новые миграции при запуске сервиса. Экземпляры, запущенные одновременно,
+// ждут друг друга на advisory lock, поэтому миграции применяются один раз.
+func migrateUp(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) error {
+	migrator, err := migrate.New(pool, migrations.FS, logger)
+
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	logger.InfoContext(ctx, "database schema is up to date", "applied", applied)
+
+	return nil
+}
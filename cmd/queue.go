@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log/slog"
+	"time"
+	"urleater/dto"
+	"urleater/internal/config"
+	kafkaProducerConsumer "urleater/internal/repository/kafka"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/pgqueue"
+	"urleater/internal/service"
+)
+
+// provideQueue создаёт продюсера и консьюмеров выбранной в QUEUE_BACKEND очереди.
+// Возвращаемая функция дописывает неотправленные сообщения и закрывает очередь.
+func provideQueue(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool, workerChannel chan dto.ConsumerMessage, logger *slog.Logger) (service.Producer, []service.Consumer, func()) {
+	consumers := make([]service.Consumer, 0, cfg.Kafka.Consumer.NumberOfConsumers)
+
+	switch cfg.Queue.Backend {
+	case config.QueueBackendMemory:
+		queue := memqueue.New(10000, logger)
+
+		for i := 0; i < cfg.Kafka.Consumer.NumberOfConsumers; i++ {
+			consumers = append(consumers, queue.NewConsumer(cfg.Kafka.Consumer.Topic, workerChannel))
+		}
+
+		return queue, consumers, func() {}
+
+	case config.QueueBackendPostgres:
+		queue := pgqueue.New(pool, logger)
+
+		for i := 0; i < cfg.Kafka.Consumer.NumberOfConsumers; i++ {
+			consumers = append(consumers, queue.NewConsumer(cfg.Kafka.Consumer.Topic, workerChannel))
+		}
+
+		return queue, consumers, func() {}
+	}
+
+	kafkaConfig := provideKafkaConfig(cfg)
+
+	kafkaConfig.Logger = logger
+
+	createKafkaTopics(ctx, cfg, kafkaConfig)
+
+	for i := 0; i < cfg.Kafka.Consumer.NumberOfConsumers; i++ {
+		consumer, err := kafkaProducerConsumer.NewConsumer(kafkaConfig, workerChannel)
+
+		if err != nil {
+			fatal("failed to create kafka consumer", "error", err)
+		}
+
+		consumers = append(consumers, consumer)
+	}
+
+	// Создаем продюсера.
+	producer, err := kafkaProducerConsumer.NewProducer(kafkaConfig)
+
+	if err != nil {
+		fatal("failed to create kafka producer", "error", err)
+	}
+
+	return producer, consumers, producer.Close
+}
+
+func provideKafkaConfig(cfg *config.Config) kafkaProducerConsumer.KafkaConfig {
+	configMap := &kafka.ConfigMap{
+		"bootstrap.servers":     cfg.Kafka.Address,
+		"group.id":              cfg.Kafka.Consumer.GroupId,
+		"auto.offset.reset":     "earliest",
+		"session.timeout.ms":    int(cfg.Kafka.SessionTimeout.Milliseconds()),
+		"heartbeat.interval.ms": int(cfg.Kafka.HeartbeatInterval.Milliseconds()),
+	}
+
+	return kafkaProducerConsumer.KafkaConfig{
+		KafkaConfig:  configMap,
+		KafkaTopics:  []string{cfg.Kafka.Consumer.Topic},
+		KafkaGroupId: cfg.Kafka.Consumer.GroupId,
+		KafkaServer:  cfg.Kafka.Address,
+		FlushTimeout: cfg.Shutdown.QueueTimeout,
+	}
+}
+
+func createKafkaTopics(ctx context.Context, cfg *config.Config, kafkaConfig kafkaProducerConsumer.KafkaConfig) {
+	admin, err := kafka.NewAdminClient(kafkaConfig.KafkaConfig)
+
+	if err != nil {
+		fatal("failed to create kafka admin client", "error", err)
+	}
+
+	defer admin.Close()
+
+	// Задаем спецификацию топиков, которые нужно создать.
+	topicSpecs := []kafka.TopicSpecification{
+		{
+			Topic:             cfg.Kafka.Consumer.Topic,
+			NumPartitions:     cfg.Kafka.NumberOfPartitions,
+			ReplicationFactor: cfg.Kafka.ReplicationFactor,
+		},
+		{
+			Topic:             cfg.Kafka.Consumer.DeadLetterTopic,
+			NumPartitions:     cfg.Kafka.NumberOfPartitions,
+			ReplicationFactor: cfg.Kafka.ReplicationFactor,
+		},
+	}
+
+	var results []kafka.TopicResult
+
+	// Создаем топики. Если топик уже существует, ошибка будет проигнорирована.
+	// Пока брокер не поднялся, CreateTopics возвращает ошибку, поэтому повторяем.
+	err = connectWithRetry(ctx, "kafka", cfg.Startup.Timeout, func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+
+		defer cancel()
+
+		results, err = admin.CreateTopics(attemptCtx, topicSpecs)
+
+		return err
+	})
+
+	if err != nil {
+		fatal("error creating kafka topics", "error", err)
+	}
+
+	for _, result := range results {
+		if result.Error.Code() != kafka.ErrNoError && result.Error.Code() != kafka.ErrTopicAlreadyExists {
+			fatal("failed to create kafka topic", "topic", result.Topic, "error", result.Error)
+		} else {
+			slog.Info("kafka topic created or already exists", "topic", result.Topic)
+		}
+	}
+}
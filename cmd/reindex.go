@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/olivere/elastic/v7"
+	"os"
+	"os/signal"
+	"syscall"
+	"urleater/internal/config"
+	"urleater/internal/reindex"
+	"urleater/internal/repository/elastic_searcher"
+	"urleater/internal/repository/postgresDB"
+)
+
+// runReindex заполняет поисковый индекс ссылками из Postgres.
+//
+//	urleater reindex -mode backfill   дозаписать ссылки в текущий индекс
+//	urleater reindex -mode rebuild    собрать новый индекс и переключить на него алиас
+//	urleater reindex -dry-run         только показать расхождения индекса с Postgres
+func runReindex(args []string) error {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+
+	mode := flags.String("mode", "backfill", "backfill or rebuild")
+	batchSize := flags.Int("batch-size", reindex.DefaultBatchSize, "number of links read from Postgres and indexed per request")
+	dryRun := flags.Bool("dry-run", false, "compare the index with Postgres without changing it")
+	verbose := flags.Bool("v", false, "print every inconsistent short link in dry-run mode")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *mode != "backfill" && *mode != "rebuild" {
+		return fmt.Errorf("unknown reindex mode %q", *mode)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	defer cancel()
+
+	cfg := config.ProvideConfig()
+
+	if cfg.Search.Backend != config.SearchBackendElastic {
+		return fmt.Errorf("reindex is only needed for the elastic search backend, link_search is filled by migrations and the outbox relay")
+	}
+
+	postgresPool := providePool(ctx, cfg.PostgresURL(), cfg.Startup.Timeout)
+
+	defer postgresPool.Close()
+
+	elasticClient, err := provideElasticClient(ctx, cfg)
+
+	if err != nil {
+		return err
+	}
+
+	searcher := elastic_searcher.NewSearcher(elasticClient, cfg.Elastic.Host)
+
+	reindexer := reindex.New(postgresDB.NewStorage(postgresPool), searcher, *batchSize, os.Stdout)
+
+	if *dryRun {
+		return printIndexDiff(ctx, searcher, reindexer, *verbose)
+	}
+
+	if *mode == "backfill" {
+		status, err := searcher.EnsureIndex(ctx)
+
+		if errors.Is(err, elastic_searcher.ErrLegacyIndex) {
+			return fmt.Errorf("%w: use -mode rebuild", err)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if status.Outdated() {
+			fmt.Printf("warning: index %s has mapping version %d, current is %d, consider -mode rebuild\n",
+				status.Index, status.MappingVersion, elastic_searcher.MappingVersion)
+		}
+
+		total, err := reindexer.Backfill(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("backfill done, %d links indexed into %s\n", total, status.Index)
+
+		return nil
+	}
+
+	total, err := reindexer.Rebuild(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("rebuild done, %d links indexed\n", total)
+
+	return nil
+}
+
+func printIndexDiff(ctx context.Context, searcher *elastic_searcher.Searcher, reindexer *reindex.Reindexer, verbose bool) error {
+	status, err := searcher.GetIndexStatus(ctx)
+
+	switch {
+	case errors.Is(err, elastic_searcher.ErrLegacyIndex):
+		fmt.Println("index short_links was created with dynamic mapping and must be rebuilt")
+	case elastic.IsNotFound(err):
+		return errors.New("index short_links does not exist, run reindex without -dry-run")
+	case err != nil:
+		return err
+	default:
+		fmt.Printf("alias short_links -> %s, mapping version %d, current %d\n",
+			status.Index, status.MappingVersion, elastic_searcher.MappingVersion)
+	}
+
+	diff, err := reindexer.Diff(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("postgres: %d links, index: %d documents\n", diff.Total, diff.Indexed)
+	fmt.Printf("missing: %d, stale: %d, orphaned: %d\n", len(diff.Missing), len(diff.Stale), len(diff.Orphaned))
+
+	if verbose {
+		for _, shortLink := range diff.Missing {
+			fmt.Println("missing", shortLink)
+		}
+
+		for _, shortLink := range diff.Stale {
+			fmt.Println("stale", shortLink)
+		}
+
+		for _, shortLink := range diff.Orphaned {
+			fmt.Println("orphaned", shortLink)
+		}
+	}
+
+	if diff.Consistent() {
+		fmt.Println("index is consistent with postgres")
+	}
+
+	return nil
+}
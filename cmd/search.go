@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/olivere/elastic/v7"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"log/slog"
+	"net/http"
+	"urleater/internal/config"
+	"urleater/internal/repository/elastic_searcher"
+	"urleater/internal/repository/pgsearch"
+	"urleater/internal/service"
+)
+
+// provideSearcher создаёт поиск по ссылкам, выбранный в SEARCH_BACKEND.
+func provideSearcher(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool) service.ElasticSearcher {
+	if cfg.Search.Backend == config.SearchBackendPostgres {
+		return pgsearch.NewSearcher(pool)
+	}
+
+	elasticClient, err := provideElasticClient(ctx, cfg)
+
+	if err != nil {
+		fatal("elasticsearch is unavailable", "error", err)
+	}
+
+	elasticSearcher := elastic_searcher.NewSearcher(elasticClient, cfg.Elastic.Host)
+
+	indexStatus, err := elasticSearcher.EnsureIndex(ctx)
+
+	switch {
+	case err != nil:
+		slog.Error("error ensuring search index", "error", err)
+	case indexStatus.Outdated():
+		slog.Warn("search index mapping is outdated, run the reindex command with -mode rebuild",
+			"index", indexStatus.Index, "mapping_version", indexStatus.MappingVersion, "current_version", elastic_searcher.MappingVersion)
+	}
+
+	return elasticSearcher
+}
+
+// provideElasticClient подключается к Elasticsearch, повторяя попытки, пока кластер не ответит.
+func provideElasticClient(ctx context.Context, cfg *config.Config) (*elastic.Client, error) {
+	var elasticClient *elastic.Client
+
+	err := connectWithRetry(ctx, "elasticsearch", cfg.Startup.Timeout, func(ctx context.Context) error {
+		client, err := elastic.DialContext(ctx,
+			elastic.SetURL(cfg.Elastic.Host),
+			elastic.SetSniff(false), // Отключаем Sniffing (важно!)
+			// каждый запрос к Elasticsearch становится спаном внутри трейса, переданного в Do(ctx)
+			elastic.SetHttpClient(&http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}),
+		)
+
+		elasticClient = client
+
+		return err
+	})
+
+	return elasticClient, err
+}
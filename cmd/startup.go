@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	connectMinBackoff = 500 * time.Millisecond
+	connectMaxBackoff = 10 * time.Second
+)
+
+// connectWithRetry повторяет connect с растущей паузой, пока зависимость не ответит или не истечёт timeout.
+// Заменяет фиксированное ожидание при запуске: сервис стартует, как только готовы все зависимости.
+func connectWithRetry(ctx context.Context, name string, timeout time.Duration, connect func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	defer cancel()
+
+	backoff := connectMinBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+
+		if err == nil {
+			if attempt > 1 {
+				slog.InfoContext(ctx, "connected", "dependency", name, "attempts", attempt)
+			}
+
+			return nil
+		}
+
+		slog.WarnContext(ctx, "dependency is not ready", "dependency", name, "attempt", attempt, "error", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("could not connect to %s in %s: %w", name, timeout, err)
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+}
@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventLinkViewed     EventType = "link_viewed"
+	EventLinkCreated    EventType = "link_created"
+	EventLinkUpdated    EventType = "link_updated"
+	EventLinkDeleted    EventType = "link_deleted"
+	EventLinkExpired    EventType = "link_expired"
+	EventUserRegistered EventType = "user_registered"
+)
+
+// EventVersions - текущие версии схем событий. Новая версия допускает только добавление полей:
+// старые потребители читают её как свою версию. Несовместимое изменение требует нового типа события.
+var EventVersions = map[EventType]int{
+	EventLinkViewed:     2,
+	EventLinkCreated:    1,
+	EventLinkUpdated:    1,
+	EventLinkDeleted:    1,
+	EventLinkExpired:    1,
+	EventUserRegistered: 1,
+}
+
+// Event - конверт события в топике Kafka. Data содержит структуру, соответствующую Type.
+type Event struct {
+	Id         string    `json:"id"`
+	Type       EventType `json:"type"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Producer   string    `json:"producer"`
+	// RequestId - идентификатор HTTP-запроса, вызвавшего событие, пустой для фоновых событий.
+	RequestId string          `json:"request_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type LinkViewed struct {
+	ShortLink string `json:"short_link"`
+	// Campaign - utm_campaign ссылки в момент перехода, появилась во второй версии.
+	Campaign string `json:"campaign,omitempty"`
+}
+
+type LinkCreated struct {
+	ShortLink string `json:"short_link"`
+	LongLink  string `json:"long_link"`
+	UserEmail string `json:"user_email"`
+}
+
+type LinkUpdated struct {
+	ShortLink string `json:"short_link"`
+	UserEmail string `json:"user_email"`
+}
+
+type LinkDeleted struct {
+	ShortLink string `json:"short_link"`
+	UserEmail string `json:"user_email"`
+}
+
+type LinkExpired struct {
+	ShortLink string `json:"short_link"`
+}
+
+type UserRegistered struct {
+	Email string `json:"email"`
+}
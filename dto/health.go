@@ -0,0 +1,23 @@
+package dto
+
+type HealthStatus string
+
+const (
+	HealthStatusOK HealthStatus = "ok"
+	// HealthStatusDegraded - некритичная зависимость недоступна, но сервис может обслуживать переходы по ссылкам.
+	HealthStatusDegraded HealthStatus = "degraded"
+	HealthStatusDown     HealthStatus = "down"
+)
+
+type DependencyHealth struct {
+	Status    HealthStatus `json:"status"`
+	Critical  bool         `json:"critical"`
+	LatencyMs int64        `json:"latency_ms"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// HealthReport - результат проверки зависимостей. Status равен down, только если недоступна критичная зависимость.
+type HealthReport struct {
+	Status       HealthStatus                `json:"status"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
@@ -1,6 +1,20 @@
 package dto
 
-type ConsumerData struct {
-	TypeOfMessage string            `json:"type"`
-	Data          map[string]string `json:"data"`
+// ConsumerMessage - прочитанное из Kafka сообщение. Смещение сообщения коммитится только после вызова Ack.
+type ConsumerMessage struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	// Headers - заголовки сообщения, в том числе контекст трассировки отправителя.
+	Headers map[string]string
+	Ack     func()
+}
+
+// DeadLetter - сообщение, которое не удалось обработать за отведённое число попыток.
+type DeadLetter struct {
+	Message  ConsumerMessage
+	Error    string
+	Attempts int
 }
@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent - событие, записанное в Postgres в одной транзакции с изменением, которое оно описывает.
+// Key - ключ партиционирования: события с одним ключом обрабатываются и публикуются по порядку.
+type OutboxEvent struct {
+	Id        int64
+	EventId   string
+	Type      EventType
+	Version   int
+	Key       string
+	Payload   json.RawMessage
+	Attempts  int
+	RequestId string
+	CreatedAt time.Time
+}
@@ -1,23 +1,317 @@
 package dto
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+)
 
 type User struct {
-	Email        string
-	PasswordHash string
-	UrlsLeft     int
+	Email         string
+	PasswordHash  string
+	UrlsLeft      int
+	EmailVerified bool
 }
 
 type Link struct {
-	ShortUrl     string
+	// ShortUrl - ключ ссылки: алиас на основном хосте или host/alias на своём домене, см. DomainShortUrl.
+	ShortUrl string
+	// Domain - свой домен ссылки, пустой для основного хоста.
+	Domain string
+	RedirectOptions
+	// UTM - метки кампании, пустые поля не дописываются.
+	UTM          UTM
 	LongUrl      string
 	UserEmail    string
 	ExpiresAt    time.Time
 	TimesVisited int
+	CreatedAt    time.Time
+	Title        string
+	Description  string
+	Tags         []string
 }
 
+// Политики совпадения параметров запроса посетителя и длинной ссылки.
+const (
+	// QueryConflictKeep оставляет значение длинной ссылки.
+	QueryConflictKeep = "keep"
+	// QueryConflictOverride заменяет его значением посетителя.
+	QueryConflictOverride = "override"
+	// QueryConflictAppend оставляет оба значения.
+	QueryConflictAppend = "append"
+)
+
+// RedirectOptions - что из запроса посетителя переносится в длинную ссылку при переходе.
+type RedirectOptions struct {
+	// ForwardQuery передаёт параметры запроса, QueryConflict решает, чьё значение остаётся при совпадении имён.
+	ForwardQuery  bool
+	QueryConflict string
+	// ForwardPath делает алиас префиксом: /alias/some/path ведёт на длинную ссылку с дописанным /some/path.
+	ForwardPath bool
+}
+
+// UTM - метки кампании ссылки. При переходе они дописываются к длинной ссылке параметрами utm_*.
+type UTM struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// utmParams - имена параметров запроса для полей UTM.
+var utmParams = []string{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content"}
+
+func (u *UTM) fields() []*string {
+	return []*string{&u.Source, &u.Medium, &u.Campaign, &u.Term, &u.Content}
+}
+
+// Params возвращает непустые метки как параметры запроса utm_*.
+func (u UTM) Params() url.Values {
+	params := url.Values{}
+
+	for i, value := range u.fields() {
+		if *value != "" {
+			params.Set(utmParams[i], *value)
+		}
+	}
+
+	return params
+}
+
+// UTMFromParams собирает метки из параметров utm_*, остальные параметры не учитываются.
+func UTMFromParams(params url.Values) UTM {
+	var u UTM
+
+	for i, value := range u.fields() {
+		*value = params.Get(utmParams[i])
+	}
+
+	return u
+}
+
+// CampaignStats - ссылки пользователя с одной меткой utm_campaign и переходы по ним.
+type CampaignStats struct {
+	Campaign string
+	Links    int
+	Clicks   int
+}
+
+type BillingPeriod string
+
+const (
+	BillingPeriodMonth   BillingPeriod = "month"
+	BillingPeriodQuarter BillingPeriod = "quarter"
+	BillingPeriodYear    BillingPeriod = "year"
+)
+
+// PlanFeatures - возможности тарифа. Нулевое значение означает, что возможность тарифом не даётся.
+type PlanFeatures struct {
+	// MaxExpiryDays - на сколько дней можно продлить ссылку.
+	MaxExpiryDays          int
+	CustomDomains          int
+	AnalyticsRetentionDays int
+	// APIRateLimit - запросов к API в минуту.
+	APIRateLimit int
+}
+
+// QuotaPolicy определяет, что происходит с квотой ссылок в начале каждого периода тарифа.
+type QuotaPolicy string
+
+const (
+	// QuotaReset заменяет остаток ссылок на TotalUrls тарифа.
+	QuotaReset QuotaPolicy = "reset"
+	// QuotaTopUp добавляет TotalUrls к остатку.
+	QuotaTopUp QuotaPolicy = "top_up"
+)
+
+// Subscription - тариф. Price задаётся в минимальных единицах валюты (копейках) за BillingPeriod.
 type Subscription struct {
-	Id        int
-	Name      string
-	TotalUrls int
+	Id            int
+	Name          string
+	TotalUrls     int
+	Price         int64
+	Currency      string
+	BillingPeriod BillingPeriod
+	Features      PlanFeatures
+	// TrialDays - длина пробного периода, который даётся один раз на пользователя. 0 - без пробного периода.
+	TrialDays   int
+	QuotaPolicy QuotaPolicy
+	// Active - тариф показывается пользователям. Отключённые тарифы видит только администратор.
+	Active       bool
+	DisplayOrder int
+}
+
+type UserSubscriptionStatus string
+
+const (
+	// UserSubscriptionPending - подписка на платный тариф ждёт оплаты первого периода, квота тарифа ещё не начислена.
+	UserSubscriptionPending UserSubscriptionStatus = "pending"
+	UserSubscriptionTrial   UserSubscriptionStatus = "trial"
+	UserSubscriptionActive  UserSubscriptionStatus = "active"
+	UserSubscriptionPastDue UserSubscriptionStatus = "past_due"
+	// UserSubscriptionCancelled - пользователь отменил подписку, она действует до конца периода.
+	UserSubscriptionCancelled UserSubscriptionStatus = "cancelled"
+)
+
+// UserSubscription - текущая подписка пользователя на тариф Plan. В PeriodEnd период продлевается
+// или подписка переходит в другой статус, у pending и past_due PeriodEnd - конец отсрочки оплаты.
+type UserSubscription struct {
+	UserEmail   string
+	Plan        Subscription
+	Status      UserSubscriptionStatus
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// PrepaidPeriods - оплаченные, но ещё не начавшиеся периоды.
+	PrepaidPeriods int
+	TrialUsed      bool
+}
+
+// QuotaChange - изменение остатка ссылок пользователя вместе со сменой подписки. Пустой Policy не меняет остаток.
+type QuotaChange struct {
+	Policy QuotaPolicy
+	Urls   int
+}
+
+type LinkCreditReason string
+
+const (
+	LinkCreditAdmin    LinkCreditReason = "admin"
+	LinkCreditPromo    LinkCreditReason = "promo"
+	LinkCreditReferral LinkCreditReason = "referral"
+	// LinkCreditSubscription - квота тарифа в начале периода; при reset записывается разница до квоты
+	LinkCreditSubscription LinkCreditReason = "subscription"
+)
+
+// LinkCredit - запись журнала начислений ссылок. Reference - промокод, email приглашённого пользователя или тариф.
+type LinkCredit struct {
+	Id        int64
+	UserEmail string
+	Amount    int
+	Reason    LinkCreditReason
+	Reference string
+	CreatedAt time.Time
+}
+
+type PromoCodeKind string
+
+const (
+	// PromoLinkCredits начисляет LinkCredits ссылок при активации кода.
+	PromoLinkCredits PromoCodeKind = "link_credits"
+	// PromoPercentOff снижает цену первого периода тарифа на PercentOff процентов при переходе на него.
+	PromoPercentOff PromoCodeKind = "percent_off"
+)
+
+// PromoCode - промокод администратора. Нулевые MaxRedemptions и ExpiresAt не ограничивают активации и срок,
+// PlanId ограничивает скидку одним тарифом.
+type PromoCode struct {
+	Code           string
+	Kind           PromoCodeKind
+	LinkCredits    int
+	PercentOff     int
+	PlanId         *int
+	MaxRedemptions int
+	Redemptions    int
+	ExpiresAt      *time.Time
+	CreatedAt      time.Time
+}
+
+// Charge - цена первого периода тарифа при переходе на него.
+type Charge struct {
+	Price     int64
+	Currency  string
+	PromoCode string
+}
+
+// Referral - регистрация по реферальной ссылке. CreditedAt заполняется, когда пригласивший получает ссылки
+// после подтверждения почты приглашённым.
+type Referral struct {
+	InviteeEmail  string
+	ReferrerEmail string
+	CreatedAt     time.Time
+	CreditedAt    *time.Time
+}
+
+// Domain - свой домен пользователя. Ссылки на нём создаются и открываются только после подтверждения владения.
+type Domain struct {
+	Host      string
+	UserEmail string
+	// VerificationToken публикуется владельцем в TXT-записи домена.
+	VerificationToken string
+	VerifiedAt        *time.Time
+	// FallbackUrl - куда перенаправлять корень домена и неизвестные ссылки. Пустой - ответить 404.
+	FallbackUrl string
+	CreatedAt   time.Time
+}
+
+// DomainShortUrl возвращает ключ ссылки alias на домене host. Ссылки основного хоста хранятся под алиасом,
+// ссылки своих доменов - под host/alias, поэтому алиасы уникальны в пределах домена.
+func DomainShortUrl(host string, alias string) string {
+	if host == "" {
+		return alias
+	}
+
+	return host + "/" + alias
+}
+
+// Alias возвращает алиас ссылки без домена.
+func (l Link) Alias() string {
+	return strings.TrimPrefix(l.ShortUrl, DomainShortUrl(l.Domain, ""))
+}
+
+type Tag struct {
+	Name        string
+	LinksNumber int
+}
+
+type LinkStatus string
+
+const (
+	LinkStatusAll     LinkStatus = ""
+	LinkStatusActive  LinkStatus = "active"
+	LinkStatusExpired LinkStatus = "expired"
+)
+
+// LinkFilter ограничивает выборку ссылок пользователя. Нулевые значения полей не фильтруют.
+type LinkFilter struct {
+	Tag         string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Status      LinkStatus
+}
+
+type LinkSortField string
+
+const (
+	LinkSortCreatedAt    LinkSortField = "created_at"
+	LinkSortExpiresAt    LinkSortField = "expires_at"
+	LinkSortTimesVisited LinkSortField = "times_visited"
+	LinkSortAlias        LinkSortField = "alias"
+)
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// LinkCursor указывает на последнюю ссылку предыдущей страницы: значение ключа сортировки и short_url для разрешения равенств.
+type LinkCursor struct {
+	Value    string
+	ShortUrl string
+}
+
+type LinkListQuery struct {
+	Filter    LinkFilter
+	SortBy    LinkSortField
+	Direction SortDirection
+	After     *LinkCursor
+	Limit     int
+}
+
+type LinkPage struct {
+	Links      []Link
+	Total      int
+	NextCursor string
 }
@@ -1,7 +1,31 @@
 package dto
 
-type SearcherMatchResult struct {
+// SearchQuery описывает поиск по ссылкам. Пустой OwnerEmail означает поиск по ссылкам всех пользователей.
+type SearchQuery struct {
+	Text       string
+	OwnerEmail string
 	Limit      int
 	Offset     int
-	ShortLinks []string
+}
+
+type SearchHit struct {
+	ShortUrl   string
+	Highlights map[string][]string
+}
+
+type SearchResult struct {
+	Total int
+	Hits  []SearchHit
+}
+
+type LinkMatch struct {
+	Link       Link
+	Highlights map[string][]string
+}
+
+type SearcherMatchResult struct {
+	Limit  int
+	Offset int
+	Total  int
+	Links  []LinkMatch
 }
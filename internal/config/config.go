@@ -4,54 +4,238 @@ import (
 	"fmt"
 	"github.com/kelseyhightower/envconfig"
 	"log"
+	"os"
+	"time"
 )
 
+// Поля с тегом secret:"true" маскируются в выводе команды config print.
+
 type DBConfig struct {
 	PostgresHost     string `envconfig:"postgres_host" required:"true"`
 	PostgresPort     string `envconfig:"postgres_port" required:"true"`
 	PostgresUser     string `envconfig:"postgres_user" required:"true"`
-	PostgresPassword string `envconfig:"postgres_password" required:"true"`
+	PostgresPassword string `envconfig:"postgres_password" required:"true" secret:"true"`
 	PostgresDatabase string `envconfig:"postgres_database" required:"true"`
 	PostgresParams   string `envconfig:"postgres_params" required:"false"`
 }
 
+// RedisConfig нужен только для кеша redis.
 type RedisConfig struct {
-	Host string `envconfig:"redis_host" required:"true"`
-	Port string `envconfig:"redis_port" required:"true"`
+	Host string `envconfig:"redis_host" required:"false"`
+	Port string `envconfig:"redis_port" required:"false"`
+}
+
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+)
+
+// CacheConfig выбирает кеш переходов и QR-кодов: redis или memory (внутри процесса, только для одного экземпляра).
+type CacheConfig struct {
+	Backend string `envconfig:"cache_backend" required:"false" default:"redis"`
 }
 
 type ElasticConfig struct {
-	Host string `envconfig:"elastic_host" required:"true"`
+	Host string `envconfig:"elastic_host" required:"false"`
+}
+
+const (
+	SearchBackendElastic  = "elastic"
+	SearchBackendPostgres = "postgres"
+)
+
+// SearchConfig выбирает поиск по ссылкам: elastic или postgres (таблица link_search с индексом pg_trgm).
+type SearchConfig struct {
+	Backend string `envconfig:"search_backend" required:"false" default:"elastic"`
+	// ResultsLimit - количество результатов поиска на странице.
+	ResultsLimit int `envconfig:"search_results_limit" required:"false" default:"20"`
+}
+
+// HTTPConfig задаёт адрес, на котором сервер принимает запросы.
+type HTTPConfig struct {
+	Address string `envconfig:"http_address" required:"false" default:":8080"`
+}
+
+// SessionConfig задаёт ключ подписи cookie сессий. Смена ключа завершает все сессии.
+type SessionConfig struct {
+	Secret string `envconfig:"session_secret" required:"true" secret:"true"`
+}
+
+// LinksConfig задаёт ограничения на короткие ссылки.
+type LinksConfig struct {
+	// TTL - срок жизни новой ссылки и шаг продления.
+	TTL            time.Duration `envconfig:"link_ttl" required:"false" default:"2160h"`
+	AliasMinLength int           `envconfig:"alias_min_length" required:"false" default:"8"`
+	AliasMaxLength int           `envconfig:"alias_max_length" required:"false" default:"20"`
+	// ReservedNames совпадают с маршрутами сервиса, поэтому их нельзя занять ссылкой.
+	ReservedNames []string `envconfig:"reserved_names" required:"false" default:"register,login,logout,create_link,buy,subscriptions"`
+	// MaxPageSize ограничивает размер страницы списка ссылок пользователя.
+	MaxPageSize int `envconfig:"links_max_page_size" required:"false" default:"50"`
+}
+
+const (
+	QueueBackendKafka    = "kafka"
+	QueueBackendPostgres = "postgres"
+	QueueBackendMemory   = "memory"
+)
+
+// QueueConfig выбирает очередь событий: kafka, postgres (таблица queue_messages) или memory (внутри процесса).
+type QueueConfig struct {
+	Backend string `envconfig:"queue_backend" required:"false" default:"kafka"`
+}
+
+// StartupConfig ограничивает время ожидания зависимостей при запуске: подключение повторяется с растущей паузой.
+type StartupConfig struct {
+	Timeout time.Duration `envconfig:"startup_timeout" required:"false" default:"2m"`
+}
+
+// ShutdownConfig задаёт таймауты этапов остановки сервиса.
+type ShutdownConfig struct {
+	// HTTPTimeout - сколько ждать завершения уже принятых HTTP-запросов.
+	HTTPTimeout time.Duration `envconfig:"shutdown_http_timeout" required:"false" default:"15s"`
+	// WorkersTimeout - сколько ждать, пока воркеры обработают прочитанные из очереди сообщения.
+	WorkersTimeout time.Duration `envconfig:"shutdown_workers_timeout" required:"false" default:"30s"`
+	// QueueTimeout - сколько ждать доставки сообщений, оставшихся в продюсере.
+	QueueTimeout time.Duration `envconfig:"shutdown_queue_timeout" required:"false" default:"10s"`
+}
+
+// MigrationsConfig определяет, применять ли встроенные миграции при запуске. Если выключено,
+// миграции применяются командой migrate up.
+type MigrationsConfig struct {
+	OnStartup bool `envconfig:"migrate_on_startup" required:"false" default:"true"`
+}
+
+// BillingConfig задаёт правила подписок на тарифы.
+type BillingConfig struct {
+	// FreePlan - тариф новых пользователей и пользователей без оплаченного периода.
+	FreePlan string `envconfig:"billing_free_plan" required:"false" default:"Free"`
+	// GracePeriod - сколько неоплаченная подписка ждёт оплаты, прежде чем перейти на FreePlan.
+	GracePeriod time.Duration `envconfig:"billing_grace_period" required:"false" default:"72h"`
+	// CheckInterval - как часто планировщик продлевает подписки.
+	CheckInterval time.Duration `envconfig:"billing_check_interval" required:"false" default:"1m"`
+}
+
+// CreditsConfig задаёт начисления ссылок за приглашения и подтверждение почты.
+type CreditsConfig struct {
+	// ReferralCredits - сколько ссылок получает пригласивший, когда приглашённый подтверждает почту.
+	ReferralCredits int `envconfig:"referral_credits" required:"false" default:"10"`
+	// VerificationTTL - срок действия ссылки подтверждения почты.
+	VerificationTTL time.Duration `envconfig:"email_verification_ttl" required:"false" default:"48h"`
+}
+
+// MailConfig задаёт отправку писем. Без SMTPAddress письма не отправляются: почту не подтвердить,
+// и приглашения не начисляют ссылки.
+type MailConfig struct {
+	SMTPAddress  string `envconfig:"smtp_address" required:"false"`
+	SMTPUsername string `envconfig:"smtp_username" required:"false"`
+	SMTPPassword string `envconfig:"smtp_password" required:"false" secret:"true"`
+	From         string `envconfig:"mail_from" required:"false" default:"noreply@urleater.ru"`
+	// BaseURL - адрес сервиса, от которого строятся ссылки в письмах.
+	BaseURL string `envconfig:"public_base_url" required:"false" default:"http://localhost:8080"`
+}
+
+// AdminConfig задаёт администраторов сервиса: они управляют тарифами, промокодами и начислениями
+// и ищут по всем ссылкам. Права действуют только после подтверждения почты.
+type AdminConfig struct {
+	Emails []string `envconfig:"admin_emails" required:"false"`
+}
+
+// DomainsConfig задаёт свои домены пользователей.
+type DomainsConfig struct {
+	// PrimaryHosts - хосты самого сервиса: запросы к ним не ищут свой домен, а сами они не могут стать своим доменом.
+	PrimaryHosts []string `envconfig:"domains_primary_hosts" required:"false" default:"localhost"`
+	// DNSServer - адрес DNS-сервера (host:port) для проверки TXT-записей. Пустой - системный резолвер.
+	DNSServer string `envconfig:"domains_dns_server" required:"false"`
+}
+
+// QRConfig задаёт QR-коды ссылок.
+type QRConfig struct {
+	// DefaultSize и MaxSize - сторона изображения в пикселях по умолчанию и наибольшая.
+	DefaultSize int `envconfig:"qr_default_size" required:"false" default:"256"`
+	MaxSize     int `envconfig:"qr_max_size" required:"false" default:"2048"`
+	// CacheTTL - сколько готовое изображение хранится в Redis.
+	CacheTTL time.Duration `envconfig:"qr_cache_ttl" required:"false" default:"24h"`
+	// LogoPath - PNG или JPEG, который ставится в центр кода по запросу. Пустой - логотипа нет.
+	LogoPath string `envconfig:"qr_logo_path" required:"false"`
+}
+
+// HealthConfig ограничивает время проверки каждой зависимости в /readyz.
+type HealthConfig struct {
+	Timeout time.Duration `envconfig:"health_check_timeout" required:"false" default:"2s"`
+}
+
+// TracingConfig задаёт экспорт трейсов OpenTelemetry: none, stdout (для локального запуска) или otlp (по HTTP).
+type TracingConfig struct {
+	Exporter string `envconfig:"otel_traces_exporter" required:"false" default:"none"`
+	// Endpoint - адрес коллектора, например http://otel-collector:4318. Пустой - берётся из OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint    string  `envconfig:"otel_exporter_otlp_traces_endpoint" required:"false"`
+	ServiceName string  `envconfig:"otel_service_name" required:"false" default:"urleater"`
+	SampleRatio float64 `envconfig:"otel_traces_sample_ratio" required:"false" default:"1"`
+}
+
+// LogConfig задаёт уровень (debug, info, warn, error) и формат (json, text) логов.
+// По умолчанию email и IP-адреса в логах маскируются.
+type LogConfig struct {
+	Level     string `envconfig:"log_level" required:"false" default:"info"`
+	Format    string `envconfig:"log_format" required:"false" default:"json"`
+	RedactPII bool   `envconfig:"log_redact_pii" required:"false" default:"true"`
 }
 
 type Config struct {
+	HTTP                   HTTPConfig
+	Session                SessionConfig
+	Links                  LinksConfig
+	Billing                BillingConfig
+	Credits                CreditsConfig
+	Mail                   MailConfig
+	Admin                  AdminConfig
+	Domains                DomainsConfig
+	QR                     QRConfig
 	DB                     DBConfig
+	Migrations             MigrationsConfig
+	Cache                  CacheConfig
 	Redis                  RedisConfig
 	Elastic                ElasticConfig
+	Search                 SearchConfig
+	Queue                  QueueConfig
 	Kafka                  KafkaConfig
+	Startup                StartupConfig
+	Shutdown               ShutdownConfig
+	Health                 HealthConfig
+	Tracing                TracingConfig
+	Log                    LogConfig
 	ConsumingWorkersNumber int `envconfig:"consuming_workers_number" required:"true" default:"100"`
 }
 
 type KafkaConfigConsumer struct {
-	GroupId           string `envconfig:"kafka_group_id" required:"true"`
-	Topic             string `envconfig:"kafka_topic" required:"true"`
+	GroupId           string `envconfig:"kafka_group_id" required:"false"`
+	Topic             string `envconfig:"kafka_topic" required:"false" default:"urleater_topic"`
 	NumberOfConsumers int    `envconfig:"number_of_consumers" required:"false" default:"1"`
+	// DeadLetterTopic получает сообщения, которые не удалось обработать за MaxProcessAttempts попыток.
+	DeadLetterTopic    string `envconfig:"kafka_dlq_topic" required:"false" default:"urleater_topic_dlq"`
+	MaxProcessAttempts int    `envconfig:"kafka_max_process_attempts" required:"false" default:"5"`
 }
 
 type KafkaConfigProducer struct {
-	Topic string `envconfig:"kafka_topic" required:"true"`
+	Topic string `envconfig:"kafka_topic" required:"false" default:"urleater_topic"`
 }
 
 type KafkaConfig struct {
-	Address            string `envconfig:"kafka_address" required:"true"`
+	Address            string `envconfig:"kafka_address" required:"false"`
 	Consumer           KafkaConfigConsumer
 	Producer           KafkaConfigProducer
 	NumberOfPartitions int `envconfig:"kafka_number_of_partitions" required:"false" default:"1"`
 	ReplicationFactor  int `envconfig:"kafka_replication_factor" required:"false" default:"1"`
+	// SessionTimeout - через сколько брокер исключит из группы консьюмера, не приславшего heartbeat.
+	SessionTimeout    time.Duration `envconfig:"kafka_session_timeout" required:"false" default:"10s"`
+	HeartbeatInterval time.Duration `envconfig:"kafka_heartbeat_interval" required:"false" default:"3s"`
 }
 
+// ConfigFileEnv - переменная окружения с путём к файлу конфигурации.
+const ConfigFileEnv = "CONFIG_FILE"
+
 func ProvideConfig() *Config {
-	cfg, err := FromEnv()
+	cfg, err := Load(os.Getenv(ConfigFileEnv))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -59,13 +243,34 @@ func ProvideConfig() *Config {
 	return cfg
 }
 
+// FromEnv читает конфигурацию только из переменных окружения.
 func FromEnv() (*Config, error) {
+	return Load("")
+}
+
+// Load читает конфигурацию и проверяет её. Каждое значение берётся из переменной окружения, если она задана,
+// иначе из файла path, иначе используется значение по умолчанию. Пустой path - файла нет.
+func Load(path string) (*Config, error) {
+	values, err := readFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := new(Config)
 
-	if err := envconfig.Process("", cfg); err != nil {
+	err = withFileValues(values, func() error {
+		return envconfig.Process("", cfg)
+	})
+
+	if err != nil {
 		return nil, fmt.Errorf("error while parse env config | %w", err)
 	}
 
+	if err = cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+
 	return cfg, nil
 }
 
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const maskedValue = "******"
+
+// Dump пишет действующую конфигурацию в формате YAML-файла конфигурации. Значения секретов маскируются.
+// Незаданные списки пропускаются: пустое значение в файле задало бы пустой список.
+func (c *Config) Dump(w io.Writer) error {
+	document := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, f := range fields(c) {
+		if f.value.Kind() == reflect.Slice && f.value.IsNil() {
+			continue
+		}
+
+		value := formatValue(f.value.Interface())
+
+		if f.secret && value != "" {
+			value = maskedValue
+		}
+
+		document.Content = append(document.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: f.key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value},
+		)
+	}
+
+	encoder := yaml.NewEncoder(w)
+
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("error writing config | %w", err)
+	}
+
+	return encoder.Close()
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
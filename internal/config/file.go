@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// readFile читает файл конфигурации YAML или TOML. Ключи совпадают с именами переменных окружения
+// в нижнем регистре, вложенные секции склеиваются через подчёркивание: postgres: {host: db} - то же, что postgres_host.
+func readFile(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file | %w", err)
+	}
+
+	raw := map[string]any{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+
+	case ".toml":
+		var tree *toml.Tree
+
+		tree, err = toml.LoadBytes(data)
+
+		if err == nil {
+			raw = tree.ToMap()
+		}
+
+	default:
+		return nil, fmt.Errorf("config file %s must have .yaml, .yml or .toml extension", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file %s | %w", path, err)
+	}
+
+	values := map[string]string{}
+
+	flatten("", raw, values)
+
+	known := map[string]bool{}
+
+	for _, f := range fields(new(Config)) {
+		known[f.key] = true
+	}
+
+	for key := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown key %q in config file %s", key, path)
+		}
+	}
+
+	return values, nil
+}
+
+func flatten(prefix string, raw map[string]any, values map[string]string) {
+	for key, value := range raw {
+		key = strings.ToLower(key)
+
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(key, v, values)
+
+		case nil:
+			values[key] = ""
+
+		case []any:
+			items := make([]string, 0, len(v))
+
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+
+			values[key] = strings.Join(items, ",")
+
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// withFileValues выполняет process, подставив значения из файла в переменные окружения, которые не заданы.
+// После выполнения окружение возвращается в исходное состояние.
+func withFileValues(values map[string]string, process func() error) error {
+	var added []string
+
+	defer func() {
+		for _, name := range added {
+			_ = os.Unsetenv(name)
+		}
+	}()
+
+	for key, value := range values {
+		name := strings.ToUpper(key)
+
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+
+		added = append(added, name)
+	}
+
+	return process()
+}
+
+type field struct {
+	key    string
+	secret bool
+	value  reflect.Value
+}
+
+// fields перечисляет поля конфигурации с тегом envconfig в порядке объявления.
+// Поле, ключ которого уже встречался (kafka_topic у консьюмера и продюсера), пропускается.
+func fields(cfg *Config) []field {
+	var result []field
+
+	seen := map[string]bool{}
+
+	var walk func(v reflect.Value)
+
+	walk = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			structField := v.Type().Field(i)
+
+			key := structField.Tag.Get("envconfig")
+
+			if key == "" {
+				if structField.Type.Kind() == reflect.Struct {
+					walk(v.Field(i))
+				}
+
+				continue
+			}
+
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+
+			result = append(result, field{key: key, secret: structField.Tag.Get("secret") == "true", value: v.Field(i)})
+		}
+	}
+
+	walk(reflect.ValueOf(cfg).Elem())
+
+	return result
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"urleater/internal/logging"
+	"urleater/internal/tracing"
+)
+
+// minSessionSecretLength - минимальная длина ключа подписи сессий.
+const minSessionSecretLength = 16
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные ошибки, по одной на строку.
+func (c *Config) Validate() error {
+	var errs []error
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	positive := func(name string, value time.Duration) {
+		check(value > 0, "%s must be positive, got %s", name, value)
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Address); err != nil {
+		errs = append(errs, fmt.Errorf("HTTP_ADDRESS %q must be host:port or :port", c.HTTP.Address))
+	}
+
+	check(len(c.Session.Secret) >= minSessionSecretLength, "SESSION_SECRET must be at least %d characters", minSessionSecretLength)
+
+	positive("LINK_TTL", c.Links.TTL)
+	check(c.Links.AliasMinLength > 0, "ALIAS_MIN_LENGTH must be positive, got %d", c.Links.AliasMinLength)
+	check(c.Links.AliasMinLength <= c.Links.AliasMaxLength, "ALIAS_MIN_LENGTH (%d) must not exceed ALIAS_MAX_LENGTH (%d)",
+		c.Links.AliasMinLength, c.Links.AliasMaxLength)
+	check(c.Links.MaxPageSize > 0, "LINKS_MAX_PAGE_SIZE must be positive, got %d", c.Links.MaxPageSize)
+	check(c.Billing.FreePlan != "", "BILLING_FREE_PLAN must not be empty")
+	positive("BILLING_GRACE_PERIOD", c.Billing.GracePeriod)
+	positive("BILLING_CHECK_INTERVAL", c.Billing.CheckInterval)
+	check(c.Credits.ReferralCredits > 0, "REFERRAL_CREDITS must be positive, got %d", c.Credits.ReferralCredits)
+	positive("EMAIL_VERIFICATION_TTL", c.Credits.VerificationTTL)
+
+	if c.Mail.SMTPAddress != "" {
+		if _, _, err := net.SplitHostPort(c.Mail.SMTPAddress); err != nil {
+			errs = append(errs, fmt.Errorf("SMTP_ADDRESS %q must be host:port", c.Mail.SMTPAddress))
+		}
+		check(c.Mail.From != "", "MAIL_FROM is required when SMTP_ADDRESS is set")
+	}
+
+	if u, err := url.Parse(c.Mail.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("PUBLIC_BASE_URL %q must be an absolute URL", c.Mail.BaseURL))
+	}
+
+	for _, email := range c.Admin.Emails {
+		check(strings.Contains(email, "@"), "ADMIN_EMAILS: %q is not an email", email)
+	}
+
+	check(len(c.Domains.PrimaryHosts) > 0, "DOMAINS_PRIMARY_HOSTS must not be empty")
+
+	if c.Domains.DNSServer != "" {
+		if _, _, err := net.SplitHostPort(c.Domains.DNSServer); err != nil {
+			errs = append(errs, fmt.Errorf("DOMAINS_DNS_SERVER %q must be host:port", c.Domains.DNSServer))
+		}
+	}
+	check(c.QR.DefaultSize > 0, "QR_DEFAULT_SIZE must be positive, got %d", c.QR.DefaultSize)
+	check(c.QR.DefaultSize <= c.QR.MaxSize, "QR_DEFAULT_SIZE (%d) must not exceed QR_MAX_SIZE (%d)", c.QR.DefaultSize, c.QR.MaxSize)
+	positive("QR_CACHE_TTL", c.QR.CacheTTL)
+	check(c.Search.ResultsLimit > 0, "SEARCH_RESULTS_LIMIT must be positive, got %d", c.Search.ResultsLimit)
+
+	switch c.Queue.Backend {
+	case QueueBackendKafka:
+		check(c.Kafka.Address != "" && c.Kafka.Consumer.GroupId != "", "KAFKA_ADDRESS and KAFKA_GROUP_ID are required for the kafka queue backend")
+		positive("KAFKA_SESSION_TIMEOUT", c.Kafka.SessionTimeout)
+		check(c.Kafka.HeartbeatInterval > 0 && c.Kafka.HeartbeatInterval < c.Kafka.SessionTimeout,
+			"KAFKA_HEARTBEAT_INTERVAL (%s) must be positive and less than KAFKA_SESSION_TIMEOUT (%s)", c.Kafka.HeartbeatInterval, c.Kafka.SessionTimeout)
+		check(c.Kafka.NumberOfPartitions > 0, "KAFKA_NUMBER_OF_PARTITIONS must be positive, got %d", c.Kafka.NumberOfPartitions)
+		check(c.Kafka.ReplicationFactor > 0, "KAFKA_REPLICATION_FACTOR must be positive, got %d", c.Kafka.ReplicationFactor)
+
+	case QueueBackendPostgres, QueueBackendMemory:
+
+	default:
+		errs = append(errs, fmt.Errorf("unknown QUEUE_BACKEND %q", c.Queue.Backend))
+	}
+
+	check(c.Kafka.Consumer.NumberOfConsumers > 0, "NUMBER_OF_CONSUMERS must be positive, got %d", c.Kafka.Consumer.NumberOfConsumers)
+	check(c.Kafka.Consumer.MaxProcessAttempts > 0, "KAFKA_MAX_PROCESS_ATTEMPTS must be positive, got %d", c.Kafka.Consumer.MaxProcessAttempts)
+	check(c.ConsumingWorkersNumber > 0, "CONSUMING_WORKERS_NUMBER must be positive, got %d", c.ConsumingWorkersNumber)
+
+	switch c.Cache.Backend {
+	case CacheBackendRedis:
+		check(c.Redis.Host != "" && c.Redis.Port != "", "REDIS_HOST and REDIS_PORT are required for the redis cache backend")
+
+	case CacheBackendMemory:
+
+	default:
+		errs = append(errs, fmt.Errorf("unknown CACHE_BACKEND %q", c.Cache.Backend))
+	}
+
+	switch c.Search.Backend {
+	case SearchBackendElastic:
+		check(c.Elastic.Host != "", "ELASTIC_HOST is required for the elastic search backend")
+
+	case SearchBackendPostgres:
+
+	default:
+		errs = append(errs, fmt.Errorf("unknown SEARCH_BACKEND %q", c.Search.Backend))
+	}
+
+	positive("STARTUP_TIMEOUT", c.Startup.Timeout)
+	positive("SHUTDOWN_HTTP_TIMEOUT", c.Shutdown.HTTPTimeout)
+	positive("SHUTDOWN_WORKERS_TIMEOUT", c.Shutdown.WorkersTimeout)
+	positive("SHUTDOWN_QUEUE_TIMEOUT", c.Shutdown.QueueTimeout)
+	positive("HEALTH_CHECK_TIMEOUT", c.Health.Timeout)
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+
+	default:
+		errs = append(errs, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", c.Tracing.Exporter))
+	}
+
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "OTEL_TRACES_SAMPLE_RATIO must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	if _, err := logging.New(logging.Config{Level: c.Log.Level, Format: c.Log.Format}, io.Discard); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL or LOG_FORMAT: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"time"
+	"urleater/dto"
+)
+
+// ProducerName записывается в Event.Producer событий этого сервиса.
+const ProducerName = "urleater"
+
+// ErrMalformed - событие невозможно разобрать, повторная обработка не поможет.
+var ErrMalformed = errors.New("malformed event")
+
+// legacyTypes переводит типы сообщений формата {type, data} без версии в типы событий.
+var legacyTypes = map[string]dto.EventType{
+	"increment_link_view": dto.EventLinkViewed,
+	"delete_expired_link": dto.EventLinkExpired,
+}
+
+// New создаёт событие текущей версии схемы.
+func New(eventType dto.EventType, payload any) (dto.Event, error) {
+	version, ok := dto.EventVersions[eventType]
+
+	if !ok {
+		return dto.Event{}, fmt.Errorf("unknown event type %s", eventType)
+	}
+
+	data, err := json.Marshal(payload)
+
+	if err != nil {
+		return dto.Event{}, fmt.Errorf("error marshalling %s payload: %w", eventType, err)
+	}
+
+	id, err := uuid.NewV4()
+
+	if err != nil {
+		return dto.Event{}, fmt.Errorf("error generating event id: %w", err)
+	}
+
+	return dto.Event{
+		Id:         id.String(),
+		Type:       eventType,
+		Version:    version,
+		OccurredAt: time.Now().UTC(),
+		Producer:   ProducerName,
+		Data:       data,
+	}, nil
+}
+
+// Decode разбирает конверт события. Сообщения старого формата без версии переводятся в события версии 1.
+func Decode(raw []byte) (dto.Event, error) {
+	var event dto.Event
+
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return dto.Event{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	if event.Type == "" {
+		return dto.Event{}, fmt.Errorf("%w: no event type", ErrMalformed)
+	}
+
+	if event.Version == 0 {
+		if eventType, ok := legacyTypes[string(event.Type)]; ok {
+			event.Type = eventType
+		}
+
+		event.Version = 1
+	}
+
+	return event, nil
+}
+
+// RequestID возвращает идентификатор запроса из конверта события, не проверяя остальные поля.
+func RequestID(raw []byte) string {
+	var envelope struct {
+		RequestId string `json:"request_id"`
+	}
+
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return ""
+	}
+
+	return envelope.RequestId
+}
+
+type handler func(ctx context.Context, event dto.Event) error
+
+// Registry направляет события обработчикам по типу.
+// События неизвестных типов пропускаются, события более новой версии разбираются в структуру известной версии.
+type Registry struct {
+	handlers map[dto.EventType]handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[dto.EventType]handler)}
+}
+
+// Handle регистрирует обработчик событий eventType с данными T. Неизвестные поля данных
+// (появившиеся в более новых версиях схемы) игнорируются.
+func Handle[T any](r *Registry, eventType dto.EventType, handle func(ctx context.Context, event dto.Event, payload T) error) {
+	r.handlers[eventType] = func(ctx context.Context, event dto.Event) error {
+		var payload T
+
+		if err := json.Unmarshal(event.Data, &payload); err != nil {
+			return fmt.Errorf("%w: %s v%d: %v", ErrMalformed, event.Type, event.Version, err)
+		}
+
+		return handle(ctx, event, payload)
+	}
+}
+
+// Dispatch разбирает сообщение и передаёт его обработчику. Возвращает nil для событий без обработчика.
+func (r *Registry) Dispatch(ctx context.Context, raw []byte) error {
+	event, err := Decode(raw)
+
+	if err != nil {
+		return err
+	}
+
+	h, ok := r.handlers[event.Type]
+
+	if !ok {
+		return nil
+	}
+
+	return h(ctx, event)
+}
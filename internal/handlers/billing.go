@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"urleater/dto"
+)
+
+// SubscribeRequest описывает тело запроса для перехода на тариф.
+type SubscribeRequest struct {
+	PlanId int `json:"plan_id" validate:"required"`
+	// PromoCode - промокод со скидкой на первый период, необязателен.
+	PromoCode string `json:"promo_code"`
+}
+
+// UserSubscriptionResponse описывает ответ с подпиской пользователя.
+type UserSubscriptionResponse struct {
+	Subscription dto.UserSubscription `json:"subscription"`
+	// Charge - цена первого периода, есть только в ответе на переход на тариф.
+	Charge *dto.Charge `json:"charge,omitempty"`
+}
+
+// Subscribe godoc
+// @Summary Переход на тариф
+// @Description Переводит пользователя на тариф. Если у тарифа есть пробный период, а пользователь его ещё не получал, подписка начинается с него. Платный тариф ждёт оплаты (pending), период и квота тарифа начинаются после оплаты; при смене тарифа остаток ссылок сохраняется. Повторный переход на отменённый тариф снимает отмену. Промокод со скидкой снижает цену первого периода.
+// @Tags Подписки
+// @Accept json
+// @Produce json
+// @Param SubscribeRequest body SubscribeRequest true "Тариф"
+// @Success 200 {object} UserSubscriptionResponse "Новая подписка"
+// @Failure 400 {object} string "Неверный запрос или неавторизован"
+// @Failure 404 {object} string "Тариф или промокод не найден"
+// @Failure 409 {object} string "Пользователь уже подписан на тариф или промокод уже использован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /subscribe [post]
+func (h *Handlers) Subscribe(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	requestData := new(SubscribeRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	sub, charge, err := h.Service.Subscribe(c.Request().Context(), email, requestData.PlanId, requestData.PromoCode)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, UserSubscriptionResponse{
+		Subscription: *sub,
+		Charge:       charge,
+	})
+}
+
+// CancelSubscription godoc
+// @Summary Отмена подписки
+// @Description Отменяет платную подписку: она действует до конца периода, затем пользователь переходит на бесплатный тариф. Неоплаченную подписку (pending) отменить нельзя.
+// @Tags Подписки
+// @Produce json
+// @Success 200 {object} UserSubscriptionResponse "Отменённая подписка"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 404 {object} string "Подписки нет"
+// @Failure 409 {object} string "Подписку нельзя отменить"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /cancel_subscription [post]
+func (h *Handlers) CancelSubscription(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	sub, err := h.Service.CancelSubscription(c.Request().Context(), email)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, UserSubscriptionResponse{
+		Subscription: *sub,
+	})
+}
+
+// RecordPaymentRequest описывает оплату периодов подписки пользователя.
+type RecordPaymentRequest struct {
+	Email   string `json:"email" validate:"required"`
+	Periods int    `json:"periods" validate:"required"`
+}
+
+// RecordPayment godoc
+// @Summary Запись оплаты подписки
+// @Description Администратор или платёжная система добавляет оплаченные периоды текущего тарифа пользователя. Подписка, ожидающая оплаты (pending или past_due), сразу начинает оплаченный период.
+// @Tags Администрирование
+// @Accept json
+// @Produce json
+// @Param RecordPaymentRequest body RecordPaymentRequest true "Оплата"
+// @Success 200 {object} UserSubscriptionResponse "Подписка после оплаты"
+// @Failure 400 {object} string "Неверный запрос"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 404 {object} string "Подписки нет"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/payments [post]
+func (h *Handlers) RecordPayment(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	requestData := new(RecordPaymentRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	sub, err := h.Service.RecordPayment(c.Request().Context(), requestData.Email, requestData.Periods)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, UserSubscriptionResponse{
+		Subscription: *sub,
+	})
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+	"urleater/dto"
+)
+
+// GetLinkCreditsResponse описывает ответ с журналом начислений ссылок.
+type GetLinkCreditsResponse struct {
+	Credits []dto.LinkCredit `json:"credits"`
+}
+
+// GetLinkCredits godoc
+// @Summary Журнал начислений ссылок
+// @Description Возвращает последние начисления ссылок авторизованному пользователю: администратором, по промокодам и за приглашения.
+// @Tags Начисления
+// @Produce json
+// @Success 200 {object} GetLinkCreditsResponse "Начисления, начиная с последних"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /link_credits [get]
+func (h *Handlers) GetLinkCredits(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	credits, err := h.Service.GetLinkCredits(c.Request().Context(), email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, GetLinkCreditsResponse{
+		Credits: credits,
+	})
+}
+
+// RedeemPromoCodeRequest описывает тело запроса для активации промокода.
+type RedeemPromoCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// PromoCodeResponse описывает ответ с промокодом.
+type PromoCodeResponse struct {
+	PromoCode dto.PromoCode `json:"promo_code"`
+}
+
+// RedeemPromoCode godoc
+// @Summary Активация промокода
+// @Description Начисляет авторизованному пользователю ссылки по промокоду. Промокоды со скидкой указываются при переходе на тариф.
+// @Tags Начисления
+// @Accept json
+// @Produce json
+// @Param RedeemPromoCodeRequest body RedeemPromoCodeRequest true "Промокод"
+// @Success 200 {object} PromoCodeResponse "Активированный промокод"
+// @Failure 400 {object} string "Неверный запрос, неавторизован или промокод со скидкой"
+// @Failure 404 {object} string "Промокод не найден"
+// @Failure 409 {object} string "Промокод истёк, закончился или уже активирован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /redeem_promo [post]
+func (h *Handlers) RedeemPromoCode(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	requestData := new(RedeemPromoCodeRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	promo, err := h.Service.RedeemPromoCode(c.Request().Context(), email, requestData.Code)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, PromoCodeResponse{
+		PromoCode: *promo,
+	})
+}
+
+// PromoCodeRequest описывает промокод в запросе администратора. Нулевые max_redemptions и expires_at
+// не ограничивают активации и срок.
+type PromoCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+	// Kind - link_credits или percent_off.
+	Kind        dto.PromoCodeKind `json:"kind" validate:"required"`
+	LinkCredits int               `json:"link_credits"`
+	PercentOff  int               `json:"percent_off"`
+	// PlanId ограничивает скидку одним тарифом.
+	PlanId         *int       `json:"plan_id"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+// GetPromoCodesResponse описывает ответ со списком промокодов.
+type GetPromoCodesResponse struct {
+	PromoCodes []dto.PromoCode `json:"promo_codes"`
+}
+
+// GetPromoCodes godoc
+// @Summary Список промокодов
+// @Description Возвращает все промокоды с числом активаций.
+// @Tags Администрирование
+// @Produce json
+// @Success 200 {object} GetPromoCodesResponse "Промокоды"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/promo_codes [get]
+func (h *Handlers) GetPromoCodes(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	promos, err := h.Service.GetPromoCodes(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, GetPromoCodesResponse{
+		PromoCodes: promos,
+	})
+}
+
+// CreatePromoCode godoc
+// @Summary Создание промокода
+// @Description Создаёт промокод на начисление ссылок или на скидку на первый период тарифа.
+// @Tags Администрирование
+// @Accept json
+// @Produce json
+// @Param PromoCodeRequest body PromoCodeRequest true "Промокод"
+// @Success 201 {object} PromoCodeResponse "Созданный промокод"
+// @Failure 400 {object} string "Неверный промокод"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 404 {object} string "Тариф не найден"
+// @Failure 409 {object} string "Промокод уже есть"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/promo_codes [post]
+func (h *Handlers) CreatePromoCode(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	requestData := new(PromoCodeRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	promo, err := h.Service.CreatePromoCode(c.Request().Context(), dto.PromoCode{
+		Code:           requestData.Code,
+		Kind:           requestData.Kind,
+		LinkCredits:    requestData.LinkCredits,
+		PercentOff:     requestData.PercentOff,
+		PlanId:         requestData.PlanId,
+		MaxRedemptions: requestData.MaxRedemptions,
+		ExpiresAt:      requestData.ExpiresAt,
+	})
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, PromoCodeResponse{
+		PromoCode: *promo,
+	})
+}
+
+// DeletePromoCode godoc
+// @Summary Удаление промокода
+// @Description Удаляет промокод. Начисленные по нему ссылки остаются.
+// @Tags Администрирование
+// @Param code path string true "Промокод"
+// @Success 204 "Промокод удалён"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 404 {object} string "Промокод не найден"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/promo_codes/{code} [delete]
+func (h *Handlers) DeletePromoCode(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	if err := h.Service.DeletePromoCode(c.Request().Context(), c.Param("code")); err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// VerifyEmail godoc
+// @Summary Подтверждение почты
+// @Description Подтверждает почту по ссылке из письма и перенаправляет на главную страницу. Если пользователь зарегистрировался по приглашению, пригласивший получает ссылки.
+// @Tags Пользователи
+// @Param token query string true "Токен из ссылки"
+// @Success 303 "Почта подтверждена"
+// @Failure 400 {object} string "Токен неверный или истёк"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /verify_email [get]
+func (h *Handlers) VerifyEmail(c echo.Context) error {
+	if _, err := h.Service.VerifyEmail(c.Request().Context(), c.QueryParam("token")); err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/")
+}
+
+// ResendEmailVerification godoc
+// @Summary Повторная ссылка подтверждения почты
+// @Description Отправляет письмом новую ссылку подтверждения почты авторизованного пользователя, прежняя перестаёт действовать.
+// @Tags Пользователи
+// @Produce json
+// @Success 204 "Письмо отправлено"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 409 {object} string "Почта уже подтверждена"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Failure 503 {object} string "Отправка писем не настроена"
+// @Router /resend_verification [post]
+func (h *Handlers) ResendEmailVerification(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	ctx := c.Request().Context()
+
+	user, err := h.Service.GetUser(ctx, email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if user.EmailVerified {
+		return c.JSON(http.StatusConflict, "email is already verified")
+	}
+
+	if err = h.Service.RequestEmailVerification(ctx, email); err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetReferralsResponse описывает реферальную ссылку пользователя и приглашённых по ней.
+type GetReferralsResponse struct {
+	ReferralCode string         `json:"referral_code"`
+	ReferralLink string         `json:"referral_link"`
+	Referrals    []dto.Referral `json:"referrals"`
+}
+
+// GetReferrals godoc
+// @Summary Реферальная ссылка
+// @Description Возвращает реферальную ссылку авторизованного пользователя и приглашённых по ней. За каждого приглашённого, подтвердившего почту, начисляются ссылки.
+// @Tags Начисления
+// @Produce json
+// @Success 200 {object} GetReferralsResponse "Реферальная ссылка и приглашённые"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /referrals [get]
+func (h *Handlers) GetReferrals(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	code, referrals, err := h.Service.GetReferrals(c.Request().Context(), email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, GetReferralsResponse{
+		ReferralCode: code,
+		ReferralLink: "/register?ref=" + code,
+		Referrals:    referrals,
+	})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"urleater/dto"
+	"urleater/internal/service"
+)
+
+// domainFallback перенаправляет на запасной адрес своего домена, а если его нет, отвечает 404.
+func domainFallback(c echo.Context, domain *dto.Domain) error {
+	if domain.FallbackUrl == "" {
+		return c.JSON(http.StatusNotFound, "short link not found")
+	}
+
+	return c.Redirect(http.StatusFound, domain.FallbackUrl)
+}
+
+// VerificationRecord - TXT-запись, которую владелец публикует для подтверждения домена.
+type VerificationRecord struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DomainResponse описывает свой домен пользователя.
+type DomainResponse struct {
+	Domain             dto.Domain         `json:"domain"`
+	VerificationRecord VerificationRecord `json:"verification_record"`
+}
+
+func newDomainResponse(domain dto.Domain) DomainResponse {
+	name, value := service.DomainVerificationRecord(domain)
+
+	return DomainResponse{
+		Domain: domain,
+		VerificationRecord: VerificationRecord{
+			Name:  name,
+			Value: value,
+		},
+	}
+}
+
+// GetDomainsResponse описывает ответ со списком своих доменов.
+type GetDomainsResponse struct {
+	Domains []DomainResponse `json:"domains"`
+}
+
+// GetDomains godoc
+// @Summary Свои домены
+// @Description Возвращает домены авторизованного пользователя с TXT-записями для подтверждения.
+// @Tags Домены
+// @Produce json
+// @Success 200 {object} GetDomainsResponse "Домены"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /domains [get]
+func (h *Handlers) GetDomains(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	domains, err := h.Service.GetUserDomains(c.Request().Context(), email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	response := GetDomainsResponse{
+		Domains: make([]DomainResponse, 0, len(domains)),
+	}
+
+	for _, domain := range domains {
+		response.Domains = append(response.Domains, newDomainResponse(domain))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// AddDomainRequest описывает тело запроса для добавления своего домена.
+type AddDomainRequest struct {
+	Host string `json:"host" validate:"required"`
+	// FallbackUrl - куда перенаправлять корень домена и неизвестные ссылки, пустой - отвечать 404.
+	FallbackUrl string `json:"fallback_url"`
+}
+
+// AddDomain godoc
+// @Summary Добавление своего домена
+// @Description Добавляет домен авторизованному пользователю, если тариф это позволяет. Ссылки на домене можно создавать после подтверждения TXT-записью.
+// @Tags Домены
+// @Accept json
+// @Produce json
+// @Param AddDomainRequest body AddDomainRequest true "Домен"
+// @Success 201 {object} DomainResponse "Добавленный домен"
+// @Failure 400 {object} string "Неверный домен или неавторизован"
+// @Failure 403 {object} string "Тариф не даёт добавить ещё один домен"
+// @Failure 409 {object} string "Домен уже подтверждён другим пользователем или уже добавлен"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /domains [post]
+func (h *Handlers) AddDomain(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	requestData := new(AddDomainRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	domain, err := h.Service.AddDomain(c.Request().Context(), email, requestData.Host, requestData.FallbackUrl)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, newDomainResponse(*domain))
+}
+
+// VerifyDomain godoc
+// @Summary Подтверждение своего домена
+// @Description Проверяет TXT-запись домена и отмечает его подтверждённым.
+// @Tags Домены
+// @Produce json
+// @Param host path string true "Домен"
+// @Success 200 {object} DomainResponse "Подтверждённый домен"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 404 {object} string "Домен не найден"
+// @Failure 409 {object} string "TXT-запись не найдена или домен уже подтвердил другой пользователь"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /domains/{host}/verify [post]
+func (h *Handlers) VerifyDomain(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	domain, err := h.Service.VerifyDomain(c.Request().Context(), email, c.Param("host"))
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, newDomainResponse(*domain))
+}
+
+// UpdateDomainRequest описывает тело запроса для изменения своего домена.
+type UpdateDomainRequest struct {
+	FallbackUrl string `json:"fallback_url"`
+}
+
+// UpdateDomain godoc
+// @Summary Изменение своего домена
+// @Description Задаёт запасной адрес домена: на него перенаправляются корень домена и неизвестные ссылки. Пустой адрес - отвечать 404.
+// @Tags Домены
+// @Accept json
+// @Produce json
+// @Param host path string true "Домен"
+// @Param UpdateDomainRequest body UpdateDomainRequest true "Запасной адрес"
+// @Success 200 {object} DomainResponse "Изменённый домен"
+// @Failure 400 {object} string "Неверный адрес или неавторизован"
+// @Failure 404 {object} string "Домен не найден"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /domains/{host} [put]
+func (h *Handlers) UpdateDomain(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	requestData := new(UpdateDomainRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	domain, err := h.Service.UpdateDomainFallback(c.Request().Context(), email, c.Param("host"), requestData.FallbackUrl)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, newDomainResponse(*domain))
+}
+
+// DeleteDomain godoc
+// @Summary Удаление своего домена
+// @Description Удаляет домен авторизованного пользователя вместе со ссылками на нём.
+// @Tags Домены
+// @Param host path string true "Домен"
+// @Success 204 "Домен удалён"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 404 {object} string "Домен не найден"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /domains/{host} [delete]
+func (h *Handlers) DeleteDomain(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	if err := h.Service.DeleteDomain(c.Request().Context(), email, c.Param("host")); err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -2,33 +2,69 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/antonlindstrom/pgstore"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo/v4"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	_ "urleater/docs"
 	"urleater/dto"
+	"urleater/internal/logging"
+	"urleater/internal/service"
 )
 
 // Service описывает бизнес-логику приложения.
 type Service interface {
 	LoginUser(ctx context.Context, email string, password string) error
 	RegisterUser(ctx context.Context, email string, password string) error
-	CreateShortLink(ctx context.Context, shortLink string, longLink string, userEmail string) (*dto.Link, error)
+	CreateShortLink(ctx context.Context, shortLink string, longLink string, userEmail string, domain string) (*dto.Link, error)
 	UpdateUserShortLinks(ctx context.Context, email string, deltaLinks int) (*dto.User, error)
-	GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email string, offset int, limit int) ([]dto.Link, *dto.User, error)
+	GetUserShortLinks(ctx context.Context, email string, listQuery dto.LinkListQuery, cursor string) (*dto.LinkPage, *dto.User, error)
 	GetSubscriptions(ctx context.Context) ([]dto.Subscription, error)
 	GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error)
 	GetUser(ctx context.Context, email string) (*dto.User, error)
+	IsAdmin(ctx context.Context, email string) (bool, error)
 	DeleteShortLink(ctx context.Context, shortLink string, email string) error
-	CreateSubscriptions(ctx context.Context) error
+	GetAllSubscriptions(ctx context.Context) ([]dto.Subscription, error)
+	CreateSubscription(ctx context.Context, plan dto.Subscription) (*dto.Subscription, error)
+	UpdateSubscription(ctx context.Context, plan dto.Subscription) (*dto.Subscription, error)
+	DeleteSubscription(ctx context.Context, id int) error
+	GetUserSubscription(ctx context.Context, email string) (*dto.UserSubscription, error)
+	Subscribe(ctx context.Context, email string, planId int, promoCode string) (*dto.UserSubscription, *dto.Charge, error)
+	CancelSubscription(ctx context.Context, email string) (*dto.UserSubscription, error)
+	RecordPayment(ctx context.Context, email string, periods int) (*dto.UserSubscription, error)
+	GetLinkCredits(ctx context.Context, email string) ([]dto.LinkCredit, error)
+	CreatePromoCode(ctx context.Context, promo dto.PromoCode) (*dto.PromoCode, error)
+	GetPromoCodes(ctx context.Context) ([]dto.PromoCode, error)
+	DeletePromoCode(ctx context.Context, code string) error
+	RedeemPromoCode(ctx context.Context, email string, code string) (*dto.PromoCode, error)
+	RequestEmailVerification(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) (string, error)
+	AddReferral(ctx context.Context, inviteeEmail string, referralCode string) error
+	GetReferrals(ctx context.Context, email string) (string, []dto.Referral, error)
+	AddDomain(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error)
+	GetUserDomains(ctx context.Context, email string) ([]dto.Domain, error)
+	VerifyDomain(ctx context.Context, email string, host string) (*dto.Domain, error)
+	UpdateDomainFallback(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error)
+	DeleteDomain(ctx context.Context, email string, host string) error
+	ResolveHost(ctx context.Context, host string) (*dto.Domain, error)
+	GetShortLinkQR(ctx context.Context, shortLink string, baseUrl string, options service.QROptions) (*service.QRImage, error)
 	GetTotalUserLinks(ctx context.Context, email string) (int, error)
-	GetShortLinksMatchingPattern(ctx context.Context, containsWord string, offset int) (dto.SearcherMatchResult, error)
+	GetShortLinksMatchingPattern(ctx context.Context, email string, global bool, containsWord string, offset int) (dto.SearcherMatchResult, error)
+	UpdateShortLinkRedirect(ctx context.Context, shortLink string, email string, options dto.RedirectOptions) (*dto.Link, error)
+	UpdateShortLinkUTM(ctx context.Context, shortLink string, email string, utm dto.UTM) (*dto.Link, error)
+	GetCampaignStats(ctx context.Context, email string) ([]dto.CampaignStats, error)
+	Redirect(ctx context.Context, shortLink string, path string, query url.Values) (string, error)
+	UpdateShortLinkInfo(ctx context.Context, shortLink string, email string, title string, description string, tags []string) (*dto.Link, error)
+	GetUserTags(ctx context.Context, email string) ([]dto.Tag, error)
+	DeleteUserTag(ctx context.Context, email string, tag string) error
 }
 
 // SessionStore описывает методы работы с сессиями.
@@ -42,6 +78,13 @@ type SessionStore interface {
 type Handlers struct {
 	Service Service
 	Store   SessionStore
+	Health  HealthChecker
+	// Logger - логгер обработчиков, если не задан, используется slog.Default().
+	Logger *slog.Logger
+}
+
+func (h *Handlers) logger() *slog.Logger {
+	return logging.OrDefault(h.Logger)
 }
 
 type PostgresSessionStore struct {
@@ -98,12 +141,23 @@ type redirectResponse struct {
 // GetMainPage godoc
 // @Summary Рендер главной страницы
 // @Description Отрисовывает главную страницу, если пользователь авторизован, иначе перенаправляет на /login.
+// @Description На своём домене пользователя перенаправляет на запасной адрес домена или отвечает 404.
 // @Tags Страницы
 // @Produce html
 // @Success 200 {string} string "HTML главной страницы"
+// @Success 302 {string} string "Перенаправление на запасной адрес своего домена"
+// @Failure 404 {object} string "У своего домена нет запасного адреса"
 // @Failure 500 {object} string "Ошибка сервера"
 // @Router / [get]
 func (h *Handlers) GetMainPage(c echo.Context) error {
+	domain, err := h.Service.ResolveHost(c.Request().Context(), c.Request().Host)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if domain != nil {
+		return domainFallback(c, domain)
+	}
+
 	email, err := h.Store.RetrieveEmailFromSession(c)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, err)
@@ -176,18 +230,18 @@ func (h *Handlers) PostLogin(c echo.Context) error {
 
 	err = h.Service.LoginUser(ctx, requestData.Email, requestData.Password)
 	if err != nil {
-		log.Println(err)
+		h.logger().WarnContext(ctx, "login failed", "error", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
 
 	session, err := h.Store.Get(c.Request(), "session_key")
 	if err != nil {
-		log.Printf("Error getting session: %v\n", err)
+		h.logger().ErrorContext(c.Request().Context(), "error getting session", "error", err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
 	if err = h.Store.Save(c, requestData.Email, session); err != nil {
-		log.Printf("Error saving session: %v\n", err)
+		h.logger().ErrorContext(c.Request().Context(), "error saving session", "error", err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
@@ -207,13 +261,13 @@ func (h *Handlers) PostLogin(c echo.Context) error {
 func (h *Handlers) GetLogout(c echo.Context) error {
 	session, err := h.Store.Get(c.Request(), "session_key")
 	if err != nil {
-		log.Printf("Error getting session: %v\n", err)
+		h.logger().ErrorContext(c.Request().Context(), "error getting session", "error", err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
 	session.Options.MaxAge = -1
 	if err = session.Save(c.Request(), c.Response()); err != nil {
-		log.Printf("Error saving session: %v\n", err)
+		h.logger().ErrorContext(c.Request().Context(), "error saving session", "error", err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
@@ -224,6 +278,8 @@ func (h *Handlers) GetLogout(c echo.Context) error {
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// ReferralCode - код из реферальной ссылки /register?ref=<код>, необязателен.
+	ReferralCode string `json:"referral_code"`
 }
 
 // PostRegister godoc
@@ -261,18 +317,25 @@ func (h *Handlers) PostRegister(c echo.Context) error {
 
 	err = h.Service.RegisterUser(ctx, requestData.Email, requestData.Password)
 	if err != nil {
-		log.Println(err)
+		h.logger().WarnContext(ctx, "registration failed", "error", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
 
+	// неверный реферальный код не мешает регистрации
+	if requestData.ReferralCode != "" {
+		if err = h.Service.AddReferral(ctx, requestData.Email, requestData.ReferralCode); err != nil {
+			h.logger().WarnContext(ctx, "referral not registered", "error", err)
+		}
+	}
+
 	session, err := h.Store.Get(c.Request(), "session_key")
 	if err != nil {
-		log.Printf("Error getting session: %v\n", err)
+		h.logger().ErrorContext(c.Request().Context(), "error getting session", "error", err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
 	if err = h.Store.Save(c, requestData.Email, session); err != nil {
-		log.Printf("Error saving session: %v\n", err)
+		h.logger().ErrorContext(c.Request().Context(), "error saving session", "error", err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
@@ -285,6 +348,10 @@ func (h *Handlers) PostRegister(c echo.Context) error {
 type CreateShortLinkRequest struct {
 	ShortURL string `json:"short_url"`
 	LongURL  string `json:"long_url" validate:"required"`
+	// Domain - подтверждённый свой домен пользователя, пустой - основной хост.
+	Domain string `json:"domain"`
+	// UTM - необязательные метки кампании.
+	UTM UTMRequest `json:"utm"`
 }
 
 // CreateShortLinkResponse описывает ответ на запрос создания короткой ссылки.
@@ -295,12 +362,15 @@ type CreateShortLinkResponse struct {
 // CreateShortLink godoc
 // @Summary Создание короткой ссылки
 // @Description Создаёт короткую ссылку, сопоставляя её с длинным URL для авторизованного пользователя.
+// @Description На своём домене алиас должен быть уникален только в пределах домена. Метки кампании дописываются к длинному URL при переходе.
 // @Tags Ссылки
 // @Accept json
 // @Produce json
 // @Param CreateShortLinkRequest body CreateShortLinkRequest true "Данные для создания ссылки"
 // @Success 200 {object} CreateShortLinkResponse "Созданная ссылка"
-// @Failure 400 {object} string "Неверный запрос или неавторизован"
+// @Failure 400 {object} string "Неверный запрос, неверные метки кампании или неавторизован"
+// @Failure 404 {object} string "Домен не найден"
+// @Failure 409 {object} string "Домен не подтверждён"
 // @Failure 500 {object} string "Ошибка сервера"
 // @Router /shortlink [post]
 func (h *Handlers) CreateShortLink(c echo.Context) error {
@@ -325,9 +395,22 @@ func (h *Handlers) CreateShortLink(c echo.Context) error {
 		}
 	}
 
-	link, err := h.Service.CreateShortLink(ctx, requestData.ShortURL, requestData.LongURL, email)
+	// метки проверяются до создания, чтобы неверные метки не тратили лимит ссылок
+	utm, err := service.NormalizeUTM(requestData.UTM.toDTO())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	link, err := h.Service.CreateShortLink(ctx, requestData.ShortURL, requestData.LongURL, email, requestData.Domain)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	if utm != (dto.UTM{}) {
+		link, err = h.Service.UpdateShortLinkUTM(ctx, link.ShortUrl, email, utm)
+		if err != nil {
+			return c.JSON(planErrorStatus(err), err.Error())
+		}
 	}
 
 	return c.JSON(http.StatusOK, CreateShortLinkResponse{
@@ -340,21 +423,36 @@ type FormattedLink struct {
 	LongUrl      string
 	UserEmail    string
 	ExpiresAt    string
+	CreatedAt    string
 	TimesVisited int
+	Title        string
+	Description  string
+	Tags         []string
+	Domain       string
+	UTM          dto.UTM
+	dto.RedirectOptions
 }
 
 type GetUserShortLinksResponse struct {
-	Links []FormattedLink `json:"links"`
-	User  dto.User        `json:"user"`
+	Links      []FormattedLink `json:"links"`
+	User       dto.User        `json:"user"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"next_cursor"`
 }
 
 // GetUserShortLinks godoc
 // @Summary Получение коротких ссылок пользователя
-// @Description Возвращает список коротких ссылок текущего пользователя с поддержкой пагинации.
+// @Description Возвращает страницу коротких ссылок текущего пользователя, общее число подходящих ссылок и курсор следующей страницы.
 // @Tags Ссылки
 // @Produce json
 // @Param limit query int false "Лимит записей"
-// @Param offset query int false "Сдвиг для пагинации"
+// @Param cursor query string false "Курсор следующей страницы из предыдущего ответа"
+// @Param sort query string false "Ключ сортировки: created_at, expires_at, times_visited или alias"
+// @Param order query string false "Направление сортировки: asc или desc"
+// @Param tag query string false "Фильтр по тегу"
+// @Param created_from query string false "Созданы не раньше даты (YYYY-MM-DD)"
+// @Param created_to query string false "Созданы не позже даты (YYYY-MM-DD)"
+// @Param status query string false "Статус ссылки: active или expired"
 // @Success 200 {object} GetUserShortLinksResponse "Список ссылок и данные пользователя"
 // @Failure 400 {object} string "Неверный запрос или неавторизован"
 // @Failure 500 {object} string "Ошибка сервера"
@@ -370,22 +468,28 @@ func (h *Handlers) GetUserShortLinks(c echo.Context) error {
 		})
 	}
 
-	limitParam, offsetParam := c.QueryParam("limit"), c.QueryParam("offset")
 	var limit int
-	if limitParam != "" {
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
 		limit, err = strconv.Atoi(limitParam)
 	}
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	offset, err := strconv.Atoi(offsetParam)
+	filter, err := parseLinkFilter(c)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
+	listQuery := dto.LinkListQuery{
+		Filter:    filter,
+		SortBy:    dto.LinkSortField(c.QueryParam("sort")),
+		Direction: dto.SortDirection(c.QueryParam("order")),
+		Limit:     limit,
+	}
+
 	ctx := c.Request().Context()
-	links, user, err := h.Service.GetUserShortLinksWithOffsetAndLimit(ctx, email, offset, limit)
+	page, user, err := h.Service.GetUserShortLinks(ctx, email, listQuery, c.QueryParam("cursor"))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
@@ -394,19 +498,238 @@ func (h *Handlers) GetUserShortLinks(c echo.Context) error {
 	user.PasswordHash = ""
 
 	var formattedLinks []FormattedLink
-	for _, l := range links {
+	for _, l := range page.Links {
 		formattedLinks = append(formattedLinks, FormattedLink{
-			ShortUrl:     l.ShortUrl,
-			LongUrl:      l.LongUrl,
-			UserEmail:    l.UserEmail,
-			TimesVisited: l.TimesVisited,
-			ExpiresAt:    l.ExpiresAt.Format(time.DateTime),
+			ShortUrl:        l.ShortUrl,
+			LongUrl:         l.LongUrl,
+			UserEmail:       l.UserEmail,
+			TimesVisited:    l.TimesVisited,
+			ExpiresAt:       l.ExpiresAt.Format(time.DateTime),
+			CreatedAt:       l.CreatedAt.Format(time.DateTime),
+			Title:           l.Title,
+			Description:     l.Description,
+			Tags:            l.Tags,
+			Domain:          l.Domain,
+			RedirectOptions: l.RedirectOptions,
+			UTM:             l.UTM,
 		})
 	}
 	return c.JSON(http.StatusOK, GetUserShortLinksResponse{
-		Links: formattedLinks,
-		User:  *user,
+		Links:      formattedLinks,
+		User:       *user,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// parseLinkFilter собирает фильтр списка ссылок из query-параметров tag, created_from, created_to и status.
+func parseLinkFilter(c echo.Context) (dto.LinkFilter, error) {
+	filter := dto.LinkFilter{
+		Tag:    c.QueryParam("tag"),
+		Status: dto.LinkStatus(c.QueryParam("status")),
+	}
+
+	if createdFrom := c.QueryParam("created_from"); createdFrom != "" {
+		from, err := time.Parse(time.DateOnly, createdFrom)
+		if err != nil {
+			return dto.LinkFilter{}, fmt.Errorf("created_from must be a date in format YYYY-MM-DD")
+		}
+		filter.CreatedFrom = from
+	}
+
+	if createdTo := c.QueryParam("created_to"); createdTo != "" {
+		to, err := time.Parse(time.DateOnly, createdTo)
+		if err != nil {
+			return dto.LinkFilter{}, fmt.Errorf("created_to must be a date in format YYYY-MM-DD")
+		}
+		// граница включительная: берём ссылки до начала следующего дня
+		filter.CreatedTo = to.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+// UpdateShortLinkInfoRequest описывает тело запроса для изменения заголовка, описания и тегов ссылки.
+type UpdateShortLinkInfoRequest struct {
+	ShortLink   string   `json:"short_link" validate:"required"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// UpdateShortLinkInfoResponse описывает ответ с обновлённой ссылкой.
+type UpdateShortLinkInfoResponse struct {
+	Link dto.Link `json:"link"`
+}
+
+// UpdateShortLinkInfo godoc
+// @Summary Изменение заголовка, описания и тегов ссылки
+// @Description Обновляет заголовок, описание и теги короткой ссылки текущего пользователя. Теги заменяются целиком.
+// @Tags Ссылки
+// @Accept json
+// @Produce json
+// @Param UpdateShortLinkInfoRequest body UpdateShortLinkInfoRequest true "Новые данные ссылки"
+// @Success 200 {object} UpdateShortLinkInfoResponse "Обновлённая ссылка"
+// @Failure 400 {object} string "Неверный запрос или неавторизован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /update_link [put]
+func (h *Handlers) UpdateShortLinkInfo(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	ctx := c.Request().Context()
+	requestData := new(UpdateShortLinkInfoRequest)
+	if err := c.Bind(&requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	link, err := h.Service.UpdateShortLinkInfo(ctx, requestData.ShortLink, email, requestData.Title, requestData.Description, requestData.Tags)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, UpdateShortLinkInfoResponse{
+		Link: *link,
+	})
+}
+
+// UpdateShortLinkRedirectRequest описывает тело запроса для изменения параметров перехода по ссылке.
+type UpdateShortLinkRedirectRequest struct {
+	ShortLink string `json:"short_link" validate:"required"`
+	// ForwardQuery переносит параметры запроса посетителя в длинный URL.
+	ForwardQuery bool `json:"forward_query"`
+	// QueryConflict - чьё значение остаётся при совпадении имён параметров: keep (ссылки), override (посетителя), append (оба).
+	QueryConflict string `json:"query_conflict"`
+	// ForwardPath делает алиас префиксом: путь после него дописывается к длинному URL.
+	ForwardPath bool `json:"forward_path"`
+}
+
+// UpdateShortLinkRedirect godoc
+// @Summary Изменение параметров перехода по ссылке
+// @Description Задаёт, переносятся ли в длинный URL параметры запроса и путь после алиаса.
+// @Tags Ссылки
+// @Accept json
+// @Produce json
+// @Param UpdateShortLinkRedirectRequest body UpdateShortLinkRedirectRequest true "Параметры перехода"
+// @Success 200 {object} UpdateShortLinkInfoResponse "Обновлённая ссылка"
+// @Failure 400 {object} string "Неверный запрос или неавторизован"
+// @Failure 404 {object} string "Ссылка не найдена"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /update_link_redirect [put]
+func (h *Handlers) UpdateShortLinkRedirect(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	requestData := new(UpdateShortLinkRedirectRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	link, err := h.Service.UpdateShortLinkRedirect(c.Request().Context(), requestData.ShortLink, email, dto.RedirectOptions{
+		ForwardQuery:  requestData.ForwardQuery,
+		QueryConflict: requestData.QueryConflict,
+		ForwardPath:   requestData.ForwardPath,
 	})
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, UpdateShortLinkInfoResponse{
+		Link: *link,
+	})
+}
+
+type GetUserTagsResponse struct {
+	Tags []dto.Tag `json:"tags"`
+}
+
+// GetUserTags godoc
+// @Summary Получение тегов пользователя
+// @Description Возвращает теги текущего пользователя с количеством отмеченных ими ссылок.
+// @Tags Ссылки
+// @Produce json
+// @Success 200 {object} GetUserTagsResponse "Список тегов"
+// @Failure 400 {object} string "Неверный запрос или неавторизован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /get_tags [get]
+func (h *Handlers) GetUserTags(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	ctx := c.Request().Context()
+	tags, err := h.Service.GetUserTags(ctx, email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, GetUserTagsResponse{
+		Tags: tags,
+	})
+}
+
+// DeleteUserTag godoc
+// @Summary Удаление тега
+// @Description Удаляет тег текущего пользователя и снимает его со всех ссылок.
+// @Tags Ссылки
+// @Produce json
+// @Param tag query string true "Тег для удаления"
+// @Success 200 {object} nil "Тег удалён"
+// @Failure 400 {object} string "Неверный запрос или неавторизован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /delete_tag [delete]
+func (h *Handlers) DeleteUserTag(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	tag := c.QueryParam("tag")
+	if tag == "" {
+		return c.JSON(http.StatusBadRequest, fmt.Errorf("tag cannot be empty").Error())
+	}
+
+	ctx := c.Request().Context()
+	if err = h.Service.DeleteUserTag(ctx, email, tag); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, nil)
 }
 
 type GetUserShortLinksTotalNumberResponse struct {
@@ -494,23 +817,21 @@ type UpdateUserShortLinksResponse struct {
 }
 
 // UpdateUserShortLinks godoc
-// @Summary Обновление количества коротких ссылок пользователя
-// @Description Администратор может изменить число доступных ссылок для указанного пользователя.
+// @Summary Начисление коротких ссылок пользователю
+// @Description Администратор начисляет указанному пользователю delta_links ссылок. Начисление записывается в журнал.
 // @Tags Администрирование
 // @Accept json
 // @Produce json
 // @Param UpdateUserShortLinksRequest body UpdateUserShortLinksRequest true "Данные для обновления"
 // @Success 200 {object} UpdateUserShortLinksResponse "Обновлённые данные пользователя"
 // @Failure 400 {object} string "Неверный запрос"
-// @Failure 500 {object} string "Ошибка сервера или неавторизованный доступ"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 404 {object} string "Пользователь не найден"
+// @Failure 500 {object} string "Ошибка сервера"
 // @Router /admin/update-links [put]
 func (h *Handlers) UpdateUserShortLinks(c echo.Context) error {
-	email, err := h.Store.RetrieveEmailFromSession(c)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err)
-	}
-	if email != "admin@admin.com" {
-		return c.JSON(http.StatusInternalServerError, fmt.Errorf("user %s is not authorized to change links number", email))
+	if ok, err := h.adminOnly(c); !ok {
+		return err
 	}
 
 	ctx := c.Request().Context()
@@ -526,7 +847,7 @@ func (h *Handlers) UpdateUserShortLinks(c echo.Context) error {
 
 	user, err := h.Service.UpdateUserShortLinks(ctx, requestData.Email, requestData.DeltaLinks)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return c.JSON(planErrorStatus(err), err.Error())
 	}
 
 	return c.JSON(http.StatusOK, UpdateUserShortLinksResponse{
@@ -556,20 +877,39 @@ func (h *Handlers) GetCreateShortLink(c echo.Context) error {
 // GetShortLink godoc
 // @Summary Редирект короткой ссылки
 // @Description Перенаправляет пользователя с короткой ссылки на соответствующий длинный URL.
+// @Description На своём домене ссылка ищется по домену из заголовка Host и алиасу, а неизвестная ссылка перенаправляется на запасной адрес домена.
+// @Description Если ссылка это разрешает, параметры запроса переносятся в длинный URL, а путь после алиаса дописывается к нему.
 // @Tags Ссылки
 // @Produce plain
 // @Param short_link path string true "Короткая ссылка"
 // @Success 302 {string} string "Перенаправление на длинный URL"
+// @Failure 404 {object} string "Ссылки нет, её срок истёк или она не передаёт путь"
 // @Failure 500 {object} string "Ошибка сервера"
 // @Router /{short_link} [get]
+// @Router /{short_link}/{path} [get]
 func (h *Handlers) GetShortLink(c echo.Context) error {
 	ctx := c.Request().Context()
-	shortLink := c.Param("short_link")
-	link, err := h.Service.GetShortLink(ctx, shortLink)
+	domain, err := h.Service.ResolveHost(ctx, c.Request().Host)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
-	return c.Redirect(http.StatusFound, link.LongUrl)
+
+	shortLink := c.Param("short_link")
+	if domain != nil {
+		shortLink = dto.DomainShortUrl(domain.Host, shortLink)
+	}
+
+	// остаток пути берётся из экранированного пути запроса: echo отдаёт параметры то экранированными, то нет
+	_, path, _ := strings.Cut(strings.TrimPrefix(c.Request().URL.EscapedPath(), "/"), "/")
+
+	target, err := h.Service.Redirect(ctx, shortLink, path, c.QueryParams())
+	if domain != nil && errors.Is(err, service.ErrLinkNotFound) {
+		return domainFallback(c, domain)
+	}
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+	return c.Redirect(http.StatusFound, target)
 }
 
 type GetSubscriptionsResponse struct {
@@ -578,7 +918,7 @@ type GetSubscriptionsResponse struct {
 
 // GetSubscriptions godoc
 // @Summary Получение подписок
-// @Description Возвращает список подписок для авторизованного пользователя.
+// @Description Возвращает включённые тарифы в порядке показа для авторизованного пользователя.
 // @Tags Подписки
 // @Produce json
 // @Success 200 {object} GetSubscriptionsResponse "Список подписок"
@@ -601,8 +941,8 @@ func (h *Handlers) GetSubscriptions(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
-	return c.JSON(http.StatusOK, echo.Map{
-		"subscriptions": subscriptions,
+	return c.JSON(http.StatusOK, GetSubscriptionsResponse{
+		Subscriptions: subscriptions,
 	})
 }
 
@@ -627,11 +967,13 @@ func (h *Handlers) GetSubscriptionsPage(c echo.Context) error {
 
 type GetUserResponse struct {
 	User dto.User `json:"user"`
+	// Subscription - текущий тариф и дата продления, nil, если пользователь ещё не переведён на тарифы.
+	Subscription *dto.UserSubscription `json:"subscription"`
 }
 
 // GetUser godoc
 // @Summary Получение данных пользователя
-// @Description Возвращает информацию об авторизованном пользователе.
+// @Description Возвращает информацию об авторизованном пользователе, его текущий тариф и дату продления.
 // @Tags Пользователь
 // @Produce json
 // @Success 200 {object} GetUserResponse "Данные пользователя"
@@ -653,8 +995,13 @@ func (h *Handlers) GetUser(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
+	subscription, err := h.Service.GetUserSubscription(ctx, email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
 	return c.JSON(http.StatusOK, GetUserResponse{
-		User: *user,
+		User:         *user,
+		Subscription: subscription,
 	})
 }
 
@@ -686,7 +1033,6 @@ func (h *Handlers) DeleteShortLink(c echo.Context) error {
 
 	ctx := c.Request().Context()
 	shortLink := c.QueryParam("short_link")
-	log.Println("short_link", shortLink)
 	if shortLink == "" {
 		return c.JSON(http.StatusBadRequest, fmt.Errorf("пустая строка").Error())
 	}
@@ -705,14 +1051,16 @@ type GetShortLinksWithMatchingPatternRequest struct {
 }
 
 type GetShortLinksWithMatchingPatternResponse struct {
-	ShortLinks []dto.Link `json:"links"`
-	Limit      int        `json:"limit"`
-	Offset     int        `json:"offset"`
+	ShortLinks []dto.Link            `json:"links"`
+	Highlights []map[string][]string `json:"highlights"`
+	Total      int                   `json:"total"`
+	Limit      int                   `json:"limit"`
+	Offset     int                   `json:"offset"`
 }
 
 // GetShortLinksMatchingPattern godoc
 // @Summary Поиск коротких ссылок по шаблону
-// @Description Ищет короткие ссылки, содержащие указанное слово, с поддержкой пагинации.
+// @Description Ищет ссылки текущего пользователя по алиасу, адресу, домену, заголовку и тегам; администратор ищет по всем ссылкам.
 // @Tags Ссылки
 // @Produce json
 // @Param contains_word query string true "Подстрока для поиска"
@@ -749,22 +1097,27 @@ func (h *Handlers) GetShortLinksMatchingPattern(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
-	links, err := h.Service.GetShortLinksMatchingPattern(ctx, containsWord, offset)
+	global, err := h.Service.IsAdmin(ctx, email)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
-	var shortLinks []dto.Link
-	for _, link := range links.ShortLinks {
-		res, err := h.Service.GetShortLink(ctx, link)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		shortLinks = append(shortLinks, *res)
+	links, err := h.Service.GetShortLinksMatchingPattern(ctx, email, global, containsWord, offset)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	shortLinks := make([]dto.Link, 0, len(links.Links))
+	highlights := make([]map[string][]string, 0, len(links.Links))
+	for _, match := range links.Links {
+		shortLinks = append(shortLinks, match.Link)
+		highlights = append(highlights, match.Highlights)
 	}
 
 	return c.JSON(http.StatusOK, GetShortLinksWithMatchingPatternResponse{
 		ShortLinks: shortLinks,
+		Highlights: highlights,
+		Total:      links.Total,
 		Limit:      links.Limit,
 		Offset:     links.Offset,
 	})
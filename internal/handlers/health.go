@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"urleater/dto"
+)
+
+// HealthChecker проверяет зависимости сервиса.
+type HealthChecker interface {
+	Check(ctx context.Context) dto.HealthReport
+}
+
+// GetHealthz godoc
+// @Summary Проверка живости
+// @Description Отвечает 200, пока процесс обрабатывает запросы. Зависимости не проверяются.
+// @Tags Служебные
+// @Produce json
+// @Success 200 {object} map[string]string "Сервис жив"
+// @Router /healthz [get]
+func (h *Handlers) GetHealthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": dto.HealthStatusOK,
+	})
+}
+
+// GetReadyz godoc
+// @Summary Проверка готовности
+// @Description Проверяет Postgres, Redis, Elasticsearch и Kafka. Отвечает 503, только если недоступна критичная зависимость,
+// @Description недоступность остальных отмечается статусом degraded.
+// @Tags Служебные
+// @Produce json
+// @Success 200 {object} dto.HealthReport "Сервис готов"
+// @Failure 503 {object} dto.HealthReport "Недоступна критичная зависимость"
+// @Router /readyz [get]
+func (h *Handlers) GetReadyz(c echo.Context) error {
+	if h.Health == nil {
+		return c.JSON(http.StatusOK, dto.HealthReport{Status: dto.HealthStatusOK})
+	}
+
+	report := h.Health.Check(c.Request().Context())
+
+	if report.Status == dto.HealthStatusDown {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
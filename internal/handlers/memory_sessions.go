@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sync"
+)
+
+// MemorySessionStore хранит сессии в памяти процесса вместо таблицы pgstore. В cookie лежит только
+// случайный идентификатор сессии. Подходит для тестов и запуска одного экземпляра без Postgres-сессий.
+type MemorySessionStore struct {
+	store *memorySessions
+}
+
+func NewMemorySessionStore() SessionStore {
+	return &MemorySessionStore{
+		store: &memorySessions{
+			sessions: make(map[string]map[interface{}]interface{}),
+			// те же параметры cookie, что у pgstore по умолчанию
+			options: sessions.Options{Path: "/", MaxAge: 86400 * 30},
+		},
+	}
+}
+
+func (m *MemorySessionStore) RetrieveEmailFromSession(c echo.Context) (string, error) {
+	session, err := m.Get(c.Request(), "session_key")
+
+	if err != nil {
+		return "", fmt.Errorf("error getting session: %w", err)
+	}
+
+	email, _ := session.Values["email"].(string)
+
+	return email, nil
+}
+
+func (m *MemorySessionStore) Get(r *http.Request, key string) (*sessions.Session, error) {
+	return m.store.Get(r, key)
+}
+
+func (m *MemorySessionStore) Save(c echo.Context, email string, session *sessions.Session) error {
+	session.Values["email"] = email
+
+	return session.Save(c.Request(), c.Response())
+}
+
+// memorySessions - sessions.Store поверх map.
+type memorySessions struct {
+	mu       sync.Mutex
+	sessions map[string]map[interface{}]interface{}
+	options  sessions.Options
+}
+
+func (m *memorySessions) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(m, name)
+}
+
+// New реализует sessions.Store: восстанавливает сессию по идентификатору из cookie или создаёт новую.
+func (m *memorySessions) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(m, name)
+
+	options := m.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+
+	if err != nil {
+		return session, nil
+	}
+
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	values, ok := m.sessions[cookie.Value]
+
+	if !ok {
+		return session, nil
+	}
+
+	session.ID = cookie.Value
+	session.IsNew = false
+
+	for k, v := range values {
+		session.Values[k] = v
+	}
+
+	return session, nil
+}
+
+// Save реализует sessions.Store. Отрицательный MaxAge удаляет сессию, как при выходе пользователя.
+func (m *memorySessions) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	if session.Options.MaxAge < 0 {
+		delete(m.sessions, session.ID)
+
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+
+		return nil
+	}
+
+	if session.ID == "" {
+		id := make([]byte, 32)
+
+		if _, err := rand.Read(id); err != nil {
+			return fmt.Errorf("error generating session id: %w", err)
+		}
+
+		session.ID = base64.RawURLEncoding.EncodeToString(id)
+	}
+
+	values := make(map[interface{}]interface{}, len(session.Values))
+
+	for k, v := range session.Values {
+		values[k] = v
+	}
+
+	m.sessions[session.ID] = values
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), session.ID, session.Options))
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"urleater/dto"
+	"urleater/internal/service"
+)
+
+// SubscriptionPlanRequest описывает тариф в запросах администратора. Цена - в копейках.
+type SubscriptionPlanRequest struct {
+	Name                   string            `json:"name" validate:"required"`
+	TotalUrls              int               `json:"total_urls" validate:"required"`
+	Price                  int64             `json:"price"`
+	Currency               string            `json:"currency" validate:"required"`
+	BillingPeriod          dto.BillingPeriod `json:"billing_period" validate:"required"`
+	MaxExpiryDays          int               `json:"max_expiry_days"`
+	CustomDomains          int               `json:"custom_domains"`
+	AnalyticsRetentionDays int               `json:"analytics_retention_days"`
+	APIRateLimit           int               `json:"api_rate_limit"`
+	TrialDays              int               `json:"trial_days"`
+	// QuotaPolicy - reset или top_up, по умолчанию reset.
+	QuotaPolicy dto.QuotaPolicy `json:"quota_policy"`
+	// Active по умолчанию true.
+	Active       *bool `json:"active"`
+	DisplayOrder int   `json:"display_order"`
+}
+
+func (r SubscriptionPlanRequest) plan(id int) dto.Subscription {
+	active := true
+
+	if r.Active != nil {
+		active = *r.Active
+	}
+
+	return dto.Subscription{
+		Id:            id,
+		Name:          r.Name,
+		TotalUrls:     r.TotalUrls,
+		Price:         r.Price,
+		Currency:      r.Currency,
+		BillingPeriod: r.BillingPeriod,
+		Features: dto.PlanFeatures{
+			MaxExpiryDays:          r.MaxExpiryDays,
+			CustomDomains:          r.CustomDomains,
+			AnalyticsRetentionDays: r.AnalyticsRetentionDays,
+			APIRateLimit:           r.APIRateLimit,
+		},
+		TrialDays:    r.TrialDays,
+		QuotaPolicy:  r.QuotaPolicy,
+		Active:       active,
+		DisplayOrder: r.DisplayOrder,
+	}
+}
+
+// SubscriptionPlanResponse описывает ответ с созданным или изменённым тарифом.
+type SubscriptionPlanResponse struct {
+	Subscription dto.Subscription `json:"subscription"`
+}
+
+// adminOnly отвечает ошибкой и возвращает false, если запрос сделал не администратор.
+func (h *Handlers) adminOnly(c echo.Context) (bool, error) {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return false, c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return false, c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+	admin, err := h.Service.IsAdmin(c.Request().Context(), email)
+	if err != nil {
+		return false, c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if !admin {
+		return false, c.JSON(http.StatusForbidden, "only the administrator can do this")
+	}
+
+	return true, nil
+}
+
+// planErrorStatus выбирает код ответа по ошибке сервиса тарифов, подписок, начислений, доменов, ссылок, их переходов и QR-кодов.
+func planErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidPlan), errors.Is(err, service.ErrInvalidPromoCode), errors.Is(err, service.ErrInvalidCredit),
+		errors.Is(err, service.ErrPromoCodeNotApplicable), errors.Is(err, service.ErrInvalidVerificationToken), errors.Is(err, service.ErrInvalidDomain),
+		errors.Is(err, service.ErrInvalidQROptions), errors.Is(err, service.ErrInvalidRedirectOptions),
+		errors.Is(err, service.ErrInvalidUTM):
+		return http.StatusBadRequest
+
+	case errors.Is(err, service.ErrDomainLimit):
+		return http.StatusForbidden
+
+	case errors.Is(err, service.ErrMailDisabled):
+		return http.StatusServiceUnavailable
+
+	case errors.Is(err, service.ErrPlanNotFound), errors.Is(err, service.ErrNoSubscription), errors.Is(err, service.ErrPromoCodeNotFound),
+		errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrReferralCodeNotFound), errors.Is(err, service.ErrDomainNotFound),
+		errors.Is(err, service.ErrLinkNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, service.ErrPlanNameTaken), errors.Is(err, service.ErrPlanInUse), errors.Is(err, service.ErrSubscriptionState),
+		errors.Is(err, service.ErrPromoCodeTaken), errors.Is(err, service.ErrPromoCodeUnavailable), errors.Is(err, service.ErrPromoCodeRedeemed),
+		errors.Is(err, service.ErrDomainTaken), errors.Is(err, service.ErrDomainNotVerified), errors.Is(err, service.ErrDomainVerificationFailed):
+		return http.StatusConflict
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handlers) bindPlan(c echo.Context) (*SubscriptionPlanRequest, error) {
+	requestData := new(SubscriptionPlanRequest)
+	if err := c.Bind(requestData); err != nil {
+		return nil, err
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return nil, err
+		}
+	}
+
+	return requestData, nil
+}
+
+// GetAllSubscriptions godoc
+// @Summary Список всех тарифов
+// @Description Возвращает администратору все тарифы, включая отключённые, в порядке показа.
+// @Tags Администрирование
+// @Produce json
+// @Success 200 {object} GetSubscriptionsResponse "Список тарифов"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/subscriptions [get]
+func (h *Handlers) GetAllSubscriptions(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	subscriptions, err := h.Service.GetAllSubscriptions(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, GetSubscriptionsResponse{
+		Subscriptions: subscriptions,
+	})
+}
+
+// CreateSubscription godoc
+// @Summary Создание тарифа
+// @Description Администратор добавляет тариф. Название тарифа должно быть уникальным.
+// @Tags Администрирование
+// @Accept json
+// @Produce json
+// @Param SubscriptionPlanRequest body SubscriptionPlanRequest true "Тариф"
+// @Success 201 {object} SubscriptionPlanResponse "Созданный тариф"
+// @Failure 400 {object} string "Неверный тариф"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 409 {object} string "Тариф с таким названием уже есть"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/subscriptions [post]
+func (h *Handlers) CreateSubscription(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	requestData, err := h.bindPlan(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	plan, err := h.Service.CreateSubscription(c.Request().Context(), requestData.plan(0))
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, SubscriptionPlanResponse{
+		Subscription: *plan,
+	})
+}
+
+// UpdateSubscription godoc
+// @Summary Изменение тарифа
+// @Description Администратор заменяет все поля тарифа. Отключённый тариф (active=false) не показывается пользователям.
+// @Tags Администрирование
+// @Accept json
+// @Produce json
+// @Param id path int true "Идентификатор тарифа"
+// @Param SubscriptionPlanRequest body SubscriptionPlanRequest true "Тариф"
+// @Success 200 {object} SubscriptionPlanResponse "Изменённый тариф"
+// @Failure 400 {object} string "Неверный тариф"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 404 {object} string "Тариф не найден"
+// @Failure 409 {object} string "Тариф с таким названием уже есть"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/subscriptions/{id} [put]
+func (h *Handlers) UpdateSubscription(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid plan id")
+	}
+
+	requestData, err := h.bindPlan(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	plan, err := h.Service.UpdateSubscription(c.Request().Context(), requestData.plan(id))
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, SubscriptionPlanResponse{
+		Subscription: *plan,
+	})
+}
+
+// DeleteSubscription godoc
+// @Summary Удаление тарифа
+// @Description Администратор удаляет тариф, на который никто не подписан.
+// @Tags Администрирование
+// @Param id path int true "Идентификатор тарифа"
+// @Success 204 "Тариф удалён"
+// @Failure 400 {object} string "Неверный идентификатор"
+// @Failure 403 {object} string "Пользователь не администратор"
+// @Failure 404 {object} string "Тариф не найден"
+// @Failure 409 {object} string "На тариф подписаны пользователи"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /admin/subscriptions/{id} [delete]
+func (h *Handlers) DeleteSubscription(c echo.Context) error {
+	if ok, err := h.adminOnly(c); !ok {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid plan id")
+	}
+
+	if err := h.Service.DeleteSubscription(c.Request().Context(), id); err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"urleater/dto"
+	"urleater/internal/service"
+)
+
+// qrOptions читает параметры QR-кода из запроса. Отсутствующие параметры берутся из service.DefaultQROptions.
+func qrOptions(c echo.Context) (service.QROptions, error) {
+	options := service.DefaultQROptions()
+
+	if format := c.QueryParam("format"); format != "" {
+		options.Format = format
+	}
+
+	if level := c.QueryParam("level"); level != "" {
+		options.Level = level
+	}
+
+	if fg := c.QueryParam("fg"); fg != "" {
+		options.Foreground = fg
+	}
+
+	if bg := c.QueryParam("bg"); bg != "" {
+		options.Background = bg
+	}
+
+	for name, value := range map[string]*int{"size": &options.Size, "margin": &options.Margin} {
+		if raw := c.QueryParam(name); raw != "" {
+			number, err := strconv.Atoi(raw)
+			if err != nil {
+				return options, fmt.Errorf("%s must be a number, got %q", name, raw)
+			}
+			*value = number
+		}
+	}
+
+	if logo := c.QueryParam("logo"); logo != "" {
+		enabled, err := strconv.ParseBool(logo)
+		if err != nil {
+			return options, fmt.Errorf("logo must be true or false, got %q", logo)
+		}
+		options.Logo = enabled
+	}
+
+	return options, nil
+}
+
+// GetShortLinkQR godoc
+// @Summary QR-код ссылки
+// @Description Возвращает QR-код короткой ссылки в PNG или SVG. Код ссылки на своём домене ведёт на этот домен, остальные - на хост запроса. Изображения кешируются.
+// @Tags Ссылки
+// @Produce png
+// @Produce image/svg+xml
+// @Param short path string true "Алиас ссылки"
+// @Param domain query string false "Свой домен ссылки"
+// @Param format query string false "png (по умолчанию) или svg"
+// @Param size query int false "Сторона изображения в пикселях"
+// @Param level query string false "Уровень коррекции ошибок: L, M (по умолчанию), Q или H"
+// @Param margin query int false "Поля в модулях, по умолчанию 4"
+// @Param fg query string false "Цвет модулей, #rrggbb"
+// @Param bg query string false "Цвет фона, #rrggbb"
+// @Param logo query bool false "Логотип в центре кода"
+// @Param download query bool false "Отдать файлом для скачивания"
+// @Success 200 {file} file "QR-код"
+// @Failure 400 {object} string "Неверные параметры"
+// @Failure 404 {object} string "Ссылки нет или её срок истёк"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /links/{short}/qr [get]
+func (h *Handlers) GetShortLinkQR(c echo.Context) error {
+	options, err := qrOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	alias := c.Param("short")
+	shortLink := dto.DomainShortUrl(c.QueryParam("domain"), alias)
+	baseUrl := c.Scheme() + "://" + c.Request().Host
+
+	qr, err := h.Service.GetShortLinkQR(c.Request().Context(), shortLink, baseUrl, options)
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	if download, _ := strconv.ParseBool(c.QueryParam("download")); download {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", alias+"."+strings.ToLower(options.Format)))
+	}
+	c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+
+	return c.Blob(http.StatusOK, qr.ContentType, qr.Data)
+}
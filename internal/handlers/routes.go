@@ -3,10 +3,15 @@ package handlers
 import (
 	"html/template"
 	"io"
+	"log/slog"
 	_ "urleater/docs"
+	"urleater/internal/logging"
+	"urleater/internal/metrics"
+	"urleater/internal/tracing"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
@@ -16,7 +21,6 @@ type ServerInterface interface {
 	PostRegister(c echo.Context) error
 	GetLogout(c echo.Context) error
 	CreateShortLink(c echo.Context) error
-	UpdateUserShortLinks(c echo.Context) error
 	GetRegisterPage(c echo.Context) error
 	GetLoginPage(c echo.Context) error
 	GetUserShortLinks(c echo.Context) error
@@ -24,12 +28,42 @@ type ServerInterface interface {
 	GetShortLink(c echo.Context) error
 	GetSubscriptions(c echo.Context) error
 	GetSubscriptionsPage(c echo.Context) error
+	GetAllSubscriptions(c echo.Context) error
+	CreateSubscription(c echo.Context) error
+	UpdateSubscription(c echo.Context) error
+	DeleteSubscription(c echo.Context) error
+	Subscribe(c echo.Context) error
+	CancelSubscription(c echo.Context) error
+	RecordPayment(c echo.Context) error
+	UpdateUserShortLinks(c echo.Context) error
+	GetLinkCredits(c echo.Context) error
+	RedeemPromoCode(c echo.Context) error
+	GetPromoCodes(c echo.Context) error
+	CreatePromoCode(c echo.Context) error
+	DeletePromoCode(c echo.Context) error
+	VerifyEmail(c echo.Context) error
+	ResendEmailVerification(c echo.Context) error
+	GetReferrals(c echo.Context) error
+	GetDomains(c echo.Context) error
+	AddDomain(c echo.Context) error
+	VerifyDomain(c echo.Context) error
+	UpdateDomain(c echo.Context) error
+	DeleteDomain(c echo.Context) error
+	GetShortLinkQR(c echo.Context) error
+	UpdateShortLinkRedirect(c echo.Context) error
+	UpdateShortLinkUTM(c echo.Context) error
+	GetCampaignStats(c echo.Context) error
 	GetUser(c echo.Context) error
 	DeleteShortLink(c echo.Context) error
 	GetUserShortLinksNumber(c echo.Context) error
 	GetLinksPage(c echo.Context) error
 	GetShortLinksMatchingPattern(c echo.Context) error
 	GetSearchLinksPage(c echo.Context) error
+	UpdateShortLinkInfo(c echo.Context) error
+	GetUserTags(c echo.Context) error
+	DeleteUserTag(c echo.Context) error
+	GetHealthz(c echo.Context) error
+	GetReadyz(c echo.Context) error
 }
 
 type Template struct {
@@ -48,6 +82,19 @@ func (t *Template) Render(w io.Writer, name string, data interface{}, c echo.Con
 func GetRoutes(si ServerInterface) *echo.Echo {
 	e := echo.New()
 
+	logger := slog.Default()
+
+	if h, ok := si.(*Handlers); ok {
+		logger = h.logger()
+	}
+
+	// внешним, чтобы access-лог видел итоговый статус ответа, а остальные middleware - ошибку обработчика
+	e.Use(logging.EchoMiddleware(logger))
+
+	e.Use(tracing.EchoMiddleware)
+
+	e.Use(metrics.EchoMiddleware)
+
 	e.Use(middleware.CORS())
 
 	e.Use(middleware.Static("/static"))
@@ -60,6 +107,11 @@ func GetRoutes(si ServerInterface) *echo.Echo {
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
+	e.GET("/healthz", si.GetHealthz)
+	e.GET("/readyz", si.GetReadyz)
+
 	e.GET("/", si.GetMainPage)
 	e.GET("/login", si.GetLoginPage)
 	e.GET("/register", si.GetRegisterPage)
@@ -69,8 +121,32 @@ func GetRoutes(si ServerInterface) *echo.Echo {
 	e.POST("/create_link", si.CreateShortLink)
 	e.GET("/create_link", si.GetCreateShortLink)
 	e.GET("/:short_link", si.GetShortLink)
+	// алиас-префикс: статические маршруты выше совпадают раньше, поэтому страницы сервиса не перекрываются
+	e.GET("/:short_link/*", si.GetShortLink)
 	e.GET("/subscriptions", si.GetSubscriptionsPage)
 	e.GET("/get_subscriptions", si.GetSubscriptions)
+	e.GET("/admin/subscriptions", si.GetAllSubscriptions)
+	e.POST("/admin/subscriptions", si.CreateSubscription)
+	e.PUT("/admin/subscriptions/:id", si.UpdateSubscription)
+	e.DELETE("/admin/subscriptions/:id", si.DeleteSubscription)
+	e.POST("/subscribe", si.Subscribe)
+	e.POST("/cancel_subscription", si.CancelSubscription)
+	e.POST("/admin/payments", si.RecordPayment)
+	e.PUT("/admin/update-links", si.UpdateUserShortLinks)
+	e.GET("/link_credits", si.GetLinkCredits)
+	e.POST("/redeem_promo", si.RedeemPromoCode)
+	e.GET("/admin/promo_codes", si.GetPromoCodes)
+	e.POST("/admin/promo_codes", si.CreatePromoCode)
+	e.DELETE("/admin/promo_codes/:code", si.DeletePromoCode)
+	e.GET("/verify_email", si.VerifyEmail)
+	e.POST("/resend_verification", si.ResendEmailVerification)
+	e.GET("/referrals", si.GetReferrals)
+	e.GET("/domains", si.GetDomains)
+	e.POST("/domains", si.AddDomain)
+	e.POST("/domains/:host/verify", si.VerifyDomain)
+	e.PUT("/domains/:host", si.UpdateDomain)
+	e.DELETE("/domains/:host", si.DeleteDomain)
+	e.GET("/links/:short/qr", si.GetShortLinkQR)
 	e.GET("/user", si.GetUser)
 	e.GET("/get_links", si.GetUserShortLinks)
 	e.GET("/get_total_links_number", si.GetUserShortLinksNumber)
@@ -78,6 +154,12 @@ func GetRoutes(si ServerInterface) *echo.Echo {
 	e.GET("/search_links", si.GetShortLinksMatchingPattern)
 	e.GET("/search_links_by_word", si.GetSearchLinksPage)
 	e.DELETE("/delete_link", si.DeleteShortLink)
+	e.PUT("/update_link", si.UpdateShortLinkInfo)
+	e.PUT("/update_link_redirect", si.UpdateShortLinkRedirect)
+	e.PUT("/update_link_utm", si.UpdateShortLinkUTM)
+	e.GET("/campaign_stats", si.GetCampaignStats)
+	e.GET("/get_tags", si.GetUserTags)
+	e.DELETE("/delete_tag", si.DeleteUserTag)
 
 	return e
 
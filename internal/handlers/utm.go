@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"urleater/dto"
+)
+
+// UTMRequest описывает метки кампании ссылки. Источник и кампания обязательны, если задана хоть одна метка.
+type UTMRequest struct {
+	Source   string `json:"source"`
+	Medium   string `json:"medium"`
+	Campaign string `json:"campaign"`
+	Term     string `json:"term"`
+	Content  string `json:"content"`
+}
+
+func (r UTMRequest) toDTO() dto.UTM {
+	return dto.UTM{
+		Source:   r.Source,
+		Medium:   r.Medium,
+		Campaign: r.Campaign,
+		Term:     r.Term,
+		Content:  r.Content,
+	}
+}
+
+// UpdateShortLinkUTMRequest описывает тело запроса для изменения меток кампании ссылки.
+type UpdateShortLinkUTMRequest struct {
+	ShortLink string     `json:"short_link" validate:"required"`
+	UTM       UTMRequest `json:"utm"`
+}
+
+// CampaignStatsResponse описывает ответ со статистикой переходов по кампаниям.
+type CampaignStatsResponse struct {
+	Campaigns []dto.CampaignStats `json:"campaigns"`
+}
+
+// UpdateShortLinkUTM godoc
+// @Summary Изменение меток кампании ссылки
+// @Description Задаёт метки utm_*, которые дописываются к длинному URL при переходе. Пустые метки убирают ссылку из кампании.
+// @Tags Ссылки
+// @Accept json
+// @Produce json
+// @Param UpdateShortLinkUTMRequest body UpdateShortLinkUTMRequest true "Метки кампании"
+// @Success 200 {object} UpdateShortLinkInfoResponse "Обновлённая ссылка"
+// @Failure 400 {object} string "Неверные метки или неавторизован"
+// @Failure 404 {object} string "Ссылка не найдена"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /update_link_utm [put]
+func (h *Handlers) UpdateShortLinkUTM(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	requestData := new(UpdateShortLinkUTMRequest)
+	if err := c.Bind(requestData); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if c.Echo().Validator != nil {
+		if err := c.Validate(requestData); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	link, err := h.Service.UpdateShortLinkUTM(c.Request().Context(), requestData.ShortLink, email, requestData.UTM.toDTO())
+	if err != nil {
+		return c.JSON(planErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, UpdateShortLinkInfoResponse{
+		Link: *link,
+	})
+}
+
+// GetCampaignStats godoc
+// @Summary Переходы по кампаниям
+// @Description Возвращает число ссылок и переходов по ним для каждой метки utm_campaign пользователя, начиная с самых посещаемых.
+// @Tags Ссылки
+// @Produce json
+// @Success 200 {object} CampaignStatsResponse "Статистика кампаний"
+// @Failure 400 {object} string "Неавторизован"
+// @Failure 500 {object} string "Ошибка сервера"
+// @Router /campaign_stats [get]
+func (h *Handlers) GetCampaignStats(c echo.Context) error {
+	email, err := h.Store.RetrieveEmailFromSession(c)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if email == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"redirectTo": "/login",
+		})
+	}
+
+	stats, err := h.Service.GetCampaignStats(c.Request().Context(), email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, CampaignStatsResponse{
+		Campaigns: stats,
+	})
+}
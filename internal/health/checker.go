@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+	"urleater/dto"
+)
+
+// Check проверяет одну зависимость. Без критичной зависимости сервис не может обслуживать запросы,
+// без некритичной работает с ограничениями: например, без Elasticsearch не работает только поиск.
+type Check struct {
+	Name     string
+	Critical bool
+	Ping     func(ctx context.Context) error
+}
+
+// Checker параллельно опрашивает зависимости, ограничивая каждую проверку таймаутом.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+func (c *Checker) Check(ctx context.Context) dto.HealthReport {
+	report := dto.HealthReport{
+		Status:       dto.HealthStatusOK,
+		Dependencies: make(map[string]dto.DependencyHealth, len(c.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, check := range c.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			dependency := c.run(ctx, check)
+
+			mu.Lock()
+
+			defer mu.Unlock()
+
+			report.Dependencies[check.Name] = dependency
+
+			switch {
+			case dependency.Status == dto.HealthStatusOK:
+			case check.Critical:
+				report.Status = dto.HealthStatusDown
+			case report.Status == dto.HealthStatusOK:
+				report.Status = dto.HealthStatusDegraded
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) dto.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+
+	defer cancel()
+
+	started := time.Now()
+
+	err := check.Ping(ctx)
+
+	dependency := dto.DependencyHealth{
+		Status:    dto.HealthStatusOK,
+		Critical:  check.Critical,
+		LatencyMs: time.Since(started).Milliseconds(),
+	}
+
+	if err != nil {
+		dependency.Status = dto.HealthStatusDegraded
+		dependency.Error = err.Error()
+
+		if check.Critical {
+			dependency.Status = dto.HealthStatusDown
+		}
+	}
+
+	return dependency
+}
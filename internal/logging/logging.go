@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Config задаёт уровень и формат логов. RedactPII маскирует email и IP-адреса в сообщениях и атрибутах.
+type Config struct {
+	Level     string
+	Format    string
+	RedactPII bool
+}
+
+// New создаёт логгер, который добавляет к записям request_id и trace_id из контекста.
+// Чтобы они попали в запись, логировать нужно через методы с контекстом: InfoContext, ErrorContext.
+func New(cfg Config, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", cfg.Level)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+
+	if cfg.RedactPII {
+		options.ReplaceAttr = redactAttr
+	}
+
+	var handler slog.Handler
+
+	switch strings.ToLower(cfg.Format) {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	return slog.New(&contextHandler{next: handler, redact: cfg.RedactPII}), nil
+}
+
+// OrDefault возвращает logger или стандартный логгер, если logger не передан.
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+
+	return logger
+}
+
+// contextHandler дописывает в запись идентификаторы запроса и трейса и маскирует персональные данные в тексте сообщения.
+type contextHandler struct {
+	next   slog.Handler
+	redact bool
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+
+	if h.redact {
+		record.Message = RedactText(record.Message)
+	}
+
+	return h.next.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs), redact: h.redact}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name), redact: h.redact}
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+!#-]+@([A-Za-z0-9-]+\.)+[A-Za-z]{2,}`)
+
+// ipKeys - атрибуты с IP-адресами, их значения скрываются целиком.
+var ipKeys = map[string]bool{
+	"ip":        true,
+	"remote_ip": true,
+	"client_ip": true,
+}
+
+// RedactText маскирует email в произвольном тексте, оставляя первую букву и домен: a***@mail.ru.
+func RedactText(text string) string {
+	return emailPattern.ReplaceAllStringFunc(text, func(email string) string {
+		at := strings.LastIndexByte(email, '@')
+
+		return email[:1] + "***" + email[at:]
+	})
+}
+
+func redactAttr(_ []string, attr slog.Attr) slog.Attr {
+	if ipKeys[attr.Key] {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, RedactText(attr.Value.String()))
+
+	case slog.KindAny:
+		// текст ошибок часто содержит email пользователя
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, RedactText(err.Error()))
+		}
+	}
+
+	return attr
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"time"
+)
+
+// RequestIDKey - имя атрибута лога и поля событий с идентификатором запроса.
+const RequestIDKey = "request_id"
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID возвращает идентификатор запроса, в рамках которого выполняется ctx, или пустую строку.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+
+	return requestID
+}
+
+// maxRequestIDLength ограничивает идентификатор, пришедший от клиента, чтобы он не раздувал логи.
+const maxRequestIDLength = 128
+
+// EchoMiddleware берёт идентификатор запроса из заголовка X-Request-ID или создаёт новый, возвращает его
+// в ответе и кладёт в контекст запроса, откуда его берут логи и события. После ответа пишет строку access-лога.
+func EchoMiddleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			started := time.Now()
+
+			request := c.Request()
+
+			requestID := request.Header.Get(echo.HeaderXRequestID)
+
+			if requestID == "" || len(requestID) > maxRequestIDLength {
+				requestID = uuid.Must(uuid.NewV4()).String()
+			}
+
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			c.SetRequest(request.WithContext(WithRequestID(request.Context(), requestID)))
+
+			err := next(c)
+
+			if err != nil {
+				// статус ответа выставит обработчик ошибок echo, поэтому вызываем его здесь, как middleware.Logger
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+
+			level := slog.LevelInfo
+
+			if status >= 500 {
+				level = slog.LevelError
+			}
+
+			// контекст запроса после обработчиков содержит и спан, созданный tracing.EchoMiddleware
+			logger.LogAttrs(c.Request().Context(), level, "request",
+				slog.String("method", request.Method),
+				slog.String("route", c.Path()),
+				slog.Int("status", status),
+				slog.Int64("latency_ms", time.Since(started).Milliseconds()),
+				slog.String("remote_ip", c.RealIP()),
+			)
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP отправляет письма через SMTP-сервер. Если задан логин, используется авторизация PLAIN:
+// net/smtp разрешает её только поверх TLS или на localhost.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(addr string, username string, password string, from string) *SMTP {
+	m := &SMTP{
+		addr: addr,
+		from: from,
+	}
+
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+// SendMail отправляет текстовое письмо. net/smtp не принимает контекст, поэтому отмена ctx проверяется только до отправки.
+func (m *SMTP) SendMail(ctx context.Context, to string, subject string, body string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("SendMail: %w", err)
+	}
+
+	// перевод строки в адресе или теме добавил бы в письмо свои заголовки
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("SendMail: recipient and subject must be a single line")
+	}
+
+	message := strings.Join([]string{
+		"From: " + m.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(message)); err != nil {
+		return fmt.Errorf("SendMail: could not send mail to %s %w", to, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute подставляется вместо шаблона для запросов, не попавших ни в один маршрут.
+const unmatchedRoute = "unmatched"
+
+// EchoMiddleware считает запросы и их длительность по маршруту и статусу ответа.
+func EchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		started := time.Now()
+
+		err := next(c)
+
+		status := c.Response().Status
+
+		if err != nil {
+			var httpErr *echo.HTTPError
+
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			} else {
+				status = http.StatusInternalServerError
+			}
+		}
+
+		route := c.Path()
+
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		labels := []string{c.Request().Method, route, strconv.Itoa(status)}
+
+		HTTPRequests.WithLabelValues(labels...).Inc()
+		HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(started).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"urleater/dto"
+)
+
+const namespace = "urleater"
+
+// Метрики HTTP-запросов. route - шаблон маршрута echo, а не путь запроса, чтобы короткие ссылки не раздували число рядов.
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Результаты поиска ссылки в кеше Redis при переходе.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+var RedirectCache = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "redirect_cache_requests_total",
+	Help:      "Redirect lookups in the Redis cache by result: hit, miss or error.",
+}, []string{"result"})
+
+var StorageQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "storage_query_duration_seconds",
+	Help:      "Postgres storage latency by storage method.",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"method"})
+
+// Результаты обработки сообщений из очереди.
+const (
+	ConsumeProcessed   = "processed"
+	ConsumeDeadLetter  = "dead_letter"
+	ConsumeInterrupted = "interrupted"
+)
+
+// Метрики очереди. Считаются в сервисе, поэтому одинаковы для Kafka, Postgres и очереди в памяти.
+var (
+	QueueProduced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_messages_produced_total",
+		Help:      "Messages published to the queue by topic and result: ok or error.",
+	}, []string{"topic", "result"})
+
+	QueueConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_messages_consumed_total",
+		Help:      "Messages handled by workers by topic and result: processed, dead_letter or interrupted.",
+	}, []string{"topic", "result"})
+
+	// ConsumerLag обновляет консьюмер Kafka по локально известной верхней границе партиции.
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "Messages in the partition after the last one read by the consumer.",
+	}, []string{"topic", "partition"})
+
+	OutboxBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "outbox_backlog",
+		Help:      "Outbox events waiting to be relayed.",
+	})
+
+	OutboxPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_published_total",
+		Help:      "Outbox events relayed and completed.",
+	})
+
+	OutboxFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_failed_total",
+		Help:      "Outbox event attempts that failed and were postponed.",
+	})
+)
+
+// Бизнес-метрики.
+var (
+	LinksCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "links_created_total",
+		Help:      "Short links created.",
+	})
+
+	UsersRegistered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_registered_total",
+		Help:      "Users registered.",
+	})
+
+	SubscriptionTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subscription_transitions_total",
+		Help:      "Subscriptions changed by the scheduler: renewed, past_due, downgraded to the free plan or expired unpaid.",
+	}, []string{"transition"})
+
+	LinkCredits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "link_credits_total",
+		Help:      "Links credited to users, by reason: admin, promo or referral.",
+	}, []string{"reason"})
+
+	PromoRedemptions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "promo_redemptions_total",
+		Help:      "Promo codes redeemed, by kind: link_credits or percent_off.",
+	}, []string{"kind"})
+
+	QuotaExhausted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "link_quota_exhausted_total",
+		Help:      "Short link creations rejected because the user has no links left.",
+	})
+)
+
+// RegisterWorkerChannel публикует заполненность канала воркеров. Вызывается один раз при запуске сервиса.
+func RegisterWorkerChannel(workerChannel chan dto.ConsumerMessage) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_channel_depth",
+		Help:      "Messages read from the queue and waiting for a worker.",
+	}, func() float64 {
+		return float64(len(workerChannel))
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_channel_capacity",
+		Help:      "Capacity of the worker channel.",
+	}, func() float64 {
+		return float64(cap(workerChannel))
+	})
+}
@@ -0,0 +1,322 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"urleater/internal/logging"
+)
+
+// lockKey - ключ advisory lock, под которым миграции применяет только один экземпляр сервиса.
+const lockKey = 7315420981
+
+// schema_migrations совместима с golang-migrate: в ней одна строка с последней применённой версией,
+// поэтому базы, которые раньше мигрировались через CLI, продолжают с той же версии.
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// ErrDirty - предыдущая миграция, запущенная через golang-migrate, не завершилась. Базу нужно исправить вручную.
+var ErrDirty = errors.New("database is dirty")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+
+	hasUp, hasDown bool
+}
+
+// Load читает пары <version>_<name>.up.sql и .down.sql из fsys и возвращает их по возрастанию версии.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations %d_%s and %d_%s have the same version", version, m.Name, version, match[2])
+		}
+
+		// файл может быть пустым, как в golang-migrate, важно только его наличие
+		if match[3] == "up" {
+			m.Up, m.hasUp = string(body), true
+		} else {
+			m.Down, m.hasDown = string(body), true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		if !m.hasUp || !m.hasDown {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrator применяет и откатывает миграции. Каждая миграция выполняется в своей транзакции вместе
+// с записью версии, поэтому ошибка в миграции не оставляет базу в промежуточном состоянии.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+func New(pool *pgxpool.Pool, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{pool: pool, migrations: migrations, logger: logging.OrDefault(logger)}, nil
+}
+
+// MigrationStatus - миграция и признак того, что она применена.
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+type Status struct {
+	// Version - последняя применённая миграция, 0 - миграции не применялись.
+	Version    int64
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+// Pending возвращает количество неприменённых миграций.
+func (s Status) Pending() int {
+	pending := 0
+
+	for _, m := range s.Migrations {
+		if !m.Applied {
+			pending++
+		}
+	}
+
+	return pending
+}
+
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	var status Status
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+
+		status.Version, status.Dirty, err = currentVersion(ctx, conn)
+
+		return err
+	})
+
+	if err != nil {
+		return Status{}, err
+	}
+
+	for _, migration := range m.migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{Migration: migration, Applied: migration.Version <= status.Version})
+	}
+
+	return status, nil
+}
+
+// Up применяет все миграции новее текущей версии и возвращает их количество.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, err := cleanVersion(ctx, conn)
+
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+
+			if err = apply(ctx, conn, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("error applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			m.logger.InfoContext(ctx, "migration applied", "version", migration.Version, "name", migration.Name)
+
+			applied++
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down откатывает steps последних применённых миграций и возвращает количество откаченных.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, err := cleanVersion(ctx, conn)
+
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+
+			if migration.Version > version {
+				continue
+			}
+
+			var previous int64
+
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+
+			if err = apply(ctx, conn, migration.Down, previous); err != nil {
+				return fmt.Errorf("error reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			m.logger.InfoContext(ctx, "migration reverted", "version", migration.Version, "name", migration.Name)
+
+			reverted++
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// withLock выполняет fn на одном соединении под advisory lock: остальные экземпляры ждут, пока миграции закончатся.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+
+	if err != nil {
+		return fmt.Errorf("error acquiring connection: %w", err)
+	}
+
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("error taking migration lock: %w", err)
+	}
+
+	defer func() {
+		// контекст мог быть отменён, а блокировку нужно снять в любом случае
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockKey)
+	}()
+
+	if _, err = conn.Exec(ctx, createVersionTable); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func currentVersion(ctx context.Context, conn *pgxpool.Conn) (int64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+func cleanVersion(ctx context.Context, conn *pgxpool.Conn) (int64, error) {
+	version, dirty, err := currentVersion(ctx, conn)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d, fix the schema and set dirty = false in schema_migrations", ErrDirty, version)
+	}
+
+	return version, nil
+}
+
+// apply выполняет sql и записывает version в одной транзакции. version 0 - миграций не осталось.
+func apply(ctx context.Context, conn *pgxpool.Conn, sql string, version int64) error {
+	return conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if strings.TrimSpace(sql) != "" {
+			if _, err := tx.Exec(ctx, sql); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(ctx, "TRUNCATE schema_migrations"); err != nil {
+			return err
+		}
+
+		if version == 0 {
+			return nil
+		}
+
+		_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", version)
+
+		return err
+	})
+}
@@ -0,0 +1,298 @@
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"urleater/dto"
+)
+
+const DefaultBatchSize = 500
+
+// LinkSource - источник ссылок для индекса, Postgres.
+type LinkSource interface {
+	CountShortLinks(ctx context.Context) (int, error)
+	GetShortLinksBatch(ctx context.Context, afterShortUrl string, limit int) ([]dto.Link, error)
+}
+
+// Index - поисковый индекс коротких ссылок, доступный через алиас.
+type Index interface {
+	Alias() string
+	CreateVersionedIndex(ctx context.Context) (string, error)
+	SwitchAlias(ctx context.Context, index string) ([]string, error)
+	DeleteIndices(ctx context.Context, indices ...string) error
+	BulkIndex(ctx context.Context, index string, links []dto.Link) error
+	BulkDelete(ctx context.Context, index string, shortLinks []string) error
+	IndexedShortLinks(ctx context.Context, index string) (map[string]dto.Link, error)
+}
+
+// Diff - расхождения между Postgres и индексом.
+type Diff struct {
+	Total    int
+	Indexed  int
+	Missing  []string // есть в Postgres, нет в индексе
+	Stale    []string // документ в индексе не совпадает с Postgres
+	Orphaned []string // есть в индексе, нет в Postgres
+}
+
+func (d Diff) Consistent() bool {
+	return len(d.Missing) == 0 && len(d.Stale) == 0 && len(d.Orphaned) == 0
+}
+
+type Reindexer struct {
+	source    LinkSource
+	index     Index
+	batchSize int
+	out       io.Writer
+}
+
+func New(source LinkSource, index Index, batchSize int, out io.Writer) *Reindexer {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Reindexer{source: source, index: index, batchSize: batchSize, out: out}
+}
+
+// Backfill дозаписывает все ссылки из Postgres в индекс за алиасом и удаляет из него ссылки, которых в Postgres нет.
+func (r *Reindexer) Backfill(ctx context.Context) (int, error) {
+	indexed, err := r.index.IndexedShortLinks(ctx, r.index.Alias())
+
+	if err != nil {
+		return 0, fmt.Errorf("Backfill: error reading index %w", err)
+	}
+
+	total, err := r.copyLinks(ctx, r.index.Alias(), func(link dto.Link) {
+		delete(indexed, link.ShortUrl)
+	})
+
+	if err != nil {
+		return total, fmt.Errorf("Backfill: %w", err)
+	}
+
+	orphaned := sortedKeys(indexed)
+
+	if err = r.index.BulkDelete(ctx, r.index.Alias(), orphaned); err != nil {
+		return total, fmt.Errorf("Backfill: error deleting orphaned links %w", err)
+	}
+
+	fmt.Fprintf(r.out, "deleted %d orphaned documents\n", len(orphaned))
+
+	return total, nil
+}
+
+// Rebuild собирает новый индекс с текущим маппингом, переключает на него алиас и удаляет прежние индексы.
+// Пока индекс собирается, поиск и запись продолжают работать со старым индексом,
+// поэтому после переключения изменения, сделанные во время копирования, дозаписываются в новый.
+func (r *Reindexer) Rebuild(ctx context.Context) (int, error) {
+	index, err := r.index.CreateVersionedIndex(ctx)
+
+	if err != nil {
+		return 0, fmt.Errorf("Rebuild: %w", err)
+	}
+
+	fmt.Fprintf(r.out, "created index %s\n", index)
+
+	total, err := r.copyLinks(ctx, index, nil)
+
+	if err != nil {
+		r.dropIndex(index)
+
+		return total, fmt.Errorf("Rebuild: %w", err)
+	}
+
+	previous, err := r.index.SwitchAlias(ctx, index)
+
+	if err != nil {
+		r.dropIndex(index)
+
+		return total, fmt.Errorf("Rebuild: %w", err)
+	}
+
+	fmt.Fprintf(r.out, "alias %s switched to %s\n", r.index.Alias(), index)
+
+	// прежние индексы не удаляются, чтобы после ошибки можно было запустить backfill
+	caught, err := r.catchUp(ctx)
+
+	if err != nil {
+		return total, fmt.Errorf("Rebuild: alias switched, but catching up failed, run backfill %w", err)
+	}
+
+	fmt.Fprintf(r.out, "caught up %d documents changed during the copy\n", caught)
+
+	if err = r.index.DeleteIndices(ctx, previous...); err != nil {
+		fmt.Fprintf(r.out, "could not delete previous indices %v: %s\n", previous, err.Error())
+	}
+
+	return total, nil
+}
+
+// Diff сравнивает ссылки в Postgres с документами индекса за алиасом, ничего не изменяя.
+func (r *Reindexer) Diff(ctx context.Context) (Diff, error) {
+	indexed, err := r.index.IndexedShortLinks(ctx, r.index.Alias())
+
+	if err != nil {
+		return Diff{}, fmt.Errorf("Diff: error reading index %w", err)
+	}
+
+	diff := Diff{Indexed: len(indexed)}
+
+	err = r.walk(ctx, func(batch []dto.Link) error {
+		for _, link := range batch {
+			doc, ok := indexed[link.ShortUrl]
+
+			switch {
+			case !ok:
+				diff.Missing = append(diff.Missing, link.ShortUrl)
+			case !sameDocument(link, doc):
+				diff.Stale = append(diff.Stale, link.ShortUrl)
+			}
+
+			delete(indexed, link.ShortUrl)
+		}
+
+		diff.Total += len(batch)
+
+		return nil
+	})
+
+	if err != nil {
+		return Diff{}, fmt.Errorf("Diff: %w", err)
+	}
+
+	diff.Orphaned = sortedKeys(indexed)
+
+	return diff, nil
+}
+
+// catchUp дозаписывает в индекс за алиасом ссылки, которые изменились в Postgres после копирования их пачки,
+// и удаляет удалённые. Более поздние изменения обработчик outbox уже пишет в индекс за алиасом.
+func (r *Reindexer) catchUp(ctx context.Context) (int, error) {
+	indexed, err := r.index.IndexedShortLinks(ctx, r.index.Alias())
+
+	if err != nil {
+		return 0, fmt.Errorf("error reading index %w", err)
+	}
+
+	changed := 0
+
+	err = r.walk(ctx, func(batch []dto.Link) error {
+		var stale []dto.Link
+
+		for _, link := range batch {
+			if doc, ok := indexed[link.ShortUrl]; !ok || !sameDocument(link, doc) {
+				stale = append(stale, link)
+			}
+
+			delete(indexed, link.ShortUrl)
+		}
+
+		if len(stale) == 0 {
+			return nil
+		}
+
+		changed += len(stale)
+
+		return r.index.BulkIndex(ctx, r.index.Alias(), stale)
+	})
+
+	if err != nil {
+		return changed, err
+	}
+
+	orphaned := sortedKeys(indexed)
+
+	if len(orphaned) == 0 {
+		return changed, nil
+	}
+
+	if err = r.index.BulkDelete(ctx, r.index.Alias(), orphaned); err != nil {
+		return changed, fmt.Errorf("error deleting orphaned links %w", err)
+	}
+
+	return changed + len(orphaned), nil
+}
+
+func (r *Reindexer) copyLinks(ctx context.Context, index string, seen func(dto.Link)) (int, error) {
+	count, err := r.source.CountShortLinks(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+
+	err = r.walk(ctx, func(batch []dto.Link) error {
+		if err := r.index.BulkIndex(ctx, index, batch); err != nil {
+			return err
+		}
+
+		if seen != nil {
+			for _, link := range batch {
+				seen(link)
+			}
+		}
+
+		total += len(batch)
+
+		fmt.Fprintf(r.out, "indexed %d/%d\n", total, count)
+
+		return nil
+	})
+
+	return total, err
+}
+
+// walk читает ссылки из Postgres пачками по batchSize.
+func (r *Reindexer) walk(ctx context.Context, handle func([]dto.Link) error) error {
+	after := ""
+
+	for {
+		batch, err := r.source.GetShortLinksBatch(ctx, after, r.batchSize)
+
+		if err != nil {
+			return err
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err = handle(batch); err != nil {
+			return err
+		}
+
+		if len(batch) < r.batchSize {
+			return nil
+		}
+
+		after = batch[len(batch)-1].ShortUrl
+	}
+}
+
+func (r *Reindexer) dropIndex(index string) {
+	if err := r.index.DeleteIndices(context.Background(), index); err != nil {
+		fmt.Fprintf(r.out, "could not delete index %s: %s\n", index, err.Error())
+	}
+}
+
+// sameDocument сравнивает только поля, которые попадают в индекс.
+func sameDocument(link dto.Link, doc dto.Link) bool {
+	return link.LongUrl == doc.LongUrl &&
+		link.UserEmail == doc.UserEmail &&
+		link.Title == doc.Title &&
+		slices.Equal(link.Tags, doc.Tags)
+}
+
+func sortedKeys(links map[string]dto.Link) []string {
+	keys := make([]string, 0, len(links))
+
+	for key := range links {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -5,82 +5,199 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/olivere/elastic/v7"
+	"net/url"
+	"strings"
+	"time"
+	"urleater/dto"
 )
 
+// shortLinksIndex - алиас, через который идут чтение и запись. Сами индексы версионируются,
+// см. index.go.
+const shortLinksIndex = "short_links"
+
+// minNgramQueryLength - длина n-граммы анализатора; более короткие запросы ищутся по префиксу short_url.
+const minNgramQueryLength = 3
+
+// shortLinksMapping индексирует текстовые поля триграммами, чтобы поиск подстроки
+// не требовал wildcard-запросов с ведущей звёздочкой.
+const shortLinksMapping = `{
+	"settings": {
+		"analysis": {
+			"tokenizer": {
+				"link_ngram_tokenizer": {
+					"type": "ngram",
+					"min_gram": 3,
+					"max_gram": 3,
+					"token_chars": ["letter", "digit"]
+				}
+			},
+			"analyzer": {
+				"link_ngram": {
+					"type": "custom",
+					"tokenizer": "link_ngram_tokenizer",
+					"filter": ["lowercase"]
+				}
+			}
+		}
+	},
+	"mappings": {
+		"dynamic": "strict",
+		"properties": {
+			"short_url": {"type": "text", "analyzer": "link_ngram", "fields": {"keyword": {"type": "keyword"}}},
+			"user_email": {"type": "keyword"},
+			"long_url": {"type": "text", "analyzer": "link_ngram"},
+			"domain": {"type": "text", "analyzer": "link_ngram", "fields": {"keyword": {"type": "keyword"}}},
+			"title": {"type": "text", "analyzer": "link_ngram"},
+			"tags": {"type": "text", "analyzer": "link_ngram", "fields": {"keyword": {"type": "keyword"}}},
+			"created_at": {"type": "date"}
+		}
+	}
+}`
+
 type Searcher struct {
 	client *elastic.Client
 	url    string
 }
 
+// shortLinkDocument - документ индекса short_links. Идентификатор документа совпадает с short_url.
+type shortLinkDocument struct {
+	ShortURL  string    `json:"short_url"`
+	UserEmail string    `json:"user_email"`
+	LongURL   string    `json:"long_url"`
+	Domain    string    `json:"domain"`
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 func NewSearcher(client *elastic.Client, url string) *Searcher {
 	return &Searcher{client: client, url: url}
 }
 
-func (s *Searcher) SearchShortLinks(ctx context.Context, word string, limit int, offset int) ([]string, error) {
-	type ShortLink struct {
-		ShortURL string `json:"short_url"`
+func (s *Searcher) SearchShortLinks(ctx context.Context, searchQuery dto.SearchQuery) (dto.SearchResult, error) {
+	text := strings.ToLower(strings.TrimSpace(searchQuery.Text))
+
+	query := elastic.NewBoolQuery()
+
+	if len([]rune(text)) < minNgramQueryLength {
+		query = query.Must(elastic.NewPrefixQuery("short_url.keyword", text))
+	} else {
+		query = query.Must(elastic.NewMultiMatchQuery(text, "short_url^3", "title^2", "tags^2", "domain", "long_url").
+			Operator("and"))
 	}
 
-	query := elastic.NewWildcardQuery("short_url.keyword", "*"+word+"*")
-	fmt.Println("*"+word+"*", offset)
-	// Выполняем запрос с учетом пагинации: offset и limit.
+	if searchQuery.OwnerEmail != "" {
+		query = query.Filter(elastic.NewTermQuery("user_email", searchQuery.OwnerEmail))
+	}
+
+	highlight := elastic.NewHighlight().
+		Fields(
+			elastic.NewHighlighterField("short_url"),
+			elastic.NewHighlighterField("title"),
+			elastic.NewHighlighterField("long_url"),
+			elastic.NewHighlighterField("tags"),
+		).
+		PreTags("<mark>").
+		PostTags("</mark>")
+
 	searchResult, err := s.client.Search().
-		Index("short_links").
+		Index(shortLinksIndex).
 		Query(query).
-		From(offset).
-		Size(limit).
+		Highlight(highlight).
+		TrackTotalHits(true).
+		From(searchQuery.Offset).
+		Size(searchQuery.Limit).
 		Do(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error executing elastic query: %w", err)
+		return dto.SearchResult{}, fmt.Errorf("error executing elastic query: %w", err)
+	}
+
+	result := dto.SearchResult{
+		Total: int(searchResult.TotalHits()),
+		Hits:  make([]dto.SearchHit, 0, len(searchResult.Hits.Hits)),
 	}
 
-	var links []string
-	fmt.Println(searchResult.Hits.Hits)
 	for _, hit := range searchResult.Hits.Hits {
-		var sl ShortLink
-		if err := json.Unmarshal(hit.Source, &sl); err != nil {
+		var doc shortLinkDocument
+		if err := json.Unmarshal(hit.Source, &doc); err != nil {
 			continue
 		}
-		links = append(links, sl.ShortURL)
+
+		result.Hits = append(result.Hits, dto.SearchHit{
+			ShortUrl:   doc.ShortURL,
+			Highlights: hit.Highlight,
+		})
 	}
 
-	fmt.Println(links)
-	return links, nil
+	return result, nil
 }
 
-func (s *Searcher) AddShortLink(ctx context.Context, link string) error {
-	doc := map[string]interface{}{
-		"short_url": link,
-	}
-
+func (s *Searcher) AddShortLink(ctx context.Context, link dto.Link) error {
 	_, err := s.client.Index().
-		Index("short_links").
-		BodyJson(doc).
+		Index(shortLinksIndex).
+		Id(link.ShortUrl).
+		BodyJson(newShortLinkDocument(link)).
 		Refresh("true").
 		Do(ctx)
 
 	if err != nil {
-		return fmt.Errorf("error getting short link: %w", err)
+		return fmt.Errorf("error indexing short link: %w", err)
 	}
 
 	return nil
 }
 
 func (s *Searcher) DeleteShortLink(ctx context.Context, link string) error {
-	query := elastic.NewTermQuery("short_url.keyword", link)
-
-	res, err := s.client.DeleteByQuery().
-		Index("short_links").
-		Query(query).
+	_, err := s.client.Delete().
+		Index(shortLinksIndex).
+		Id(link).
 		Refresh("true").
 		Do(ctx)
+
+	if elastic.IsNotFound(err) {
+		return nil
+	}
+
 	if err != nil {
 		return fmt.Errorf("ошибка удаления короткой ссылки: %w", err)
 	}
 
-	if res.Deleted == 0 {
-		return fmt.Errorf("документ не найден")
+	return nil
+}
+
+// Ping проверяет, что кластер отвечает и его состояние не red.
+func (s *Searcher) Ping(ctx context.Context) error {
+	health, err := s.client.ClusterHealth().Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("ошибка проверки состояния кластера: %w", err)
+	}
+
+	if health.Status == "red" {
+		return fmt.Errorf("кластер %s в состоянии red", health.ClusterName)
 	}
 
 	return nil
 }
+
+func newShortLinkDocument(link dto.Link) shortLinkDocument {
+	return shortLinkDocument{
+		ShortURL:  link.ShortUrl,
+		UserEmail: link.UserEmail,
+		LongURL:   link.LongUrl,
+		Domain:    linkDomain(link.LongUrl),
+		Title:     link.Title,
+		Tags:      link.Tags,
+		CreatedAt: link.CreatedAt,
+	}
+}
+
+func linkDomain(longUrl string) string {
+	u, err := url.Parse(longUrl)
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
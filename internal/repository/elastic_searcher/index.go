@@ -0,0 +1,267 @@
+package elastic_searcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/olivere/elastic/v7"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+	"urleater/dto"
+)
+
+// MappingVersion - версия shortLinksMapping. Её нужно увеличивать при каждом изменении маппинга:
+// индекс со старой версией остаётся рабочим, но его нужно перестроить командой reindex.
+const MappingVersion = 1
+
+var ErrLegacyIndex = errors.New("short_links is a plain index created with dynamic mapping, rebuild it with the reindex command")
+
+var versionedIndexName = regexp.MustCompile(`^` + shortLinksIndex + `_v(\d+)_\d{14}$`)
+
+// IndexStatus описывает индекс, на который сейчас указывает алиас short_links.
+type IndexStatus struct {
+	Index          string
+	MappingVersion int
+}
+
+func (st IndexStatus) Outdated() bool {
+	return st.MappingVersion < MappingVersion
+}
+
+func (s *Searcher) Alias() string {
+	return shortLinksIndex
+}
+
+// EnsureIndex создаёт индекс текущей версии маппинга и алиас short_links, если алиаса ещё нет.
+// Существующий алиас не трогает: смена версии маппинга требует перестройки индекса.
+func (s *Searcher) EnsureIndex(ctx context.Context) (IndexStatus, error) {
+	status, err := s.GetIndexStatus(ctx)
+
+	switch {
+	case err == nil:
+		return status, nil
+
+	case errors.Is(err, ErrLegacyIndex):
+		return IndexStatus{}, err
+
+	case !elastic.IsNotFound(err):
+		return IndexStatus{}, err
+	}
+
+	index, err := s.CreateVersionedIndex(ctx)
+
+	if err != nil {
+		return IndexStatus{}, err
+	}
+
+	if _, err = s.SwitchAlias(ctx, index); err != nil {
+		return IndexStatus{}, err
+	}
+
+	return IndexStatus{Index: index, MappingVersion: MappingVersion}, nil
+}
+
+// GetIndexStatus возвращает индекс за алиасом short_links и версию его маппинга.
+func (s *Searcher) GetIndexStatus(ctx context.Context) (IndexStatus, error) {
+	exists, err := s.client.IndexExists(shortLinksIndex).Do(ctx)
+
+	if err != nil {
+		return IndexStatus{}, fmt.Errorf("error checking index %s: %w", shortLinksIndex, err)
+	}
+
+	if !exists {
+		return IndexStatus{}, &elastic.Error{Status: 404}
+	}
+
+	indices, err := s.aliasIndices(ctx)
+
+	if err != nil {
+		return IndexStatus{}, err
+	}
+
+	if len(indices) == 0 {
+		return IndexStatus{}, ErrLegacyIndex
+	}
+
+	if len(indices) > 1 {
+		return IndexStatus{}, fmt.Errorf("alias %s points to several indices %v", shortLinksIndex, indices)
+	}
+
+	status := IndexStatus{Index: indices[0]}
+
+	if match := versionedIndexName.FindStringSubmatch(indices[0]); match != nil {
+		status.MappingVersion, _ = strconv.Atoi(match[1])
+	}
+
+	return status, nil
+}
+
+func (s *Searcher) aliasIndices(ctx context.Context) ([]string, error) {
+	res, err := s.client.Aliases().Alias(shortLinksIndex).Do(ctx)
+
+	if elastic.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting alias %s: %w", shortLinksIndex, err)
+	}
+
+	return res.IndicesByAlias(shortLinksIndex), nil
+}
+
+// CreateVersionedIndex создаёт пустой индекс с текущим маппингом и возвращает его имя.
+func (s *Searcher) CreateVersionedIndex(ctx context.Context) (string, error) {
+	index := fmt.Sprintf("%s_v%d_%s", shortLinksIndex, MappingVersion, time.Now().UTC().Format("20060102150405"))
+
+	_, err := s.client.CreateIndex(index).BodyString(shortLinksMapping).Do(ctx)
+
+	if err != nil {
+		return "", fmt.Errorf("error creating index %s: %w", index, err)
+	}
+
+	return index, nil
+}
+
+// SwitchAlias атомарно переключает алиас short_links на index и возвращает индексы, на которые он указывал раньше.
+// Индекс без версии, созданный динамическим маппингом под именем short_links, удаляется в том же запросе.
+func (s *Searcher) SwitchAlias(ctx context.Context, index string) ([]string, error) {
+	exists, err := s.client.IndexExists(shortLinksIndex).Do(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("error checking index %s: %w", shortLinksIndex, err)
+	}
+
+	previous, err := s.aliasIndices(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []elastic.AliasAction{elastic.NewAliasAddAction(shortLinksIndex).Index(index)}
+
+	if exists && len(previous) == 0 {
+		actions = append(actions, elastic.NewAliasRemoveIndexAction(shortLinksIndex))
+	}
+
+	for _, old := range previous {
+		if old != index {
+			actions = append(actions, elastic.NewAliasRemoveAction(shortLinksIndex).Index(old))
+		}
+	}
+
+	_, err = s.client.Alias().Action(actions...).Do(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("error switching alias %s to %s: %w", shortLinksIndex, index, err)
+	}
+
+	return previous, nil
+}
+
+func (s *Searcher) DeleteIndices(ctx context.Context, indices ...string) error {
+	if len(indices) == 0 {
+		return nil
+	}
+
+	_, err := s.client.DeleteIndex(indices...).Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("error deleting indices %v: %w", indices, err)
+	}
+
+	return nil
+}
+
+// BulkIndex записывает ссылки в индекс (или алиас) одним bulk-запросом.
+func (s *Searcher) BulkIndex(ctx context.Context, index string, links []dto.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	bulk := s.client.Bulk().Index(index)
+
+	for _, link := range links {
+		bulk = bulk.Add(elastic.NewBulkIndexRequest().Id(link.ShortUrl).Doc(newShortLinkDocument(link)))
+	}
+
+	res, err := bulk.Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("error bulk indexing into %s: %w", index, err)
+	}
+
+	if failed := res.Failed(); len(failed) > 0 {
+		return fmt.Errorf("error bulk indexing into %s: %d documents failed, first: %s", index, len(failed), failed[0].Error.Reason)
+	}
+
+	return nil
+}
+
+// BulkDelete удаляет документы по short_url. Отсутствующие документы ошибкой не считаются.
+func (s *Searcher) BulkDelete(ctx context.Context, index string, shortLinks []string) error {
+	if len(shortLinks) == 0 {
+		return nil
+	}
+
+	bulk := s.client.Bulk().Index(index)
+
+	for _, shortLink := range shortLinks {
+		bulk = bulk.Add(elastic.NewBulkDeleteRequest().Id(shortLink))
+	}
+
+	res, err := bulk.Do(ctx)
+
+	if err != nil {
+		return fmt.Errorf("error bulk deleting from %s: %w", index, err)
+	}
+
+	for _, item := range res.Failed() {
+		if item.Status != 404 {
+			return fmt.Errorf("error bulk deleting from %s: %s", index, item.Error.Reason)
+		}
+	}
+
+	return nil
+}
+
+// IndexedShortLinks читает все документы индекса через scroll и восстанавливает по ним ссылки
+// (без счётчиков и дат, которых в индексе нет).
+func (s *Searcher) IndexedShortLinks(ctx context.Context, index string) (map[string]dto.Link, error) {
+	links := make(map[string]dto.Link)
+
+	scroll := s.client.Scroll(index).Size(1000)
+
+	defer scroll.Clear(context.Background())
+
+	for {
+		res, err := scroll.Do(ctx)
+
+		if err == io.EOF {
+			return links, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error scrolling index %s: %w", index, err)
+		}
+
+		for _, hit := range res.Hits.Hits {
+			var doc shortLinkDocument
+
+			if err = json.Unmarshal(hit.Source, &doc); err != nil {
+				return nil, fmt.Errorf("error decoding document %s: %w", hit.Id, err)
+			}
+
+			links[hit.Id] = dto.Link{
+				ShortUrl:  doc.ShortURL,
+				LongUrl:   doc.LongURL,
+				UserEmail: doc.UserEmail,
+				Title:     doc.Title,
+				Tags:      doc.Tags,
+			}
+		}
+	}
+}
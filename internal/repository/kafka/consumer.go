@@ -2,18 +2,21 @@ package kafkaProducerConsumer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"log"
+	"log/slog"
+	"strconv"
 	"time"
 	"urleater/dto"
+	"urleater/internal/logging"
+	"urleater/internal/metrics"
 )
 
 type Consumer struct {
 	config        KafkaConfig
 	consumer      *kafka.Consumer
-	workerChannel chan dto.ConsumerData
+	workerChannel chan dto.ConsumerMessage
+	tracker       *OffsetTracker
 }
 
 type KafkaConfig struct {
@@ -21,93 +24,198 @@ type KafkaConfig struct {
 	KafkaTopics  []string
 	KafkaGroupId string
 	KafkaServer  string
+	// FlushTimeout ограничивает ожидание доставки сообщений при закрытии продюсера.
+	FlushTimeout time.Duration
+	// Logger получает ошибки, которые не возвращаются вызывающему коду. Если не задан, используется slog.Default().
+	Logger *slog.Logger
 }
 
-func (c *Consumer) GetConfig() KafkaConfig {
-	return c.config
+func (c KafkaConfig) logger() *slog.Logger {
+	return logging.OrDefault(c.Logger)
 }
 
-func (c *Consumer) GetWorkerChannel() chan dto.ConsumerData {
-	return c.workerChannel
+func NewConsumer(config KafkaConfig, workerChannel chan dto.ConsumerMessage) (*Consumer, error) {
+	c := &Consumer{
+		config:        config,
+		workerChannel: workerChannel,
+	}
+
+	if err := c.Reconnect(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
 }
 
-func NewConsumer(config KafkaConfig, workerChannel chan dto.ConsumerData) (*Consumer, error) {
-	newConsumer, err := kafka.NewConsumer(config.KafkaConfig)
+// Reconnect создаёт нового консьюмера вместо закрытого после ошибки в StartConsuming.
+// Неподтверждённые сообщения старого консьюмера будут прочитаны заново.
+func (c *Consumer) Reconnect() error {
+	newConsumer, err := newManualCommitConsumer(c.config.KafkaConfig)
+
+	if err != nil {
+		return err
+	}
+
+	c.consumer = newConsumer
+	c.tracker = NewOffsetTracker()
+
+	err = newConsumer.SubscribeTopics(c.config.KafkaTopics, c.rebalance)
+
+	if err != nil {
+		newConsumer.Close()
+
+		return fmt.Errorf("error subscribing to topics: %w", err)
+	}
+
+	return nil
+}
+
+// newManualCommitConsumer создаёт консьюмера без автокоммита: смещения коммитятся только после обработки сообщений.
+func newManualCommitConsumer(config *kafka.ConfigMap) (*kafka.Consumer, error) {
+	configMap := kafka.ConfigMap{}
+
+	for key, value := range *config {
+		configMap[key] = value
+	}
+
+	configMap["enable.auto.commit"] = false
+
+	newConsumer, err := kafka.NewConsumer(&configMap)
 
 	if err != nil {
 		return nil, fmt.Errorf("error creating kafka consumer: %w", err)
 	}
 
-	err = newConsumer.SubscribeTopics(config.KafkaTopics, nil)
+	return newConsumer, nil
+}
+
+// rebalance перед отзывом партиций коммитит уже обработанное.
+func (c *Consumer) rebalance(_ *kafka.Consumer, event kafka.Event) error {
+	if revoked, ok := event.(kafka.RevokedPartitions); ok {
+		c.commit()
+
+		c.tracker.Forget(revoked.Partitions)
+	}
+
+	return nil
+}
+
+func (c *Consumer) commit() {
+	offsets := c.tracker.Committable()
+
+	if len(offsets) == 0 {
+		return
+	}
+
+	committed, err := c.consumer.CommitOffsets(offsets)
 
 	if err != nil {
-		return nil, fmt.Errorf("error subscribing to topics: %w", err)
+		c.config.logger().Error("error committing offsets", "error", err)
+
+		return
 	}
 
-	return &Consumer{
-		config:        config,
-		consumer:      newConsumer,
-		workerChannel: workerChannel,
-	}, nil
+	c.tracker.MarkCommitted(committed)
 }
 
+// StartConsuming читает сообщения, пока не отменён ctx. После отмены консьюмер остаётся открытым, чтобы Close
+// закоммитил сообщения, которые воркеры подтвердят позже. После ошибки консьюмер закрывается и пересоздаётся через Reconnect.
 func (c *Consumer) StartConsuming(ctx context.Context) error {
-	defer c.consumer.Close()
+	err := c.consume(ctx)
 
+	if err != nil {
+		c.closeConsumer()
+	}
+
+	return err
+}
+
+// Close коммитит подтверждённые сообщения и закрывает консьюмера.
+func (c *Consumer) Close() error {
+	return c.closeConsumer()
+}
+
+func (c *Consumer) closeConsumer() error {
+	if c.consumer == nil {
+		return nil
+	}
+
+	c.commit()
+
+	err := c.consumer.Close()
+
+	c.consumer = nil
+
+	if err != nil {
+		return fmt.Errorf("error closing kafka consumer: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Consumer) consume(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
+			c.commit()
+
 			msg, err := c.consumer.ReadMessage(500 * time.Millisecond)
 			if err != nil {
 				err, ok := err.(kafka.Error)
 
 				// Если ошибка является таймаутом, продолжаем цикл
 				if !ok {
-					log.Println("error while running consumer", err)
-
 					return fmt.Errorf("error while running consumer: %w", err)
 				} else {
 					if !err.IsTimeout() {
-						log.Println("kafka error while running consumer", err)
-
 						return fmt.Errorf("kafka error while running consumer: %w", err)
 					} else {
-						//log.Println("timeout, continuing...")
 						continue
 					}
 				}
 			}
 
-			var data dto.ConsumerData
-			if err = json.Unmarshal(msg.Value, &data); err != nil {
-				log.Println("error unmarshalling message:", err)
-
-				continue
+			message := dto.ConsumerMessage{
+				Topic:     *msg.TopicPartition.Topic,
+				Partition: msg.TopicPartition.Partition,
+				Offset:    int64(msg.TopicPartition.Offset),
+				Key:       msg.Key,
+				Value:     msg.Value,
+				Headers:   headersMap(msg.Headers),
+				Ack:       c.tracker.Track(*msg.TopicPartition.Topic, msg.TopicPartition.Partition, int64(msg.TopicPartition.Offset)),
 			}
 
-			log.Println("sending data to worker")
+			c.observeLag(msg.TopicPartition)
 
 			select {
-			case c.workerChannel <- data:
-				log.Println("sent data to worker")
+			case c.workerChannel <- message:
 			case <-ctx.Done():
 				return nil
 			}
-
-			_, err = c.consumer.CommitOffsets([]kafka.TopicPartition{
-				{
-					Topic:     msg.TopicPartition.Topic,
-					Partition: msg.TopicPartition.Partition,
-					Offset:    msg.TopicPartition.Offset + 1,
-				},
-			})
-			if err != nil {
-				log.Println("error committing offsets:", err)
-				return fmt.Errorf("failed to commit offsets: %w", err)
-			}
-			log.Println("committed offset")
 		}
 	}
 }
+
+func headersMap(headers []kafka.Header) map[string]string {
+	values := make(map[string]string, len(headers))
+
+	for _, header := range headers {
+		values[header.Key] = string(header.Value)
+	}
+
+	return values
+}
+
+// observeLag обновляет отставание по партиции. Верхняя граница берётся из последнего ответа брокера без запроса к нему.
+func (c *Consumer) observeLag(partition kafka.TopicPartition) {
+	_, high, err := c.consumer.GetWatermarkOffsets(*partition.Topic, partition.Partition)
+
+	if err != nil || high <= 0 {
+		return
+	}
+
+	metrics.ConsumerLag.WithLabelValues(*partition.Topic, strconv.Itoa(int(partition.Partition))).
+		Set(float64(max(high-int64(partition.Offset)-1, 0)))
+}
@@ -0,0 +1,131 @@
+package kafkaProducerConsumer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"sync"
+)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+type partitionOffsets struct {
+	pending     []int64
+	acked       map[int64]bool
+	committable kafka.Offset
+	committed   kafka.Offset
+}
+
+// OffsetTracker запоминает прочитанные, но ещё не обработанные сообщения.
+// Воркеры подтверждают сообщения в произвольном порядке, а коммитить можно только смещение,
+// до которого обработаны все сообщения партиции.
+type OffsetTracker struct {
+	mu         sync.Mutex
+	partitions map[topicPartition]*partitionOffsets
+}
+
+func NewOffsetTracker() *OffsetTracker {
+	return &OffsetTracker{partitions: make(map[topicPartition]*partitionOffsets)}
+}
+
+// Track регистрирует прочитанное сообщение и возвращает функцию его подтверждения.
+func (t *OffsetTracker) Track(topic string, partition int32, offset int64) func() {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	key := topicPartition{topic: topic, partition: partition}
+
+	offsets, ok := t.partitions[key]
+
+	if !ok {
+		offsets = &partitionOffsets{
+			acked:       make(map[int64]bool),
+			committable: kafka.OffsetInvalid,
+			committed:   kafka.OffsetInvalid,
+		}
+
+		t.partitions[key] = offsets
+	}
+
+	offsets.pending = append(offsets.pending, offset)
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			t.ack(offsets, offset)
+		})
+	}
+}
+
+func (t *OffsetTracker) ack(offsets *partitionOffsets, offset int64) {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	offsets.acked[offset] = true
+
+	for len(offsets.pending) > 0 && offsets.acked[offsets.pending[0]] {
+		delete(offsets.acked, offsets.pending[0])
+
+		offsets.committable = kafka.Offset(offsets.pending[0] + 1)
+		offsets.pending = offsets.pending[1:]
+	}
+}
+
+// Committable возвращает смещения, которые можно закоммитить и которые ещё не закоммичены.
+func (t *OffsetTracker) Committable() []kafka.TopicPartition {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	var result []kafka.TopicPartition
+
+	for key, offsets := range t.partitions {
+		if offsets.committable == kafka.OffsetInvalid || offsets.committable == offsets.committed {
+			continue
+		}
+
+		topic := key.topic
+
+		result = append(result, kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: key.partition,
+			Offset:    offsets.committable,
+		})
+	}
+
+	return result
+}
+
+func (t *OffsetTracker) MarkCommitted(partitions []kafka.TopicPartition) {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	for _, tp := range partitions {
+		if tp.Topic == nil || tp.Error != nil {
+			continue
+		}
+
+		if offsets, ok := t.partitions[topicPartition{topic: *tp.Topic, partition: tp.Partition}]; ok {
+			offsets.committed = tp.Offset
+		}
+	}
+}
+
+// Forget перестаёт отслеживать отозванные партиции: подтверждения их сообщений больше ни на что не влияют,
+// а сами сообщения получит новый владелец партиции.
+func (t *OffsetTracker) Forget(partitions []kafka.TopicPartition) {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	for _, tp := range partitions {
+		if tp.Topic != nil {
+			delete(t.partitions, topicPartition{topic: *tp.Topic, partition: tp.Partition})
+		}
+	}
+}
@@ -1,69 +1,335 @@
 package kafkaProducerConsumer
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
 	"urleater/dto"
+	"urleater/internal/tracing"
 )
 
+const (
+	// messageTimeout ограничивает время, за которое librdkafka должна доставить сообщение
+	// и вернуть отчёт о доставке, включая её собственные повторы.
+	messageTimeout    = 10 * time.Second
+	publishAttempts   = 3
+	publishMinBackoff = 200 * time.Millisecond
+	// defaultFlushTimeout используется, если в KafkaConfig не задан FlushTimeout.
+	defaultFlushTimeout = 10 * time.Second
+	defaultPingTimeout  = 2 * time.Second
+)
+
+// Тип и версия события дублируются в заголовках, чтобы потребители могли фильтровать события, не разбирая их.
+const (
+	HeaderEventType    = "event-type"
+	HeaderEventVersion = "event-version"
+)
+
+// Заголовки сообщений очереди недоставленных сообщений.
+const (
+	HeaderOriginalTopic     = "dlq-original-topic"
+	HeaderOriginalPartition = "dlq-original-partition"
+	HeaderOriginalOffset    = "dlq-original-offset"
+	HeaderError             = "dlq-error"
+	HeaderAttempts          = "dlq-attempts"
+	HeaderFailedAt          = "dlq-failed-at"
+)
+
+var ErrProducerClosed = errors.New("kafka producer is closed")
+
+// Producer публикует сообщения и дожидается отчёта о доставке каждого из них.
+// Все методы безопасны для вызова из нескольких горутин.
 type Producer struct {
-	config   KafkaConfig
+	config KafkaConfig
+
+	mu       sync.RWMutex
 	producer *kafka.Producer
+	closed   bool
 }
 
 func NewProducer(config KafkaConfig) (*Producer, error) {
-	newProducer, err := kafka.NewProducer(config.KafkaConfig)
+	p := &Producer{config: config}
+
+	newProducer, err := p.newKafkaProducer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.producer = newProducer
+
+	return p, nil
+}
+
+func (p *Producer) newKafkaProducer() (*kafka.Producer, error) {
+	configMap := kafka.ConfigMap{}
+
+	for key, value := range *p.config.KafkaConfig {
+		configMap[key] = value
+	}
+
+	// идемпотентность не даёт внутренним повторам librdkafka задублировать или переставить сообщения
+	configMap["enable.idempotence"] = true
+	configMap["acks"] = "all"
+	configMap["message.timeout.ms"] = int(messageTimeout.Milliseconds())
+
+	newProducer, err := kafka.NewProducer(&configMap)
 
 	if err != nil {
 		return nil, fmt.Errorf("error creating kafka producer: %w", err)
 	}
 
-	return &Producer{
-		config:   config,
-		producer: newProducer,
-	}, nil
+	go logProducerEvents(newProducer, p.config.logger())
+
+	return newProducer, nil
 }
 
-func (p *Producer) GetConfig() KafkaConfig {
-	return p.config
+// logProducerEvents вычитывает общий канал событий: без этого librdkafka заблокируется, когда он заполнится.
+// Отчёты о доставке сюда не попадают, они приходят в канал, переданный в Produce.
+func logProducerEvents(producer *kafka.Producer, logger *slog.Logger) {
+	for event := range producer.Events() {
+		switch e := event.(type) {
+		case kafka.Error:
+			logger.Error("kafka producer error", "error", e)
+		case *kafka.Message:
+			if e.TopicPartition.Error != nil {
+				logger.Error("kafka delivery failed", "error", e.TopicPartition.Error)
+			}
+		}
+	}
 }
 
-func (p *Producer) PublishMsg(msgType string, data map[string]string, topic string) error {
+// PublishEvent публикует событие с ключом key (события с одним ключом попадают в одну партицию и читаются по порядку)
+// и возвращается после подтверждения доставки. Неудачная отправка повторяется с задержкой,
+// при фатальной ошибке продюсер пересоздаётся.
+func (p *Producer) PublishEvent(ctx context.Context, key string, event dto.Event, topic string) error {
+	byteData, err := json.Marshal(event)
+
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %w", err)
+	}
+
+	headers := []kafka.Header{
+		{Key: HeaderEventType, Value: []byte(event.Type)},
+		{Key: HeaderEventVersion, Value: []byte(strconv.Itoa(event.Version))},
+	}
+
+	headers = appendHeaders(headers, tracing.Inject(ctx))
+
+	return p.publishRaw(ctx, topic, []byte(key), byteData, headers)
+}
+
+// PublishDeadLetter публикует необработанное сообщение в topic как есть; причина ошибки и
+// исходное положение сообщения передаются в заголовках.
+func (p *Producer) PublishDeadLetter(ctx context.Context, topic string, letter dto.DeadLetter) error {
+	headers := []kafka.Header{
+		{Key: HeaderOriginalTopic, Value: []byte(letter.Message.Topic)},
+		{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(int(letter.Message.Partition)))},
+		{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(letter.Message.Offset, 10))},
+		{Key: HeaderError, Value: []byte(letter.Error)},
+		{Key: HeaderAttempts, Value: []byte(strconv.Itoa(letter.Attempts))},
+		{Key: HeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	}
+
+	// контекст трассировки исходного сообщения сохраняется, чтобы повторная обработка продолжила тот же трейс
+	headers = appendHeaders(headers, letter.Message.Headers)
+
+	return p.publishRaw(ctx, topic, letter.Message.Key, letter.Message.Value, headers)
+}
+
+func appendHeaders(headers []kafka.Header, values map[string]string) []kafka.Header {
+	for key, value := range values {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	return headers
+}
+
+func (p *Producer) publishRaw(ctx context.Context, topic string, key []byte, value []byte, headers []kafka.Header) error {
 	var err error
 
-	defer func() {
-		if err != nil {
-			p.Close()
+	backoff := publishMinBackoff
+
+	for attempt := 1; ; attempt++ {
+		var producer *kafka.Producer
+
+		producer, err = p.publish(ctx, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{
+				Topic:     &topic,
+				Partition: kafka.PartitionAny,
+			},
+			Key:     key,
+			Value:   value,
+			Headers: headers,
+		})
+
+		if err == nil || errors.Is(err, ErrProducerClosed) || attempt == publishAttempts {
+			break
+		}
+
+		var kafkaErr kafka.Error
+
+		if errors.As(err, &kafkaErr) && kafkaErr.IsFatal() {
+			if reconnectErr := p.reconnect(producer); reconnectErr != nil {
+				p.config.logger().ErrorContext(ctx, "error while reconnecting kafka producer", "error", reconnectErr)
+			}
 		}
-	}()
 
-	byteData, err := json.Marshal(dto.ConsumerData{
-		TypeOfMessage: msgType,
-		Data:          data,
-	})
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error publishing data: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
 
 	if err != nil {
-		return fmt.Errorf("error marshalling data: %w", err)
+		return fmt.Errorf("error publishing data: %w", err)
 	}
 
-	err = p.producer.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{
-			Topic:     &topic,
-			Partition: kafka.PartitionAny,
-		},
-		Value: byteData,
-	}, nil)
+	return nil
+}
+
+// publish отправляет сообщение и ждёт отчёт о доставке. Возвращает продюсер, через который шла отправка.
+func (p *Producer) publish(ctx context.Context, msg *kafka.Message) (*kafka.Producer, error) {
+	deliveryChan := make(chan kafka.Event, 1)
+
+	p.mu.RLock()
+
+	if p.closed {
+		p.mu.RUnlock()
+
+		return nil, ErrProducerClosed
+	}
+
+	producer := p.producer
+
+	err := producer.Produce(msg, deliveryChan)
+
+	p.mu.RUnlock()
 
 	if err != nil {
-		return fmt.Errorf("error publishing data: %w", err)
+		return producer, err
+	}
+
+	timer := time.NewTimer(messageTimeout + 5*time.Second)
+
+	defer timer.Stop()
+
+	select {
+	case event := <-deliveryChan:
+		delivered, ok := event.(*kafka.Message)
+
+		if !ok {
+			return producer, fmt.Errorf("unexpected delivery event %v", event)
+		}
+
+		return producer, delivered.TopicPartition.Error
+
+	case <-ctx.Done():
+		return producer, ctx.Err()
+
+	case <-timer.C:
+		return producer, errors.New("no delivery report received")
+	}
+}
+
+// Ping запрашивает у брокеров метаданные, дожидаясь ответа не дольше дедлайна ctx.
+func (p *Producer) Ping(ctx context.Context) error {
+	timeout := defaultPingTimeout
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	p.mu.RLock()
+
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	if _, err := p.producer.GetMetadata(nil, false, int(timeout.Milliseconds())); err != nil {
+		return fmt.Errorf("error getting kafka metadata: %w", err)
+	}
+
+	return nil
+}
+
+// Reconnect пересоздаёт продюсер. Неотправленные сообщения старого продюсера перед закрытием дописываются.
+func (p *Producer) Reconnect() error {
+	p.mu.Lock()
+
+	defer p.mu.Unlock()
+
+	return p.replaceLocked()
+}
+
+// reconnect пересоздаёт продюсер, только если его ещё не пересоздала другая горутина.
+func (p *Producer) reconnect(failed *kafka.Producer) error {
+	p.mu.Lock()
+
+	defer p.mu.Unlock()
+
+	if p.producer != failed {
+		return nil
+	}
+
+	return p.replaceLocked()
+}
+
+func (p *Producer) replaceLocked() error {
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	newProducer, err := p.newKafkaProducer()
+
+	if err != nil {
+		return fmt.Errorf("error reconnecting kafka producer: %w", err)
 	}
 
+	old := p.producer
+
+	p.producer = newProducer
+
+	old.Flush(p.flushTimeoutMs())
+	old.Close()
+
 	return nil
 }
 
+// Close дожидается доставки поставленных в очередь сообщений и закрывает продюсер.
 func (p *Producer) Close() {
-	if p.producer != nil {
-		p.producer.Close()
+	p.mu.Lock()
+
+	defer p.mu.Unlock()
+
+	if p.closed || p.producer == nil {
+		return
+	}
+
+	p.closed = true
+
+	if pending := p.producer.Flush(p.flushTimeoutMs()); pending > 0 {
+		p.config.logger().Warn("kafka producer closed with undelivered messages", "pending", pending)
 	}
+
+	p.producer.Close()
+}
+
+func (p *Producer) flushTimeoutMs() int {
+	if p.config.FlushTimeout > 0 {
+		return int(p.config.FlushTimeout.Milliseconds())
+	}
+
+	return int(defaultFlushTimeout.Milliseconds())
 }
@@ -0,0 +1,111 @@
+package kafkaProducerConsumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"io"
+	"time"
+)
+
+type ReplayOptions struct {
+	// Limit - сколько сообщений переотправить, 0 - все.
+	Limit int
+	// DryRun только печатает сообщения, ничего не переотправляя и не коммитя.
+	DryRun bool
+	// IdleTimeout - через сколько без новых сообщений очередь считается прочитанной.
+	IdleTimeout time.Duration
+	Out         io.Writer
+}
+
+// ReplayDeadLetters переотправляет сообщения из очереди недоставленных в их исходные топики.
+// Смещение в очереди коммитится после доставки каждого сообщения, поэтому прерванный повтор можно продолжить.
+func ReplayDeadLetters(ctx context.Context, config KafkaConfig, deadLetterTopic string, producer *Producer, opts ReplayOptions) (int, error) {
+	configMap := kafka.ConfigMap{}
+
+	for key, value := range *config.KafkaConfig {
+		configMap[key] = value
+	}
+
+	configMap["group.id"] = config.KafkaGroupId + "_dlq_replay"
+	configMap["auto.offset.reset"] = "earliest"
+
+	consumer, err := newManualCommitConsumer(&configMap)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer consumer.Close()
+
+	if err = consumer.SubscribeTopics([]string{deadLetterTopic}, nil); err != nil {
+		return 0, fmt.Errorf("error subscribing to %s: %w", deadLetterTopic, err)
+	}
+
+	replayed := 0
+	idleSince := time.Now()
+
+	for opts.Limit == 0 || replayed < opts.Limit {
+		if ctx.Err() != nil {
+			return replayed, ctx.Err()
+		}
+
+		msg, err := consumer.ReadMessage(time.Second)
+
+		var kafkaErr kafka.Error
+
+		if errors.As(err, &kafkaErr) && kafkaErr.IsTimeout() {
+			if time.Since(idleSince) >= opts.IdleTimeout {
+				break
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return replayed, fmt.Errorf("error reading %s: %w", deadLetterTopic, err)
+		}
+
+		idleSince = time.Now()
+
+		headers := messageHeaders(msg)
+
+		originalTopic := headers[HeaderOriginalTopic]
+
+		fmt.Fprintf(opts.Out, "offset %d: key %q, topic %q, attempts %s, failed at %s, error: %s\n",
+			msg.TopicPartition.Offset, msg.Key, originalTopic, headers[HeaderAttempts], headers[HeaderFailedAt], headers[HeaderError])
+
+		if opts.DryRun {
+			replayed++
+
+			continue
+		}
+
+		if originalTopic == "" {
+			return replayed, fmt.Errorf("message at offset %d has no %s header", msg.TopicPartition.Offset, HeaderOriginalTopic)
+		}
+
+		if err = producer.publishRaw(ctx, originalTopic, msg.Key, msg.Value, nil); err != nil {
+			return replayed, err
+		}
+
+		if _, err = consumer.CommitMessage(msg); err != nil {
+			return replayed, fmt.Errorf("error committing %s offset: %w", deadLetterTopic, err)
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+func messageHeaders(msg *kafka.Message) map[string]string {
+	headers := make(map[string]string, len(msg.Headers))
+
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	return headers
+}
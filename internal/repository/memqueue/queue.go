@@ -0,0 +1,129 @@
+package memqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"urleater/dto"
+	"urleater/internal/logging"
+	"urleater/internal/tracing"
+)
+
+// Queue - очередь сообщений внутри процесса для запуска без Kafka. Сообщения не переживают перезапуск,
+// поэтому очередь подходит для локальной разработки и небольших установок, где потеря кликов допустима.
+type Queue struct {
+	mu          sync.Mutex
+	topics      map[string]chan dto.ConsumerMessage
+	buffer      int
+	offset      int64
+	deadLetters []dto.DeadLetter
+	logger      *slog.Logger
+}
+
+func New(buffer int, logger *slog.Logger) *Queue {
+	return &Queue{
+		topics: make(map[string]chan dto.ConsumerMessage),
+		buffer: buffer,
+		logger: logging.OrDefault(logger),
+	}
+}
+
+func (q *Queue) topic(name string) chan dto.ConsumerMessage {
+	q.mu.Lock()
+
+	defer q.mu.Unlock()
+
+	ch, ok := q.topics[name]
+
+	if !ok {
+		ch = make(chan dto.ConsumerMessage, q.buffer)
+
+		q.topics[name] = ch
+	}
+
+	return ch
+}
+
+func (q *Queue) PublishEvent(ctx context.Context, key string, event dto.Event, topic string) error {
+	value, err := json.Marshal(event)
+
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %w", err)
+	}
+
+	q.mu.Lock()
+
+	q.offset++
+
+	offset := q.offset
+
+	q.mu.Unlock()
+
+	select {
+	case q.topic(topic) <- dto.ConsumerMessage{Topic: topic, Offset: offset, Key: []byte(key), Value: value, Headers: tracing.Inject(ctx), Ack: func() {}}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PublishDeadLetter сохраняет сообщение в памяти, посмотреть их можно через DeadLetters.
+func (q *Queue) PublishDeadLetter(ctx context.Context, topic string, letter dto.DeadLetter) error {
+	q.logger.WarnContext(ctx, "dead letter", "topic", topic, "key", string(letter.Message.Key),
+		"attempts", letter.Attempts, "error", letter.Error)
+
+	q.mu.Lock()
+
+	defer q.mu.Unlock()
+
+	q.deadLetters = append(q.deadLetters, letter)
+
+	return nil
+}
+
+func (q *Queue) DeadLetters() []dto.DeadLetter {
+	q.mu.Lock()
+
+	defer q.mu.Unlock()
+
+	return append([]dto.DeadLetter(nil), q.deadLetters...)
+}
+
+// Consumer передаёт сообщения топика воркерам.
+type Consumer struct {
+	queue         *Queue
+	topic         string
+	workerChannel chan dto.ConsumerMessage
+}
+
+func (q *Queue) NewConsumer(topic string, workerChannel chan dto.ConsumerMessage) *Consumer {
+	return &Consumer{queue: q, topic: topic, workerChannel: workerChannel}
+}
+
+func (c *Consumer) StartConsuming(ctx context.Context) error {
+	messages := c.queue.topic(c.topic)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-messages:
+			select {
+			case c.workerChannel <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (c *Consumer) Reconnect() error {
+	return nil
+}
+
+// Close ничего не делает: сообщения подтверждаются сразу в Ack.
+func (c *Consumer) Close() error {
+	return nil
+}
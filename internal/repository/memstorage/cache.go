@@ -0,0 +1,109 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+	"urleater/dto"
+)
+
+// Cache - аналог redisDB.Storage в памяти. Срок действия хранится с точностью до секунды, как в значении Redis.
+type Cache struct {
+	mu      sync.Mutex
+	links   map[string]dto.Link
+	qrCodes map[string]cachedQRCode
+}
+
+type cachedQRCode struct {
+	image     []byte
+	expiresAt time.Time
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		links:   make(map[string]dto.Link),
+		qrCodes: make(map[string]cachedQRCode),
+	}
+}
+
+func (c *Cache) GetShortLinkByLongLink(ctx context.Context, shortLink string) (*dto.Link, error) {
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	link, ok := c.links[shortLink]
+
+	if !ok {
+		return nil, fmt.Errorf("error while getting short link by long link from redis %w", redis.Nil)
+	}
+
+	return &link, nil
+}
+
+func (c *Cache) SaveShortLinkToLongLink(ctx context.Context, link dto.Link) error {
+	expiresAt, err := time.Parse(time.RFC3339, link.ExpiresAt.Format(time.RFC3339))
+
+	if err != nil {
+		return fmt.Errorf("error while saving short link to redis %w", err)
+	}
+
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	c.links[link.ShortUrl] = dto.Link{
+		ShortUrl:        link.ShortUrl,
+		RedirectOptions: link.RedirectOptions,
+		UTM:             link.UTM,
+		ExpiresAt:       expiresAt,
+		UserEmail:       link.UserEmail,
+		LongUrl:         link.LongUrl,
+	}
+
+	return nil
+}
+
+func (c *Cache) DeleteLongLinkByShortLink(ctx context.Context, shortLink string) error {
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	delete(c.links, shortLink)
+
+	return nil
+}
+
+func (c *Cache) GetQRCode(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	code, ok := c.qrCodes[key]
+
+	if !ok || !code.expiresAt.IsZero() && !code.expiresAt.After(time.Now()) {
+		delete(c.qrCodes, key)
+
+		return nil, fmt.Errorf("error while getting qr code from redis %w", redis.Nil)
+	}
+
+	return append([]byte(nil), code.image...), nil
+}
+
+// SaveQRCode сохраняет изображение на ttl, нулевой ttl - без срока, как в Redis.
+func (c *Cache) SaveQRCode(ctx context.Context, key string, image []byte, ttl time.Duration) error {
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	code := cachedQRCode{image: append([]byte(nil), image...)}
+
+	if ttl > 0 {
+		code.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.qrCodes[key] = code
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"time"
+	"urleater/dto"
+)
+
+type promoRedemption struct {
+	code      string
+	userEmail string
+}
+
+// creditLinks добавляет ссылки пользователю и записывает начисление в журнал. Вызывается под s.mu.
+func (s *Storage) creditLinks(credit dto.LinkCredit) (*dto.User, error) {
+	u, ok := s.users[credit.UserEmail]
+
+	if !ok {
+		return nil, fmt.Errorf("credit links query error | %w", pgx.ErrNoRows)
+	}
+
+	u.urlsLeft += credit.Amount
+
+	s.nextLinkCreditId++
+
+	credit.Id = s.nextLinkCreditId
+	credit.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	s.linkCredits = append(s.linkCredits, credit)
+
+	// RETURNING в postgresDB возвращает только email, urls_left и email_verified
+	return &dto.User{
+		Email:         u.email,
+		UrlsLeft:      u.urlsLeft,
+		EmailVerified: u.emailVerified,
+	}, nil
+}
+
+func (s *Storage) AddLinkCredit(ctx context.Context, credit dto.LinkCredit) (*dto.User, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	user, err := s.creditLinks(credit)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddLinkCredit %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) GetLinkCredits(ctx context.Context, email string, limit int) ([]dto.LinkCredit, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var credits []dto.LinkCredit
+
+	for i := len(s.linkCredits) - 1; i >= 0 && len(credits) < limit; i-- {
+		if s.linkCredits[i].UserEmail == email {
+			credits = append(credits, s.linkCredits[i])
+		}
+	}
+
+	return credits, nil
+}
+
+func copyPromoCode(promo *dto.PromoCode) dto.PromoCode {
+	result := *promo
+
+	if promo.PlanId != nil {
+		planId := *promo.PlanId
+		result.PlanId = &planId
+	}
+
+	if promo.ExpiresAt != nil {
+		expiresAt := *promo.ExpiresAt
+		result.ExpiresAt = &expiresAt
+	}
+
+	return result
+}
+
+func (s *Storage) CreatePromoCode(ctx context.Context, promo dto.PromoCode) (*dto.PromoCode, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if _, ok := s.promoCodes[promo.Code]; ok {
+		return nil, fmt.Errorf("CreatePromoCode query error | %w", uniqueViolation("promo_codes", "promo_codes_pkey"))
+	}
+
+	if promo.PlanId != nil && s.subscriptionIndex(*promo.PlanId) < 0 {
+		return nil, fmt.Errorf("CreatePromoCode query error | %w", foreignKeyViolation("promo_codes", "promo_codes_plan_id_fkey"))
+	}
+
+	stored := copyPromoCode(&promo)
+	stored.Redemptions = 0
+	stored.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	if stored.ExpiresAt != nil {
+		expiresAt := stored.ExpiresAt.UTC().Truncate(time.Microsecond)
+		stored.ExpiresAt = &expiresAt
+	}
+
+	s.promoCodes[promo.Code] = &stored
+
+	result := copyPromoCode(&stored)
+
+	return &result, nil
+}
+
+func (s *Storage) GetPromoCodes(ctx context.Context) ([]dto.PromoCode, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var promos []dto.PromoCode
+
+	for _, promo := range s.promoCodes {
+		promos = append(promos, copyPromoCode(promo))
+	}
+
+	sort.Slice(promos, func(i, j int) bool {
+		if !promos[i].CreatedAt.Equal(promos[j].CreatedAt) {
+			return promos[i].CreatedAt.After(promos[j].CreatedAt)
+		}
+
+		return promos[i].Code < promos[j].Code
+	})
+
+	return promos, nil
+}
+
+func (s *Storage) GetPromoCode(ctx context.Context, code string) (*dto.PromoCode, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	promo, ok := s.promoCodes[code]
+
+	if !ok {
+		return nil, fmt.Errorf("GetPromoCode query error | %w", pgx.ErrNoRows)
+	}
+
+	result := copyPromoCode(promo)
+
+	return &result, nil
+}
+
+// deletePromoCode удаляет промокод вместе с активациями, как ON DELETE CASCADE. Вызывается под s.mu.
+func (s *Storage) deletePromoCode(code string) {
+	delete(s.promoCodes, code)
+
+	for redemption := range s.promoRedemptions {
+		if redemption.code == code {
+			delete(s.promoRedemptions, redemption)
+		}
+	}
+}
+
+func (s *Storage) DeletePromoCode(ctx context.Context, code string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if _, ok := s.promoCodes[code]; !ok {
+		return fmt.Errorf("DeletePromoCode query error | %w", pgx.ErrNoRows)
+	}
+
+	s.deletePromoCode(code)
+
+	return nil
+}
+
+func (s *Storage) RedeemPromoCode(ctx context.Context, code string, email string, now time.Time) (*dto.PromoCode, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	promo, err := s.redeemablePromoCode(code, email, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.redeemPromoCode(promo, email, now)
+
+	return &result, nil
+}
+
+// redeemablePromoCode проверяет, что пользователь может активировать код, ничего не изменяя.
+func (s *Storage) redeemablePromoCode(code string, email string, now time.Time) (*dto.PromoCode, error) {
+	promo, ok := s.promoCodes[code]
+
+	if !ok || (promo.ExpiresAt != nil && !promo.ExpiresAt.After(now)) ||
+		(promo.MaxRedemptions > 0 && promo.Redemptions >= promo.MaxRedemptions) {
+		return nil, fmt.Errorf("RedeemPromoCode query error | %w", pgx.ErrNoRows)
+	}
+
+	if _, ok = s.promoRedemptions[promoRedemption{code: code, userEmail: email}]; ok {
+		return nil, fmt.Errorf("RedeemPromoCode query error | %w", uniqueViolation("promo_redemptions", "promo_redemptions_pkey"))
+	}
+
+	if _, ok = s.users[email]; !ok {
+		return nil, fmt.Errorf("RedeemPromoCode query error | %w", foreignKeyViolation("promo_redemptions", "promo_redemptions_user_email_fkey"))
+	}
+
+	return promo, nil
+}
+
+// redeemPromoCode активирует проверенный redeemablePromoCode код.
+func (s *Storage) redeemPromoCode(promo *dto.PromoCode, email string, now time.Time) dto.PromoCode {
+	if promo.Kind == dto.PromoLinkCredits && promo.LinkCredits > 0 {
+		// пользователь уже проверен, поэтому начисление не может не удаться
+		_, _ = s.creditLinks(dto.LinkCredit{
+			UserEmail: email,
+			Amount:    promo.LinkCredits,
+			Reason:    dto.LinkCreditPromo,
+			Reference: promo.Code,
+		})
+	}
+
+	promo.Redemptions++
+	s.promoRedemptions[promoRedemption{code: promo.Code, userEmail: email}] = now.UTC()
+
+	return copyPromoCode(promo)
+}
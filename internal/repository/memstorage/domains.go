@@ -0,0 +1,200 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"time"
+	"urleater/dto"
+)
+
+// domainClaim - ключ домена: на один хост могут претендовать несколько пользователей, пока никто его не подтвердил.
+type domainClaim struct {
+	host      string
+	userEmail string
+}
+
+func copyDomain(domain *dto.Domain) dto.Domain {
+	result := *domain
+
+	if domain.VerifiedAt != nil {
+		verifiedAt := *domain.VerifiedAt
+		result.VerifiedAt = &verifiedAt
+	}
+
+	return result
+}
+
+func (s *Storage) CreateDomain(ctx context.Context, domain dto.Domain) (*dto.Domain, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if _, ok := s.domains[domainClaim{host: domain.Host, userEmail: domain.UserEmail}]; ok {
+		return nil, fmt.Errorf("CreateDomain query error | %w", uniqueViolation("domains", "domains_pkey"))
+	}
+
+	if _, ok := s.users[domain.UserEmail]; !ok {
+		return nil, fmt.Errorf("CreateDomain query error | %w", foreignKeyViolation("domains", "domains_user_email_fkey"))
+	}
+
+	created := &dto.Domain{
+		Host:              domain.Host,
+		UserEmail:         domain.UserEmail,
+		VerificationToken: domain.VerificationToken,
+		FallbackUrl:       domain.FallbackUrl,
+		CreatedAt:         time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	s.domains[domainClaim{host: created.Host, userEmail: created.UserEmail}] = created
+
+	result := copyDomain(created)
+
+	return &result, nil
+}
+
+func (s *Storage) GetDomain(ctx context.Context, host string) (*dto.Domain, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	domain := s.verifiedDomain(host)
+
+	if domain == nil {
+		return nil, fmt.Errorf("GetDomain query error | %w", pgx.ErrNoRows)
+	}
+
+	result := copyDomain(domain)
+
+	return &result, nil
+}
+
+// verifiedDomain возвращает подтверждённый домен host или nil.
+func (s *Storage) verifiedDomain(host string) *dto.Domain {
+	for claim, domain := range s.domains {
+		if claim.host == host && domain.VerifiedAt != nil {
+			return domain
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) GetUserDomain(ctx context.Context, email string, host string) (*dto.Domain, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	domain, ok := s.domains[domainClaim{host: host, userEmail: email}]
+
+	if !ok {
+		return nil, fmt.Errorf("GetUserDomain query error | %w", pgx.ErrNoRows)
+	}
+
+	result := copyDomain(domain)
+
+	return &result, nil
+}
+
+func (s *Storage) GetUserDomains(ctx context.Context, email string) ([]dto.Domain, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var domains []dto.Domain
+
+	for _, domain := range s.domains {
+		if domain.UserEmail == email {
+			domains = append(domains, copyDomain(domain))
+		}
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		if !domains[i].CreatedAt.Equal(domains[j].CreatedAt) {
+			return domains[i].CreatedAt.Before(domains[j].CreatedAt)
+		}
+
+		return domains[i].Host < domains[j].Host
+	})
+
+	return domains, nil
+}
+
+func (s *Storage) VerifyDomain(ctx context.Context, email string, host string, verifiedAt time.Time) (*dto.Domain, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	domain, ok := s.domains[domainClaim{host: host, userEmail: email}]
+
+	if !ok {
+		return nil, fmt.Errorf("VerifyDomain query error | %w", pgx.ErrNoRows)
+	}
+
+	if domain.VerifiedAt == nil {
+		if s.verifiedDomain(host) != nil {
+			return nil, fmt.Errorf("VerifyDomain query error | %w", uniqueViolation("domains", "domains_verified_host_idx"))
+		}
+
+		verifiedAt = verifiedAt.UTC().Truncate(time.Microsecond)
+		domain.VerifiedAt = &verifiedAt
+	}
+
+	for claim, other := range s.domains {
+		if claim.host == host && other.VerifiedAt == nil {
+			delete(s.domains, claim)
+		}
+	}
+
+	result := copyDomain(domain)
+
+	return &result, nil
+}
+
+func (s *Storage) UpdateDomainFallback(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	domain, ok := s.domains[domainClaim{host: host, userEmail: email}]
+
+	if !ok {
+		return nil, fmt.Errorf("UpdateDomainFallback query error | %w", pgx.ErrNoRows)
+	}
+
+	domain.FallbackUrl = fallbackUrl
+
+	result := copyDomain(domain)
+
+	return &result, nil
+}
+
+func (s *Storage) DeleteDomain(ctx context.Context, email string, host string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	claim := domainClaim{host: host, userEmail: email}
+
+	if _, ok := s.domains[claim]; !ok {
+		return fmt.Errorf("DeleteDomain query error | %w", pgx.ErrNoRows)
+	}
+
+	for _, l := range s.sortedLinks(func(l *link) bool { return l.domain == host && l.userEmail == email }) {
+		delete(s.links, l.shortUrl)
+
+		err := s.insertOutboxEvent(ctx, dto.EventLinkDeleted, l.shortUrl, dto.LinkDeleted{
+			ShortLink: l.shortUrl,
+			UserEmail: l.userEmail,
+		})
+
+		if err != nil {
+			return fmt.Errorf("DeleteDomain %w", err)
+		}
+	}
+
+	delete(s.domains, claim)
+
+	return nil
+}
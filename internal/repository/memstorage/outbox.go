@@ -0,0 +1,128 @@
+package memstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"time"
+	"urleater/dto"
+	"urleater/internal/logging"
+)
+
+type outboxEvent struct {
+	event       dto.OutboxEvent
+	availableAt time.Time
+	lastError   string
+}
+
+// insertOutboxEvent вызывается под s.mu вместе с изменением, которое описывает событие.
+func (s *Storage) insertOutboxEvent(ctx context.Context, eventType dto.EventType, key string, payload any) error {
+	payloadJSON, err := json.Marshal(payload)
+
+	if err != nil {
+		return fmt.Errorf("outbox payload error | %w", err)
+	}
+
+	eventId, err := uuid.NewV4()
+
+	if err != nil {
+		return fmt.Errorf("outbox query error | %w", err)
+	}
+
+	s.nextOutboxId++
+
+	createdAt := time.Now().UTC()
+
+	s.outbox = append(s.outbox, &outboxEvent{
+		event: dto.OutboxEvent{
+			Id:        s.nextOutboxId,
+			EventId:   eventId.String(),
+			Type:      eventType,
+			Version:   dto.EventVersions[eventType],
+			Key:       key,
+			Payload:   payloadJSON,
+			RequestId: logging.RequestID(ctx),
+			CreatedAt: createdAt,
+		},
+		availableAt: createdAt,
+	})
+
+	return nil
+}
+
+// LeaseOutboxEvents выдаёт события так же, как leaseOutboxEventsQuery: по порядку id, не раньше
+// обработки предыдущего события с тем же ключом, и откладывает выданные на время аренды.
+func (s *Storage) LeaseOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]dto.OutboxEvent, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var events = make([]dto.OutboxEvent, 0, limit)
+
+	now := time.Now().UTC()
+	keys := make(map[string]struct{})
+
+	// s.outbox упорядочен по id: события добавляются в конец
+	for _, e := range s.outbox {
+		if len(events) == limit {
+			break
+		}
+
+		_, blocked := keys[e.event.Key]
+
+		keys[e.event.Key] = struct{}{}
+
+		if blocked || e.availableAt.After(now) {
+			continue
+		}
+
+		e.event.Attempts++
+		e.availableAt = now.Add(lease)
+
+		events = append(events, e.event)
+	}
+
+	return events, nil
+}
+
+// CompleteOutboxEvent удаляет обработанное событие.
+func (s *Storage) CompleteOutboxEvent(ctx context.Context, id int64) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	for i, e := range s.outbox {
+		if e.event.Id == id {
+			s.outbox = append(s.outbox[:i], s.outbox[i+1:]...)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// RetryOutboxEvent откладывает событие до retryAt и сохраняет причину неудачи.
+func (s *Storage) RetryOutboxEvent(ctx context.Context, id int64, retryAt time.Time, lastError string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	for _, e := range s.outbox {
+		if e.event.Id == id {
+			e.availableAt = retryAt.UTC()
+			e.lastError = lastError
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) CountOutboxEvents(ctx context.Context) (int, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	return len(s.outbox), nil
+}
@@ -0,0 +1,187 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"time"
+	"urleater/dto"
+)
+
+type emailVerification struct {
+	tokenHash string
+	expiresAt time.Time
+}
+
+func copyReferral(referral *dto.Referral) dto.Referral {
+	result := *referral
+
+	if referral.CreditedAt != nil {
+		creditedAt := *referral.CreditedAt
+		result.CreditedAt = &creditedAt
+	}
+
+	return result
+}
+
+func (s *Storage) CreateEmailVerification(ctx context.Context, email string, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[email]; !ok {
+		return fmt.Errorf("CreateEmailVerification query error | %w", foreignKeyViolation("email_verifications", "email_verifications_user_email_fkey"))
+	}
+
+	for otherEmail, verification := range s.emailVerifications {
+		if otherEmail != email && verification.tokenHash == tokenHash {
+			return fmt.Errorf("CreateEmailVerification query error | %w", uniqueViolation("email_verifications", "email_verifications_token_hash_key"))
+		}
+	}
+
+	s.emailVerifications[email] = &emailVerification{
+		tokenHash: tokenHash,
+		expiresAt: expiresAt.UTC(),
+	}
+
+	return nil
+}
+
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, now time.Time, referralCredits int) (string, *dto.Referral, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	email := ""
+
+	for candidate, verification := range s.emailVerifications {
+		if verification.tokenHash == tokenHash && verification.expiresAt.After(now) {
+			email = candidate
+		}
+	}
+
+	if email == "" {
+		return "", nil, fmt.Errorf("VerifyEmail query error | %w", pgx.ErrNoRows)
+	}
+
+	referral, ok := s.referrals[email]
+
+	if ok && referral.CreditedAt == nil && referralCredits > 0 {
+		_, err := s.creditLinks(dto.LinkCredit{
+			UserEmail: referral.ReferrerEmail,
+			Amount:    referralCredits,
+			Reason:    dto.LinkCreditReferral,
+			Reference: email,
+		})
+
+		if err != nil {
+			return "", nil, fmt.Errorf("VerifyEmail %w", err)
+		}
+	}
+
+	delete(s.emailVerifications, email)
+
+	if u, ok := s.users[email]; ok {
+		u.emailVerified = true
+	}
+
+	if !ok || referral.CreditedAt != nil {
+		return email, nil, nil
+	}
+
+	creditedAt := now.UTC()
+	referral.CreditedAt = &creditedAt
+
+	result := copyReferral(referral)
+
+	return email, &result, nil
+}
+
+func (s *Storage) EnsureReferralCode(ctx context.Context, email string, code string) (string, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	u, ok := s.users[email]
+
+	if !ok {
+		return "", fmt.Errorf("EnsureReferralCode query error | %w", pgx.ErrNoRows)
+	}
+
+	if u.referralCode != "" {
+		return u.referralCode, nil
+	}
+
+	for _, other := range s.users {
+		if other.referralCode == code {
+			return "", fmt.Errorf("EnsureReferralCode query error | %w", uniqueViolation("users", "users_referral_code_key"))
+		}
+	}
+
+	u.referralCode = code
+
+	return code, nil
+}
+
+func (s *Storage) CreateReferral(ctx context.Context, inviteeEmail string, referralCode string) (*dto.Referral, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	referrerEmail := ""
+
+	for email, u := range s.users {
+		if referralCode != "" && u.referralCode == referralCode && email != inviteeEmail {
+			referrerEmail = email
+		}
+	}
+
+	if referrerEmail == "" {
+		return nil, fmt.Errorf("CreateReferral query error | %w", pgx.ErrNoRows)
+	}
+
+	if _, ok := s.referrals[inviteeEmail]; ok {
+		return nil, fmt.Errorf("CreateReferral query error | %w", uniqueViolation("referrals", "referrals_pkey"))
+	}
+
+	if _, ok := s.users[inviteeEmail]; !ok {
+		return nil, fmt.Errorf("CreateReferral query error | %w", foreignKeyViolation("referrals", "referrals_invitee_email_fkey"))
+	}
+
+	referral := &dto.Referral{
+		InviteeEmail:  inviteeEmail,
+		ReferrerEmail: referrerEmail,
+		CreatedAt:     time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	s.referrals[inviteeEmail] = referral
+
+	result := copyReferral(referral)
+
+	return &result, nil
+}
+
+func (s *Storage) GetReferrals(ctx context.Context, referrerEmail string) ([]dto.Referral, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var referrals []dto.Referral
+
+	for _, referral := range s.referrals {
+		if referral.ReferrerEmail == referrerEmail {
+			referrals = append(referrals, copyReferral(referral))
+		}
+	}
+
+	sort.Slice(referrals, func(i, j int) bool {
+		if !referrals[i].CreatedAt.Equal(referrals[j].CreatedAt) {
+			return referrals[i].CreatedAt.After(referrals[j].CreatedAt)
+		}
+
+		return referrals[i].InviteeEmail < referrals[j].InviteeEmail
+	})
+
+	return referrals, nil
+}
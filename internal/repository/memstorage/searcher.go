@@ -0,0 +1,111 @@
+package memstorage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"urleater/dto"
+	"urleater/internal/repository/searchtext"
+)
+
+// Searcher - поиск по ссылкам в памяти с теми же правилами совпадения, что и pgsearch.
+type Searcher struct {
+	mu    sync.Mutex
+	links map[string]dto.Link
+}
+
+func NewSearcher() *Searcher {
+	return &Searcher{links: make(map[string]dto.Link)}
+}
+
+func (s *Searcher) SearchShortLinks(ctx context.Context, searchQuery dto.SearchQuery) (dto.SearchResult, error) {
+	text := searchtext.Normalize(searchQuery.Text)
+
+	terms := searchtext.Terms(text)
+
+	if searchtext.IsPrefixQuery(text) {
+		terms = nil
+	}
+
+	s.mu.Lock()
+
+	matched := make([]dto.Link, 0)
+
+	for _, link := range s.links {
+		if searchQuery.OwnerEmail != "" && link.UserEmail != searchQuery.OwnerEmail {
+			continue
+		}
+
+		if terms == nil && !strings.HasPrefix(link.ShortUrl, text) {
+			continue
+		}
+
+		if terms != nil && !containsAll(searchtext.Document(link), terms) {
+			continue
+		}
+
+		matched = append(matched, link)
+	}
+
+	s.mu.Unlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+
+		return matched[i].ShortUrl < matched[j].ShortUrl
+	})
+
+	result := dto.SearchResult{
+		Total: len(matched),
+		Hits:  make([]dto.SearchHit, 0, searchQuery.Limit),
+	}
+
+	for i := searchQuery.Offset; i < len(matched) && len(result.Hits) < searchQuery.Limit; i++ {
+		result.Hits = append(result.Hits, dto.SearchHit{
+			ShortUrl:   matched[i].ShortUrl,
+			Highlights: searchtext.Highlights(matched[i], terms),
+		})
+	}
+
+	return result, nil
+}
+
+func containsAll(document string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(document, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Searcher) AddShortLink(ctx context.Context, link dto.Link) error {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+
+	link.Tags = append([]string(nil), link.Tags...)
+
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	s.links[link.ShortUrl] = link
+
+	return nil
+}
+
+func (s *Searcher) DeleteShortLink(ctx context.Context, link string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	delete(s.links, link)
+
+	return nil
+}
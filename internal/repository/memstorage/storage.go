@@ -0,0 +1,983 @@
+// Package memstorage содержит реализации хранилищ сервиса в памяти процесса. Они повторяют поведение
+// Postgres, Redis и поискового индекса, включая ошибки (pgx.ErrNoRows, redis.Nil), и нужны, чтобы гонять сервис
+// целиком в обычном go test. Поведение сверяется с настоящими хранилищами контрактными тестами в tests/.
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/crypto/bcrypt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"urleater/dto"
+)
+
+// DefaultLinkTTL - срок жизни ссылки, если он не задан через WithLinkTTL.
+const DefaultLinkTTL = 90 * 24 * time.Hour
+
+// defaultUrlsLeft - значение users.urls_left по умолчанию.
+const defaultUrlsLeft = 10
+
+// cursorTimeLayout - текстовое представление timestamp, в котором сервис кладёт время в курсор.
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+type user struct {
+	email         string
+	passwordHash  string
+	urlsLeft      int
+	emailVerified bool
+	referralCode  string
+}
+
+type link struct {
+	shortUrl     string
+	domain       string
+	redirect     dto.RedirectOptions
+	utm          dto.UTM
+	longUrl      string
+	userEmail    string
+	createdAt    time.Time
+	expiresAt    time.Time
+	timesVisited int
+	// campaignClicks - переходы по кампании, которая была у ссылки в момент перехода
+	campaignClicks map[string]int
+	title          string
+	description    string
+	tagIds         map[int]struct{}
+}
+
+type tag struct {
+	id        int
+	userEmail string
+	name      string
+}
+
+// Storage - аналог postgresDB.Storage в памяти. Каждый метод выполняется под одной блокировкой,
+// поэтому, как и транзакции Postgres, изменения и события outbox видны только целиком.
+type Storage struct {
+	mu sync.Mutex
+
+	users         map[string]*user
+	links         map[string]*link
+	tags          map[int]*tag
+	subscriptions []dto.Subscription
+	outbox        []*outboxEvent
+
+	userSubscriptions  map[string]*userSubscription
+	linkCredits        []dto.LinkCredit
+	promoCodes         map[string]*dto.PromoCode
+	promoRedemptions   map[promoRedemption]time.Time
+	emailVerifications map[string]*emailVerification
+	referrals          map[string]*dto.Referral
+	domains            map[domainClaim]*dto.Domain
+
+	nextTagId          int
+	nextSubscriptionId int
+	nextOutboxId       int64
+	nextLinkCreditId   int64
+
+	// passwordCost - стоимость bcrypt. Минимальная, чтобы тесты не тратили время на хеширование.
+	passwordCost int
+	linkTTL      time.Duration
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		users: make(map[string]*user),
+		links: make(map[string]*link),
+		tags:  make(map[int]*tag),
+		// как и база после миграций, хранилище начинается с тарифов по умолчанию
+		subscriptions:      seedSubscriptions(),
+		nextSubscriptionId: 4,
+		userSubscriptions:  make(map[string]*userSubscription),
+		promoCodes:         make(map[string]*dto.PromoCode),
+		promoRedemptions:   make(map[promoRedemption]time.Time),
+		emailVerifications: make(map[string]*emailVerification),
+		referrals:          make(map[string]*dto.Referral),
+		domains:            make(map[domainClaim]*dto.Domain),
+		passwordCost:       bcrypt.MinCost,
+		linkTTL:            DefaultLinkTTL,
+	}
+}
+
+// WithLinkTTL задаёт срок жизни новых и продлённых ссылок.
+func (s *Storage) WithLinkTTL(ttl time.Duration) *Storage {
+	s.linkTTL = ttl
+
+	return s
+}
+
+// uniqueViolation и foreignKeyViolation повторяют ошибки, которые Postgres возвращает при нарушении ограничений таблиц.
+func uniqueViolation(table string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(table string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23503",
+		Message:        fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// timestampNow возвращает текущее время с точностью, с которой его записывает postgresDB (RFC3339, секунды).
+func timestampNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func (s *Storage) CreateUser(ctx context.Context, email string, password string) error {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), s.passwordCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[email]; ok {
+		return fmt.Errorf("CreateUser query error | %w", uniqueViolation("users", "users_pkey"))
+	}
+
+	s.users[email] = &user{
+		email:        email,
+		passwordHash: string(passwordHash),
+		urlsLeft:     defaultUrlsLeft,
+	}
+
+	err = s.insertOutboxEvent(ctx, dto.EventUserRegistered, email, dto.UserRegistered{Email: email})
+
+	if err != nil {
+		return fmt.Errorf("CreateUser %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ChangePassword(ctx context.Context, email string, password string) error {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), s.passwordCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[email]; ok {
+		u.passwordHash = string(passwordHash)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (*dto.User, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	u, ok := s.users[email]
+
+	if !ok {
+		return &dto.User{}, fmt.Errorf("GetUser query error | %w", pgx.ErrNoRows)
+	}
+
+	return &dto.User{
+		Email:         u.email,
+		PasswordHash:  u.passwordHash,
+		UrlsLeft:      u.urlsLeft,
+		EmailVerified: u.emailVerified,
+	}, nil
+}
+
+func (s *Storage) VerifyUserPassword(ctx context.Context, email string, password string) error {
+	user, err := s.GetUser(ctx, email)
+
+	if err != nil {
+		return fmt.Errorf("VerifyUserPassword query error | %w", err)
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		return fmt.Errorf("VerifyUserPassword query error | %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateUserLinks(ctx context.Context, email string, newUrlsNumber int) (*dto.User, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	u, ok := s.users[email]
+
+	if !ok {
+		return &dto.User{}, fmt.Errorf("UpdateUserLinks query error | %w", pgx.ErrNoRows)
+	}
+
+	u.urlsLeft = newUrlsNumber
+
+	// RETURNING в postgresDB возвращает только email и urls_left
+	return &dto.User{
+		Email:    u.email,
+		UrlsLeft: u.urlsLeft,
+	}, nil
+}
+
+func (s *Storage) CreateShortLink(ctx context.Context, shortLink string, longLink string, userEmail string, domain string) (*dto.Link, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[shortLink]; ok {
+		return nil, fmt.Errorf("CreateShortLink query error | %w", uniqueViolation("urls", "urls_pkey"))
+	}
+
+	if _, ok := s.users[userEmail]; !ok {
+		return nil, fmt.Errorf("CreateShortLink query error | %w", foreignKeyViolation("urls", "urls_user_email_fkey"))
+	}
+
+	if _, ok := s.domains[domainClaim{host: domain, userEmail: userEmail}]; domain != "" && !ok {
+		return nil, fmt.Errorf("CreateShortLink query error | %w", foreignKeyViolation("urls", "urls_domain_fkey"))
+	}
+
+	createdAt := timestampNow()
+
+	l := &link{
+		shortUrl:  shortLink,
+		domain:    domain,
+		redirect:  dto.RedirectOptions{QueryConflict: dto.QueryConflictKeep},
+		longUrl:   longLink,
+		userEmail: userEmail,
+		createdAt: createdAt,
+		expiresAt: createdAt.Add(s.linkTTL),
+		tagIds:    make(map[int]struct{}),
+	}
+
+	s.links[shortLink] = l
+
+	err := s.insertOutboxEvent(ctx, dto.EventLinkCreated, l.shortUrl, dto.LinkCreated{
+		ShortLink: l.shortUrl,
+		LongLink:  l.longUrl,
+		UserEmail: l.userEmail,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateShortLink %w", err)
+	}
+
+	return &dto.Link{
+		ShortUrl:        l.shortUrl,
+		Domain:          l.domain,
+		RedirectOptions: l.redirect,
+		UTM:             l.utm,
+		LongUrl:         l.longUrl,
+		UserEmail:       l.userEmail,
+		ExpiresAt:       l.expiresAt,
+		CreatedAt:       l.createdAt,
+	}, nil
+}
+
+// toDTO собирает ссылку так же, как её читают запросы postgresDB: с пустыми строками вместо NULL и отсортированными тегами.
+func (s *Storage) toDTO(l *link) dto.Link {
+	tags := make([]string, 0, len(l.tagIds))
+
+	for id := range l.tagIds {
+		tags = append(tags, s.tags[id].name)
+	}
+
+	sort.Strings(tags)
+
+	return dto.Link{
+		ShortUrl:        l.shortUrl,
+		Domain:          l.domain,
+		RedirectOptions: l.redirect,
+		UTM:             l.utm,
+		LongUrl:         l.longUrl,
+		UserEmail:       l.userEmail,
+		ExpiresAt:       l.expiresAt,
+		TimesVisited:    l.timesVisited,
+		CreatedAt:       l.createdAt,
+		Title:           l.title,
+		Description:     l.description,
+		Tags:            tags,
+	}
+}
+
+func (s *Storage) GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	l, ok := s.links[shortLink]
+
+	if !ok {
+		return nil, fmt.Errorf("GetShortLink query error | %w", pgx.ErrNoRows)
+	}
+
+	link := s.toDTO(l)
+
+	return &link, nil
+}
+
+func (s *Storage) GetShortLinksByShortUrls(ctx context.Context, shortLinks []string) ([]dto.Link, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var links = make([]dto.Link, 0, len(shortLinks))
+
+	seen := make(map[string]struct{}, len(shortLinks))
+
+	for _, shortLink := range shortLinks {
+		if _, ok := seen[shortLink]; ok {
+			continue
+		}
+
+		seen[shortLink] = struct{}{}
+
+		if l, ok := s.links[shortLink]; ok {
+			links = append(links, s.toDTO(l))
+		}
+	}
+
+	return links, nil
+}
+
+func (s *Storage) CountShortLinks(ctx context.Context) (int, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	return len(s.links), nil
+}
+
+// GetShortLinksBatch возвращает до limit ссылок всех пользователей, следующих за afterShortUrl в порядке short_url.
+func (s *Storage) GetShortLinksBatch(ctx context.Context, afterShortUrl string, limit int) ([]dto.Link, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var links = make([]dto.Link, 0, limit)
+
+	for _, l := range s.sortedLinks(func(l *link) bool { return l.shortUrl > afterShortUrl }) {
+		if len(links) == limit {
+			break
+		}
+
+		links = append(links, s.toDTO(l))
+	}
+
+	return links, nil
+}
+
+// sortedLinks возвращает подходящие под match ссылки в порядке short_url.
+func (s *Storage) sortedLinks(match func(l *link) bool) []*link {
+	links := make([]*link, 0, len(s.links))
+
+	for _, l := range s.links {
+		if match(l) {
+			links = append(links, l)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].shortUrl < links[j].shortUrl
+	})
+
+	return links
+}
+
+func (s *Storage) GetUserShortLinks(ctx context.Context, email string, listQuery dto.LinkListQuery) ([]dto.Link, error) {
+	var links = make([]dto.Link, 0)
+
+	compare, ok := linkComparators[listQuery.SortBy]
+
+	if !ok {
+		return nil, fmt.Errorf("GetUserShortLinks unknown sort field %s", listQuery.SortBy)
+	}
+
+	direction := 1
+
+	if listQuery.Direction == dto.SortDesc {
+		direction = -1
+	}
+
+	var after *link
+
+	if listQuery.After != nil {
+		var err error
+
+		after, err = cursorLink(listQuery.SortBy, *listQuery.After)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetUserShortLinks query error | %w", err)
+		}
+	}
+
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	matched := s.sortedLinks(func(l *link) bool {
+		if l.userEmail != email || !s.matchFilter(l, listQuery.Filter) {
+			return false
+		}
+
+		// пара (ключ сортировки, short_url) задаёт стабильный порядок, курсор указывает на последнюю выданную ссылку
+		return after == nil || compareLinks(compare, l, after)*direction > 0
+	})
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return compareLinks(compare, matched[i], matched[j])*direction < 0
+	})
+
+	for _, l := range matched {
+		if len(links) == listQuery.Limit {
+			break
+		}
+
+		links = append(links, s.toDTO(l))
+	}
+
+	return links, nil
+}
+
+func (s *Storage) GetTotalUserLinksNumber(ctx context.Context, email string, filter dto.LinkFilter) (int, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var totalUserLinks int
+
+	for _, l := range s.links {
+		if l.userEmail == email && s.matchFilter(l, filter) {
+			totalUserLinks++
+		}
+	}
+
+	return totalUserLinks, nil
+}
+
+// linkComparators сравнивают ссылки по ключу сортировки, как linkSortColumns в postgresDB.
+var linkComparators = map[dto.LinkSortField]func(a, b *link) int{
+	dto.LinkSortCreatedAt: func(a, b *link) int {
+		return a.createdAt.Compare(b.createdAt)
+	},
+	dto.LinkSortExpiresAt: func(a, b *link) int {
+		return a.expiresAt.Compare(b.expiresAt)
+	},
+	dto.LinkSortTimesVisited: func(a, b *link) int {
+		return a.timesVisited - b.timesVisited
+	},
+	dto.LinkSortAlias: func(a, b *link) int {
+		return 0
+	},
+}
+
+func compareLinks(compare func(a, b *link) int, a, b *link) int {
+	if c := compare(a, b); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a.shortUrl, b.shortUrl)
+}
+
+// cursorLink разбирает значение курсора так же, как Postgres приводит его к типу столбца сортировки.
+func cursorLink(sortBy dto.LinkSortField, cursor dto.LinkCursor) (*link, error) {
+	l := &link{shortUrl: cursor.ShortUrl}
+
+	var err error
+
+	switch sortBy {
+	case dto.LinkSortCreatedAt:
+		l.createdAt, err = time.Parse(cursorTimeLayout, cursor.Value)
+	case dto.LinkSortExpiresAt:
+		l.expiresAt, err = time.Parse(cursorTimeLayout, cursor.Value)
+	case dto.LinkSortTimesVisited:
+		l.timesVisited, err = strconv.Atoi(cursor.Value)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor value %q: %w", cursor.Value, err)
+	}
+
+	return l, nil
+}
+
+func (s *Storage) matchFilter(l *link, filter dto.LinkFilter) bool {
+	if filter.Tag != "" {
+		found := false
+
+		for id := range l.tagIds {
+			if s.tags[id].name == filter.Tag {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if !filter.CreatedFrom.IsZero() && l.createdAt.Before(filter.CreatedFrom.UTC().Truncate(time.Second)) {
+		return false
+	}
+
+	if !filter.CreatedTo.IsZero() && !l.createdAt.Before(filter.CreatedTo.UTC().Truncate(time.Second)) {
+		return false
+	}
+
+	switch filter.Status {
+	case dto.LinkStatusActive:
+		return l.expiresAt.After(time.Now().UTC())
+	case dto.LinkStatusExpired:
+		return !l.expiresAt.After(time.Now().UTC())
+	}
+
+	return true
+}
+
+func (s *Storage) UpdateShortLinkInfo(ctx context.Context, shortLink string, title string, description string, tags []string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	l, ok := s.links[shortLink]
+
+	if !ok {
+		return fmt.Errorf("UpdateShortLinkInfo query error | %w", pgx.ErrNoRows)
+	}
+
+	l.title = title
+	l.description = description
+	l.tagIds = make(map[int]struct{}, len(tags))
+
+	for _, name := range tags {
+		l.tagIds[s.userTag(l.userEmail, name).id] = struct{}{}
+	}
+
+	err := s.insertOutboxEvent(ctx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+		ShortLink: shortLink,
+		UserEmail: l.userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkInfo %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateShortLinkRedirect(ctx context.Context, shortLink string, options dto.RedirectOptions) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	l, ok := s.links[shortLink]
+
+	if !ok {
+		return fmt.Errorf("UpdateShortLinkRedirect query error | %w", pgx.ErrNoRows)
+	}
+
+	l.redirect = options
+
+	err := s.insertOutboxEvent(ctx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+		ShortLink: shortLink,
+		UserEmail: l.userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkRedirect %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateShortLinkUTM(ctx context.Context, shortLink string, utm dto.UTM) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	l, ok := s.links[shortLink]
+
+	if !ok {
+		return fmt.Errorf("UpdateShortLinkUTM query error | %w", pgx.ErrNoRows)
+	}
+
+	l.utm = utm
+
+	err := s.insertOutboxEvent(ctx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+		ShortLink: shortLink,
+		UserEmail: l.userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkUTM %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetUserCampaignStats(ctx context.Context, email string) ([]dto.CampaignStats, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var stats = make([]dto.CampaignStats, 0)
+
+	campaigns := make(map[string]int)
+
+	campaign := func(name string) *dto.CampaignStats {
+		i, ok := campaigns[name]
+
+		if !ok {
+			i = len(stats)
+			campaigns[name] = i
+			stats = append(stats, dto.CampaignStats{Campaign: name})
+		}
+
+		return &stats[i]
+	}
+
+	for _, l := range s.links {
+		if l.userEmail != email {
+			continue
+		}
+
+		if l.utm.Campaign != "" {
+			campaign(l.utm.Campaign).Links++
+		}
+
+		for name, clicks := range l.campaignClicks {
+			campaign(name).Clicks += clicks
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Clicks != stats[j].Clicks {
+			return stats[i].Clicks > stats[j].Clicks
+		}
+
+		return stats[i].Campaign < stats[j].Campaign
+	})
+
+	return stats, nil
+}
+
+// userTag возвращает тег пользователя, создавая его при необходимости.
+func (s *Storage) userTag(email string, name string) *tag {
+	for _, t := range s.tags {
+		if t.userEmail == email && t.name == name {
+			return t
+		}
+	}
+
+	s.nextTagId++
+
+	t := &tag{id: s.nextTagId, userEmail: email, name: name}
+
+	s.tags[t.id] = t
+
+	return t
+}
+
+func (s *Storage) GetUserTags(ctx context.Context, email string) ([]dto.Tag, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var tags = make([]dto.Tag, 0)
+
+	for _, t := range s.tags {
+		if t.userEmail != email {
+			continue
+		}
+
+		linksNumber := 0
+
+		for _, l := range s.links {
+			if _, ok := l.tagIds[t.id]; ok {
+				linksNumber++
+			}
+		}
+
+		tags = append(tags, dto.Tag{Name: t.name, LinksNumber: linksNumber})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+func (s *Storage) DeleteUserTag(ctx context.Context, email string, tag string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	for id, t := range s.tags {
+		if t.userEmail != email || t.name != tag {
+			continue
+		}
+
+		delete(s.tags, id)
+
+		for shortLink, l := range s.links {
+			if _, ok := l.tagIds[id]; !ok {
+				continue
+			}
+
+			delete(l.tagIds, id)
+
+			err := s.insertOutboxEvent(ctx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+				ShortLink: shortLink,
+				UserEmail: email,
+			})
+
+			if err != nil {
+				return fmt.Errorf("DeleteUserTag %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteShortLink(ctx context.Context, shortLink string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	l, ok := s.links[shortLink]
+
+	// ссылку уже удалили: событие об удалении записал тот вызов
+	if !ok {
+		return nil
+	}
+
+	delete(s.links, shortLink)
+
+	err := s.insertOutboxEvent(ctx, dto.EventLinkDeleted, shortLink, dto.LinkDeleted{
+		ShortLink: shortLink,
+		UserEmail: l.userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("DeleteShortLink %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ExtendShortLink(ctx context.Context, shortLink string, expiresAt time.Time) (*dto.Link, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	l, ok := s.links[shortLink]
+
+	if !ok {
+		return nil, fmt.Errorf("ExtendShortLink query error | %w", pgx.ErrNoRows)
+	}
+
+	l.expiresAt = expiresAt.Add(s.linkTTL).UTC().Truncate(time.Second)
+
+	err := s.insertOutboxEvent(ctx, dto.EventLinkUpdated, l.shortUrl, dto.LinkUpdated{
+		ShortLink: l.shortUrl,
+		UserEmail: l.userEmail,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ExtendShortLink %w", err)
+	}
+
+	return &dto.Link{
+		ShortUrl:        l.shortUrl,
+		Domain:          l.domain,
+		RedirectOptions: l.redirect,
+		UTM:             l.utm,
+		LongUrl:         l.longUrl,
+		UserEmail:       l.userEmail,
+		ExpiresAt:       l.expiresAt,
+	}, nil
+}
+
+// seedSubscriptions - тарифы, которые добавляют миграции 20261019180000, 20261019190000 и 20261019200000.
+func seedSubscriptions() []dto.Subscription {
+	return []dto.Subscription{
+		{Id: 1, Name: "Bronze", TotalUrls: 1000, Price: 19900, Currency: "RUB", BillingPeriod: dto.BillingPeriodQuarter,
+			Features:    dto.PlanFeatures{MaxExpiryDays: 180, AnalyticsRetentionDays: 30, APIRateLimit: 60},
+			QuotaPolicy: dto.QuotaReset, Active: true, DisplayOrder: 1},
+		{Id: 2, Name: "Silver", TotalUrls: 5000, Price: 49900, Currency: "RUB", BillingPeriod: dto.BillingPeriodQuarter,
+			Features:    dto.PlanFeatures{MaxExpiryDays: 365, CustomDomains: 1, AnalyticsRetentionDays: 90, APIRateLimit: 300},
+			QuotaPolicy: dto.QuotaReset, Active: true, DisplayOrder: 2},
+		{Id: 3, Name: "Gold", TotalUrls: 10000, Price: 99900, Currency: "RUB", BillingPeriod: dto.BillingPeriodQuarter,
+			Features:    dto.PlanFeatures{MaxExpiryDays: 730, CustomDomains: 5, AnalyticsRetentionDays: 365, APIRateLimit: 1000},
+			QuotaPolicy: dto.QuotaReset, Active: true, DisplayOrder: 3},
+		{Id: 4, Name: "Free", TotalUrls: 10, Currency: "RUB", BillingPeriod: dto.BillingPeriodMonth,
+			Features:    dto.PlanFeatures{MaxExpiryDays: 90, AnalyticsRetentionDays: 7},
+			QuotaPolicy: dto.QuotaReset, Active: true},
+	}
+}
+
+func (s *Storage) GetSubscriptions(ctx context.Context, activeOnly bool) ([]dto.Subscription, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var subscriptions []dto.Subscription
+
+	for _, sub := range s.subscriptions {
+		if activeOnly && !sub.Active {
+			continue
+		}
+
+		subscriptions = append(subscriptions, sub)
+	}
+
+	sort.SliceStable(subscriptions, func(i, j int) bool {
+		if subscriptions[i].DisplayOrder != subscriptions[j].DisplayOrder {
+			return subscriptions[i].DisplayOrder < subscriptions[j].DisplayOrder
+		}
+
+		return subscriptions[i].Id < subscriptions[j].Id
+	})
+
+	return subscriptions, nil
+}
+
+func (s *Storage) subscriptionIndex(id int) int {
+	return slices.IndexFunc(s.subscriptions, func(sub dto.Subscription) bool { return sub.Id == id })
+}
+
+func (s *Storage) subscriptionNameTaken(name string, exceptId int) bool {
+	return slices.ContainsFunc(s.subscriptions, func(sub dto.Subscription) bool { return sub.Name == name && sub.Id != exceptId })
+}
+
+func (s *Storage) GetSubscription(ctx context.Context, id int) (*dto.Subscription, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	i := s.subscriptionIndex(id)
+
+	if i < 0 {
+		return nil, fmt.Errorf("GetSubscription query error | %w", pgx.ErrNoRows)
+	}
+
+	sub := s.subscriptions[i]
+
+	return &sub, nil
+}
+
+func (s *Storage) CreateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	if s.subscriptionNameTaken(sub.Name, 0) {
+		return nil, fmt.Errorf("CreateSubscription query error | %w", uniqueViolation("subscriptions", "subscriptions_name_key"))
+	}
+
+	s.nextSubscriptionId++
+
+	sub.Id = s.nextSubscriptionId
+
+	s.subscriptions = append(s.subscriptions, sub)
+
+	return &sub, nil
+}
+
+func (s *Storage) UpdateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	i := s.subscriptionIndex(sub.Id)
+
+	if i < 0 {
+		return nil, fmt.Errorf("UpdateSubscription query error | %w", pgx.ErrNoRows)
+	}
+
+	if s.subscriptionNameTaken(sub.Name, sub.Id) {
+		return nil, fmt.Errorf("UpdateSubscription query error | %w", uniqueViolation("subscriptions", "subscriptions_name_key"))
+	}
+
+	s.subscriptions[i] = sub
+
+	return &sub, nil
+}
+
+func (s *Storage) DeleteSubscription(ctx context.Context, id int) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	i := s.subscriptionIndex(id)
+
+	if i < 0 {
+		return fmt.Errorf("DeleteSubscription query error | %w", pgx.ErrNoRows)
+	}
+
+	for _, sub := range s.userSubscriptions {
+		if sub.planId == id {
+			return fmt.Errorf("DeleteSubscription query error | %w", foreignKeyViolation("user_subscriptions", "user_subscriptions_plan_id_fkey"))
+		}
+	}
+
+	s.subscriptions = slices.Delete(s.subscriptions, i, i+1)
+
+	// promo_codes.plan_id ON DELETE CASCADE
+	for code, promo := range s.promoCodes {
+		if promo.PlanId != nil && *promo.PlanId == id {
+			s.deletePromoCode(code)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) IncrementShortLinkTimesWatchedCount(ctx context.Context, shortLink string, campaign string) error {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	l, ok := s.links[shortLink]
+
+	if !ok {
+		return nil
+	}
+
+	l.timesVisited++
+
+	if campaign == "" {
+		return nil
+	}
+
+	if l.campaignClicks == nil {
+		l.campaignClicks = make(map[string]int)
+	}
+
+	l.campaignClicks[campaign]++
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"time"
+	"urleater/dto"
+)
+
+type userSubscription struct {
+	planId         int
+	status         dto.UserSubscriptionStatus
+	periodStart    time.Time
+	periodEnd      time.Time
+	prepaidPeriods int
+	trialUsed      bool
+}
+
+// userSubscription собирает подписку с текущим состоянием тарифа, как JOIN в postgresDB. Вызывается под s.mu.
+func (s *Storage) userSubscription(email string, sub *userSubscription) dto.UserSubscription {
+	result := dto.UserSubscription{
+		UserEmail:      email,
+		Status:         sub.status,
+		PeriodStart:    sub.periodStart,
+		PeriodEnd:      sub.periodEnd,
+		PrepaidPeriods: sub.prepaidPeriods,
+		TrialUsed:      sub.trialUsed,
+	}
+
+	if i := s.subscriptionIndex(sub.planId); i >= 0 {
+		result.Plan = s.subscriptions[i]
+	}
+
+	return result
+}
+
+func (s *Storage) GetUserSubscription(ctx context.Context, email string) (*dto.UserSubscription, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	sub, ok := s.userSubscriptions[email]
+
+	if !ok {
+		return nil, fmt.Errorf("GetUserSubscription query error | %w", pgx.ErrNoRows)
+	}
+
+	result := s.userSubscription(email, sub)
+
+	return &result, nil
+}
+
+func (s *Storage) GetDueUserSubscriptions(ctx context.Context, now time.Time, limit int) ([]dto.UserSubscription, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	var due []dto.UserSubscription
+
+	for email, sub := range s.userSubscriptions {
+		if !sub.periodEnd.After(now) {
+			due = append(due, s.userSubscription(email, sub))
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].PeriodEnd.Equal(due[j].PeriodEnd) {
+			return due[i].PeriodEnd.Before(due[j].PeriodEnd)
+		}
+
+		return due[i].UserEmail < due[j].UserEmail
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+func (s *Storage) SaveUserSubscription(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange) (bool, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	return s.saveUserSubscription(sub, expectedPeriodEnd, quota)
+}
+
+// SaveUserSubscriptionWithPromoCode повторяет транзакцию postgresDB: код активируется, только если подписка записана.
+func (s *Storage) SaveUserSubscriptionWithPromoCode(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange, code string, now time.Time) (*dto.PromoCode, bool, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	promo, err := s.redeemablePromoCode(code, sub.UserEmail, now)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("SaveUserSubscriptionWithPromoCode %w", err)
+	}
+
+	// saveUserSubscription ничего не меняет, если возвращает ошибку или false
+	saved, err := s.saveUserSubscription(sub, expectedPeriodEnd, quota)
+
+	if err != nil || !saved {
+		return nil, false, err
+	}
+
+	result := s.redeemPromoCode(promo, sub.UserEmail, now)
+
+	return &result, true, nil
+}
+
+func (s *Storage) saveUserSubscription(sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange) (bool, error) {
+	// как UPDATE ... WHERE period_end в postgresDB: изменённая подписка не трогается
+	if !expectedPeriodEnd.IsZero() {
+		current, ok := s.userSubscriptions[sub.UserEmail]
+
+		if !ok || !current.periodEnd.Equal(expectedPeriodEnd) {
+			return false, nil
+		}
+	}
+
+	u, ok := s.users[sub.UserEmail]
+
+	if !ok {
+		return false, fmt.Errorf("SaveUserSubscription query error | %w", foreignKeyViolation("user_subscriptions", "user_subscriptions_user_email_fkey"))
+	}
+
+	if s.subscriptionIndex(sub.Plan.Id) < 0 {
+		return false, fmt.Errorf("SaveUserSubscription query error | %w", foreignKeyViolation("user_subscriptions", "user_subscriptions_plan_id_fkey"))
+	}
+
+	s.userSubscriptions[sub.UserEmail] = &userSubscription{
+		planId:         sub.Plan.Id,
+		status:         sub.Status,
+		periodStart:    sub.PeriodStart.UTC(),
+		periodEnd:      sub.PeriodEnd.UTC(),
+		prepaidPeriods: sub.PrepaidPeriods,
+		trialUsed:      sub.TrialUsed,
+	}
+
+	var amount int
+
+	switch quota.Policy {
+	case dto.QuotaReset:
+		amount = quota.Urls - u.urlsLeft
+
+	case dto.QuotaTopUp:
+		amount = quota.Urls
+	}
+
+	if amount != 0 {
+		_, err := s.creditLinks(dto.LinkCredit{
+			UserEmail: sub.UserEmail,
+			Amount:    amount,
+			Reason:    dto.LinkCreditSubscription,
+			Reference: sub.Plan.Name,
+		})
+
+		if err != nil {
+			return false, fmt.Errorf("SaveUserSubscription %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+func (s *Storage) AddPrepaidPeriods(ctx context.Context, email string, periods int) (*dto.UserSubscription, error) {
+	s.mu.Lock()
+
+	defer s.mu.Unlock()
+
+	sub, ok := s.userSubscriptions[email]
+
+	if !ok {
+		return nil, fmt.Errorf("AddPrepaidPeriods query error | %w", pgx.ErrNoRows)
+	}
+
+	sub.prepaidPeriods += periods
+
+	result := s.userSubscription(email, sub)
+
+	return &result, nil
+}
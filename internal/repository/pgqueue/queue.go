@@ -0,0 +1,417 @@
+package pgqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+	"urleater/dto"
+	"urleater/internal/logging"
+	"urleater/internal/tracing"
+)
+
+const (
+	pollInterval = 500 * time.Millisecond
+	batchSize    = 100
+	// lease - время, на которое выданное сообщение скрывается от других консьюмеров.
+	// Пока консьюмер работает, аренда неподтверждённых сообщений продлевается, поэтому повторно выдаются
+	// только сообщения остановившегося консьюмера.
+	lease = 5 * time.Minute
+	// leaseRenewInterval - как часто продлевается аренда сообщений, которые ждут воркера или обрабатываются.
+	leaseRenewInterval = lease / 3
+)
+
+const leaseMessagesQuery = `
+WITH next AS (
+	SELECT id
+	FROM queue_messages
+	WHERE topic = $1 AND available_at <= timezone('utc', now())
+	ORDER BY id
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED
+)
+UPDATE queue_messages
+SET attempts = queue_messages.attempts + 1,
+	available_at = timezone('utc', now()) + make_interval(secs => $3)
+FROM next
+WHERE queue_messages.id = next.id
+RETURNING queue_messages.id, queue_messages.message_key, queue_messages.payload, queue_messages.headers`
+
+// Queue - очередь сообщений в таблице queue_messages для запуска без Kafka.
+// Консьюмеры нескольких экземпляров сервиса разбирают сообщения через SKIP LOCKED, как группа консьюмеров Kafka,
+// но порядок сообщений с одним ключом не гарантируется.
+type Queue struct {
+	pgxPool      *pgxpool.Pool
+	queryBuilder squirrel.StatementBuilderType
+	logger       *slog.Logger
+}
+
+func New(pgxPool *pgxpool.Pool, logger *slog.Logger) *Queue {
+	return &Queue{
+		pgxPool:      pgxPool,
+		queryBuilder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		logger:       logging.OrDefault(logger),
+	}
+}
+
+func (q *Queue) PublishEvent(ctx context.Context, key string, event dto.Event, topic string) error {
+	value, err := json.Marshal(event)
+
+	if err != nil {
+		return fmt.Errorf("PublishEvent marshal error | %w", err)
+	}
+
+	headers, err := json.Marshal(tracing.Inject(ctx))
+
+	if err != nil {
+		return fmt.Errorf("PublishEvent marshal error | %w", err)
+	}
+
+	query, args, err := q.queryBuilder.
+		Insert("queue_messages").
+		Columns("topic", "message_key", "payload", "headers").
+		Values(topic, key, value, string(headers)).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("PublishEvent query error | %w", err)
+	}
+
+	_, err = q.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("PublishEvent query error | %w", err)
+	}
+
+	return nil
+}
+
+// PublishDeadLetter переносит сообщение в топик недоставленных, запоминая исходный топик и ошибку.
+func (q *Queue) PublishDeadLetter(ctx context.Context, topic string, letter dto.DeadLetter) error {
+	headers, err := json.Marshal(letter.Message.Headers)
+
+	if err != nil {
+		return fmt.Errorf("PublishDeadLetter marshal error | %w", err)
+	}
+
+	query, args, err := q.queryBuilder.
+		Insert("queue_messages").
+		Columns("topic", "message_key", "payload", "headers", "attempts", "original_topic", "last_error").
+		Values(topic, string(letter.Message.Key), letter.Message.Value, string(headers), letter.Attempts, letter.Message.Topic, letter.Error).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("PublishDeadLetter query error | %w", err)
+	}
+
+	_, err = q.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("PublishDeadLetter query error | %w", err)
+	}
+
+	return nil
+}
+
+func (q *Queue) leaseMessages(ctx context.Context, topic string, limit int) ([]dto.ConsumerMessage, error) {
+	rows, err := q.pgxPool.Query(ctx, leaseMessagesQuery, topic, limit, lease.Seconds())
+
+	if err != nil {
+		return nil, fmt.Errorf("leaseMessages query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	var messages []dto.ConsumerMessage
+
+	for rows.Next() {
+		var (
+			msg     dto.ConsumerMessage
+			key     string
+			headers []byte
+		)
+
+		if err = rows.Scan(&msg.Offset, &key, &msg.Value, &headers); err != nil {
+			return nil, fmt.Errorf("leaseMessages scan error | %w", err)
+		}
+
+		if err = json.Unmarshal(headers, &msg.Headers); err != nil {
+			return nil, fmt.Errorf("leaseMessages unmarshal error | %w", err)
+		}
+
+		msg.Topic = topic
+		msg.Key = []byte(key)
+		msg.Ack = q.ack(msg.Offset)
+
+		messages = append(messages, msg)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("leaseMessages query error | %w", err)
+	}
+
+	return messages, nil
+}
+
+// extendLeases продлевает аренду сообщений ids ещё на lease.
+func (q *Queue) extendLeases(ctx context.Context, ids []int64) error {
+	query, args, err := q.queryBuilder.
+		Update("queue_messages").
+		Set("available_at", squirrel.Expr("timezone('utc', now()) + make_interval(secs => ?)", lease.Seconds())).
+		Where(squirrel.Expr("id = ANY(?)", ids)).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("extendLeases query error | %w", err)
+	}
+
+	if _, err = q.pgxPool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("extendLeases query error | %w", err)
+	}
+
+	return nil
+}
+
+// ack удаляет обработанное сообщение. Ошибку удаления только логируем: сообщение будет выдано повторно.
+func (q *Queue) ack(id int64) func() {
+	return func() {
+		query, args, err := q.queryBuilder.
+			Delete("queue_messages").
+			Where(squirrel.Eq{"id": id}).
+			ToSql()
+
+		if err == nil {
+			_, err = q.pgxPool.Exec(context.Background(), query, args...)
+		}
+
+		if err != nil {
+			q.logger.Error("error while acking queue message", "id", id, "error", err)
+		}
+	}
+}
+
+type ReplayOptions struct {
+	Limit  int
+	DryRun bool
+	Out    io.Writer
+}
+
+// ReplayDeadLetters возвращает сообщения из топика недоставленных в их исходные топики.
+func (q *Queue) ReplayDeadLetters(ctx context.Context, deadLetterTopic string, opts ReplayOptions) (int, error) {
+	builder := q.queryBuilder.
+		Select("id", "message_key", "COALESCE(original_topic, '')", "attempts", "COALESCE(last_error, '')", "created_at").
+		From("queue_messages").
+		Where(squirrel.Eq{"topic": deadLetterTopic}).
+		OrderBy("id")
+
+	if opts.Limit > 0 {
+		builder = builder.Limit(uint64(opts.Limit))
+	}
+
+	query, args, err := builder.ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("ReplayDeadLetters query error | %w", err)
+	}
+
+	rows, err := q.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return 0, fmt.Errorf("ReplayDeadLetters query error | %w", err)
+	}
+
+	var ids []int64
+
+	for rows.Next() {
+		var (
+			id                       int64
+			key, originalTopic, text string
+			attempts                 int
+			failedAt                 time.Time
+		)
+
+		if err = rows.Scan(&id, &key, &originalTopic, &attempts, &text, &failedAt); err != nil {
+			rows.Close()
+
+			return 0, fmt.Errorf("ReplayDeadLetters scan error | %w", err)
+		}
+
+		fmt.Fprintf(opts.Out, "id %d: key %q, topic %q, attempts %d, failed at %s, error: %s\n",
+			id, key, originalTopic, attempts, failedAt.Format(time.RFC3339), text)
+
+		if originalTopic != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("ReplayDeadLetters query error | %w", err)
+	}
+
+	if opts.DryRun || len(ids) == 0 {
+		return len(ids), nil
+	}
+
+	query, args, err = q.queryBuilder.
+		Update("queue_messages").
+		Set("topic", squirrel.Expr("original_topic")).
+		Set("original_topic", nil).
+		Set("last_error", nil).
+		Set("attempts", 0).
+		Set("available_at", squirrel.Expr("timezone('utc', now())")).
+		Where(squirrel.Eq{"id": ids, "topic": deadLetterTopic}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("ReplayDeadLetters query error | %w", err)
+	}
+
+	tag, err := q.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return 0, fmt.Errorf("ReplayDeadLetters query error | %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// Consumer периодически забирает сообщения топика и передаёт их воркерам. Берёт не больше сообщений,
+// чем свободных мест в канале воркеров, и продлевает аренду выданных сообщений до их подтверждения.
+type Consumer struct {
+	queue         *Queue
+	topic         string
+	workerChannel chan dto.ConsumerMessage
+
+	mu sync.Mutex
+	// leased - выданные воркерам, но ещё не подтверждённые сообщения
+	leased map[int64]struct{}
+}
+
+func (q *Queue) NewConsumer(topic string, workerChannel chan dto.ConsumerMessage) *Consumer {
+	return &Consumer{queue: q, topic: topic, workerChannel: workerChannel, leased: make(map[int64]struct{})}
+}
+
+func (c *Consumer) StartConsuming(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+
+	defer ticker.Stop()
+
+	renewCtx, stopRenew := context.WithCancel(ctx)
+
+	defer stopRenew()
+
+	go c.renewLeases(renewCtx)
+
+	for {
+		// канал воркеров общий для всех консьюмеров, поэтому свободные места считаются при каждой выборке
+		free := min(batchSize, cap(c.workerChannel)-len(c.workerChannel))
+
+		if free <= 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			continue
+		}
+
+		messages, err := c.queue.leaseMessages(ctx, c.topic, free)
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		for _, msg := range messages {
+			c.track(&msg)
+
+			select {
+			case c.workerChannel <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		// полная пачка - скорее всего, есть ещё сообщения, не ждём тика
+		if len(messages) == free {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// track запоминает выданное сообщение, чтобы продлевать его аренду, пока Ack его не удалит.
+func (c *Consumer) track(msg *dto.ConsumerMessage) {
+	id, ack := msg.Offset, msg.Ack
+
+	c.mu.Lock()
+	c.leased[id] = struct{}{}
+	c.mu.Unlock()
+
+	msg.Ack = func() {
+		ack()
+
+		c.mu.Lock()
+		delete(c.leased, id)
+		c.mu.Unlock()
+	}
+}
+
+// renewLeases продлевает аренду неподтверждённых сообщений, пока консьюмер работает. После остановки
+// консьюмера аренда истекает, и необработанные сообщения получат другие консьюмеры.
+func (c *Consumer) renewLeases(ctx context.Context) {
+	ticker := time.NewTicker(leaseRenewInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.Lock()
+
+		ids := make([]int64, 0, len(c.leased))
+
+		for id := range c.leased {
+			ids = append(ids, id)
+		}
+
+		c.mu.Unlock()
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		if err := c.queue.extendLeases(ctx, ids); err != nil && ctx.Err() == nil {
+			c.queue.logger.ErrorContext(ctx, "error while extending queue message leases", "topic", c.topic, "messages", len(ids), "error", err)
+		}
+	}
+}
+
+func (c *Consumer) Reconnect() error {
+	return nil
+}
+
+// Close ничего не делает: сообщения подтверждаются сразу в Ack.
+func (c *Consumer) Close() error {
+	return nil
+}
@@ -0,0 +1,161 @@
+package pgsearch
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"strings"
+	"time"
+	"urleater/dto"
+	"urleater/internal/repository/searchtext"
+)
+
+// Searcher ищет ссылки по таблице link_search с триграммным индексом pg_trgm.
+// Таблица - копия urls для поиска, её заполняет outbox relay так же, как индекс Elasticsearch.
+type Searcher struct {
+	pgxPool      *pgxpool.Pool
+	queryBuilder squirrel.StatementBuilderType
+}
+
+func NewSearcher(pool *pgxpool.Pool) *Searcher {
+	return &Searcher{
+		pgxPool:      pool,
+		queryBuilder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (s *Searcher) SearchShortLinks(ctx context.Context, searchQuery dto.SearchQuery) (dto.SearchResult, error) {
+	text := searchtext.Normalize(searchQuery.Text)
+
+	terms := searchtext.Terms(text)
+
+	where := squirrel.And{}
+
+	if searchtext.IsPrefixQuery(text) {
+		where = append(where, squirrel.Like{"short_url": escapeLike(text) + "%"})
+
+		terms = nil
+	} else {
+		for _, term := range terms {
+			where = append(where, squirrel.Like{"document": "%" + escapeLike(term) + "%"})
+		}
+	}
+
+	if searchQuery.OwnerEmail != "" {
+		where = append(where, squirrel.Eq{"user_email": searchQuery.OwnerEmail})
+	}
+
+	countQuery, args, err := s.queryBuilder.
+		Select("count(*)").
+		From("link_search").
+		Where(where).
+		ToSql()
+	if err != nil {
+		return dto.SearchResult{}, fmt.Errorf("SearchShortLinks query error | %w", err)
+	}
+
+	var result dto.SearchResult
+
+	if err = s.pgxPool.QueryRow(ctx, countQuery, args...).Scan(&result.Total); err != nil {
+		return dto.SearchResult{}, fmt.Errorf("SearchShortLinks query error | %w", err)
+	}
+
+	query, args, err := s.queryBuilder.
+		Select("short_url", "long_url", "title", "tags").
+		From("link_search").
+		Where(where).
+		OrderByClause("similarity(document, ?) DESC", text).
+		OrderBy("created_at DESC", "short_url").
+		Offset(uint64(searchQuery.Offset)).
+		Limit(uint64(searchQuery.Limit)).
+		ToSql()
+	if err != nil {
+		return dto.SearchResult{}, fmt.Errorf("SearchShortLinks query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		return dto.SearchResult{}, fmt.Errorf("SearchShortLinks query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	result.Hits = make([]dto.SearchHit, 0, searchQuery.Limit)
+
+	for rows.Next() {
+		var link dto.Link
+
+		if err = rows.Scan(&link.ShortUrl, &link.LongUrl, &link.Title, &link.Tags); err != nil {
+			return dto.SearchResult{}, fmt.Errorf("SearchShortLinks scan error | %w", err)
+		}
+
+		result.Hits = append(result.Hits, dto.SearchHit{
+			ShortUrl:   link.ShortUrl,
+			Highlights: searchtext.Highlights(link, terms),
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return dto.SearchResult{}, fmt.Errorf("SearchShortLinks query error | %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *Searcher) AddShortLink(ctx context.Context, link dto.Link) error {
+	tags := link.Tags
+
+	if tags == nil {
+		tags = []string{}
+	}
+
+	createdAt := link.CreatedAt
+
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	query, args, err := s.queryBuilder.
+		Insert("link_search").
+		Columns("short_url", "user_email", "long_url", "domain", "title", "tags", "created_at", "document").
+		Values(link.ShortUrl, link.UserEmail, link.LongUrl, searchtext.Domain(link.LongUrl), link.Title, tags, createdAt, searchtext.Document(link)).
+		Suffix(`ON CONFLICT (short_url) DO UPDATE SET
+			user_email = EXCLUDED.user_email,
+			long_url = EXCLUDED.long_url,
+			domain = EXCLUDED.domain,
+			title = EXCLUDED.title,
+			tags = EXCLUDED.tags,
+			created_at = EXCLUDED.created_at,
+			document = EXCLUDED.document`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("AddShortLink query error | %w", err)
+	}
+
+	if _, err = s.pgxPool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("AddShortLink query error | %w", err)
+	}
+
+	return nil
+}
+
+func (s *Searcher) DeleteShortLink(ctx context.Context, link string) error {
+	query, args, err := s.queryBuilder.
+		Delete("link_search").
+		Where(squirrel.Eq{"short_url": link}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("DeleteShortLink query error | %w", err)
+	}
+
+	if _, err = s.pgxPool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("DeleteShortLink query error | %w", err)
+	}
+
+	return nil
+}
+
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
@@ -0,0 +1,349 @@
+package postgresDB
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"time"
+	"urleater/dto"
+)
+
+// promoCodeColumns - колонки promo_codes в порядке promoCodeFields.
+var promoCodeColumns = []string{
+	"code",
+	"kind",
+	"link_credits",
+	"percent_off",
+	"plan_id",
+	"max_redemptions",
+	"redemptions",
+	"expires_at",
+	"created_at",
+}
+
+func promoCodeFields(promo *dto.PromoCode) []interface{} {
+	return []interface{}{
+		&promo.Code,
+		&promo.Kind,
+		&promo.LinkCredits,
+		&promo.PercentOff,
+		&promo.PlanId,
+		&promo.MaxRedemptions,
+		&promo.Redemptions,
+		&promo.ExpiresAt,
+		&promo.CreatedAt,
+	}
+}
+
+func scanPromoCode(row pgx.Row) (*dto.PromoCode, error) {
+	var promo dto.PromoCode
+
+	if err := row.Scan(promoCodeFields(&promo)...); err != nil {
+		return nil, err
+	}
+
+	return &promo, nil
+}
+
+// creditLinks добавляет ссылки пользователю и записывает начисление в журнал в транзакции tx.
+// Если пользователя нет, возвращает pgx.ErrNoRows.
+func (s *Storage) creditLinks(ctx context.Context, tx pgx.Tx, credit dto.LinkCredit) (*dto.User, error) {
+	query, args, err := s.queryBuilder.
+		Update("users").
+		Set("urls_left", squirrel.Expr("urls_left + ?", credit.Amount)).
+		Where(squirrel.Eq{"email": credit.UserEmail}).
+		Suffix("RETURNING users.email, users.urls_left, users.email_verified").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("credit links query error | %w", err)
+	}
+
+	var user dto.User
+
+	if err = tx.QueryRow(ctx, query, args...).Scan(&user.Email, &user.UrlsLeft, &user.EmailVerified); err != nil {
+		return nil, fmt.Errorf("credit links query error | %w", err)
+	}
+
+	query, args, err = s.queryBuilder.
+		Insert("link_credits").
+		Columns("user_email", "amount", "reason", "reference", "created_at").
+		Values(credit.UserEmail, credit.Amount, string(credit.Reason), credit.Reference, time.Now().UTC()).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("credit links query error | %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("credit links query error | %w", err)
+	}
+
+	return &user, nil
+}
+
+func (s *Storage) AddLinkCredit(ctx context.Context, credit dto.LinkCredit) (*dto.User, error) {
+	defer observeQuery(ctx, "AddLinkCredit")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddLinkCredit begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	user, err := s.creditLinks(ctx, tx, credit)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddLinkCredit %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("AddLinkCredit commit error | %w", err)
+	}
+
+	return user, nil
+}
+
+// GetLinkCredits возвращает до limit последних начислений пользователя, начиная с новых.
+func (s *Storage) GetLinkCredits(ctx context.Context, email string, limit int) ([]dto.LinkCredit, error) {
+	defer observeQuery(ctx, "GetLinkCredits")()
+
+	query, args, err := s.queryBuilder.
+		Select("id", "user_email", "amount", "reason", "reference", "created_at").
+		From("link_credits").
+		Where(squirrel.Eq{"user_email": email}).
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetLinkCredits query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetLinkCredits query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	var credits []dto.LinkCredit
+
+	for rows.Next() {
+		var credit dto.LinkCredit
+
+		err = rows.Scan(&credit.Id, &credit.UserEmail, &credit.Amount, &credit.Reason, &credit.Reference, &credit.CreatedAt)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetLinkCredits scan error | %w", err)
+		}
+
+		credits = append(credits, credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetLinkCredits query error | %w", err)
+	}
+
+	return credits, nil
+}
+
+func (s *Storage) CreatePromoCode(ctx context.Context, promo dto.PromoCode) (*dto.PromoCode, error) {
+	defer observeQuery(ctx, "CreatePromoCode")()
+
+	query, args, err := s.queryBuilder.
+		Insert("promo_codes").
+		SetMap(map[string]interface{}{
+			"code":            promo.Code,
+			"kind":            string(promo.Kind),
+			"link_credits":    promo.LinkCredits,
+			"percent_off":     promo.PercentOff,
+			"plan_id":         promo.PlanId,
+			"max_redemptions": promo.MaxRedemptions,
+			"expires_at":      promo.ExpiresAt,
+			"created_at":      time.Now().UTC(),
+		}).
+		Suffix("RETURNING " + strings.Join(promoCodeColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("CreatePromoCode query error | %w", err)
+	}
+
+	created, err := scanPromoCode(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("CreatePromoCode query error | %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *Storage) GetPromoCodes(ctx context.Context) ([]dto.PromoCode, error) {
+	defer observeQuery(ctx, "GetPromoCodes")()
+
+	query, args, err := s.queryBuilder.
+		Select(promoCodeColumns...).
+		From("promo_codes").
+		OrderBy("created_at DESC", "code").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetPromoCodes query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetPromoCodes query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	var promos []dto.PromoCode
+
+	for rows.Next() {
+		promo, err := scanPromoCode(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetPromoCodes scan error | %w", err)
+		}
+
+		promos = append(promos, *promo)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetPromoCodes query error | %w", err)
+	}
+
+	return promos, nil
+}
+
+func (s *Storage) GetPromoCode(ctx context.Context, code string) (*dto.PromoCode, error) {
+	defer observeQuery(ctx, "GetPromoCode")()
+
+	query, args, err := s.queryBuilder.
+		Select(promoCodeColumns...).
+		From("promo_codes").
+		Where(squirrel.Eq{"code": code}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetPromoCode query error | %w", err)
+	}
+
+	promo, err := scanPromoCode(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("GetPromoCode query error | %w", err)
+	}
+
+	return promo, nil
+}
+
+func (s *Storage) DeletePromoCode(ctx context.Context, code string) error {
+	defer observeQuery(ctx, "DeletePromoCode")()
+
+	query, args, err := s.queryBuilder.
+		Delete("promo_codes").
+		Where(squirrel.Eq{"code": code}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("DeletePromoCode query error | %w", err)
+	}
+
+	tag, err := s.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("DeletePromoCode query error | %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeletePromoCode query error | %w", pgx.ErrNoRows)
+	}
+
+	return nil
+}
+
+// RedeemPromoCode активирует промокод для пользователя и, если код начисляет ссылки, начисляет их в той же транзакции.
+// Возвращает pgx.ErrNoRows, если кода нет, его срок истёк или активации закончились, и нарушение уникальности,
+// если пользователь уже активировал код.
+func (s *Storage) RedeemPromoCode(ctx context.Context, code string, email string, now time.Time) (*dto.PromoCode, error) {
+	defer observeQuery(ctx, "RedeemPromoCode")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	promo, err := s.redeemPromoCode(ctx, tx, code, email, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode commit error | %w", err)
+	}
+
+	return promo, nil
+}
+
+func (s *Storage) redeemPromoCode(ctx context.Context, tx pgx.Tx, code string, email string, now time.Time) (*dto.PromoCode, error) {
+	query, args, err := s.queryBuilder.
+		Update("promo_codes").
+		Set("redemptions", squirrel.Expr("redemptions + 1")).
+		Where(squirrel.Eq{"code": code}).
+		Where(squirrel.Or{squirrel.Eq{"expires_at": nil}, squirrel.Gt{"expires_at": now.UTC()}}).
+		Where(squirrel.Or{squirrel.Eq{"max_redemptions": 0}, squirrel.Expr("redemptions < max_redemptions")}).
+		Suffix("RETURNING " + strings.Join(promoCodeColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode query error | %w", err)
+	}
+
+	promo, err := scanPromoCode(tx.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode query error | %w", err)
+	}
+
+	query, args, err = s.queryBuilder.
+		Insert("promo_redemptions").
+		Columns("code", "user_email", "redeemed_at").
+		Values(code, email, now.UTC()).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode query error | %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode query error | %w", err)
+	}
+
+	if promo.Kind == dto.PromoLinkCredits && promo.LinkCredits > 0 {
+		_, err = s.creditLinks(ctx, tx, dto.LinkCredit{
+			UserEmail: email,
+			Amount:    promo.LinkCredits,
+			Reason:    dto.LinkCreditPromo,
+			Reference: code,
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("RedeemPromoCode %w", err)
+		}
+	}
+
+	return promo, nil
+}
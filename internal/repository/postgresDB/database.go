@@ -2,29 +2,73 @@ package postgresDB
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"time"
 	"urleater/dto"
+	"urleater/internal/metrics"
+	"urleater/internal/tracing"
 )
 
-const linkExpireIn = 90 * 24 * time.Hour
+// DefaultLinkTTL - срок жизни ссылки, если он не задан через WithLinkTTL.
+const DefaultLinkTTL = 90 * 24 * time.Hour
+
+// linkTagsColumn выбирает отсортированные теги ссылки из таблицы url_tags.
+const linkTagsColumn = "ARRAY(SELECT t.name FROM url_tags ut JOIN tags t ON t.id = ut.tag_id WHERE ut.url_id = l.id ORDER BY t.name)"
 
 type Storage struct {
 	pgxPool      *pgxpool.Pool
 	queryBuilder squirrel.StatementBuilderType
+	linkTTL      time.Duration
+}
+
+// observeQuery начинает спан метода хранилища и возвращает функцию, которая завершает его
+// и учитывает длительность метода. Вызывается в начале метода: defer observeQuery(ctx, "Method")().
+// Спан создаётся, только если ctx уже в трейсе: фоновые опросы без родителя трейсов не порождают.
+func observeQuery(ctx context.Context, method string) func() {
+	started := time.Now()
+
+	endSpan := func() {}
+
+	if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		_, span := tracing.Start(ctx, "postgres."+method, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL))
+
+		endSpan = func() { span.End() }
+	}
+
+	return func() {
+		endSpan()
+
+		metrics.StorageQueryDuration.WithLabelValues(method).Observe(time.Since(started).Seconds())
+	}
 }
 
 func NewStorage(pgxPool *pgxpool.Pool) *Storage {
 	return &Storage{
 		pgxPool:      pgxPool,
 		queryBuilder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		linkTTL:      DefaultLinkTTL,
 	}
 }
 
+// WithLinkTTL задаёт срок жизни новых и продлённых ссылок.
+func (s *Storage) WithLinkTTL(ttl time.Duration) *Storage {
+	s.linkTTL = ttl
+
+	return s
+}
+
 func (s *Storage) CreateUser(ctx context.Context, email string, password string) error {
+	defer observeQuery(ctx, "CreateUser")()
+
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -39,16 +83,36 @@ func (s *Storage) CreateUser(ctx context.Context, email string, password string)
 		return fmt.Errorf("CreateUser query error | %w", err)
 	}
 
-	_, err = s.pgxPool.Exec(ctx, query, args...)
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("CreateUser begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("CreateUser query error | %w", err)
 	}
 
+	err = s.insertOutboxEvent(ctx, tx, dto.EventUserRegistered, email, dto.UserRegistered{Email: email})
+
+	if err != nil {
+		return fmt.Errorf("CreateUser %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("CreateUser commit error | %w", err)
+	}
+
 	return nil
 
 }
 
 func (s *Storage) ChangePassword(ctx context.Context, email string, password string) error {
+	defer observeQuery(ctx, "ChangePassword")()
+
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -74,6 +138,8 @@ func (s *Storage) ChangePassword(ctx context.Context, email string, password str
 }
 
 func (s *Storage) GetUser(ctx context.Context, email string) (*dto.User, error) {
+	defer observeQuery(ctx, "GetUser")()
+
 	var user dto.User
 
 	query, args, err := s.queryBuilder.
@@ -81,6 +147,7 @@ func (s *Storage) GetUser(ctx context.Context, email string) (*dto.User, error)
 			"email",
 			"password_hash",
 			"urls_left",
+			"email_verified",
 		).
 		From("users").
 		Where(squirrel.Eq{"email": email}).
@@ -90,7 +157,7 @@ func (s *Storage) GetUser(ctx context.Context, email string) (*dto.User, error)
 		return nil, fmt.Errorf("GetUser query error | %w", err)
 	}
 
-	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(&user.Email, &user.PasswordHash, &user.UrlsLeft)
+	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(&user.Email, &user.PasswordHash, &user.UrlsLeft, &user.EmailVerified)
 	if err != nil {
 		return &dto.User{}, fmt.Errorf("GetUser query error | %w", err)
 	}
@@ -98,6 +165,8 @@ func (s *Storage) GetUser(ctx context.Context, email string) (*dto.User, error)
 }
 
 func (s *Storage) VerifyUserPassword(ctx context.Context, email string, password string) error {
+	defer observeQuery(ctx, "VerifyUserPassword")()
+
 	user, err := s.GetUser(ctx, email)
 
 	if err != nil {
@@ -113,6 +182,8 @@ func (s *Storage) VerifyUserPassword(ctx context.Context, email string, password
 }
 
 func (s *Storage) UpdateUserLinks(ctx context.Context, email string, newUrlsNumber int) (*dto.User, error) {
+	defer observeQuery(ctx, "UpdateUserLinks")()
+
 	var user dto.User
 
 	query, args, err := s.queryBuilder.
@@ -122,8 +193,6 @@ func (s *Storage) UpdateUserLinks(ctx context.Context, email string, newUrlsNumb
 		Suffix("RETURNING users.email, users.urls_left").
 		ToSql()
 
-	fmt.Println(query, args)
-
 	if err != nil {
 		return nil, fmt.Errorf("UpdateUserLinks query error | %w", err)
 	}
@@ -140,46 +209,98 @@ func (s *Storage) UpdateUserLinks(ctx context.Context, email string, newUrlsNumb
 	return &user, nil
 }
 
-func (s *Storage) CreateShortLink(ctx context.Context, shortLink string, longLink string, userEmail string) (*dto.Link, error) {
+// CreateShortLink создаёт ссылку с ключом shortLink. Для ссылки на своём домене domain - его хост,
+// а shortLink - ключ из dto.DomainShortUrl; для основного хоста domain пустой.
+func (s *Storage) CreateShortLink(ctx context.Context, shortLink string, longLink string, userEmail string, domain string) (*dto.Link, error) {
+	defer observeQuery(ctx, "CreateShortLink")()
+
 	var link dto.Link
-	expiresAt := time.Now().UTC().Add(linkExpireIn)
+	expiresAt := time.Now().UTC().Add(s.linkTTL)
 
 	query, args, err := s.queryBuilder.Insert("urls").
-		Columns("short_url", "long_url", "created_at", "user_email", "expires_at", "times_visited").
-		Values(shortLink, longLink, time.Now().UTC().Format(time.RFC3339), userEmail, expiresAt.Format(time.RFC3339), 0).
-		Suffix("RETURNING short_url, long_url, expires_at").
+		Columns("short_url", "domain", "long_url", "created_at", "user_email", "expires_at", "times_visited").
+		Values(shortLink, squirrel.Expr("NULLIF(?, '')", domain), longLink, time.Now().UTC().Format(time.RFC3339), userEmail, expiresAt.Format(time.RFC3339), 0).
+		Suffix("RETURNING short_url, COALESCE(domain, ''), forward_query, query_conflict, forward_path, utm_source, utm_medium, utm_campaign, utm_term, utm_content, long_url, user_email, expires_at, created_at").
 		ToSql()
 
 	if err != nil {
 		return nil, fmt.Errorf("CreateShortLink query error | %w", err)
 	}
 
-	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateShortLink begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, args...).Scan(
 		&link.ShortUrl,
+		&link.Domain,
+		&link.ForwardQuery,
+		&link.QueryConflict,
+		&link.ForwardPath,
+		&link.UTM.Source,
+		&link.UTM.Medium,
+		&link.UTM.Campaign,
+		&link.UTM.Term,
+		&link.UTM.Content,
 		&link.LongUrl,
+		&link.UserEmail,
 		&link.ExpiresAt,
+		&link.CreatedAt,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("CreateShortLink query error | %w", err)
 	}
 
+	err = s.insertOutboxEvent(ctx, tx, dto.EventLinkCreated, link.ShortUrl, dto.LinkCreated{
+		ShortLink: link.ShortUrl,
+		LongLink:  link.LongUrl,
+		UserEmail: link.UserEmail,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateShortLink %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("CreateShortLink commit error | %w", err)
+	}
+
 	return &link, nil
 }
 
 func (s *Storage) GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error) {
+	defer observeQuery(ctx, "GetShortLink")()
+
 	var link dto.Link
 
 	query, args, err := s.queryBuilder.
 		Select(
-			"short_url",
-			"user_email",
-			"long_url",
-			"expires_at",
-			"times_visited",
+			"l.short_url",
+			"COALESCE(l.domain, '')",
+			"l.forward_query",
+			"l.query_conflict",
+			"l.forward_path",
+			"l.utm_source",
+			"l.utm_medium",
+			"l.utm_campaign",
+			"l.utm_term",
+			"l.utm_content",
+			"l.user_email",
+			"l.long_url",
+			"l.expires_at",
+			"l.times_visited",
+			"l.created_at",
+			"COALESCE(l.title, '')",
+			"COALESCE(l.description, '')",
+			linkTagsColumn,
 		).
-		From("urls").
-		Where(squirrel.Eq{"short_url": shortLink}).
+		From("urls l").
+		Where(squirrel.Eq{"l.short_url": shortLink}).
 		ToSql()
 
 	if err != nil {
@@ -187,10 +308,23 @@ func (s *Storage) GetShortLink(ctx context.Context, shortLink string) (*dto.Link
 	}
 
 	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(&link.ShortUrl,
+		&link.Domain,
+		&link.ForwardQuery,
+		&link.QueryConflict,
+		&link.ForwardPath,
+		&link.UTM.Source,
+		&link.UTM.Medium,
+		&link.UTM.Campaign,
+		&link.UTM.Term,
+		&link.UTM.Content,
 		&link.UserEmail,
 		&link.LongUrl,
 		&link.ExpiresAt,
-		&link.TimesVisited)
+		&link.TimesVisited,
+		&link.CreatedAt,
+		&link.Title,
+		&link.Description,
+		&link.Tags)
 
 	if err != nil {
 		return nil, fmt.Errorf("GetShortLink query error | %w", err)
@@ -198,32 +332,44 @@ func (s *Storage) GetShortLink(ctx context.Context, shortLink string) (*dto.Link
 	return &link, nil
 }
 
-func (s *Storage) GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email string, offset int, limit int) ([]dto.Link, error) {
-	var links = make([]dto.Link, 0)
+func (s *Storage) GetShortLinksByShortUrls(ctx context.Context, shortLinks []string) ([]dto.Link, error) {
+	defer observeQuery(ctx, "GetShortLinksByShortUrls")()
+
+	var links = make([]dto.Link, 0, len(shortLinks))
 
 	query, args, err := s.queryBuilder.
 		Select(
 			"l.short_url",
+			"COALESCE(l.domain, '')",
+			"l.forward_query",
+			"l.query_conflict",
+			"l.forward_path",
+			"l.utm_source",
+			"l.utm_medium",
+			"l.utm_campaign",
+			"l.utm_term",
+			"l.utm_content",
 			"l.long_url",
 			"l.user_email",
 			"l.expires_at",
 			"l.times_visited",
+			"l.created_at",
+			"COALESCE(l.title, '')",
+			"COALESCE(l.description, '')",
+			linkTagsColumn,
 		).
 		From("urls l").
-		Join("users u ON l.user_email = u.email").
-		Where(squirrel.Eq{"l.user_email": email}).
-		Offset(uint64(offset)).
-		Limit(uint64(limit)).
+		Where(squirrel.Eq{"l.short_url": shortLinks}).
 		ToSql()
 
 	if err != nil {
-		return nil, fmt.Errorf("GetAllUserShortLinks query error | %w", err)
+		return nil, fmt.Errorf("GetShortLinksByShortUrls query error | %w", err)
 	}
 
 	rows, err := s.pgxPool.Query(ctx, query, args...)
 
 	if err != nil {
-		return nil, fmt.Errorf("GetAllUserShortLinks query error | %w", err)
+		return nil, fmt.Errorf("GetShortLinksByShortUrls query error | %w", err)
 	}
 
 	defer rows.Close()
@@ -233,174 +379,1124 @@ func (s *Storage) GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email
 
 		err = rows.Scan(
 			&link.ShortUrl,
+			&link.Domain,
+			&link.ForwardQuery,
+			&link.QueryConflict,
+			&link.ForwardPath,
+			&link.UTM.Source,
+			&link.UTM.Medium,
+			&link.UTM.Campaign,
+			&link.UTM.Term,
+			&link.UTM.Content,
 			&link.LongUrl,
 			&link.UserEmail,
 			&link.ExpiresAt,
 			&link.TimesVisited,
+			&link.CreatedAt,
+			&link.Title,
+			&link.Description,
+			&link.Tags,
 		)
 
 		if err != nil {
-			return nil, fmt.Errorf("GetAllUserShortLinks query error | %w", err)
+			return nil, fmt.Errorf("GetShortLinksByShortUrls scan error | %w", err)
 		}
 
 		links = append(links, link)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetShortLinksByShortUrls query error | %w", err)
+	}
+
 	return links, nil
 }
 
-func (s *Storage) GetTotalUserLinksNumber(ctx context.Context, email string) (int, error) {
+func (s *Storage) CountShortLinks(ctx context.Context) (int, error) {
+	defer observeQuery(ctx, "CountShortLinks")()
+
+	var count int
+
 	query, args, err := s.queryBuilder.
-		Select(
-			"COUNT(*)",
-		).
-		From("urls l").
-		Join("users u ON l.user_email = u.email").
-		Where(squirrel.Eq{"l.user_email": email}).
+		Select("COUNT(*)").
+		From("urls").
 		ToSql()
 
 	if err != nil {
-		return 0, fmt.Errorf("GetTotalUserLinksNumber query build error | %w", err)
+		return 0, fmt.Errorf("CountShortLinks query error | %w", err)
 	}
 
-	var totalUserLinks int
-
-	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(
-		&totalUserLinks)
+	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(&count)
 
 	if err != nil {
-		return 0, fmt.Errorf("GetTotalUserLinksNumber query error | %w", err)
+		return 0, fmt.Errorf("CountShortLinks query error | %w", err)
 	}
 
-	return totalUserLinks, nil
+	return count, nil
 }
 
-func (s *Storage) DeleteShortLink(ctx context.Context, shortLink string) error {
+// GetShortLinksBatch возвращает до limit ссылок всех пользователей, следующих за afterShortUrl в порядке short_url.
+// Пустой afterShortUrl означает начало таблицы.
+func (s *Storage) GetShortLinksBatch(ctx context.Context, afterShortUrl string, limit int) ([]dto.Link, error) {
+	defer observeQuery(ctx, "GetShortLinksBatch")()
+
+	var links = make([]dto.Link, 0, limit)
+
 	query, args, err := s.queryBuilder.
-		Delete("urls").
-		Where(squirrel.Eq{"short_url": shortLink}).
+		Select(
+			"l.short_url",
+			"COALESCE(l.domain, '')",
+			"l.forward_query",
+			"l.query_conflict",
+			"l.forward_path",
+			"l.utm_source",
+			"l.utm_medium",
+			"l.utm_campaign",
+			"l.utm_term",
+			"l.utm_content",
+			"l.long_url",
+			"l.user_email",
+			"l.expires_at",
+			"l.times_visited",
+			"l.created_at",
+			"COALESCE(l.title, '')",
+			"COALESCE(l.description, '')",
+			linkTagsColumn,
+		).
+		From("urls l").
+		Where(squirrel.Gt{"l.short_url": afterShortUrl}).
+		OrderBy("l.short_url").
+		Limit(uint64(limit)).
 		ToSql()
 
 	if err != nil {
-		return fmt.Errorf("DeleteShortLink query error | %w", err)
+		return nil, fmt.Errorf("GetShortLinksBatch query error | %w", err)
 	}
 
-	_, err = s.pgxPool.Exec(ctx, query, args...)
+	rows, err := s.pgxPool.Query(ctx, query, args...)
 
 	if err != nil {
-		return fmt.Errorf("DeleteShortLink query error | %w", err)
+		return nil, fmt.Errorf("GetShortLinksBatch query error | %w", err)
 	}
 
-	return nil
-}
+	defer rows.Close()
 
-func (s *Storage) ExtendShortLink(ctx context.Context, shortLink string, expiresAt time.Time) (*dto.Link, error) {
-	var link dto.Link
+	for rows.Next() {
+		var link dto.Link
 
-	query, args, err := s.queryBuilder.
-		Update("urls").
-		Set("expires_at", expiresAt.Add(linkExpireIn).UTC().Format(time.RFC3339)).
-		Where(squirrel.Eq{"short_url": shortLink}).
-		Suffix("RETURNING short_url, long_url, expires_at").
-		ToSql()
+		err = rows.Scan(
+			&link.ShortUrl,
+			&link.Domain,
+			&link.ForwardQuery,
+			&link.QueryConflict,
+			&link.ForwardPath,
+			&link.UTM.Source,
+			&link.UTM.Medium,
+			&link.UTM.Campaign,
+			&link.UTM.Term,
+			&link.UTM.Content,
+			&link.LongUrl,
+			&link.UserEmail,
+			&link.ExpiresAt,
+			&link.TimesVisited,
+			&link.CreatedAt,
+			&link.Title,
+			&link.Description,
+			&link.Tags,
+		)
 
-	if err != nil {
-		return nil, fmt.Errorf("ExtendShortLink query error | %w", err)
-	}
+		if err != nil {
+			return nil, fmt.Errorf("GetShortLinksBatch scan error | %w", err)
+		}
 
-	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(&link.ShortUrl,
-		&link.LongUrl,
-		&link.ExpiresAt)
+		links = append(links, link)
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("ExtendShortLink query error | %w", err)
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetShortLinksBatch query error | %w", err)
 	}
 
-	return &link, nil
+	return links, nil
 }
 
-func (s *Storage) GetSubscriptions(ctx context.Context) ([]dto.Subscription, error) {
-	var subscriptions []dto.Subscription
+// linkSortColumns сопоставляет ключ сортировки со столбцом таблицы urls.
+var linkSortColumns = map[dto.LinkSortField]string{
+	dto.LinkSortCreatedAt:    "l.created_at",
+	dto.LinkSortExpiresAt:    "l.expires_at",
+	dto.LinkSortTimesVisited: "l.times_visited",
+	dto.LinkSortAlias:        "l.short_url",
+}
 
-	query, args, err := s.queryBuilder.
+func (s *Storage) GetUserShortLinks(ctx context.Context, email string, listQuery dto.LinkListQuery) ([]dto.Link, error) {
+	defer observeQuery(ctx, "GetUserShortLinks")()
+
+	var links = make([]dto.Link, 0)
+
+	sortColumn, ok := linkSortColumns[listQuery.SortBy]
+
+	if !ok {
+		return nil, fmt.Errorf("GetUserShortLinks unknown sort field %s", listQuery.SortBy)
+	}
+
+	direction, comparison := "ASC", ">"
+
+	if listQuery.Direction == dto.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	builder := s.queryBuilder.
 		Select(
-			"id",
-			"name",
-			"total_urls",
+			"l.short_url",
+			"COALESCE(l.domain, '')",
+			"l.forward_query",
+			"l.query_conflict",
+			"l.forward_path",
+			"l.utm_source",
+			"l.utm_medium",
+			"l.utm_campaign",
+			"l.utm_term",
+			"l.utm_content",
+			"l.long_url",
+			"l.user_email",
+			"l.expires_at",
+			"l.times_visited",
+			"l.created_at",
+			"COALESCE(l.title, '')",
+			"COALESCE(l.description, '')",
+			linkTagsColumn,
 		).
-		From("subscriptions").
+		From("urls l").
+		Where(squirrel.Eq{"l.user_email": email})
+
+	builder = applyLinkFilter(builder, listQuery.Filter)
+
+	// short_url уникален, поэтому пара (ключ сортировки, short_url) задаёт стабильный порядок
+	if sortColumn == "l.short_url" {
+		if listQuery.After != nil {
+			builder = builder.Where(squirrel.Expr("l.short_url "+comparison+" ?", listQuery.After.ShortUrl))
+		}
+
+		builder = builder.OrderBy("l.short_url " + direction)
+	} else {
+		if listQuery.After != nil {
+			builder = builder.Where(squirrel.Expr(
+				"("+sortColumn+", l.short_url) "+comparison+" (?, ?)",
+				listQuery.After.Value,
+				listQuery.After.ShortUrl,
+			))
+		}
+
+		builder = builder.OrderBy(sortColumn+" "+direction, "l.short_url "+direction)
+	}
+
+	query, args, err := builder.
+		Limit(uint64(listQuery.Limit)).
 		ToSql()
 
 	if err != nil {
-		return nil, fmt.Errorf("GetSubscriptions query error | %w", err)
+		return nil, fmt.Errorf("GetUserShortLinks query error | %w", err)
 	}
 
 	rows, err := s.pgxPool.Query(ctx, query, args...)
 
 	if err != nil {
-		return nil, fmt.Errorf("GetSubscriptions query error | %w", err)
+		return nil, fmt.Errorf("GetUserShortLinks query error | %w", err)
 	}
 
 	defer rows.Close()
 
 	for rows.Next() {
-		var sub dto.Subscription
+		var link dto.Link
+
 		err = rows.Scan(
-			&sub.Id,
-			&sub.Name,
-			&sub.TotalUrls,
+			&link.ShortUrl,
+			&link.Domain,
+			&link.ForwardQuery,
+			&link.QueryConflict,
+			&link.ForwardPath,
+			&link.UTM.Source,
+			&link.UTM.Medium,
+			&link.UTM.Campaign,
+			&link.UTM.Term,
+			&link.UTM.Content,
+			&link.LongUrl,
+			&link.UserEmail,
+			&link.ExpiresAt,
+			&link.TimesVisited,
+			&link.CreatedAt,
+			&link.Title,
+			&link.Description,
+			&link.Tags,
 		)
 
 		if err != nil {
-			return nil, fmt.Errorf("GetSubscriptions scan error | %w", err)
+			return nil, fmt.Errorf("GetUserShortLinks query error | %w", err)
 		}
 
-		subscriptions = append(subscriptions, sub)
-
+		links = append(links, link)
 	}
 
-	return subscriptions, nil
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetUserShortLinks query error | %w", err)
+	}
 
+	return links, nil
 }
 
-func (s *Storage) CreateSubscriptions(ctx context.Context) error {
-	query, args, err := s.queryBuilder.Insert("subscriptions").
-		Columns("name", "total_urls").
-		Values("Bronze", 1000).
-		Values("Silver", 5000).
-		Values("Gold", 10000).
+func (s *Storage) GetTotalUserLinksNumber(ctx context.Context, email string, filter dto.LinkFilter) (int, error) {
+	defer observeQuery(ctx, "GetTotalUserLinksNumber")()
+
+	builder := s.queryBuilder.
+		Select(
+			"COUNT(*)",
+		).
+		From("urls l").
+		Where(squirrel.Eq{"l.user_email": email})
+
+	query, args, err := applyLinkFilter(builder, filter).
 		ToSql()
 
 	if err != nil {
-		return fmt.Errorf("CreateSubscriptions query build error | %w", err)
+		return 0, fmt.Errorf("GetTotalUserLinksNumber query build error | %w", err)
 	}
 
-	_, err = s.pgxPool.Exec(ctx, query, args...)
+	var totalUserLinks int
+
+	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(
+		&totalUserLinks)
 
 	if err != nil {
-		return fmt.Errorf("CreateShortLink query error | %w", err)
+		return 0, fmt.Errorf("GetTotalUserLinksNumber query error | %w", err)
 	}
 
-	return nil
+	return totalUserLinks, nil
+}
+
+func applyLinkFilter(builder squirrel.SelectBuilder, filter dto.LinkFilter) squirrel.SelectBuilder {
+	if filter.Tag != "" {
+		builder = builder.Where(squirrel.Expr(
+			"EXISTS (SELECT 1 FROM url_tags ut JOIN tags t ON t.id = ut.tag_id WHERE ut.url_id = l.id AND t.name = ?)",
+			filter.Tag,
+		))
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		builder = builder.Where(squirrel.GtOrEq{"l.created_at": filter.CreatedFrom.UTC().Format(time.RFC3339)})
+	}
+
+	if !filter.CreatedTo.IsZero() {
+		builder = builder.Where(squirrel.Lt{"l.created_at": filter.CreatedTo.UTC().Format(time.RFC3339)})
+	}
+
+	switch filter.Status {
+	case dto.LinkStatusActive:
+		builder = builder.Where("l.expires_at > timezone('utc', now())")
+	case dto.LinkStatusExpired:
+		builder = builder.Where("l.expires_at <= timezone('utc', now())")
+	}
+
+	return builder
 }
 
-func (s *Storage) IncrementShortLinkTimesWatchedCount(ctx context.Context, shortLink string) error {
+func (s *Storage) UpdateShortLinkInfo(ctx context.Context, shortLink string, title string, description string, tags []string) error {
+	defer observeQuery(ctx, "UpdateShortLinkInfo")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkInfo begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
 	query, args, err := s.queryBuilder.
 		Update("urls").
-		Set("times_visited", squirrel.Expr("times_visited + 1")).
+		Set("title", title).
+		Set("description", description).
+		Set("updated_at", time.Now().UTC().Format(time.RFC3339)).
 		Where(squirrel.Eq{"short_url": shortLink}).
+		Suffix("RETURNING id, user_email").
 		ToSql()
 
 	if err != nil {
-		return fmt.Errorf("IncrementShortLinkTimesWatchedCount query error | %w", err)
+		return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
 	}
 
-	fmt.Println(query)
+	var (
+		urlID     int64
+		userEmail string
+	)
 
-	_, err = s.pgxPool.Exec(ctx, query, args...)
+	err = tx.QueryRow(ctx, query, args...).Scan(&urlID, &userEmail)
 
 	if err != nil {
-		return fmt.Errorf("IncrementShortLinkTimesWatchedCount query error | %w", err)
+		return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+	}
+
+	query, args, err = s.queryBuilder.
+		Delete("url_tags").
+		Where(squirrel.Eq{"url_id": urlID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+	}
+
+	if len(tags) > 0 {
+		tagsBuilder := s.queryBuilder.
+			Insert("tags").
+			Columns("user_email", "name")
+
+		for _, tag := range tags {
+			tagsBuilder = tagsBuilder.Values(userEmail, tag)
+		}
+
+		// DO UPDATE вместо DO NOTHING, чтобы RETURNING вернул id и уже существующих тегов
+		query, args, err = tagsBuilder.
+			Suffix("ON CONFLICT (user_email, name) DO UPDATE SET name = EXCLUDED.name RETURNING id").
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+		}
+
+		rows, err := tx.Query(ctx, query, args...)
+
+		if err != nil {
+			return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+		}
+
+		var tagIDs []int
+
+		for rows.Next() {
+			var tagID int
+
+			if err = rows.Scan(&tagID); err != nil {
+				rows.Close()
+
+				return fmt.Errorf("UpdateShortLinkInfo scan error | %w", err)
+			}
+
+			tagIDs = append(tagIDs, tagID)
+		}
+
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+		}
+
+		urlTagsBuilder := s.queryBuilder.
+			Insert("url_tags").
+			Columns("url_id", "tag_id")
+
+		for _, tagID := range tagIDs {
+			urlTagsBuilder = urlTagsBuilder.Values(urlID, tagID)
+		}
+
+		query, args, err = urlTagsBuilder.ToSql()
+
+		if err != nil {
+			return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+		}
+
+		_, err = tx.Exec(ctx, query, args...)
+
+		if err != nil {
+			return fmt.Errorf("UpdateShortLinkInfo query error | %w", err)
+		}
+	}
+
+	err = s.insertOutboxEvent(ctx, tx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+		ShortLink: shortLink,
+		UserEmail: userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkInfo %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("UpdateShortLinkInfo commit error | %w", err)
+	}
+
+	return nil
+}
+
+// UpdateShortLinkRedirect меняет параметры перехода по ссылке. Событие обновления нужно, чтобы они попали в кеш Redis.
+func (s *Storage) UpdateShortLinkRedirect(ctx context.Context, shortLink string, options dto.RedirectOptions) error {
+	defer observeQuery(ctx, "UpdateShortLinkRedirect")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkRedirect begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	query, args, err := s.queryBuilder.
+		Update("urls").
+		Set("forward_query", options.ForwardQuery).
+		Set("query_conflict", options.QueryConflict).
+		Set("forward_path", options.ForwardPath).
+		Set("updated_at", time.Now().UTC().Format(time.RFC3339)).
+		Where(squirrel.Eq{"short_url": shortLink}).
+		Suffix("RETURNING user_email").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkRedirect query error | %w", err)
+	}
+
+	var userEmail string
+
+	if err = tx.QueryRow(ctx, query, args...).Scan(&userEmail); err != nil {
+		return fmt.Errorf("UpdateShortLinkRedirect query error | %w", err)
+	}
+
+	err = s.insertOutboxEvent(ctx, tx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+		ShortLink: shortLink,
+		UserEmail: userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkRedirect %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("UpdateShortLinkRedirect commit error | %w", err)
+	}
+
+	return nil
+}
+
+// UpdateShortLinkUTM меняет метки кампании ссылки. Событие обновления нужно, чтобы они попали в кеш Redis.
+func (s *Storage) UpdateShortLinkUTM(ctx context.Context, shortLink string, utm dto.UTM) error {
+	defer observeQuery(ctx, "UpdateShortLinkUTM")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkUTM begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	query, args, err := s.queryBuilder.
+		Update("urls").
+		Set("utm_source", utm.Source).
+		Set("utm_medium", utm.Medium).
+		Set("utm_campaign", utm.Campaign).
+		Set("utm_term", utm.Term).
+		Set("utm_content", utm.Content).
+		Set("updated_at", time.Now().UTC().Format(time.RFC3339)).
+		Where(squirrel.Eq{"short_url": shortLink}).
+		Suffix("RETURNING user_email").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkUTM query error | %w", err)
+	}
+
+	var userEmail string
+
+	if err = tx.QueryRow(ctx, query, args...).Scan(&userEmail); err != nil {
+		return fmt.Errorf("UpdateShortLinkUTM query error | %w", err)
+	}
+
+	err = s.insertOutboxEvent(ctx, tx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+		ShortLink: shortLink,
+		UserEmail: userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("UpdateShortLinkUTM %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("UpdateShortLinkUTM commit error | %w", err)
+	}
+
+	return nil
+}
+
+// GetUserCampaignStats считает для каждой кампании пользователя ссылки, которые сейчас в ней, и переходы,
+// сделанные, пока у ссылки была эта кампания. Начинает с кампаний с наибольшим числом переходов.
+func (s *Storage) GetUserCampaignStats(ctx context.Context, email string) ([]dto.CampaignStats, error) {
+	defer observeQuery(ctx, "GetUserCampaignStats")()
+
+	var stats = make([]dto.CampaignStats, 0)
+
+	links := s.queryBuilder.
+		Select("utm_campaign AS campaign", "COUNT(*) AS links").
+		From("urls").
+		Where(squirrel.Eq{"user_email": email}).
+		Where(squirrel.NotEq{"utm_campaign": ""}).
+		GroupBy("utm_campaign")
+
+	clicks := s.queryBuilder.
+		Select("c.campaign", "SUM(c.clicks)::bigint AS clicks").
+		From("link_campaign_clicks c").
+		Join("urls u ON u.short_url = c.short_url").
+		Where(squirrel.Eq{"u.user_email": email}).
+		GroupBy("c.campaign")
+
+	query, args, err := s.queryBuilder.
+		Select(
+			"COALESCE(l.campaign, c.campaign)",
+			"COALESCE(l.links, 0)",
+			"COALESCE(c.clicks, 0)",
+		).
+		FromSelect(links, "l").
+		JoinClause(clicks.Prefix("FULL JOIN (").Suffix(") c ON c.campaign = l.campaign")).
+		OrderBy("3 DESC", "1").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserCampaignStats query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserCampaignStats query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var campaign dto.CampaignStats
+
+		err = rows.Scan(&campaign.Campaign, &campaign.Links, &campaign.Clicks)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetUserCampaignStats scan error | %w", err)
+		}
+
+		stats = append(stats, campaign)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetUserCampaignStats query error | %w", err)
+	}
+
+	return stats, nil
+}
+
+func (s *Storage) GetUserTags(ctx context.Context, email string) ([]dto.Tag, error) {
+	defer observeQuery(ctx, "GetUserTags")()
+
+	var tags = make([]dto.Tag, 0)
+
+	query, args, err := s.queryBuilder.
+		Select(
+			"t.name",
+			"COUNT(ut.url_id)",
+		).
+		From("tags t").
+		LeftJoin("url_tags ut ON ut.tag_id = t.id").
+		Where(squirrel.Eq{"t.user_email": email}).
+		GroupBy("t.id", "t.name").
+		OrderBy("t.name").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTags query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTags query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag dto.Tag
+
+		err = rows.Scan(&tag.Name, &tag.LinksNumber)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetUserTags scan error | %w", err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetUserTags query error | %w", err)
+	}
+
+	return tags, nil
+}
+
+// DeleteUserTag снимает тег со всех ссылок пользователя и удаляет его. Для каждой ссылки с тегом записывается
+// событие обновления, чтобы тег пропал и из поискового индекса.
+func (s *Storage) DeleteUserTag(ctx context.Context, email string, tag string) error {
+	defer observeQuery(ctx, "DeleteUserTag")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("DeleteUserTag begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	// ссылки с тегом выбираются до его удаления: каскад уберёт их связи с тегом
+	query, args, err := s.queryBuilder.
+		Update("urls").
+		Set("updated_at", time.Now().UTC().Format(time.RFC3339)).
+		Where("id IN (SELECT ut.url_id FROM url_tags ut JOIN tags t ON t.id = ut.tag_id WHERE t.user_email = ? AND t.name = ?)", email, tag).
+		Suffix("RETURNING short_url").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("DeleteUserTag query error | %w", err)
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("DeleteUserTag query error | %w", err)
+	}
+
+	var shortLinks []string
+
+	for rows.Next() {
+		var shortLink string
+
+		if err = rows.Scan(&shortLink); err != nil {
+			rows.Close()
+
+			return fmt.Errorf("DeleteUserTag scan error | %w", err)
+		}
+
+		shortLinks = append(shortLinks, shortLink)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("DeleteUserTag query error | %w", err)
+	}
+
+	query, args, err = s.queryBuilder.
+		Delete("tags").
+		Where(squirrel.Eq{"user_email": email, "name": tag}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("DeleteUserTag query error | %w", err)
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("DeleteUserTag query error | %w", err)
+	}
+
+	for _, shortLink := range shortLinks {
+		err = s.insertOutboxEvent(ctx, tx, dto.EventLinkUpdated, shortLink, dto.LinkUpdated{
+			ShortLink: shortLink,
+			UserEmail: email,
+		})
+
+		if err != nil {
+			return fmt.Errorf("DeleteUserTag %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("DeleteUserTag commit error | %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteShortLink(ctx context.Context, shortLink string) error {
+	defer observeQuery(ctx, "DeleteShortLink")()
+
+	query, args, err := s.queryBuilder.
+		Delete("urls").
+		Where(squirrel.Eq{"short_url": shortLink}).
+		Suffix("RETURNING user_email").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("DeleteShortLink query error | %w", err)
+	}
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("DeleteShortLink begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	var userEmail string
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&userEmail)
+
+	// ссылку уже удалили: событие об удалении записала та транзакция
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("DeleteShortLink query error | %w", err)
+	}
+
+	err = s.insertOutboxEvent(ctx, tx, dto.EventLinkDeleted, shortLink, dto.LinkDeleted{
+		ShortLink: shortLink,
+		UserEmail: userEmail,
+	})
+
+	if err != nil {
+		return fmt.Errorf("DeleteShortLink %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("DeleteShortLink commit error | %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ExtendShortLink(ctx context.Context, shortLink string, expiresAt time.Time) (*dto.Link, error) {
+	defer observeQuery(ctx, "ExtendShortLink")()
+
+	var link dto.Link
+
+	query, args, err := s.queryBuilder.
+		Update("urls").
+		Set("expires_at", expiresAt.Add(s.linkTTL).UTC().Format(time.RFC3339)).
+		Where(squirrel.Eq{"short_url": shortLink}).
+		Suffix("RETURNING short_url, COALESCE(domain, ''), forward_query, query_conflict, forward_path, utm_source, utm_medium, utm_campaign, utm_term, utm_content, long_url, user_email, expires_at").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("ExtendShortLink query error | %w", err)
+	}
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("ExtendShortLink begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query, args...).Scan(&link.ShortUrl,
+		&link.Domain,
+		&link.ForwardQuery,
+		&link.QueryConflict,
+		&link.ForwardPath,
+		&link.UTM.Source,
+		&link.UTM.Medium,
+		&link.UTM.Campaign,
+		&link.UTM.Term,
+		&link.UTM.Content,
+		&link.LongUrl,
+		&link.UserEmail,
+		&link.ExpiresAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("ExtendShortLink query error | %w", err)
+	}
+
+	// срок действия хранится и в кеше Redis, поэтому его тоже нужно обновить
+	err = s.insertOutboxEvent(ctx, tx, dto.EventLinkUpdated, link.ShortUrl, dto.LinkUpdated{
+		ShortLink: link.ShortUrl,
+		UserEmail: link.UserEmail,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("ExtendShortLink %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ExtendShortLink commit error | %w", err)
+	}
+
+	return &link, nil
+}
+
+// subscriptionColumns - колонки тарифа в порядке полей, которые заполняет scanSubscription.
+var subscriptionColumns = []string{
+	"id",
+	"name",
+	"total_urls",
+	"price",
+	"currency",
+	"billing_period",
+	"max_expiry_days",
+	"custom_domains",
+	"analytics_retention_days",
+	"api_rate_limit",
+	"trial_days",
+	"quota_policy",
+	"active",
+	"display_order",
+}
+
+// subscriptionFields возвращает адреса полей тарифа в порядке subscriptionColumns.
+func subscriptionFields(sub *dto.Subscription) []interface{} {
+	return []interface{}{
+		&sub.Id,
+		&sub.Name,
+		&sub.TotalUrls,
+		&sub.Price,
+		&sub.Currency,
+		&sub.BillingPeriod,
+		&sub.Features.MaxExpiryDays,
+		&sub.Features.CustomDomains,
+		&sub.Features.AnalyticsRetentionDays,
+		&sub.Features.APIRateLimit,
+		&sub.TrialDays,
+		&sub.QuotaPolicy,
+		&sub.Active,
+		&sub.DisplayOrder,
+	}
+}
+
+func scanSubscription(row pgx.Row) (*dto.Subscription, error) {
+	var sub dto.Subscription
+
+	if err := row.Scan(subscriptionFields(&sub)...); err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+// subscriptionValues - значения колонок тарифа без id для INSERT и UPDATE.
+func subscriptionValues(sub dto.Subscription) map[string]interface{} {
+	return map[string]interface{}{
+		"name":                     sub.Name,
+		"total_urls":               sub.TotalUrls,
+		"price":                    sub.Price,
+		"currency":                 sub.Currency,
+		"billing_period":           string(sub.BillingPeriod),
+		"max_expiry_days":          sub.Features.MaxExpiryDays,
+		"custom_domains":           sub.Features.CustomDomains,
+		"analytics_retention_days": sub.Features.AnalyticsRetentionDays,
+		"api_rate_limit":           sub.Features.APIRateLimit,
+		"trial_days":               sub.TrialDays,
+		"quota_policy":             string(sub.QuotaPolicy),
+		"active":                   sub.Active,
+		"display_order":            sub.DisplayOrder,
+	}
+}
+
+// GetSubscriptions возвращает тарифы в порядке показа, activeOnly оставляет только включённые.
+func (s *Storage) GetSubscriptions(ctx context.Context, activeOnly bool) ([]dto.Subscription, error) {
+	defer observeQuery(ctx, "GetSubscriptions")()
+
+	var subscriptions []dto.Subscription
+
+	builder := s.queryBuilder.
+		Select(subscriptionColumns...).
+		From("subscriptions").
+		OrderBy("display_order", "id")
+
+	if activeOnly {
+		builder = builder.Where(squirrel.Eq{"active": true})
+	}
+
+	query, args, err := builder.ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetSubscriptions query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetSubscriptions query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetSubscriptions scan error | %w", err)
+		}
+
+		subscriptions = append(subscriptions, *sub)
+
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetSubscriptions query error | %w", err)
+	}
+
+	return subscriptions, nil
+
+}
+
+func (s *Storage) GetSubscription(ctx context.Context, id int) (*dto.Subscription, error) {
+	defer observeQuery(ctx, "GetSubscription")()
+
+	query, args, err := s.queryBuilder.
+		Select(subscriptionColumns...).
+		From("subscriptions").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetSubscription query error | %w", err)
+	}
+
+	sub, err := scanSubscription(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("GetSubscription query error | %w", err)
+	}
+
+	return sub, nil
+}
+
+// CreateSubscription добавляет тариф. Название тарифа уникально (subscriptions_name_key).
+func (s *Storage) CreateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error) {
+	defer observeQuery(ctx, "CreateSubscription")()
+
+	query, args, err := s.queryBuilder.
+		Insert("subscriptions").
+		SetMap(subscriptionValues(sub)).
+		Suffix("RETURNING " + strings.Join(subscriptionColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateSubscription query error | %w", err)
+	}
+
+	created, err := scanSubscription(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateSubscription query error | %w", err)
+	}
+
+	return created, nil
+}
+
+// UpdateSubscription заменяет все поля тарифа sub.Id. Если тарифа нет, возвращает pgx.ErrNoRows.
+func (s *Storage) UpdateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error) {
+	defer observeQuery(ctx, "UpdateSubscription")()
+
+	query, args, err := s.queryBuilder.
+		Update("subscriptions").
+		SetMap(subscriptionValues(sub)).
+		Where(squirrel.Eq{"id": sub.Id}).
+		Suffix("RETURNING " + strings.Join(subscriptionColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateSubscription query error | %w", err)
+	}
+
+	updated, err := scanSubscription(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateSubscription query error | %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteSubscription удаляет тариф. Если тарифа нет, возвращает pgx.ErrNoRows.
+func (s *Storage) DeleteSubscription(ctx context.Context, id int) error {
+	defer observeQuery(ctx, "DeleteSubscription")()
+
+	query, args, err := s.queryBuilder.
+		Delete("subscriptions").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("DeleteSubscription query error | %w", err)
+	}
+
+	tag, err := s.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("DeleteSubscription query error | %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeleteSubscription query error | %w", pgx.ErrNoRows)
+	}
+
+	return nil
+}
+
+// IncrementShortLinkTimesWatchedCount учитывает переход по ссылке, а если у ссылки была кампания,
+// ещё и переход в этой кампании.
+func (s *Storage) IncrementShortLinkTimesWatchedCount(ctx context.Context, shortLink string, campaign string) error {
+	defer observeQuery(ctx, "IncrementShortLinkTimesWatchedCount")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("IncrementShortLinkTimesWatchedCount begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	query, args, err := s.queryBuilder.
+		Update("urls").
+		Set("times_visited", squirrel.Expr("times_visited + 1")).
+		Where(squirrel.Eq{"short_url": shortLink}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("IncrementShortLinkTimesWatchedCount query error | %w", err)
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("IncrementShortLinkTimesWatchedCount query error | %w", err)
+	}
+
+	if campaign != "" {
+		// ссылку могли удалить до обработки перехода, тогда строка не вставляется
+		query, args, err = s.queryBuilder.
+			Insert("link_campaign_clicks").
+			Columns("short_url", "campaign", "clicks").
+			Select(s.queryBuilder.
+				Select("short_url").
+				Column(squirrel.Expr("?::varchar", campaign)).
+				Column("1").
+				From("urls").
+				Where(squirrel.Eq{"short_url": shortLink})).
+			Suffix("ON CONFLICT (short_url, campaign) DO UPDATE SET clicks = link_campaign_clicks.clicks + 1").
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("IncrementShortLinkTimesWatchedCount query error | %w", err)
+		}
+
+		_, err = tx.Exec(ctx, query, args...)
+
+		if err != nil {
+			return fmt.Errorf("IncrementShortLinkTimesWatchedCount query error | %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("IncrementShortLinkTimesWatchedCount commit error | %w", err)
 	}
 
 	return nil
@@ -0,0 +1,309 @@
+package postgresDB
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"time"
+	"urleater/dto"
+)
+
+// domainColumns - колонки domains в порядке domainFields.
+var domainColumns = []string{
+	"host",
+	"user_email",
+	"verification_token",
+	"verified_at",
+	"fallback_url",
+	"created_at",
+}
+
+func domainFields(domain *dto.Domain) []interface{} {
+	return []interface{}{
+		&domain.Host,
+		&domain.UserEmail,
+		&domain.VerificationToken,
+		&domain.VerifiedAt,
+		&domain.FallbackUrl,
+		&domain.CreatedAt,
+	}
+}
+
+func scanDomain(row pgx.Row) (*dto.Domain, error) {
+	var domain dto.Domain
+
+	if err := row.Scan(domainFields(&domain)...); err != nil {
+		return nil, err
+	}
+
+	return &domain, nil
+}
+
+func (s *Storage) CreateDomain(ctx context.Context, domain dto.Domain) (*dto.Domain, error) {
+	defer observeQuery(ctx, "CreateDomain")()
+
+	query, args, err := s.queryBuilder.
+		Insert("domains").
+		Columns("host", "user_email", "verification_token", "fallback_url", "created_at").
+		Values(domain.Host, domain.UserEmail, domain.VerificationToken, domain.FallbackUrl, time.Now().UTC()).
+		Suffix("RETURNING " + strings.Join(domainColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateDomain query error | %w", err)
+	}
+
+	created, err := scanDomain(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateDomain query error | %w", err)
+	}
+
+	return created, nil
+}
+
+// GetDomain возвращает подтверждённый домен host. Неподтверждённые заявки на него не учитываются.
+func (s *Storage) GetDomain(ctx context.Context, host string) (*dto.Domain, error) {
+	defer observeQuery(ctx, "GetDomain")()
+
+	query, args, err := s.queryBuilder.
+		Select(domainColumns...).
+		From("domains").
+		Where(squirrel.Eq{"host": host}).
+		Where(squirrel.NotEq{"verified_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetDomain query error | %w", err)
+	}
+
+	domain, err := scanDomain(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("GetDomain query error | %w", err)
+	}
+
+	return domain, nil
+}
+
+// GetUserDomain возвращает домен host пользователя, подтверждённый или нет.
+func (s *Storage) GetUserDomain(ctx context.Context, email string, host string) (*dto.Domain, error) {
+	defer observeQuery(ctx, "GetUserDomain")()
+
+	query, args, err := s.queryBuilder.
+		Select(domainColumns...).
+		From("domains").
+		Where(squirrel.Eq{"host": host, "user_email": email}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserDomain query error | %w", err)
+	}
+
+	domain, err := scanDomain(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserDomain query error | %w", err)
+	}
+
+	return domain, nil
+}
+
+// GetUserDomains возвращает домены пользователя в порядке добавления.
+func (s *Storage) GetUserDomains(ctx context.Context, email string) ([]dto.Domain, error) {
+	defer observeQuery(ctx, "GetUserDomains")()
+
+	query, args, err := s.queryBuilder.
+		Select(domainColumns...).
+		From("domains").
+		Where(squirrel.Eq{"user_email": email}).
+		OrderBy("created_at", "host").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserDomains query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserDomains query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	var domains []dto.Domain
+
+	for rows.Next() {
+		domain, err := scanDomain(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetUserDomains scan error | %w", err)
+		}
+
+		domains = append(domains, *domain)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetUserDomains query error | %w", err)
+	}
+
+	return domains, nil
+}
+
+// VerifyDomain отмечает домен пользователя подтверждённым и удаляет чужие неподтверждённые заявки на тот же хост.
+// Повторное подтверждение не меняет verified_at. Если хост уже подтвердил другой пользователь, возвращает нарушение уникальности.
+func (s *Storage) VerifyDomain(ctx context.Context, email string, host string, verifiedAt time.Time) (*dto.Domain, error) {
+	defer observeQuery(ctx, "VerifyDomain")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("VerifyDomain begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	query, args, err := s.queryBuilder.
+		Update("domains").
+		Set("verified_at", squirrel.Expr("COALESCE(verified_at, ?)", verifiedAt.UTC())).
+		Where(squirrel.Eq{"host": host, "user_email": email}).
+		Suffix("RETURNING " + strings.Join(domainColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("VerifyDomain query error | %w", err)
+	}
+
+	domain, err := scanDomain(tx.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("VerifyDomain query error | %w", err)
+	}
+
+	query, args, err = s.queryBuilder.
+		Delete("domains").
+		Where(squirrel.Eq{"host": host, "verified_at": nil}).
+		Where(squirrel.NotEq{"user_email": email}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("VerifyDomain query error | %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("VerifyDomain query error | %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("VerifyDomain commit error | %w", err)
+	}
+
+	return domain, nil
+}
+
+func (s *Storage) UpdateDomainFallback(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error) {
+	defer observeQuery(ctx, "UpdateDomainFallback")()
+
+	query, args, err := s.queryBuilder.
+		Update("domains").
+		Set("fallback_url", fallbackUrl).
+		Where(squirrel.Eq{"host": host, "user_email": email}).
+		Suffix("RETURNING " + strings.Join(domainColumns, ", ")).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateDomainFallback query error | %w", err)
+	}
+
+	domain, err := scanDomain(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateDomainFallback query error | %w", err)
+	}
+
+	return domain, nil
+}
+
+// DeleteDomain удаляет домен пользователя вместе с его ссылками. Об удалении каждой ссылки записывается событие,
+// чтобы обработчик outbox убрал её из кеша и индекса. Если домена нет, возвращает pgx.ErrNoRows.
+func (s *Storage) DeleteDomain(ctx context.Context, email string, host string) error {
+	defer observeQuery(ctx, "DeleteDomain")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return fmt.Errorf("DeleteDomain begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	query, args, err := s.queryBuilder.
+		Delete("urls").
+		Where(squirrel.Eq{"domain": host, "user_email": email}).
+		Suffix("RETURNING short_url, user_email").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("DeleteDomain query error | %w", err)
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("DeleteDomain query error | %w", err)
+	}
+
+	var deleted []dto.LinkDeleted
+
+	for rows.Next() {
+		var link dto.LinkDeleted
+
+		if err = rows.Scan(&link.ShortLink, &link.UserEmail); err != nil {
+			rows.Close()
+
+			return fmt.Errorf("DeleteDomain scan error | %w", err)
+		}
+
+		deleted = append(deleted, link)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("DeleteDomain query error | %w", err)
+	}
+
+	for _, link := range deleted {
+		if err = s.insertOutboxEvent(ctx, tx, dto.EventLinkDeleted, link.ShortLink, link); err != nil {
+			return fmt.Errorf("DeleteDomain %w", err)
+		}
+	}
+
+	query, args, err = s.queryBuilder.
+		Delete("domains").
+		Where(squirrel.Eq{"host": host, "user_email": email}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("DeleteDomain query error | %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("DeleteDomain query error | %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeleteDomain query error | %w", pgx.ErrNoRows)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("DeleteDomain commit error | %w", err)
+	}
+
+	return nil
+}
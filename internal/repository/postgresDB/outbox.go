@@ -0,0 +1,170 @@
+package postgresDB
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"time"
+	"urleater/dto"
+	"urleater/internal/logging"
+)
+
+// leaseOutboxEventsQuery выдаёт события, для которых подошло время, и откладывает их на время аренды:
+// если обработчик упадёт, событие снова станет доступно после её окончания.
+// Событие не выдаётся, пока не обработано предыдущее событие с тем же ключом,
+// поэтому изменения одной ссылки применяются по порядку даже при нескольких обработчиках.
+const leaseOutboxEventsQuery = `
+WITH next AS (
+	SELECT o.id
+	FROM outbox o
+	WHERE o.available_at <= timezone('utc', now())
+		AND NOT EXISTS (SELECT 1 FROM outbox p WHERE p.event_key = o.event_key AND p.id < o.id)
+	ORDER BY o.id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+)
+UPDATE outbox
+SET attempts = outbox.attempts + 1,
+	available_at = timezone('utc', now()) + make_interval(secs => $2)
+FROM next
+WHERE outbox.id = next.id
+RETURNING outbox.id, outbox.event_id::text, outbox.event_type, outbox.event_version, outbox.event_key, outbox.payload, outbox.attempts, outbox.request_id, outbox.created_at`
+
+func (s *Storage) insertOutboxEvent(ctx context.Context, tx pgx.Tx, eventType dto.EventType, key string, payload any) error {
+	payloadJSON, err := json.Marshal(payload)
+
+	if err != nil {
+		return fmt.Errorf("outbox payload error | %w", err)
+	}
+
+	query, args, err := s.queryBuilder.
+		Insert("outbox").
+		Columns("event_type", "event_version", "event_key", "payload", "request_id").
+		Values(eventType, dto.EventVersions[eventType], key, string(payloadJSON), logging.RequestID(ctx)).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("outbox query error | %w", err)
+	}
+
+	_, err = tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("outbox query error | %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) LeaseOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]dto.OutboxEvent, error) {
+	defer observeQuery(ctx, "LeaseOutboxEvents")()
+
+	var events = make([]dto.OutboxEvent, 0, limit)
+
+	rows, err := s.pgxPool.Query(ctx, leaseOutboxEventsQuery, limit, lease.Seconds())
+
+	if err != nil {
+		return nil, fmt.Errorf("LeaseOutboxEvents query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			event   dto.OutboxEvent
+			payload []byte
+		)
+
+		err = rows.Scan(&event.Id, &event.EventId, &event.Type, &event.Version, &event.Key, &payload, &event.Attempts, &event.RequestId, &event.CreatedAt)
+
+		if err != nil {
+			return nil, fmt.Errorf("LeaseOutboxEvents scan error | %w", err)
+		}
+
+		event.Payload = payload
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("LeaseOutboxEvents query error | %w", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+
+	return events, nil
+}
+
+// CompleteOutboxEvent удаляет обработанное событие.
+func (s *Storage) CompleteOutboxEvent(ctx context.Context, id int64) error {
+	defer observeQuery(ctx, "CompleteOutboxEvent")()
+
+	query, args, err := s.queryBuilder.
+		Delete("outbox").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("CompleteOutboxEvent query error | %w", err)
+	}
+
+	_, err = s.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("CompleteOutboxEvent query error | %w", err)
+	}
+
+	return nil
+}
+
+// RetryOutboxEvent откладывает событие до retryAt и сохраняет причину неудачи.
+func (s *Storage) RetryOutboxEvent(ctx context.Context, id int64, retryAt time.Time, lastError string) error {
+	defer observeQuery(ctx, "RetryOutboxEvent")()
+
+	query, args, err := s.queryBuilder.
+		Update("outbox").
+		Set("available_at", retryAt.UTC().Format(time.RFC3339Nano)).
+		Set("last_error", lastError).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("RetryOutboxEvent query error | %w", err)
+	}
+
+	_, err = s.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return fmt.Errorf("RetryOutboxEvent query error | %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CountOutboxEvents(ctx context.Context) (int, error) {
+	defer observeQuery(ctx, "CountOutboxEvents")()
+
+	var count int
+
+	query, args, err := s.queryBuilder.
+		Select("COUNT(*)").
+		From("outbox").
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("CountOutboxEvents query error | %w", err)
+	}
+
+	err = s.pgxPool.QueryRow(ctx, query, args...).Scan(&count)
+
+	if err != nil {
+		return 0, fmt.Errorf("CountOutboxEvents query error | %w", err)
+	}
+
+	return count, nil
+}
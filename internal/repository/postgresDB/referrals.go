@@ -0,0 +1,229 @@
+package postgresDB
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"urleater/dto"
+)
+
+const referralColumns = "invitee_email, referrer_email, created_at, credited_at"
+
+func scanReferral(row pgx.Row) (*dto.Referral, error) {
+	var referral dto.Referral
+
+	if err := row.Scan(&referral.InviteeEmail, &referral.ReferrerEmail, &referral.CreatedAt, &referral.CreditedAt); err != nil {
+		return nil, err
+	}
+
+	return &referral, nil
+}
+
+// CreateEmailVerification сохраняет хеш токена подтверждения почты. Новый токен заменяет прежний.
+func (s *Storage) CreateEmailVerification(ctx context.Context, email string, tokenHash string, expiresAt time.Time) error {
+	defer observeQuery(ctx, "CreateEmailVerification")()
+
+	query, args, err := s.queryBuilder.
+		Insert("email_verifications").
+		Columns("user_email", "token_hash", "expires_at").
+		Values(email, tokenHash, expiresAt.UTC()).
+		Suffix("ON CONFLICT (user_email) DO UPDATE SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("CreateEmailVerification query error | %w", err)
+	}
+
+	if _, err = s.pgxPool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("CreateEmailVerification query error | %w", err)
+	}
+
+	return nil
+}
+
+// VerifyEmail подтверждает почту по хешу токена, срок которого не истёк к now, и в той же транзакции начисляет
+// пригласившему referralCredits ссылок. Возвращает email пользователя и приглашение, если оно было,
+// или pgx.ErrNoRows, если токена нет или он истёк.
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, now time.Time, referralCredits int) (string, *dto.Referral, error) {
+	defer observeQuery(ctx, "VerifyEmail")()
+
+	query, args, err := s.queryBuilder.
+		Delete("email_verifications").
+		Where(squirrel.Eq{"token_hash": tokenHash}).
+		Where(squirrel.Gt{"expires_at": now.UTC()}).
+		Suffix("RETURNING user_email").
+		ToSql()
+
+	if err != nil {
+		return "", nil, fmt.Errorf("VerifyEmail query error | %w", err)
+	}
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return "", nil, fmt.Errorf("VerifyEmail begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	var email string
+
+	if err = tx.QueryRow(ctx, query, args...).Scan(&email); err != nil {
+		return "", nil, fmt.Errorf("VerifyEmail query error | %w", err)
+	}
+
+	query, args, err = s.queryBuilder.
+		Update("users").
+		Set("email_verified", true).
+		Where(squirrel.Eq{"email": email}).
+		ToSql()
+
+	if err != nil {
+		return "", nil, fmt.Errorf("VerifyEmail query error | %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return "", nil, fmt.Errorf("VerifyEmail query error | %w", err)
+	}
+
+	query, args, err = s.queryBuilder.
+		Update("referrals").
+		Set("credited_at", now.UTC()).
+		Where(squirrel.Eq{"invitee_email": email, "credited_at": nil}).
+		Suffix("RETURNING " + referralColumns).
+		ToSql()
+
+	if err != nil {
+		return "", nil, fmt.Errorf("VerifyEmail query error | %w", err)
+	}
+
+	referral, err := scanReferral(tx.QueryRow(ctx, query, args...))
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		referral = nil
+
+	case err != nil:
+		return "", nil, fmt.Errorf("VerifyEmail query error | %w", err)
+
+	case referralCredits > 0:
+		_, err = s.creditLinks(ctx, tx, dto.LinkCredit{
+			UserEmail: referral.ReferrerEmail,
+			Amount:    referralCredits,
+			Reason:    dto.LinkCreditReferral,
+			Reference: email,
+		})
+
+		if err != nil {
+			return "", nil, fmt.Errorf("VerifyEmail %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return "", nil, fmt.Errorf("VerifyEmail commit error | %w", err)
+	}
+
+	return email, referral, nil
+}
+
+// EnsureReferralCode возвращает реферальный код пользователя, сохраняя code, если кода ещё нет.
+func (s *Storage) EnsureReferralCode(ctx context.Context, email string, code string) (string, error) {
+	defer observeQuery(ctx, "EnsureReferralCode")()
+
+	query, args, err := s.queryBuilder.
+		Update("users").
+		Set("referral_code", squirrel.Expr("COALESCE(referral_code, ?)", code)).
+		Where(squirrel.Eq{"email": email}).
+		Suffix("RETURNING referral_code").
+		ToSql()
+
+	if err != nil {
+		return "", fmt.Errorf("EnsureReferralCode query error | %w", err)
+	}
+
+	var referralCode string
+
+	if err = s.pgxPool.QueryRow(ctx, query, args...).Scan(&referralCode); err != nil {
+		return "", fmt.Errorf("EnsureReferralCode query error | %w", err)
+	}
+
+	return referralCode, nil
+}
+
+// CreateReferral записывает, что inviteeEmail зарегистрировался по реферальному коду. Возвращает pgx.ErrNoRows,
+// если кода нет или это код самого приглашённого.
+func (s *Storage) CreateReferral(ctx context.Context, inviteeEmail string, referralCode string) (*dto.Referral, error) {
+	defer observeQuery(ctx, "CreateReferral")()
+
+	referrer := squirrel.
+		Select().
+		Column(squirrel.Expr("?::varchar", inviteeEmail)).
+		Column("email").
+		From("users").
+		Where(squirrel.Eq{"referral_code": referralCode}).
+		Where(squirrel.NotEq{"email": inviteeEmail})
+
+	query, args, err := s.queryBuilder.
+		Insert("referrals").
+		Columns("invitee_email", "referrer_email").
+		Select(referrer).
+		Suffix("RETURNING " + referralColumns).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateReferral query error | %w", err)
+	}
+
+	referral, err := scanReferral(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateReferral query error | %w", err)
+	}
+
+	return referral, nil
+}
+
+// GetReferrals возвращает пользователей, приглашённых referrerEmail, начиная с последних.
+func (s *Storage) GetReferrals(ctx context.Context, referrerEmail string) ([]dto.Referral, error) {
+	defer observeQuery(ctx, "GetReferrals")()
+
+	query, args, err := s.queryBuilder.
+		Select("invitee_email", "referrer_email", "created_at", "credited_at").
+		From("referrals").
+		Where(squirrel.Eq{"referrer_email": referrerEmail}).
+		OrderBy("created_at DESC", "invitee_email").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetReferrals query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetReferrals query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	var referrals []dto.Referral
+
+	for rows.Next() {
+		referral, err := scanReferral(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetReferrals scan error | %w", err)
+		}
+
+		referrals = append(referrals, *referral)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetReferrals query error | %w", err)
+	}
+
+	return referrals, nil
+}
@@ -0,0 +1,302 @@
+package postgresDB
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"urleater/dto"
+)
+
+// userSubscriptionColumns - колонки подписки пользователя вместе с тарифом p, в порядке userSubscriptionFields.
+var userSubscriptionColumns = func() []string {
+	columns := []string{
+		"us.user_email",
+		"us.status",
+		"us.period_start",
+		"us.period_end",
+		"us.prepaid_periods",
+		"us.trial_used",
+	}
+
+	for _, column := range subscriptionColumns {
+		columns = append(columns, "p."+column)
+	}
+
+	return columns
+}()
+
+func scanUserSubscription(row pgx.Row) (*dto.UserSubscription, error) {
+	var sub dto.UserSubscription
+
+	fields := append([]interface{}{
+		&sub.UserEmail,
+		&sub.Status,
+		&sub.PeriodStart,
+		&sub.PeriodEnd,
+		&sub.PrepaidPeriods,
+		&sub.TrialUsed,
+	}, subscriptionFields(&sub.Plan)...)
+
+	if err := row.Scan(fields...); err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func (s *Storage) selectUserSubscriptions() squirrel.SelectBuilder {
+	return s.queryBuilder.
+		Select(userSubscriptionColumns...).
+		From("user_subscriptions us").
+		Join("subscriptions p ON p.id = us.plan_id")
+}
+
+func (s *Storage) GetUserSubscription(ctx context.Context, email string) (*dto.UserSubscription, error) {
+	defer observeQuery(ctx, "GetUserSubscription")()
+
+	query, args, err := s.selectUserSubscriptions().
+		Where(squirrel.Eq{"us.user_email": email}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserSubscription query error | %w", err)
+	}
+
+	sub, err := scanUserSubscription(s.pgxPool.QueryRow(ctx, query, args...))
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserSubscription query error | %w", err)
+	}
+
+	return sub, nil
+}
+
+// GetDueUserSubscriptions возвращает до limit подписок, период которых закончился к now, начиная с самых старых.
+func (s *Storage) GetDueUserSubscriptions(ctx context.Context, now time.Time, limit int) ([]dto.UserSubscription, error) {
+	defer observeQuery(ctx, "GetDueUserSubscriptions")()
+
+	query, args, err := s.selectUserSubscriptions().
+		Where(squirrel.LtOrEq{"us.period_end": now.UTC()}).
+		OrderBy("us.period_end", "us.user_email").
+		Limit(uint64(limit)).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("GetDueUserSubscriptions query error | %w", err)
+	}
+
+	rows, err := s.pgxPool.Query(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetDueUserSubscriptions query error | %w", err)
+	}
+
+	defer rows.Close()
+
+	var subscriptions []dto.UserSubscription
+
+	for rows.Next() {
+		sub, err := scanUserSubscription(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("GetDueUserSubscriptions scan error | %w", err)
+		}
+
+		subscriptions = append(subscriptions, *sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetDueUserSubscriptions query error | %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// SaveUserSubscription записывает подписку и меняет остаток ссылок пользователя в одной транзакции.
+// Нулевой expectedPeriodEnd заменяет подписку безусловно. Иначе подписка меняется, только если её период
+// всё ещё заканчивается в expectedPeriodEnd, и false означает, что её уже изменил другой запрос или экземпляр.
+func (s *Storage) SaveUserSubscription(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange) (bool, error) {
+	defer observeQuery(ctx, "SaveUserSubscription")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return false, fmt.Errorf("SaveUserSubscription begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	saved, err := s.saveUserSubscription(ctx, tx, sub, expectedPeriodEnd, quota)
+
+	if err != nil || !saved {
+		return false, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("SaveUserSubscription commit error | %w", err)
+	}
+
+	return true, nil
+}
+
+// SaveUserSubscriptionWithPromoCode активирует промокод code и записывает подписку в одной транзакции:
+// если подписку не удалось записать или её изменил другой запрос, активация откатывается.
+func (s *Storage) SaveUserSubscriptionWithPromoCode(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange, code string, now time.Time) (*dto.PromoCode, bool, error) {
+	defer observeQuery(ctx, "SaveUserSubscriptionWithPromoCode")()
+
+	tx, err := s.pgxPool.Begin(ctx)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("SaveUserSubscriptionWithPromoCode begin error | %w", err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	// код активируется первым: строка промокода блокируется до конца транзакции, и лимит активаций не превысят параллельные запросы
+	promo, err := s.redeemPromoCode(ctx, tx, code, sub.UserEmail, now)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("SaveUserSubscriptionWithPromoCode %w", err)
+	}
+
+	saved, err := s.saveUserSubscription(ctx, tx, sub, expectedPeriodEnd, quota)
+
+	if err != nil || !saved {
+		return nil, false, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("SaveUserSubscriptionWithPromoCode commit error | %w", err)
+	}
+
+	return promo, true, nil
+}
+
+func (s *Storage) saveUserSubscription(ctx context.Context, tx pgx.Tx, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange) (bool, error) {
+	values := map[string]interface{}{
+		"plan_id":         sub.Plan.Id,
+		"status":          string(sub.Status),
+		"period_start":    sub.PeriodStart.UTC(),
+		"period_end":      sub.PeriodEnd.UTC(),
+		"prepaid_periods": sub.PrepaidPeriods,
+		"trial_used":      sub.TrialUsed,
+		"updated_at":      time.Now().UTC(),
+	}
+
+	var (
+		query string
+		args  []interface{}
+		err   error
+	)
+
+	if expectedPeriodEnd.IsZero() {
+		values["user_email"] = sub.UserEmail
+
+		query, args, err = s.queryBuilder.
+			Insert("user_subscriptions").
+			SetMap(values).
+			Suffix(`ON CONFLICT (user_email) DO UPDATE SET plan_id = EXCLUDED.plan_id, status = EXCLUDED.status,
+				period_start = EXCLUDED.period_start, period_end = EXCLUDED.period_end, prepaid_periods = EXCLUDED.prepaid_periods,
+				trial_used = EXCLUDED.trial_used, updated_at = EXCLUDED.updated_at`).
+			ToSql()
+	} else {
+		query, args, err = s.queryBuilder.
+			Update("user_subscriptions").
+			SetMap(values).
+			Where(squirrel.Eq{"user_email": sub.UserEmail, "period_end": expectedPeriodEnd.UTC()}).
+			ToSql()
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("SaveUserSubscription query error | %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, query, args...)
+
+	if err != nil {
+		return false, fmt.Errorf("SaveUserSubscription query error | %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	// квота тарифа начисляется через журнал, как и остальные начисления ссылок
+	var amount int
+
+	switch quota.Policy {
+	case dto.QuotaReset:
+		query, args, err = s.queryBuilder.
+			Select("urls_left").
+			From("users").
+			Where(squirrel.Eq{"email": sub.UserEmail}).
+			Suffix("FOR UPDATE").
+			ToSql()
+
+		if err != nil {
+			return false, fmt.Errorf("SaveUserSubscription query error | %w", err)
+		}
+
+		var urlsLeft int
+
+		if err = tx.QueryRow(ctx, query, args...).Scan(&urlsLeft); err != nil {
+			return false, fmt.Errorf("SaveUserSubscription quota error | %w", err)
+		}
+
+		amount = quota.Urls - urlsLeft
+
+	case dto.QuotaTopUp:
+		amount = quota.Urls
+	}
+
+	if amount != 0 {
+		_, err = s.creditLinks(ctx, tx, dto.LinkCredit{
+			UserEmail: sub.UserEmail,
+			Amount:    amount,
+			Reason:    dto.LinkCreditSubscription,
+			Reference: sub.Plan.Name,
+		})
+
+		if err != nil {
+			return false, fmt.Errorf("SaveUserSubscription %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// AddPrepaidPeriods добавляет оплаченные периоды к подписке пользователя. Если подписки нет, возвращает pgx.ErrNoRows.
+func (s *Storage) AddPrepaidPeriods(ctx context.Context, email string, periods int) (*dto.UserSubscription, error) {
+	defer observeQuery(ctx, "AddPrepaidPeriods")()
+
+	query, args, err := s.queryBuilder.
+		Update("user_subscriptions").
+		Set("prepaid_periods", squirrel.Expr("prepaid_periods + ?", periods)).
+		Set("updated_at", time.Now().UTC()).
+		Where(squirrel.Eq{"user_email": email}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("AddPrepaidPeriods query error | %w", err)
+	}
+
+	tag, err := s.pgxPool.Exec(ctx, query, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddPrepaidPeriods query error | %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, fmt.Errorf("AddPrepaidPeriods query error | %w", pgx.ErrNoRows)
+	}
+
+	sub, err := s.GetUserSubscription(ctx, email)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddPrepaidPeriods %w", err)
+	}
+
+	return sub, nil
+}
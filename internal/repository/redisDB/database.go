@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"net/url"
 	"strings"
 	"time"
 	"urleater/dto"
@@ -28,7 +29,8 @@ func (s *Storage) GetShortLinkByLongLink(ctx context.Context, shortLink string)
 
 	values := strings.Split(res, "::::")
 
-	if len(values) != 3 {
+	// значения из трёх частей записаны до появления параметров перехода, из шести - до меток кампании
+	if len(values) != 3 && len(values) != 6 && len(values) != 7 {
 		return nil, fmt.Errorf("error while getting short link by long link from redis %s", res)
 	}
 
@@ -38,16 +40,47 @@ func (s *Storage) GetShortLinkByLongLink(ctx context.Context, shortLink string)
 		return nil, fmt.Errorf("error while parsing short link expiry time %w", err)
 	}
 
-	return &dto.Link{
-		ShortUrl:  shortLink,
-		ExpiresAt: expiresAt,
-		UserEmail: values[2],
-		LongUrl:   values[0],
-	}, nil
+	link := &dto.Link{
+		ShortUrl:        shortLink,
+		RedirectOptions: dto.RedirectOptions{QueryConflict: dto.QueryConflictKeep},
+		ExpiresAt:       expiresAt,
+		UserEmail:       values[2],
+		LongUrl:         values[0],
+	}
+
+	if len(values) >= 6 {
+		link.RedirectOptions = dto.RedirectOptions{
+			ForwardQuery:  values[3] == "1",
+			QueryConflict: values[4],
+			ForwardPath:   values[5] == "1",
+		}
+	}
+
+	if len(values) == 7 {
+		params, err := url.ParseQuery(values[6])
+
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing short link utm %w", err)
+		}
+
+		link.UTM = dto.UTMFromParams(params)
+	}
+
+	return link, nil
+}
+
+func redisFlag(value bool) string {
+	if value {
+		return "1"
+	}
+
+	return "0"
 }
 
 func (s *Storage) SaveShortLinkToLongLink(ctx context.Context, link dto.Link) error {
-	value := fmt.Sprintf("%s::::%s::::%s", link.LongUrl, link.ExpiresAt.Format(time.RFC3339), link.UserEmail)
+	// метки кодируются как запрос: двоеточия в них экранируются и не ломают разделитель
+	value := fmt.Sprintf("%s::::%s::::%s::::%s::::%s::::%s::::%s", link.LongUrl, link.ExpiresAt.Format(time.RFC3339), link.UserEmail,
+		redisFlag(link.ForwardQuery), link.QueryConflict, redisFlag(link.ForwardPath), link.UTM.Params().Encode())
 
 	_, err := s.redisClient.Set(ctx, link.ShortUrl, value, 0).Result()
 
@@ -67,3 +100,28 @@ func (s *Storage) DeleteLongLinkByShortLink(ctx context.Context, shortLink strin
 
 	return nil
 }
+
+// qrCodeKey отделяет изображения QR-кодов от ссылок, которые хранятся под своими короткими адресами.
+func qrCodeKey(key string) string {
+	return "qr:" + key
+}
+
+func (s *Storage) GetQRCode(ctx context.Context, key string) ([]byte, error) {
+	res, err := s.redisClient.Get(ctx, qrCodeKey(key)).Bytes()
+
+	if err != nil {
+		return nil, fmt.Errorf("error while getting qr code from redis %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *Storage) SaveQRCode(ctx context.Context, key string, image []byte, ttl time.Duration) error {
+	_, err := s.redisClient.Set(ctx, qrCodeKey(key), image, ttl).Result()
+
+	if err != nil {
+		return fmt.Errorf("error while saving qr code to redis %w", err)
+	}
+
+	return nil
+}
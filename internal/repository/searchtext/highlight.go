@@ -0,0 +1,91 @@
+package searchtext
+
+import (
+	"strings"
+	"unicode"
+	"urleater/dto"
+)
+
+const (
+	highlightPreTag  = "<mark>"
+	highlightPostTag = "</mark>"
+)
+
+// Highlights размечает совпадения с запросом в тех же полях, что подсвечивает elastic_searcher.
+func Highlights(link dto.Link, terms []string) map[string][]string {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	highlights := make(map[string][]string)
+
+	add := func(field, value string) {
+		if fragment, ok := highlight(value, terms); ok {
+			highlights[field] = append(highlights[field], fragment)
+		}
+	}
+
+	add("short_url", link.ShortUrl)
+	add("title", link.Title)
+	add("long_url", link.LongUrl)
+
+	for _, tag := range link.Tags {
+		add("tags", tag)
+	}
+
+	if len(highlights) == 0 {
+		return nil
+	}
+
+	return highlights
+}
+
+// highlight оборачивает в теги все вхождения слов запроса без учёта регистра. Пересекающиеся вхождения
+// объединяются в один фрагмент.
+func highlight(value string, terms []string) (string, bool) {
+	original := []rune(value)
+	lower := make([]rune, len(original))
+
+	for i, r := range original {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(original))
+	found := false
+
+	for _, term := range terms {
+		needle := []rune(term)
+
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) != term {
+				continue
+			}
+
+			for j := i; j < i+len(needle); j++ {
+				marked[j] = true
+			}
+
+			found = true
+		}
+	}
+
+	if !found {
+		return "", false
+	}
+
+	var b strings.Builder
+
+	for i, r := range original {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString(highlightPreTag)
+		}
+
+		b.WriteRune(r)
+
+		if marked[i] && (i == len(original)-1 || !marked[i+1]) {
+			b.WriteString(highlightPostTag)
+		}
+	}
+
+	return b.String(), true
+}
@@ -0,0 +1,51 @@
+// Package searchtext содержит правила поиска по ссылкам, общие для поисков без Elasticsearch:
+// разбиение запроса на слова, текст документа и подсветку совпадений.
+package searchtext
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"urleater/dto"
+)
+
+// MinQueryLength - запросы короче триграммы ищутся по префиксу short_url, как и в elastic_searcher.
+const MinQueryLength = 3
+
+// Normalize приводит запрос к виду, в котором он сравнивается с документом.
+func Normalize(text string) string {
+	return strings.ToLower(strings.TrimSpace(text))
+}
+
+// IsPrefixQuery сообщает, что нормализованный запрос ищется по префиксу short_url, а не по словам.
+func IsPrefixQuery(text string) bool {
+	return len([]rune(text)) < MinQueryLength
+}
+
+// Terms разбивает запрос на слова так же, как токенизатор индекса Elasticsearch: по всему, что не буква и не цифра.
+func Terms(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Document склеивает поля ссылки в строку, в которой ищутся слова запроса.
+func Document(link dto.Link) string {
+	return strings.ToLower(strings.Join([]string{
+		link.ShortUrl,
+		link.Title,
+		strings.Join(link.Tags, " "),
+		Domain(link.LongUrl),
+		link.LongUrl,
+	}, " "))
+}
+
+func Domain(longUrl string) string {
+	u, err := url.Parse(longUrl)
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"slices"
+	"strings"
+	"urleater/internal/tracing"
+)
+
+// AdminRules задаёт администраторов сервиса. Без списка администраторов нет.
+type AdminRules struct {
+	// Emails - почта администраторов. Права действуют, только когда владелец подтвердил почту:
+	// иначе их получил бы любой, кто первым зарегистрируется с этим адресом.
+	Emails []string
+}
+
+func (r AdminRules) withDefaults() AdminRules {
+	emails := make([]string, 0, len(r.Emails))
+
+	for _, email := range r.Emails {
+		emails = append(emails, strings.ToLower(strings.TrimSpace(email)))
+	}
+
+	r.Emails = emails
+
+	return r
+}
+
+// WithAdmins задаёт администраторов сервиса.
+func (s *Service) WithAdmins(rules AdminRules) *Service {
+	s.admins = rules.withDefaults()
+
+	return s
+}
+
+// IsAdmin сообщает, что email входит в список администраторов и его владелец подтвердил почту.
+func (s *Service) IsAdmin(ctx context.Context, email string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "Service.IsAdmin")
+
+	defer span.End()
+
+	if !slices.Contains(s.admins.Emails, strings.ToLower(email)) {
+		return false, nil
+	}
+
+	user, err := s.postgresStorage.GetUser(ctx, email)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("IsAdmin: could not get user %s %w", email, err)
+	}
+
+	return user.EmailVerified, nil
+}
@@ -0,0 +1,513 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"urleater/dto"
+	"urleater/internal/metrics"
+	"urleater/internal/tracing"
+)
+
+// renewalBatchSize - сколько подписок с закончившимся периодом обрабатывается за один проход планировщика.
+const renewalBatchSize = 100
+
+var (
+	// ErrSubscriptionState - подписку нельзя так изменить в её текущем состоянии.
+	ErrSubscriptionState = errors.New("subscription cannot be changed in its current state")
+	// ErrNoSubscription - у пользователя нет подписки.
+	ErrNoSubscription = errors.New("user has no subscription")
+)
+
+// BillingRules - правила подписок, которые задаются в конфигурации. Нулевые поля заменяются значениями по умолчанию.
+type BillingRules struct {
+	// FreePlan - название тарифа, который получают новые пользователи и пользователи без оплаченного периода.
+	FreePlan string
+	// GracePeriod - сколько подписка ждёт оплаты в статусе past_due, прежде чем перейти на FreePlan.
+	GracePeriod time.Duration
+	// CheckInterval - как часто планировщик ищет подписки с закончившимся периодом.
+	CheckInterval time.Duration
+}
+
+func DefaultBillingRules() BillingRules {
+	return BillingRules{
+		FreePlan:      "Free",
+		GracePeriod:   72 * time.Hour,
+		CheckInterval: time.Minute,
+	}
+}
+
+func (r BillingRules) withDefaults() BillingRules {
+	defaults := DefaultBillingRules()
+
+	if r.FreePlan == "" {
+		r.FreePlan = defaults.FreePlan
+	}
+
+	if r.GracePeriod <= 0 {
+		r.GracePeriod = defaults.GracePeriod
+	}
+
+	if r.CheckInterval <= 0 {
+		r.CheckInterval = defaults.CheckInterval
+	}
+
+	return r
+}
+
+// WithBilling задаёт правила подписок.
+func (s *Service) WithBilling(rules BillingRules) *Service {
+	s.billing = rules.withDefaults()
+
+	return s
+}
+
+// billingNow возвращает текущее время с точностью, с которой его хранит Postgres в user_subscriptions.
+func billingNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func periodEnd(start time.Time, period dto.BillingPeriod) time.Time {
+	switch period {
+	case dto.BillingPeriodYear:
+		return start.AddDate(1, 0, 0)
+
+	case dto.BillingPeriodQuarter:
+		return start.AddDate(0, 3, 0)
+
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// planQuota - изменение остатка ссылок в начале периода тарифа.
+func planQuota(plan dto.Subscription) dto.QuotaChange {
+	policy := plan.QuotaPolicy
+
+	if policy == "" {
+		policy = dto.QuotaReset
+	}
+
+	return dto.QuotaChange{Policy: policy, Urls: plan.TotalUrls}
+}
+
+func (s *Service) freePlan(ctx context.Context) (*dto.Subscription, error) {
+	plans, err := s.postgresStorage.GetSubscriptions(ctx, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range plans {
+		if plans[i].Name == s.billing.FreePlan {
+			return &plans[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("free plan %q does not exist", s.billing.FreePlan)
+}
+
+// startFreePlan переводит пользователя на бесплатный тариф с нового периода.
+func (s *Service) startFreePlan(ctx context.Context, email string, trialUsed bool) (dto.UserSubscription, dto.QuotaChange, error) {
+	free, err := s.freePlan(ctx)
+
+	if err != nil {
+		return dto.UserSubscription{}, dto.QuotaChange{}, err
+	}
+
+	now := billingNow()
+
+	return dto.UserSubscription{
+		UserEmail:   email,
+		Plan:        *free,
+		Status:      dto.UserSubscriptionActive,
+		PeriodStart: now,
+		PeriodEnd:   periodEnd(now, free.BillingPeriod),
+		TrialUsed:   trialUsed,
+	}, planQuota(*free), nil
+}
+
+// GetUserSubscription возвращает текущую подписку пользователя или nil, если её нет.
+func (s *Service) GetUserSubscription(ctx context.Context, email string) (*dto.UserSubscription, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetUserSubscription")
+
+	defer span.End()
+
+	sub, err := s.postgresStorage.GetUserSubscription(ctx, email)
+
+	switch {
+	case err == nil:
+		return sub, nil
+
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("GetUserSubscription: could not get subscription of %s %w", email, err)
+	}
+}
+
+// Subscribe переводит пользователя на тариф planId. Если у тарифа есть пробный период и пользователь его ещё
+// не получал, подписка начинается с него. Платный тариф без пробного периода ждёт оплаты в статусе pending
+// до конца отсрочки: период и квота тарифа начинаются, когда записана оплата (RecordPayment).
+// Смена тарифа сохраняет остаток ссылок, иначе переключение между тарифами пополняло бы квоту сколько угодно раз.
+// Повторная подписка на отменённый тариф снимает отмену, не меняя период.
+// Промокод со скидкой снижает цену первого периода, возвращённую в Charge; к пробному периоду, отмене отмены
+// и бесплатному тарифу он не применяется.
+func (s *Service) Subscribe(ctx context.Context, email string, planId int, promoCode string) (*dto.UserSubscription, *dto.Charge, error) {
+	ctx, span := tracing.Start(ctx, "Service.Subscribe")
+
+	defer span.End()
+
+	plan, err := s.postgresStorage.GetSubscription(ctx, planId)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), err == nil && !plan.Active:
+		return nil, nil, fmt.Errorf("Subscribe: %w", ErrPlanNotFound)
+
+	case err != nil:
+		return nil, nil, fmt.Errorf("Subscribe: could not get plan %d %w", planId, err)
+	}
+
+	var promo *dto.PromoCode
+
+	if promoCode != "" {
+		promo, err = s.getPromoCode(ctx, normalizePromoCode(promoCode), dto.PromoPercentOff)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Subscribe: %w", err)
+		}
+
+		if promo.PlanId != nil && *promo.PlanId != plan.Id {
+			return nil, nil, fmt.Errorf("Subscribe: promo code %s is for another plan %w", promo.Code, ErrPromoCodeNotApplicable)
+		}
+	}
+
+	current, err := s.GetUserSubscription(ctx, email)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Subscribe: %w", err)
+	}
+
+	now := billingNow()
+
+	next := dto.UserSubscription{
+		UserEmail:   email,
+		Plan:        *plan,
+		Status:      dto.UserSubscriptionActive,
+		PeriodStart: now,
+		PeriodEnd:   periodEnd(now, plan.BillingPeriod),
+	}
+
+	var quota dto.QuotaChange
+
+	expectedPeriodEnd := time.Time{}
+
+	if current != nil {
+		next.TrialUsed = current.TrialUsed
+
+		if current.Plan.Id == plan.Id {
+			if current.Status != dto.UserSubscriptionCancelled {
+				return nil, nil, fmt.Errorf("Subscribe: user %s is already subscribed to %s %w", email, plan.Name, ErrSubscriptionState)
+			}
+
+			next = *current
+			next.Status = dto.UserSubscriptionActive
+			expectedPeriodEnd = current.PeriodEnd
+		}
+	}
+
+	switch {
+	case !expectedPeriodEnd.IsZero():
+		// отмена отмены: период и квота не меняются
+
+	case plan.TrialDays > 0 && !next.TrialUsed:
+		next.Status = dto.UserSubscriptionTrial
+		next.PeriodEnd = now.AddDate(0, 0, plan.TrialDays)
+		next.TrialUsed = true
+		quota = planQuota(*plan)
+
+	case plan.Price > 0:
+		next.Status = dto.UserSubscriptionPending
+		next.PeriodEnd = now.Add(s.billing.GracePeriod)
+
+	case current == nil:
+		quota = planQuota(*plan)
+	}
+
+	// платится только новый период платного тарифа
+	charge := dto.Charge{Currency: plan.Currency}
+
+	if next.Status == dto.UserSubscriptionPending {
+		charge = planCharge(*plan, promo)
+	} else if promo != nil {
+		return nil, nil, fmt.Errorf("Subscribe: nothing to pay for %s %w", plan.Name, ErrPromoCodeNotApplicable)
+	}
+
+	var saved bool
+
+	if promo != nil {
+		// код активируется в одной транзакции с подпиской: его лимит активаций не превысят параллельные запросы,
+		// а если подписку записать не удалось, активация откатывается
+		promo, saved, err = s.postgresStorage.SaveUserSubscriptionWithPromoCode(ctx, next, expectedPeriodEnd, quota, promo.Code, time.Now().UTC())
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Subscribe: could not redeem %s and save subscription of %s %w", charge.PromoCode, email, promoRedemptionError(err))
+		}
+	} else {
+		saved, err = s.postgresStorage.SaveUserSubscription(ctx, next, expectedPeriodEnd, quota)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Subscribe: could not save subscription of %s %w", email, err)
+		}
+	}
+
+	if !saved {
+		return nil, nil, fmt.Errorf("Subscribe: subscription of %s was changed concurrently %w", email, ErrSubscriptionState)
+	}
+
+	if promo != nil {
+		s.promoCodeRedeemed(ctx, promo, email)
+	}
+
+	s.logger.InfoContext(ctx, "user subscribed", "email", email, "plan", plan.Name, "status", next.Status,
+		"period_end", next.PeriodEnd, "price", charge.Price, "promo_code", charge.PromoCode)
+
+	return &next, &charge, nil
+}
+
+// CancelSubscription отменяет платную подписку: она действует до конца периода, после чего пользователь
+// переходит на бесплатный тариф. Неоплаченную подписку (pending) не отменить, её меняют на бесплатный тариф.
+func (s *Service) CancelSubscription(ctx context.Context, email string) (*dto.UserSubscription, error) {
+	ctx, span := tracing.Start(ctx, "Service.CancelSubscription")
+
+	defer span.End()
+
+	current, err := s.GetUserSubscription(ctx, email)
+
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("CancelSubscription: %w", err)
+
+	case current == nil:
+		return nil, fmt.Errorf("CancelSubscription: %w", ErrNoSubscription)
+
+	case current.Plan.Price == 0 || current.Status == dto.UserSubscriptionCancelled || current.Status == dto.UserSubscriptionPending:
+		return nil, fmt.Errorf("CancelSubscription: %s subscription to %s cannot be cancelled %w", current.Status, current.Plan.Name, ErrSubscriptionState)
+	}
+
+	next := *current
+	next.Status = dto.UserSubscriptionCancelled
+
+	saved, err := s.postgresStorage.SaveUserSubscription(ctx, next, current.PeriodEnd, dto.QuotaChange{})
+
+	if err != nil {
+		return nil, fmt.Errorf("CancelSubscription: could not save subscription of %s %w", email, err)
+	}
+
+	if !saved {
+		return nil, fmt.Errorf("CancelSubscription: subscription of %s was changed concurrently %w", email, ErrSubscriptionState)
+	}
+
+	s.logger.InfoContext(ctx, "subscription cancelled", "email", email, "plan", current.Plan.Name, "period_end", current.PeriodEnd)
+
+	return &next, nil
+}
+
+// RecordPayment добавляет оплаченные периоды. Подписка, ожидающая оплаты (pending или past_due), сразу
+// начинает оплаченный период с квотой тарифа.
+func (s *Service) RecordPayment(ctx context.Context, email string, periods int) (*dto.UserSubscription, error) {
+	ctx, span := tracing.Start(ctx, "Service.RecordPayment")
+
+	defer span.End()
+
+	if periods <= 0 {
+		return nil, fmt.Errorf("RecordPayment: number of periods must be positive %w", ErrSubscriptionState)
+	}
+
+	sub, err := s.postgresStorage.AddPrepaidPeriods(ctx, email, periods)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("RecordPayment: %w", ErrNoSubscription)
+
+	case err != nil:
+		return nil, fmt.Errorf("RecordPayment: could not add periods to %s %w", email, err)
+	}
+
+	s.logger.InfoContext(ctx, "payment recorded", "email", email, "plan", sub.Plan.Name, "periods", periods)
+
+	if sub.Status != dto.UserSubscriptionPending && sub.Status != dto.UserSubscriptionPastDue {
+		return sub, nil
+	}
+
+	if _, err = s.advanceSubscription(ctx, *sub, billingNow()); err != nil {
+		return nil, fmt.Errorf("RecordPayment: %w", err)
+	}
+
+	return s.GetUserSubscription(ctx, email)
+}
+
+// nextSubscription определяет, что происходит с подпиской sub, период которой закончился к now:
+//   - бесплатный тариф продлевается;
+//   - оплаченный наперёд период начинается сразу после текущего (у pending и past_due - с момента оплаты);
+//   - неоплаченная подписка переходит в past_due до конца отсрочки;
+//   - отменённая подписка и past_due после отсрочки переводятся на бесплатный тариф;
+//   - pending после отсрочки тоже переводится на бесплатный тариф, но без его квоты: период платного тарифа
+//     так и не начался, и остаток ссылок остаётся прежним.
+//
+// Возвращает новое состояние, изменение квоты и название перехода для метрик.
+func (s *Service) nextSubscription(ctx context.Context, sub dto.UserSubscription, now time.Time) (dto.UserSubscription, dto.QuotaChange, string, error) {
+	renew := func(start time.Time) (dto.UserSubscription, dto.QuotaChange, string, error) {
+		next := sub
+		next.Status = dto.UserSubscriptionActive
+		next.PeriodStart = start
+		next.PeriodEnd = periodEnd(start, sub.Plan.BillingPeriod)
+
+		if sub.Plan.Price > 0 {
+			next.PrepaidPeriods--
+		}
+
+		return next, planQuota(sub.Plan), "renewed", nil
+	}
+
+	downgrade := func() (dto.UserSubscription, dto.QuotaChange, string, error) {
+		next, quota, err := s.startFreePlan(ctx, sub.UserEmail, sub.TrialUsed)
+
+		return next, quota, "downgraded", err
+	}
+
+	switch {
+	case sub.Plan.Price == 0:
+		return renew(sub.PeriodEnd)
+
+	case sub.Status == dto.UserSubscriptionCancelled:
+		return downgrade()
+
+	case (sub.Status == dto.UserSubscriptionPending || sub.Status == dto.UserSubscriptionPastDue) && sub.PrepaidPeriods > 0:
+		return renew(now)
+
+	case sub.Status == dto.UserSubscriptionPending:
+		next, _, err := s.startFreePlan(ctx, sub.UserEmail, sub.TrialUsed)
+
+		return next, dto.QuotaChange{}, "expired", err
+
+	case sub.Status == dto.UserSubscriptionPastDue:
+		return downgrade()
+
+	case sub.PrepaidPeriods > 0:
+		return renew(sub.PeriodEnd)
+
+	default:
+		next := sub
+		next.Status = dto.UserSubscriptionPastDue
+		next.PeriodEnd = sub.PeriodEnd.Add(s.billing.GracePeriod)
+
+		return next, dto.QuotaChange{}, "past_due", nil
+	}
+}
+
+// advanceSubscription переводит подписку в следующее состояние. Возвращает false, если подписку
+// уже изменил другой экземпляр сервиса или запрос пользователя.
+func (s *Service) advanceSubscription(ctx context.Context, sub dto.UserSubscription, now time.Time) (bool, error) {
+	next, quota, transition, err := s.nextSubscription(ctx, sub, now)
+
+	if err != nil {
+		return false, fmt.Errorf("could not advance subscription of %s %w", sub.UserEmail, err)
+	}
+
+	saved, err := s.postgresStorage.SaveUserSubscription(ctx, next, sub.PeriodEnd, quota)
+
+	if err != nil {
+		return false, fmt.Errorf("could not save subscription of %s %w", sub.UserEmail, err)
+	}
+
+	if saved {
+		metrics.SubscriptionTransitions.WithLabelValues(transition).Inc()
+
+		s.logger.InfoContext(ctx, "subscription "+transition, "email", sub.UserEmail, "plan", next.Plan.Name,
+			"status", next.Status, "period_end", next.PeriodEnd)
+	}
+
+	return saved, nil
+}
+
+// RenewSubscriptions обрабатывает одну пачку подписок, период которых закончился, и возвращает количество
+// изменённых. Ошибка одной подписки не мешает обработать остальные.
+func (s *Service) RenewSubscriptions(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "Service.RenewSubscriptions")
+
+	defer span.End()
+
+	now := billingNow()
+
+	due, err := s.postgresStorage.GetDueUserSubscriptions(ctx, now, renewalBatchSize)
+
+	if err != nil {
+		return 0, fmt.Errorf("RenewSubscriptions: could not get due subscriptions %w", err)
+	}
+
+	var (
+		advanced int
+		errs     []error
+	)
+
+	for _, sub := range due {
+		saved, err := s.advanceSubscription(ctx, sub, now)
+
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if saved {
+			advanced++
+		}
+	}
+
+	if len(errs) > 0 {
+		return advanced, fmt.Errorf("RenewSubscriptions: %w", errors.Join(errs...))
+	}
+
+	return advanced, nil
+}
+
+// StartSubscriptionScheduler в фоне продлевает подписки и переводит просроченные на бесплатный тариф.
+// Планировщик можно запускать на нескольких экземплярах: подписка меняется, только если её не изменил другой.
+func (s *Service) StartSubscriptionScheduler(ctx context.Context) {
+	ctx, s.stopScheduler = context.WithCancel(ctx)
+
+	s.schedulerWG.Add(1)
+
+	go func() {
+		defer s.schedulerWG.Done()
+
+		ticker := time.NewTicker(s.billing.CheckInterval)
+
+		defer ticker.Stop()
+
+		for {
+			for {
+				advanced, err := s.RenewSubscriptions(ctx)
+
+				if err != nil {
+					s.logger.ErrorContext(ctx, "subscription renewal failed", "error", err)
+				}
+
+				// пачка могла быть полной, продолжаем без ожидания тика
+				if err != nil || advanced < renewalBatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
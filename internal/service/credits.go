@@ -0,0 +1,498 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"urleater/dto"
+	"urleater/internal/metrics"
+	"urleater/internal/tracing"
+)
+
+// creditHistoryLimit - сколько последних начислений показывается пользователю.
+const creditHistoryLimit = 50
+
+// maxPromoCodeLength ограничивает длину промокода, который вводит пользователь.
+const maxPromoCodeLength = 32
+
+var (
+	// ErrInvalidCredit - начисление ссылок с неположительным количеством.
+	ErrInvalidCredit = errors.New("number of credited links must be positive")
+	// ErrUserNotFound - пользователя с таким email нет.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidPromoCode оборачивает ошибки проверки полей промокода.
+	ErrInvalidPromoCode = errors.New("invalid promo code")
+	// ErrPromoCodeNotFound - промокода нет.
+	ErrPromoCodeNotFound = errors.New("promo code not found")
+	// ErrPromoCodeTaken - промокод с таким кодом уже есть.
+	ErrPromoCodeTaken = errors.New("promo code already exists")
+	// ErrPromoCodeUnavailable - срок промокода истёк или активации закончились.
+	ErrPromoCodeUnavailable = errors.New("promo code has expired or has no redemptions left")
+	// ErrPromoCodeRedeemed - пользователь уже активировал промокод.
+	ErrPromoCodeRedeemed = errors.New("promo code has already been redeemed")
+	// ErrPromoCodeNotApplicable - промокод нельзя применить к этому действию или тарифу.
+	ErrPromoCodeNotApplicable = errors.New("promo code cannot be applied here")
+	// ErrInvalidVerificationToken - токена подтверждения почты нет или его срок истёк.
+	ErrInvalidVerificationToken = errors.New("email verification token is invalid or has expired")
+	// ErrReferralCodeNotFound - реферального кода нет или это код самого пользователя.
+	ErrReferralCodeNotFound = errors.New("referral code not found")
+	// ErrMailDisabled - почтовый сервер не настроен, письма не отправляются.
+	ErrMailDisabled = errors.New("email delivery is not configured")
+)
+
+// Mailer отправляет письма пользователям.
+type Mailer interface {
+	SendMail(ctx context.Context, to string, subject string, body string) error
+}
+
+// CreditRules - правила начислений, которые задаются в конфигурации. Нулевые поля заменяются значениями по умолчанию.
+type CreditRules struct {
+	// ReferralCredits - сколько ссылок получает пригласивший, когда приглашённый подтверждает почту.
+	ReferralCredits int
+	// VerificationTTL - срок действия ссылки подтверждения почты.
+	VerificationTTL time.Duration
+	// Mailer отправляет ссылки подтверждения почты. Без него почту не подтвердить, и приглашения не начисляют ссылки.
+	Mailer Mailer
+	// BaseUrl - адрес сервиса, от которого строятся ссылки в письмах.
+	BaseUrl string
+}
+
+func DefaultCreditRules() CreditRules {
+	return CreditRules{
+		ReferralCredits: 10,
+		VerificationTTL: 48 * time.Hour,
+		BaseUrl:         "http://localhost:8080",
+	}
+}
+
+func (r CreditRules) withDefaults() CreditRules {
+	defaults := DefaultCreditRules()
+
+	if r.ReferralCredits <= 0 {
+		r.ReferralCredits = defaults.ReferralCredits
+	}
+
+	if r.VerificationTTL <= 0 {
+		r.VerificationTTL = defaults.VerificationTTL
+	}
+
+	if r.BaseUrl == "" {
+		r.BaseUrl = defaults.BaseUrl
+	}
+
+	return r
+}
+
+// WithCredits задаёт правила начислений.
+func (s *Service) WithCredits(rules CreditRules) *Service {
+	s.credits = rules.withDefaults()
+
+	return s
+}
+
+// randomToken возвращает n случайных байт в виде строки, пригодной для URL.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken - в базе хранится только хеш токена подтверждения, чтобы по дампу нельзя было подтвердить чужую почту.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Service) creditLinks(ctx context.Context, credit dto.LinkCredit) (*dto.User, error) {
+	if credit.Amount <= 0 {
+		return nil, ErrInvalidCredit
+	}
+
+	user, err := s.postgresStorage.AddLinkCredit(ctx, credit)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.LinkCredits.WithLabelValues(string(credit.Reason)).Add(float64(credit.Amount))
+
+	s.logger.InfoContext(ctx, "links credited", "email", credit.UserEmail, "amount", credit.Amount, "reason", credit.Reason)
+
+	return user, nil
+}
+
+// GetLinkCredits возвращает последние начисления ссылок пользователю.
+func (s *Service) GetLinkCredits(ctx context.Context, email string) ([]dto.LinkCredit, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetLinkCredits")
+
+	defer span.End()
+
+	credits, err := s.postgresStorage.GetLinkCredits(ctx, email, creditHistoryLimit)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetLinkCredits: could not get credits of %s %w", email, err)
+	}
+
+	return credits, nil
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validatePromoCode проверяет все поля промокода и возвращает все ошибки сразу, обёрнутые в ErrInvalidPromoCode.
+func validatePromoCode(promo dto.PromoCode, now time.Time) error {
+	var errs []error
+
+	valid := promo.Code != "" && len(promo.Code) <= maxPromoCodeLength
+
+	for _, r := range promo.Code {
+		if r > unicode.MaxASCII || !(unicode.IsUpper(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			valid = false
+		}
+	}
+
+	if !valid {
+		errs = append(errs, fmt.Errorf("code must be 1 to %d latin letters, digits, '-' or '_'", maxPromoCodeLength))
+	}
+
+	switch promo.Kind {
+	case dto.PromoLinkCredits:
+		if promo.LinkCredits <= 0 {
+			errs = append(errs, fmt.Errorf("link credits must be positive"))
+		}
+
+		if promo.PercentOff != 0 || promo.PlanId != nil {
+			errs = append(errs, fmt.Errorf("link credits code cannot have percent off or plan"))
+		}
+
+	case dto.PromoPercentOff:
+		if promo.PercentOff <= 0 || promo.PercentOff > 100 {
+			errs = append(errs, fmt.Errorf("percent off must be from 1 to 100"))
+		}
+
+		if promo.LinkCredits != 0 {
+			errs = append(errs, fmt.Errorf("percent off code cannot have link credits"))
+		}
+
+	default:
+		errs = append(errs, fmt.Errorf("kind %q must be link_credits or percent_off", promo.Kind))
+	}
+
+	if promo.MaxRedemptions < 0 {
+		errs = append(errs, fmt.Errorf("max redemptions must not be negative"))
+	}
+
+	if promo.ExpiresAt != nil && !promo.ExpiresAt.After(now) {
+		errs = append(errs, fmt.Errorf("expiry must be in the future"))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidPromoCode, errors.Join(errs...))
+}
+
+// promoStorageError заменяет ошибки хранилища, о которых нужно сказать пользователю или администратору, ошибками сервиса.
+func promoStorageError(err error) error {
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrPromoCodeNotFound
+
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode:
+		return ErrPromoCodeTaken
+
+	case errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode:
+		return ErrPlanNotFound
+
+	default:
+		return err
+	}
+}
+
+func (s *Service) CreatePromoCode(ctx context.Context, promo dto.PromoCode) (*dto.PromoCode, error) {
+	ctx, span := tracing.Start(ctx, "Service.CreatePromoCode")
+
+	defer span.End()
+
+	promo.Code = normalizePromoCode(promo.Code)
+
+	if err := validatePromoCode(promo, time.Now()); err != nil {
+		return nil, fmt.Errorf("CreatePromoCode: %w", err)
+	}
+
+	created, err := s.postgresStorage.CreatePromoCode(ctx, promo)
+
+	if err != nil {
+		return nil, fmt.Errorf("CreatePromoCode: error while creating promo code %s: %w", promo.Code, promoStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "promo code created", "code", created.Code, "kind", created.Kind)
+
+	return created, nil
+}
+
+func (s *Service) GetPromoCodes(ctx context.Context) ([]dto.PromoCode, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetPromoCodes")
+
+	defer span.End()
+
+	promos, err := s.postgresStorage.GetPromoCodes(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetPromoCodes: could not get promo codes %w", err)
+	}
+
+	return promos, nil
+}
+
+// DeletePromoCode удаляет промокод вместе с его активациями. Начисленные по нему ссылки остаются в журнале.
+func (s *Service) DeletePromoCode(ctx context.Context, code string) error {
+	ctx, span := tracing.Start(ctx, "Service.DeletePromoCode")
+
+	defer span.End()
+
+	code = normalizePromoCode(code)
+
+	if err := s.postgresStorage.DeletePromoCode(ctx, code); err != nil {
+		return fmt.Errorf("DeletePromoCode: error while deleting promo code %s: %w", code, promoStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "promo code deleted", "code", code)
+
+	return nil
+}
+
+// getPromoCode возвращает промокод, если его можно применить как kind.
+func (s *Service) getPromoCode(ctx context.Context, code string, kind dto.PromoCodeKind) (*dto.PromoCode, error) {
+	promo, err := s.postgresStorage.GetPromoCode(ctx, code)
+
+	if err != nil {
+		return nil, promoStorageError(err)
+	}
+
+	if promo.Kind != kind {
+		return nil, fmt.Errorf("%s promo code %s %w", promo.Kind, code, ErrPromoCodeNotApplicable)
+	}
+
+	return promo, nil
+}
+
+// redeemPromoCode отмечает активацию промокода пользователем, для кода на ссылки - вместе с начислением.
+func (s *Service) redeemPromoCode(ctx context.Context, code string, email string) (*dto.PromoCode, error) {
+	promo, err := s.postgresStorage.RedeemPromoCode(ctx, code, email, time.Now().UTC())
+
+	if err != nil {
+		return nil, promoRedemptionError(err)
+	}
+
+	s.promoCodeRedeemed(ctx, promo, email)
+
+	return promo, nil
+}
+
+// promoRedemptionError заменяет ошибки хранилища при активации промокода ошибками сервиса.
+func promoRedemptionError(err error) error {
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrPromoCodeUnavailable
+
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode:
+		return ErrPromoCodeRedeemed
+
+	default:
+		return err
+	}
+}
+
+// promoCodeRedeemed учитывает активацию промокода в метриках и журнале.
+func (s *Service) promoCodeRedeemed(ctx context.Context, promo *dto.PromoCode, email string) {
+	metrics.PromoRedemptions.WithLabelValues(string(promo.Kind)).Inc()
+
+	if promo.Kind == dto.PromoLinkCredits {
+		metrics.LinkCredits.WithLabelValues(string(dto.LinkCreditPromo)).Add(float64(promo.LinkCredits))
+	}
+
+	s.logger.InfoContext(ctx, "promo code redeemed", "email", email, "code", promo.Code, "kind", promo.Kind)
+}
+
+// RedeemPromoCode начисляет пользователю ссылки по промокоду. Промокоды со скидкой применяются при переходе на тариф.
+func (s *Service) RedeemPromoCode(ctx context.Context, email string, code string) (*dto.PromoCode, error) {
+	ctx, span := tracing.Start(ctx, "Service.RedeemPromoCode")
+
+	defer span.End()
+
+	code = normalizePromoCode(code)
+
+	if _, err := s.getPromoCode(ctx, code, dto.PromoLinkCredits); err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode: %w", err)
+	}
+
+	promo, err := s.redeemPromoCode(ctx, code, email)
+
+	if err != nil {
+		return nil, fmt.Errorf("RedeemPromoCode: could not redeem %s for %s %w", code, email, err)
+	}
+
+	return promo, nil
+}
+
+// planCharge считает цену первого периода тарифа со скидкой промокода promo, если он есть.
+func planCharge(plan dto.Subscription, promo *dto.PromoCode) dto.Charge {
+	charge := dto.Charge{Price: plan.Price, Currency: plan.Currency}
+
+	if promo != nil {
+		charge.Price = plan.Price * int64(100-promo.PercentOff) / 100
+		charge.PromoCode = promo.Code
+	}
+
+	return charge
+}
+
+// RequestEmailVerification создаёт новую ссылку подтверждения почты и отправляет её письмом, прежняя перестаёт действовать.
+// Без Mailer возвращает ErrMailDisabled. Токен в лог не пишется: по нему можно подтвердить чужую почту.
+func (s *Service) RequestEmailVerification(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "Service.RequestEmailVerification")
+
+	defer span.End()
+
+	if s.credits.Mailer == nil {
+		return fmt.Errorf("RequestEmailVerification: %w", ErrMailDisabled)
+	}
+
+	token, err := randomToken(32)
+
+	if err != nil {
+		return fmt.Errorf("RequestEmailVerification: could not generate token %w", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(s.credits.VerificationTTL)
+
+	err = s.postgresStorage.CreateEmailVerification(ctx, email, hashToken(token), expiresAt)
+
+	if err != nil {
+		return fmt.Errorf("RequestEmailVerification: could not save token of %s %w", email, err)
+	}
+
+	link := strings.TrimSuffix(s.credits.BaseUrl, "/") + "/verify_email?token=" + url.QueryEscape(token)
+
+	body := fmt.Sprintf("Confirm your email for urleater by opening this link:\n\n%s\n\nThe link is valid until %s.\n",
+		link, expiresAt.Format(time.RFC1123))
+
+	if err = s.credits.Mailer.SendMail(ctx, email, "Confirm your email", body); err != nil {
+		return fmt.Errorf("RequestEmailVerification: could not send verification link to %s %w", email, err)
+	}
+
+	s.logger.InfoContext(ctx, "email verification requested", "email", email, "expires_at", expiresAt)
+
+	return nil
+}
+
+// VerifyEmail подтверждает почту по токену из ссылки и начисляет ссылки пригласившему пользователю.
+// Возвращает email подтверждённого пользователя.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (string, error) {
+	ctx, span := tracing.Start(ctx, "Service.VerifyEmail")
+
+	defer span.End()
+
+	token = strings.TrimSpace(token)
+
+	if token == "" {
+		return "", fmt.Errorf("VerifyEmail: %w", ErrInvalidVerificationToken)
+	}
+
+	email, referral, err := s.postgresStorage.VerifyEmail(ctx, hashToken(token), time.Now().UTC(), s.credits.ReferralCredits)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return "", fmt.Errorf("VerifyEmail: %w", ErrInvalidVerificationToken)
+
+	case err != nil:
+		return "", fmt.Errorf("VerifyEmail: could not verify email %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "email verified", "email", email)
+
+	if referral != nil {
+		metrics.LinkCredits.WithLabelValues(string(dto.LinkCreditReferral)).Add(float64(s.credits.ReferralCredits))
+
+		s.logger.InfoContext(ctx, "referral credited", "email", referral.ReferrerEmail, "invitee", email,
+			"amount", s.credits.ReferralCredits)
+	}
+
+	return email, nil
+}
+
+// AddReferral записывает, что inviteeEmail зарегистрировался по реферальному коду. Пригласивший получит ссылки,
+// когда приглашённый подтвердит почту.
+func (s *Service) AddReferral(ctx context.Context, inviteeEmail string, referralCode string) error {
+	ctx, span := tracing.Start(ctx, "Service.AddReferral")
+
+	defer span.End()
+
+	referral, err := s.postgresStorage.CreateReferral(ctx, inviteeEmail, strings.TrimSpace(referralCode))
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("AddReferral: %w", ErrReferralCodeNotFound)
+
+	case err != nil:
+		return fmt.Errorf("AddReferral: could not save referral of %s %w", inviteeEmail, err)
+	}
+
+	s.logger.InfoContext(ctx, "referral registered", "email", referral.ReferrerEmail, "invitee", inviteeEmail)
+
+	return nil
+}
+
+// GetReferrals возвращает реферальный код пользователя, при первом запросе создавая его, и приглашённых им пользователей.
+func (s *Service) GetReferrals(ctx context.Context, email string) (string, []dto.Referral, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetReferrals")
+
+	defer span.End()
+
+	b := make([]byte, 5)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("GetReferrals: could not generate referral code %w", err)
+	}
+
+	code, err := s.postgresStorage.EnsureReferralCode(ctx, email, strings.ToLower(base32.StdEncoding.EncodeToString(b)))
+
+	if err != nil {
+		return "", nil, fmt.Errorf("GetReferrals: could not get referral code of %s %w", email, err)
+	}
+
+	referrals, err := s.postgresStorage.GetReferrals(ctx, email)
+
+	if err != nil {
+		return "", nil, fmt.Errorf("GetReferrals: could not get referrals of %s %w", email, err)
+	}
+
+	return code, referrals, nil
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"net"
+	"slices"
+	"strings"
+	"time"
+	"urleater/dto"
+	"urleater/internal/tracing"
+)
+
+// domainVerificationPrefix - поддомен, в котором владелец публикует TXT-запись подтверждения.
+const domainVerificationPrefix = "_urleater."
+
+// domainVerificationValue - начало значения TXT-записи, за ним идёт токен домена.
+const domainVerificationValue = "urleater-verification="
+
+// maxHostLength - максимальная длина доменного имени.
+const maxHostLength = 253
+
+var (
+	// ErrInvalidDomain оборачивает ошибки проверки домена и его запасного адреса.
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrDomainNotFound - домена нет или он принадлежит другому пользователю.
+	ErrDomainNotFound = errors.New("domain not found")
+	// ErrDomainTaken - домен подтвердил другой пользователь или этот пользователь уже добавил его.
+	ErrDomainTaken = errors.New("domain is already taken")
+	// ErrDomainLimit - тариф пользователя не даёт добавить ещё один домен.
+	ErrDomainLimit = errors.New("plan does not allow more custom domains")
+	// ErrDomainNotVerified - владение доменом ещё не подтверждено.
+	ErrDomainNotVerified = errors.New("domain ownership is not verified")
+	// ErrDomainVerificationFailed - TXT-запись с токеном домена не найдена.
+	ErrDomainVerificationFailed = errors.New("domain verification TXT record not found")
+)
+
+// DNSResolver ищет TXT-записи. Подходит *net.Resolver, в тестах подставляется заглушка.
+type DNSResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DomainRules - правила своих доменов, которые задаются в конфигурации. Нулевые поля заменяются значениями по умолчанию.
+type DomainRules struct {
+	// PrimaryHosts - хосты самого сервиса. Запросы к ним не ищут свой домен.
+	PrimaryHosts []string
+	Resolver     DNSResolver
+}
+
+func DefaultDomainRules() DomainRules {
+	return DomainRules{
+		PrimaryHosts: []string{"localhost"},
+		Resolver:     net.DefaultResolver,
+	}
+}
+
+func (r DomainRules) withDefaults() DomainRules {
+	defaults := DefaultDomainRules()
+
+	if r.PrimaryHosts == nil {
+		r.PrimaryHosts = defaults.PrimaryHosts
+	}
+
+	if r.Resolver == nil {
+		r.Resolver = defaults.Resolver
+	}
+
+	return r
+}
+
+// WithDomains задаёт правила своих доменов.
+func (s *Service) WithDomains(rules DomainRules) *Service {
+	s.domains = rules.withDefaults()
+
+	return s
+}
+
+// DomainVerificationRecord возвращает имя и значение TXT-записи, которую владелец публикует для подтверждения домена.
+func DomainVerificationRecord(domain dto.Domain) (string, string) {
+	return domainVerificationPrefix + domain.Host, domainVerificationValue + domain.VerificationToken
+}
+
+// normalizeHost приводит имя домена к нижнему регистру и убирает завершающую точку.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// requestHost возвращает домен из заголовка Host без порта.
+func requestHost(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+
+	return normalizeHost(host)
+}
+
+func (s *Service) validateDomain(host string, fallbackUrl string) error {
+	var errs []error
+
+	if err := validateHostname(host); err != nil {
+		errs = append(errs, err)
+	}
+
+	if slices.Contains(s.domains.PrimaryHosts, host) {
+		errs = append(errs, fmt.Errorf("domain %s is served by the service itself", host))
+	}
+
+	if err := validateFallbackUrl(fallbackUrl); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidDomain, errors.Join(errs...))
+}
+
+// validateHostname проверяет, что host - доменное имя хотя бы из двух частей, а не IP-адрес.
+func validateHostname(host string) error {
+	if host == "" || len(host) > maxHostLength {
+		return fmt.Errorf("domain must be from 1 to %d characters", maxHostLength)
+	}
+
+	if net.ParseIP(host) != nil {
+		return fmt.Errorf("domain %s is an IP address", host)
+	}
+
+	labels := strings.Split(host, ".")
+
+	if len(labels) < 2 {
+		return fmt.Errorf("domain %s must have at least two labels", host)
+	}
+
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("domain %s has invalid label %q", host, label)
+		}
+
+		for _, char := range label {
+			if (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '-' {
+				return fmt.Errorf("domain %s contains invalid character %q", host, char)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateFallbackUrl(fallbackUrl string) error {
+	if fallbackUrl != "" && !IsValidUrl(fallbackUrl) {
+		return fmt.Errorf("fallback url %s is not a valid url", fallbackUrl)
+	}
+
+	return nil
+}
+
+// domainStorageError заменяет ошибки хранилища, о которых нужно сказать пользователю, ошибками сервиса.
+func domainStorageError(err error) error {
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrDomainNotFound
+
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode:
+		return ErrDomainTaken
+
+	case errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode:
+		return ErrUserNotFound
+
+	default:
+		return err
+	}
+}
+
+// AddDomain добавляет пользователю свой домен. Число доменов ограничено тарифом пользователя.
+// Пока домен не подтверждён, это только заявка: на тот же хост могут претендовать другие пользователи,
+// а ссылки на домене можно создавать после подтверждения владения, см. VerifyDomain.
+func (s *Service) AddDomain(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error) {
+	ctx, span := tracing.Start(ctx, "Service.AddDomain")
+
+	defer span.End()
+
+	host = normalizeHost(host)
+	fallbackUrl = strings.TrimSpace(fallbackUrl)
+
+	if err := s.validateDomain(host, fallbackUrl); err != nil {
+		return nil, fmt.Errorf("AddDomain: %w", err)
+	}
+
+	sub, err := s.GetUserSubscription(ctx, email)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddDomain: %w", err)
+	}
+
+	domains, err := s.postgresStorage.GetUserDomains(ctx, email)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddDomain: could not get domains of %s %w", email, err)
+	}
+
+	if sub == nil || len(domains) >= sub.Plan.Features.CustomDomains {
+		return nil, fmt.Errorf("AddDomain: user %s has %d domains %w", email, len(domains), ErrDomainLimit)
+	}
+
+	_, err = s.postgresStorage.GetDomain(ctx, host)
+
+	switch {
+	case err == nil:
+		return nil, fmt.Errorf("AddDomain: %s is verified %w", host, ErrDomainTaken)
+
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("AddDomain: could not get domain %s %w", host, err)
+	}
+
+	token, err := randomToken(16)
+
+	if err != nil {
+		return nil, fmt.Errorf("AddDomain: could not generate token %w", err)
+	}
+
+	domain, err := s.postgresStorage.CreateDomain(ctx, dto.Domain{
+		Host:              host,
+		UserEmail:         email,
+		VerificationToken: token,
+		FallbackUrl:       fallbackUrl,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("AddDomain: error while adding domain %s: %w", host, domainStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "domain added", "email", email, "host", host)
+
+	return domain, nil
+}
+
+// GetUserDomains возвращает домены пользователя в порядке добавления.
+func (s *Service) GetUserDomains(ctx context.Context, email string) ([]dto.Domain, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetUserDomains")
+
+	defer span.End()
+
+	domains, err := s.postgresStorage.GetUserDomains(ctx, email)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetUserDomains: could not get domains %w", err)
+	}
+
+	return domains, nil
+}
+
+// userDomain возвращает домен пользователя. Чужой домен не отличается от несуществующего.
+func (s *Service) userDomain(ctx context.Context, email string, host string) (*dto.Domain, error) {
+	domain, err := s.postgresStorage.GetUserDomain(ctx, email, host)
+
+	if err != nil {
+		return nil, domainStorageError(err)
+	}
+
+	return domain, nil
+}
+
+// VerifyDomain подтверждает владение доменом, если в DNS есть TXT-запись из DomainVerificationRecord.
+// Подтверждённый домен остаётся подтверждённым, даже если запись потом удалят. Домен достаётся тому, кто подтвердил его первым,
+// заявки остальных пользователей удаляются.
+func (s *Service) VerifyDomain(ctx context.Context, email string, host string) (*dto.Domain, error) {
+	ctx, span := tracing.Start(ctx, "Service.VerifyDomain")
+
+	defer span.End()
+
+	host = normalizeHost(host)
+
+	domain, err := s.userDomain(ctx, email, host)
+
+	if err != nil {
+		return nil, fmt.Errorf("VerifyDomain: %w", err)
+	}
+
+	if domain.VerifiedAt != nil {
+		return domain, nil
+	}
+
+	name, value := DomainVerificationRecord(*domain)
+
+	records, err := s.domains.Resolver.LookupTXT(ctx, name)
+
+	if err != nil {
+		return nil, fmt.Errorf("VerifyDomain: lookup of %s failed: %w: %w", name, ErrDomainVerificationFailed, err)
+	}
+
+	if !slices.Contains(records, value) {
+		return nil, fmt.Errorf("VerifyDomain: %s %w", name, ErrDomainVerificationFailed)
+	}
+
+	domain, err = s.postgresStorage.VerifyDomain(ctx, email, host, time.Now())
+
+	if err != nil {
+		return nil, fmt.Errorf("VerifyDomain: error while verifying domain %s: %w", host, domainStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "domain verified", "email", email, "host", host)
+
+	return domain, nil
+}
+
+// UpdateDomainFallback задаёт адрес, на который перенаправляются корень домена и неизвестные ссылки.
+// Пустой адрес - отвечать 404.
+func (s *Service) UpdateDomainFallback(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateDomainFallback")
+
+	defer span.End()
+
+	host = normalizeHost(host)
+	fallbackUrl = strings.TrimSpace(fallbackUrl)
+
+	if err := validateFallbackUrl(fallbackUrl); err != nil {
+		return nil, fmt.Errorf("UpdateDomainFallback: %w: %w", ErrInvalidDomain, err)
+	}
+
+	if _, err := s.userDomain(ctx, email, host); err != nil {
+		return nil, fmt.Errorf("UpdateDomainFallback: %w", err)
+	}
+
+	domain, err := s.postgresStorage.UpdateDomainFallback(ctx, email, host, fallbackUrl)
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateDomainFallback: error while updating domain %s: %w", host, domainStorageError(err))
+	}
+
+	return domain, nil
+}
+
+// DeleteDomain удаляет домен пользователя вместе со ссылками на нём.
+func (s *Service) DeleteDomain(ctx context.Context, email string, host string) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteDomain")
+
+	defer span.End()
+
+	host = normalizeHost(host)
+
+	if _, err := s.userDomain(ctx, email, host); err != nil {
+		return fmt.Errorf("DeleteDomain: %w", err)
+	}
+
+	if err := s.postgresStorage.DeleteDomain(ctx, email, host); err != nil {
+		return fmt.Errorf("DeleteDomain: error while deleting domain %s: %w", host, domainStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "domain deleted", "email", email, "host", host)
+
+	return nil
+}
+
+// ResolveHost возвращает подтверждённый свой домен по заголовку Host запроса. Для хостов сервиса,
+// неизвестных и неподтверждённых доменов возвращает nil: такие запросы обслуживаются как запросы к основному хосту.
+func (s *Service) ResolveHost(ctx context.Context, host string) (*dto.Domain, error) {
+	ctx, span := tracing.Start(ctx, "Service.ResolveHost")
+
+	defer span.End()
+
+	host = requestHost(host)
+
+	if host == "" || slices.Contains(s.domains.PrimaryHosts, host) || net.ParseIP(host) != nil {
+		return nil, nil
+	}
+
+	domain, err := s.postgresStorage.GetDomain(ctx, host)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, nil
+
+	case err != nil:
+		return nil, fmt.Errorf("ResolveHost: could not get domain %s %w", host, err)
+	}
+
+	return domain, nil
+}
+
+// linkDomain проверяет, что пользователь может создавать ссылки на домене host, и возвращает его нормализованное имя.
+func (s *Service) linkDomain(ctx context.Context, email string, host string) (string, error) {
+	host = normalizeHost(host)
+
+	domain, err := s.userDomain(ctx, email, host)
+
+	if err != nil {
+		return "", err
+	}
+
+	if domain.VerifiedAt == nil {
+		return "", fmt.Errorf("%s %w", host, ErrDomainNotVerified)
+	}
+
+	return host, nil
+}
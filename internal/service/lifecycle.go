@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Shutdown останавливает фоновую обработку в порядке, при котором сообщения не теряются:
+//  1. дожидается отправки событий о переходах, начатых обработанными HTTP-запросами;
+//  2. останавливает планировщик подписок и outbox relay после текущей пачки;
+//  3. останавливает чтение из очереди;
+//  4. закрывает канал воркеров и ждёт, пока они обработают уже прочитанные сообщения;
+//  5. закрывает консьюмеров, коммитя смещения подтверждённых сообщений.
+//
+// Если ctx истекает раньше, воркеры прерываются, а неподтверждённые сообщения будут прочитаны заново после перезапуска.
+func (s *Service) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	if err := waitGroup(ctx, &s.viewsWG); err != nil {
+		errs = append(errs, fmt.Errorf("Shutdown: link views were not published %w", err))
+	}
+
+	s.stopScheduler()
+
+	if err := waitGroup(ctx, &s.schedulerWG); err != nil {
+		errs = append(errs, fmt.Errorf("Shutdown: subscription scheduler did not stop %w", err))
+	}
+
+	s.stopRelay()
+
+	if err := waitGroup(ctx, &s.relayWG); err != nil {
+		errs = append(errs, fmt.Errorf("Shutdown: outbox relay did not stop %w", err))
+	}
+
+	s.stopConsumers()
+
+	if err := waitGroup(ctx, &s.consumersWG); err != nil {
+		// консьюмеры ещё могут писать в канал воркеров, поэтому закрывать его и консьюмеров нельзя
+		s.stopWorkers()
+
+		return errors.Join(append(errs, fmt.Errorf("Shutdown: consumers did not stop %w", err))...)
+	}
+
+	if s.workerChannel != nil {
+		close(s.workerChannel)
+	}
+
+	if err := waitGroup(ctx, &s.workersWG); err != nil {
+		s.stopWorkers()
+
+		s.workersWG.Wait()
+
+		errs = append(errs, fmt.Errorf("Shutdown: workers did not drain the queue %w", err))
+	}
+
+	for _, consumer := range s.consumers {
+		if err := consumer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("Shutdown: error while closing consumer %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// waitGroup ждёт wg, но не дольше ctx.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"time"
+	"urleater/dto"
+	"urleater/internal/events"
+	"urleater/internal/logging"
+	"urleater/internal/metrics"
+	"urleater/internal/tracing"
+)
+
+const (
+	outboxBatchSize    = 100
+	outboxLease        = 30 * time.Second
+	outboxPollInterval = time.Second
+	outboxMinBackoff   = time.Second
+	outboxMaxBackoff   = 5 * time.Minute
+)
+
+// StartOutboxRelay в фоне переносит события из outbox в Redis, поисковый индекс и Kafka.
+// Обработчики можно запускать на нескольких экземплярах сервиса: события разбираются через SKIP LOCKED.
+func (s *Service) StartOutboxRelay(ctx context.Context) {
+	ctx, s.stopRelay = context.WithCancel(ctx)
+
+	s.relayWG.Add(1)
+
+	go func() {
+		defer s.relayWG.Done()
+
+		ticker := time.NewTicker(outboxPollInterval)
+
+		defer ticker.Stop()
+
+		for {
+			for {
+				processed, err := s.RelayOutboxEvents(ctx)
+
+				if err != nil {
+					s.logger.ErrorContext(ctx, "outbox relay failed", "error", err)
+				}
+
+				// полная пачка - скорее всего, есть ещё события, не ждём тика
+				if err != nil || processed < outboxBatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RelayOutboxEvents обрабатывает одну пачку событий и возвращает их количество.
+// Неудачное событие откладывается с экспоненциальной задержкой и повторяется, пока не будет обработано.
+func (s *Service) RelayOutboxEvents(ctx context.Context) (int, error) {
+	backlog, err := s.postgresStorage.CountOutboxEvents(ctx)
+
+	if err != nil {
+		return 0, fmt.Errorf("RelayOutboxEvents: error while counting outbox events %w", err)
+	}
+
+	metrics.OutboxBacklog.Set(float64(backlog))
+
+	if backlog == 0 {
+		return 0, nil
+	}
+
+	// спан начинается только при наличии событий, чтобы опрос пустого outbox не порождал трейсы
+	ctx, span := tracing.Start(ctx, "Service.RelayOutboxEvents")
+
+	defer span.End()
+
+	events, err := s.postgresStorage.LeaseOutboxEvents(ctx, outboxBatchSize, outboxLease)
+
+	if err != nil {
+		return 0, fmt.Errorf("RelayOutboxEvents: error while leasing outbox events %w", err)
+	}
+
+	for _, event := range events {
+		err = s.applyOutboxEvent(ctx, event)
+
+		if err != nil {
+			metrics.OutboxFailed.Inc()
+
+			retryAt := time.Now().Add(outboxBackoff(event.Attempts))
+
+			s.logger.WarnContext(ctx, "outbox event failed", "event_id", event.Id, "event_type", event.Type,
+				"key", event.Key, "attempt", event.Attempts, "retry_at", retryAt, "error", err)
+
+			if err = s.postgresStorage.RetryOutboxEvent(ctx, event.Id, retryAt, err.Error()); err != nil {
+				s.logger.ErrorContext(ctx, "error while postponing outbox event", "event_id", event.Id, "error", err)
+			}
+
+			continue
+		}
+
+		if err = s.postgresStorage.CompleteOutboxEvent(ctx, event.Id); err != nil {
+			// событие обработается повторно после окончания аренды, все действия идемпотентны
+			s.logger.ErrorContext(ctx, "error while completing outbox event", "event_id", event.Id, "error", err)
+
+			continue
+		}
+
+		metrics.OutboxPublished.Inc()
+		metrics.OutboxBacklog.Dec()
+	}
+
+	return len(events), nil
+}
+
+// applyOutboxEvent синхронизирует кеш и индекс для событий ссылок и публикует событие в Kafka.
+func (s *Service) applyOutboxEvent(ctx context.Context, event dto.OutboxEvent) error {
+	// событие обрабатывается от имени запроса, который его создал
+	ctx = logging.WithRequestID(ctx, event.RequestId)
+
+	switch event.Type {
+	case dto.EventLinkCreated, dto.EventLinkUpdated, dto.EventLinkDeleted:
+		if err := s.syncLinkCopies(ctx, event.Key); err != nil {
+			return err
+		}
+	}
+
+	err := s.publishEvent(ctx, event.Key, dto.Event{
+		Id:         event.EventId,
+		Type:       event.Type,
+		Version:    event.Version,
+		OccurredAt: event.CreatedAt,
+		Producer:   events.ProducerName,
+		RequestId:  event.RequestId,
+		Data:       event.Payload,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while publishing to %s topic: %w", s.producerTopic, err)
+	}
+
+	return nil
+}
+
+// syncLinkCopies приводит кеш и индекс к текущему состоянию ссылки в Postgres.
+// Состояние перечитывается, а не берётся из события, поэтому повторная обработка ничего не портит.
+func (s *Service) syncLinkCopies(ctx context.Context, shortLink string) error {
+	link, err := s.postgresStorage.GetShortLink(ctx, shortLink)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		if err = s.redisStorage.DeleteLongLinkByShortLink(ctx, shortLink); err != nil {
+			return err
+		}
+
+		return s.searcher.DeleteShortLink(ctx, shortLink)
+
+	case err != nil:
+		return fmt.Errorf("error while getting short link %w", err)
+	}
+
+	if err = s.redisStorage.SaveShortLinkToLongLink(ctx, *link); err != nil {
+		return err
+	}
+
+	return s.searcher.AddShortLink(ctx, *link)
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxMinBackoff
+
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, outboxMaxBackoff)
+}
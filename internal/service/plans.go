@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"unicode"
+	"urleater/dto"
+	"urleater/internal/tracing"
+)
+
+var (
+	// ErrPlanNotFound - тарифа с таким id нет.
+	ErrPlanNotFound = errors.New("subscription plan not found")
+	// ErrInvalidPlan оборачивает ошибки проверки полей тарифа.
+	ErrInvalidPlan = errors.New("invalid subscription plan")
+	// ErrPlanNameTaken - тариф с таким названием уже есть.
+	ErrPlanNameTaken = errors.New("subscription plan name is already taken")
+	// ErrPlanInUse - на тариф подписаны пользователи, его можно только отключить.
+	ErrPlanInUse = errors.New("subscription plan has subscribers")
+)
+
+// maxPlanNameLength ограничивает название тарифа, чтобы оно помещалось в карточку на странице тарифов.
+const maxPlanNameLength = 64
+
+// Коды ошибок Postgres при нарушении ограничений уникальности и внешнего ключа.
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
+// GetAllSubscriptions возвращает все тарифы, включая отключённые, для администратора.
+func (s *Service) GetAllSubscriptions(ctx context.Context) ([]dto.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetAllSubscriptions")
+
+	defer span.End()
+
+	subs, err := s.postgresStorage.GetSubscriptions(ctx, false)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetAllSubscriptions: could not get subscriptions %w", err)
+	}
+
+	return subs, nil
+}
+
+func (s *Service) CreateSubscription(ctx context.Context, plan dto.Subscription) (*dto.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "Service.CreateSubscription")
+
+	defer span.End()
+
+	plan = normalizePlan(plan)
+
+	if err := validatePlan(plan); err != nil {
+		return nil, fmt.Errorf("CreateSubscription: %w", err)
+	}
+
+	created, err := s.postgresStorage.CreateSubscription(ctx, plan)
+
+	if err != nil {
+		return nil, fmt.Errorf("CreateSubscription: error while creating plan %s: %w", plan.Name, planStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "subscription plan created", "plan_id", created.Id, "plan", created.Name)
+
+	return created, nil
+}
+
+// UpdateSubscription заменяет все поля тарифа plan.Id.
+func (s *Service) UpdateSubscription(ctx context.Context, plan dto.Subscription) (*dto.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateSubscription")
+
+	defer span.End()
+
+	plan = normalizePlan(plan)
+
+	if err := validatePlan(plan); err != nil {
+		return nil, fmt.Errorf("UpdateSubscription: %w", err)
+	}
+
+	updated, err := s.postgresStorage.UpdateSubscription(ctx, plan)
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateSubscription: error while updating plan %d: %w", plan.Id, planStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "subscription plan updated", "plan_id", updated.Id, "plan", updated.Name, "active", updated.Active)
+
+	return updated, nil
+}
+
+// DeleteSubscription удаляет тариф без подписчиков. Чтобы только скрыть тариф от пользователей, его отключают через UpdateSubscription.
+func (s *Service) DeleteSubscription(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteSubscription")
+
+	defer span.End()
+
+	err := s.postgresStorage.DeleteSubscription(ctx, id)
+
+	if err != nil {
+		return fmt.Errorf("DeleteSubscription: error while deleting plan %d: %w", id, planStorageError(err))
+	}
+
+	s.logger.InfoContext(ctx, "subscription plan deleted", "plan_id", id)
+
+	return nil
+}
+
+// planStorageError заменяет ошибки хранилища, о которых нужно сказать администратору, ошибками сервиса.
+func planStorageError(err error) error {
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrPlanNotFound
+
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode:
+		return ErrPlanNameTaken
+
+	case errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode:
+		return ErrPlanInUse
+
+	default:
+		return err
+	}
+}
+
+func normalizePlan(plan dto.Subscription) dto.Subscription {
+	plan.Name = strings.TrimSpace(plan.Name)
+	plan.Currency = strings.ToUpper(strings.TrimSpace(plan.Currency))
+	plan.BillingPeriod = dto.BillingPeriod(strings.ToLower(strings.TrimSpace(string(plan.BillingPeriod))))
+
+	if plan.QuotaPolicy == "" {
+		plan.QuotaPolicy = dto.QuotaReset
+	}
+
+	return plan
+}
+
+// validatePlan проверяет все поля тарифа и возвращает все ошибки сразу, обёрнутые в ErrInvalidPlan.
+func validatePlan(plan dto.Subscription) error {
+	var errs []error
+
+	if plan.Name == "" || len([]rune(plan.Name)) > maxPlanNameLength {
+		errs = append(errs, fmt.Errorf("name must be 1 to %d characters", maxPlanNameLength))
+	}
+
+	if plan.TotalUrls <= 0 {
+		errs = append(errs, fmt.Errorf("total urls must be positive"))
+	}
+
+	if plan.Price < 0 {
+		errs = append(errs, fmt.Errorf("price must not be negative"))
+	}
+
+	if !validCurrency(plan.Currency) {
+		errs = append(errs, fmt.Errorf("currency %q is not a three-letter ISO 4217 code", plan.Currency))
+	}
+
+	switch plan.BillingPeriod {
+	case dto.BillingPeriodMonth, dto.BillingPeriodQuarter, dto.BillingPeriodYear:
+
+	default:
+		errs = append(errs, fmt.Errorf("billing period %q must be month, quarter or year", plan.BillingPeriod))
+	}
+
+	features := plan.Features
+
+	if features.MaxExpiryDays < 0 || features.CustomDomains < 0 || features.AnalyticsRetentionDays < 0 || features.APIRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("features must not be negative"))
+	}
+
+	if plan.TrialDays < 0 {
+		errs = append(errs, fmt.Errorf("trial days must not be negative"))
+	}
+
+	switch plan.QuotaPolicy {
+	case dto.QuotaReset, dto.QuotaTopUp:
+
+	default:
+		errs = append(errs, fmt.Errorf("quota policy %q must be reset or top_up", plan.QuotaPolicy))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidPlan, errors.Join(errs...))
+}
+
+func validCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+
+	for _, r := range currency {
+		if r > unicode.MaxASCII || !unicode.IsUpper(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/skip2/go-qrcode"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"urleater/dto"
+	"urleater/internal/tracing"
+)
+
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+const (
+	// minQRSize - минимальная сторона изображения в пикселях.
+	minQRSize = 64
+	// maxQRMargin - максимальные поля вокруг кода в модулях.
+	maxQRMargin = 16
+	// qrLogoShare - доля стороны кода под логотип. Уровень H восстанавливает до 30% модулей, логотип закрывает меньше.
+	qrLogoShare = 0.2
+)
+
+// ErrInvalidQROptions оборачивает ошибки проверки параметров QR-кода.
+var ErrInvalidQROptions = errors.New("invalid qr code options")
+
+// qrLevels - уровни коррекции ошибок QR-кода по их буквам.
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// QRRules - правила QR-кодов, которые задаются в конфигурации. Нулевые поля заменяются значениями по умолчанию.
+type QRRules struct {
+	DefaultSize int
+	MaxSize     int
+	// CacheTTL - сколько готовое изображение хранится в кеше.
+	CacheTTL time.Duration
+	// Logo - логотип, который ставится в центр кода по запросу. nil - логотипа нет.
+	Logo image.Image
+}
+
+func DefaultQRRules() QRRules {
+	return QRRules{
+		DefaultSize: 256,
+		MaxSize:     2048,
+		CacheTTL:    24 * time.Hour,
+	}
+}
+
+func (r QRRules) withDefaults() QRRules {
+	defaults := DefaultQRRules()
+
+	if r.DefaultSize <= 0 {
+		r.DefaultSize = defaults.DefaultSize
+	}
+
+	if r.MaxSize <= 0 {
+		r.MaxSize = defaults.MaxSize
+	}
+
+	if r.CacheTTL <= 0 {
+		r.CacheTTL = defaults.CacheTTL
+	}
+
+	return r
+}
+
+// WithQR задаёт правила QR-кодов.
+func (s *Service) WithQR(rules QRRules) *Service {
+	s.qr = rules.withDefaults()
+
+	return s
+}
+
+// QROptions - параметры изображения QR-кода.
+type QROptions struct {
+	// Format - png или svg.
+	Format string
+	// Size - сторона изображения в пикселях, 0 - QRRules.DefaultSize.
+	Size int
+	// Level - уровень коррекции ошибок: L, M, Q или H. С логотипом всегда H.
+	Level string
+	// Margin - поля вокруг кода в модулях.
+	Margin int
+	// Foreground и Background - цвета модулей и фона в виде #rrggbb или #rgb.
+	Foreground string
+	Background string
+	Logo       bool
+}
+
+func DefaultQROptions() QROptions {
+	return QROptions{
+		Format:     QRFormatPNG,
+		Level:      "M",
+		Margin:     4,
+		Foreground: "#000000",
+		Background: "#ffffff",
+	}
+}
+
+// QRImage - готовое изображение QR-кода.
+type QRImage struct {
+	Data        []byte
+	ContentType string
+}
+
+// qrDrawing - проверенные параметры, по которым рисуется код.
+type qrDrawing struct {
+	format     string
+	size       int
+	level      qrcode.RecoveryLevel
+	margin     int
+	foreground color.RGBA
+	background color.RGBA
+	logo       image.Image
+}
+
+func (s *Service) qrDrawing(options QROptions) (qrDrawing, error) {
+	var errs []error
+
+	drawing := qrDrawing{
+		format: strings.ToLower(options.Format),
+		size:   options.Size,
+		margin: options.Margin,
+	}
+
+	if drawing.format != QRFormatPNG && drawing.format != QRFormatSVG {
+		errs = append(errs, fmt.Errorf("format must be %s or %s, got %q", QRFormatPNG, QRFormatSVG, options.Format))
+	}
+
+	if drawing.size == 0 {
+		drawing.size = s.qr.DefaultSize
+	}
+
+	if drawing.size < minQRSize || drawing.size > s.qr.MaxSize {
+		errs = append(errs, fmt.Errorf("size must be from %d to %d pixels, got %d", minQRSize, s.qr.MaxSize, drawing.size))
+	}
+
+	level, ok := qrLevels[strings.ToUpper(options.Level)]
+
+	if !ok {
+		errs = append(errs, fmt.Errorf("level must be L, M, Q or H, got %q", options.Level))
+	}
+
+	drawing.level = level
+
+	if drawing.margin < 0 || drawing.margin > maxQRMargin {
+		errs = append(errs, fmt.Errorf("margin must be from 0 to %d modules, got %d", maxQRMargin, drawing.margin))
+	}
+
+	foreground, foregroundErr := parseHexColor(options.Foreground)
+
+	if foregroundErr != nil {
+		errs = append(errs, fmt.Errorf("foreground: %w", foregroundErr))
+	}
+
+	background, backgroundErr := parseHexColor(options.Background)
+
+	if backgroundErr != nil {
+		errs = append(errs, fmt.Errorf("background: %w", backgroundErr))
+	}
+
+	drawing.foreground, drawing.background = foreground, background
+
+	if foregroundErr == nil && backgroundErr == nil && foreground == background {
+		errs = append(errs, fmt.Errorf("foreground and background must differ"))
+	}
+
+	if options.Logo {
+		if s.qr.Logo == nil {
+			errs = append(errs, fmt.Errorf("logo is not configured"))
+		}
+
+		drawing.logo = s.qr.Logo
+		drawing.level = qrcode.Highest
+	}
+
+	if len(errs) > 0 {
+		return qrDrawing{}, fmt.Errorf("%w: %w", ErrInvalidQROptions, errors.Join(errs...))
+	}
+
+	return drawing, nil
+}
+
+// parseHexColor разбирает цвет #rrggbb или #rgb, решётка необязательна.
+func parseHexColor(value string) (color.RGBA, error) {
+	hexValue := strings.TrimPrefix(value, "#")
+
+	if len(hexValue) == 3 {
+		hexValue = string([]byte{hexValue[0], hexValue[0], hexValue[1], hexValue[1], hexValue[2], hexValue[2]})
+	}
+
+	rgb, err := strconv.ParseUint(hexValue, 16, 32)
+
+	if len(hexValue) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("color must be #rrggbb or #rgb, got %q", value)
+	}
+
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+// cacheKey зависит от содержимого кода и всех параметров изображения.
+func (d qrDrawing) cacheKey(content string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%d|%v|%v|%t",
+		content, d.format, d.size, d.level, d.margin, d.foreground, d.background, d.logo != nil)))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func (d qrDrawing) contentType() string {
+	if d.format == QRFormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+// qrContent возвращает адрес ссылки, который кодируется в QR-код. Ссылка на своём домене открывается
+// по этому домену, остальные - по baseUrl, адресу сервиса.
+func qrContent(baseUrl string, link dto.Link) (string, error) {
+	base, err := url.Parse(baseUrl)
+
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("invalid base url %q", baseUrl)
+	}
+
+	if link.Domain != "" {
+		return base.Scheme + "://" + link.ShortUrl, nil
+	}
+
+	return strings.TrimSuffix(base.String(), "/") + "/" + link.ShortUrl, nil
+}
+
+// GetShortLinkQR возвращает QR-код ссылки shortLink. baseUrl - адрес сервиса, по которому открываются
+// ссылки основного хоста. Готовые изображения кешируются на QRRules.CacheTTL, а просмотр кода
+// не считается переходом по ссылке.
+func (s *Service) GetShortLinkQR(ctx context.Context, shortLink string, baseUrl string, options QROptions) (*QRImage, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetShortLinkQR")
+
+	defer span.End()
+
+	drawing, err := s.qrDrawing(options)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetShortLinkQR: %w", err)
+	}
+
+	link, err := s.postgresStorage.GetShortLink(ctx, shortLink)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = fmt.Errorf("%w: %w", ErrLinkNotFound, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("GetShortLinkQR: error while getting short link %s: %w", shortLink, err)
+	}
+
+	if link.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("GetShortLinkQR: short link %s expired: %w", shortLink, ErrLinkNotFound)
+	}
+
+	content, err := qrContent(baseUrl, *link)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetShortLinkQR: %w", err)
+	}
+
+	key := drawing.cacheKey(content)
+
+	data, err := s.redisStorage.GetQRCode(ctx, key)
+
+	if err == nil {
+		return &QRImage{Data: data, ContentType: drawing.contentType()}, nil
+	}
+
+	if !errors.Is(err, redis.Nil) {
+		s.logger.WarnContext(ctx, "qr code cache is unavailable", "short_link", shortLink, "error", err)
+	}
+
+	data, err = drawing.render(content)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetShortLinkQR: could not render qr code for %s %w", shortLink, err)
+	}
+
+	if err = s.redisStorage.SaveQRCode(ctx, key, data, s.qr.CacheTTL); err != nil {
+		s.logger.WarnContext(ctx, "error while saving qr code to redis", "short_link", shortLink, "error", err)
+	}
+
+	return &QRImage{Data: data, ContentType: drawing.contentType()}, nil
+}
+
+func (d qrDrawing) render(content string) ([]byte, error) {
+	code, err := qrcode.New(content, d.level)
+
+	if err != nil {
+		return nil, err
+	}
+
+	code.DisableBorder = true
+
+	modules := code.Bitmap()
+
+	if d.format == QRFormatSVG {
+		return d.renderSVG(modules)
+	}
+
+	return d.renderPNG(modules)
+}
+
+// logoBox возвращает сторону квадрата под логотип в модулях для кода из n модулей.
+func logoBox(n int) float64 {
+	return float64(n) * qrLogoShare
+}
+
+func (d qrDrawing) renderPNG(modules [][]bool) ([]byte, error) {
+	n := len(modules)
+	total := n + 2*d.margin
+	scale := d.size / total
+
+	if scale < 1 {
+		return nil, fmt.Errorf("%w: size %d is too small for %d modules", ErrInvalidQROptions, d.size, total)
+	}
+
+	// остаток, который не делится на модули, распределяется по краям
+	offset := (d.size-scale*total)/2 + d.margin*scale
+
+	img := image.NewRGBA(image.Rect(0, 0, d.size, d.size))
+
+	draw.Draw(img, img.Bounds(), image.NewUniform(d.background), image.Point{}, draw.Src)
+
+	foreground := image.NewUniform(d.foreground)
+
+	for y, row := range modules {
+		for x, set := range row {
+			if set {
+				rect := image.Rect(offset+x*scale, offset+y*scale, offset+(x+1)*scale, offset+(y+1)*scale)
+
+				draw.Draw(img, rect, foreground, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	if d.logo != nil {
+		box := int(logoBox(n) * float64(scale))
+		padding := scale
+		start := offset + (n*scale-box)/2
+
+		draw.Draw(img, image.Rect(start-padding, start-padding, start+box+padding, start+box+padding),
+			image.NewUniform(d.background), image.Point{}, draw.Src)
+
+		logo := fitImage(d.logo, box)
+		at := image.Pt(start+(box-logo.Bounds().Dx())/2, start+(box-logo.Bounds().Dy())/2)
+
+		draw.Draw(img, logo.Bounds().Add(at), logo, image.Point{}, draw.Over)
+	}
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fitImage уменьшает или увеличивает src до квадрата box с сохранением пропорций, ближайшим соседом.
+func fitImage(src image.Image, box int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := box, box
+
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, box*bounds.Dy()/bounds.Dx())
+	} else {
+		width = max(1, box*bounds.Dx()/bounds.Dy())
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height))
+		}
+	}
+
+	return dst
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// renderSVG рисует код в координатах модулей: соседние модули строки объединяются в один прямоугольник.
+func (d qrDrawing) renderSVG(modules [][]bool) ([]byte, error) {
+	n := len(modules)
+	total := n + 2*d.margin
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		d.size, d.size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, total, total, hexColor(d.background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(d.foreground))
+
+	for y, row := range modules {
+		for x := 0; x < n; x++ {
+			if !row[x] {
+				continue
+			}
+
+			run := 1
+
+			for x+run < n && row[x+run] {
+				run++
+			}
+
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x+d.margin, y+d.margin, run, run)
+
+			x += run - 1
+		}
+	}
+
+	buf.WriteString(`"/>`)
+
+	if d.logo != nil {
+		var logo bytes.Buffer
+
+		if err := png.Encode(&logo, d.logo); err != nil {
+			return nil, err
+		}
+
+		box := logoBox(n)
+		start := float64(d.margin) + (float64(n)-box)/2
+
+		fmt.Fprintf(&buf, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`,
+			start-1, start-1, box+2, box+2, hexColor(d.background))
+		fmt.Fprintf(&buf, `<image x="%g" y="%g" width="%g" height="%g" preserveAspectRatio="xMidYMid meet" href="data:image/png;base64,%s"/>`,
+			start, start, box, box, base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+
+	buf.WriteString(`</svg>`)
+
+	return buf.Bytes(), nil
+}
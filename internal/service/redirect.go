@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"urleater/dto"
+	"urleater/internal/tracing"
+)
+
+// ErrInvalidRedirectOptions - неизвестная политика совпадения параметров запроса.
+var ErrInvalidRedirectOptions = errors.New("invalid redirect options")
+
+var queryConflicts = []string{dto.QueryConflictKeep, dto.QueryConflictOverride, dto.QueryConflictAppend}
+
+// UpdateShortLinkRedirect задаёт, что из запроса посетителя переносится в длинную ссылку. Пустая политика
+// совпадения параметров - dto.QueryConflictKeep.
+func (s *Service) UpdateShortLinkRedirect(ctx context.Context, shortLink string, email string, options dto.RedirectOptions) (*dto.Link, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateShortLinkRedirect")
+
+	defer span.End()
+
+	if options.QueryConflict == "" {
+		options.QueryConflict = dto.QueryConflictKeep
+	}
+
+	if !slices.Contains(queryConflicts, options.QueryConflict) {
+		return nil, fmt.Errorf("UpdateShortLinkRedirect: query conflict must be one of %s, got %q %w",
+			strings.Join(queryConflicts, ", "), options.QueryConflict, ErrInvalidRedirectOptions)
+	}
+
+	link, err := s.postgresStorage.GetShortLink(ctx, shortLink)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), err == nil && link.UserEmail != email:
+		return nil, fmt.Errorf("UpdateShortLinkRedirect: short link %s %w", shortLink, ErrLinkNotFound)
+
+	case err != nil:
+		return nil, fmt.Errorf("UpdateShortLinkRedirect: error while getting short link %s: %w", shortLink, err)
+	}
+
+	if err = s.postgresStorage.UpdateShortLinkRedirect(ctx, shortLink, options); err != nil {
+		return nil, fmt.Errorf("UpdateShortLinkRedirect: error while updating short link %s: %w", shortLink, err)
+	}
+
+	link, err = s.postgresStorage.GetShortLink(ctx, shortLink)
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateShortLinkRedirect: error while getting updated short link %s: %w", shortLink, err)
+	}
+
+	return link, nil
+}
+
+// Redirect возвращает адрес перехода по ссылке shortLink. path - остаток пути после алиаса в экранированном виде,
+// он дописывается к длинной ссылке, только если алиас - префикс (ForwardPath). query - параметры запроса
+// посетителя, они переносятся при ForwardQuery.
+func (s *Service) Redirect(ctx context.Context, shortLink string, path string, query url.Values) (string, error) {
+	ctx, span := tracing.Start(ctx, "Service.Redirect")
+
+	defer span.End()
+
+	link, err := s.cachedShortLink(ctx, shortLink)
+
+	if err != nil {
+		return "", fmt.Errorf("Redirect: error while getting short link %s: %w", shortLink, err)
+	}
+
+	path = strings.TrimPrefix(path, "/")
+
+	if path != "" && !link.ForwardPath {
+		return "", fmt.Errorf("Redirect: short link %s does not forward paths: %w", shortLink, ErrLinkNotFound)
+	}
+
+	s.countLinkView(ctx, shortLink, link.UTM.Campaign)
+
+	if link.ExpiresAt.Before(time.Now()) {
+		return "", fmt.Errorf("Redirect: short link %s expired: %w", shortLink, ErrLinkNotFound)
+	}
+
+	target, err := redirectTarget(*link, path, query)
+
+	if err != nil {
+		return "", fmt.Errorf("Redirect: short link %s: %w", shortLink, err)
+	}
+
+	return target, nil
+}
+
+// redirectTarget дописывает к длинной ссылке путь, метки кампании и параметры посетителя по параметрам перехода ссылки.
+// Метки кампании считаются параметрами ссылки: политика совпадения решает, может ли посетитель их заменить.
+func redirectTarget(link dto.Link, path string, query url.Values) (string, error) {
+	forwardQuery := link.ForwardQuery && len(query) > 0
+	tagged := link.UTM != dto.UTM{}
+
+	if path == "" && !forwardQuery && !tagged {
+		return link.LongUrl, nil
+	}
+
+	target, err := url.Parse(link.LongUrl)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid long url %w", err)
+	}
+
+	if path != "" {
+		unescaped, err := url.PathUnescape(path)
+
+		if err != nil {
+			return "", fmt.Errorf("invalid path %q %w", path, ErrLinkNotFound)
+		}
+
+		// RawPath сохраняет запись посетителя, например %2F внутри сегмента
+		rawPath := strings.TrimSuffix(target.EscapedPath(), "/") + "/" + path
+
+		target.Path = strings.TrimSuffix(target.Path, "/") + "/" + unescaped
+		target.RawPath = rawPath
+	}
+
+	if tagged {
+		target.RawQuery = withUTM(target.RawQuery, link.UTM)
+	}
+
+	if forwardQuery {
+		target.RawQuery = mergeQuery(target.RawQuery, query, link.QueryConflict)
+	}
+
+	return target.String(), nil
+}
+
+// mergeQuery дописывает параметры посетителя к запросу длинной ссылки rawQuery. При совпадении имён
+// conflict решает, чьё значение остаётся. Параметры ссылки сохраняют свой порядок и запись.
+func mergeQuery(rawQuery string, visitor url.Values, conflict string) string {
+	extra := url.Values{}
+
+	switch conflict {
+	case dto.QueryConflictOverride:
+		rawQuery = withoutParams(rawQuery, visitor)
+		extra = visitor
+
+	case dto.QueryConflictAppend:
+		extra = visitor
+
+	default:
+		own, _ := url.ParseQuery(rawQuery)
+
+		for name, values := range visitor {
+			if !own.Has(name) {
+				extra[name] = values
+			}
+		}
+	}
+
+	if rawQuery == "" || len(extra) == 0 {
+		return rawQuery + extra.Encode()
+	}
+
+	return rawQuery + "&" + extra.Encode()
+}
+
+// withoutParams убирает из rawQuery параметры с именами из names.
+func withoutParams(rawQuery string, names url.Values) string {
+	var kept []string
+
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+
+		if name, err := url.QueryUnescape(key); pair == "" || err == nil && names.Has(name) {
+			continue
+		}
+
+		kept = append(kept, pair)
+	}
+
+	return strings.Join(kept, "&")
+}
@@ -0,0 +1,56 @@
+package service
+
+import "slices"
+
+// LinkRules - ограничения на ссылки, которые задаются в конфигурации. Нулевые поля заменяются значениями по умолчанию.
+type LinkRules struct {
+	AliasMinLength int
+	AliasMaxLength int
+	// ReservedNames нельзя занять ни псевдонимом, ни сгенерированной ссылкой: они совпадают с маршрутами сервиса.
+	ReservedNames []string
+	// MaxPageSize ограничивает размер страницы списка ссылок пользователя.
+	MaxPageSize int
+	// SearchLimit - количество результатов поиска на странице.
+	SearchLimit int
+}
+
+// DefaultLinkRules возвращает ограничения, которые действуют, если конфигурация их не задаёт.
+func DefaultLinkRules() LinkRules {
+	return LinkRules{
+		AliasMinLength: 8,
+		AliasMaxLength: 20,
+		ReservedNames:  []string{"register", "login", "logout", "create_link", "buy", "subscriptions"},
+		MaxPageSize:    50,
+		SearchLimit:    20,
+	}
+}
+
+func (r LinkRules) withDefaults() LinkRules {
+	defaults := DefaultLinkRules()
+
+	if r.AliasMinLength <= 0 {
+		r.AliasMinLength = defaults.AliasMinLength
+	}
+
+	if r.AliasMaxLength <= 0 {
+		r.AliasMaxLength = defaults.AliasMaxLength
+	}
+
+	if r.ReservedNames == nil {
+		r.ReservedNames = defaults.ReservedNames
+	}
+
+	if r.MaxPageSize <= 0 {
+		r.MaxPageSize = defaults.MaxPageSize
+	}
+
+	if r.SearchLimit <= 0 {
+		r.SearchLimit = defaults.SearchLimit
+	}
+
+	return r
+}
+
+func (r LinkRules) reserved(shortLink string) bool {
+	return slices.Contains(r.ReservedNames, shortLink)
+}
@@ -2,59 +2,112 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v4"
-	"log"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"math/rand"
 	"net/mail"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
 	"urleater/dto"
-	kafkaProducerConsumer "urleater/internal/repository/kafka"
+	"urleater/internal/events"
+	"urleater/internal/logging"
+	"urleater/internal/metrics"
+	"urleater/internal/tracing"
 )
 
 type PostgresStorage interface {
 	CreateUser(ctx context.Context, email string, password string) error
 	ChangePassword(ctx context.Context, email string, password string) error
 	GetUser(ctx context.Context, email string) (*dto.User, error)
-	CreateShortLink(ctx context.Context, shortLink string, longLink string, userID string) (*dto.Link, error)
+	CreateShortLink(ctx context.Context, shortLink string, longLink string, userID string, domain string) (*dto.Link, error)
 	GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error)
 	DeleteShortLink(ctx context.Context, shortLink string) error
 	ExtendShortLink(ctx context.Context, shortLink string, expiresAt time.Time) (*dto.Link, error)
-	GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email string, offset int, limit int) ([]dto.Link, error)
+	GetUserShortLinks(ctx context.Context, email string, listQuery dto.LinkListQuery) ([]dto.Link, error)
 	UpdateUserLinks(ctx context.Context, email string, newUrlsNumber int) (*dto.User, error)
-	GetSubscriptions(ctx context.Context) ([]dto.Subscription, error)
+	GetSubscriptions(ctx context.Context, activeOnly bool) ([]dto.Subscription, error)
+	GetSubscription(ctx context.Context, id int) (*dto.Subscription, error)
+	CreateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error)
+	UpdateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error)
+	DeleteSubscription(ctx context.Context, id int) error
+	GetUserSubscription(ctx context.Context, email string) (*dto.UserSubscription, error)
+	GetDueUserSubscriptions(ctx context.Context, now time.Time, limit int) ([]dto.UserSubscription, error)
+	SaveUserSubscription(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange) (bool, error)
+	SaveUserSubscriptionWithPromoCode(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange, code string, now time.Time) (*dto.PromoCode, bool, error)
+	AddPrepaidPeriods(ctx context.Context, email string, periods int) (*dto.UserSubscription, error)
+	AddLinkCredit(ctx context.Context, credit dto.LinkCredit) (*dto.User, error)
+	GetLinkCredits(ctx context.Context, email string, limit int) ([]dto.LinkCredit, error)
+	CreatePromoCode(ctx context.Context, promo dto.PromoCode) (*dto.PromoCode, error)
+	GetPromoCodes(ctx context.Context) ([]dto.PromoCode, error)
+	GetPromoCode(ctx context.Context, code string) (*dto.PromoCode, error)
+	DeletePromoCode(ctx context.Context, code string) error
+	RedeemPromoCode(ctx context.Context, code string, email string, now time.Time) (*dto.PromoCode, error)
+	CreateEmailVerification(ctx context.Context, email string, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash string, now time.Time, referralCredits int) (string, *dto.Referral, error)
+	EnsureReferralCode(ctx context.Context, email string, code string) (string, error)
+	CreateReferral(ctx context.Context, inviteeEmail string, referralCode string) (*dto.Referral, error)
+	GetReferrals(ctx context.Context, referrerEmail string) ([]dto.Referral, error)
+	CreateDomain(ctx context.Context, domain dto.Domain) (*dto.Domain, error)
+	GetDomain(ctx context.Context, host string) (*dto.Domain, error)
+	GetUserDomain(ctx context.Context, email string, host string) (*dto.Domain, error)
+	GetUserDomains(ctx context.Context, email string) ([]dto.Domain, error)
+	VerifyDomain(ctx context.Context, email string, host string, verifiedAt time.Time) (*dto.Domain, error)
+	UpdateDomainFallback(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error)
+	DeleteDomain(ctx context.Context, email string, host string) error
 	VerifyUserPassword(ctx context.Context, email string, password string) error
-	CreateSubscriptions(ctx context.Context) error
-	GetTotalUserLinksNumber(ctx context.Context, email string) (int, error)
-	IncrementShortLinkTimesWatchedCount(ctx context.Context, shortLink string) error
+	GetTotalUserLinksNumber(ctx context.Context, email string, filter dto.LinkFilter) (int, error)
+	IncrementShortLinkTimesWatchedCount(ctx context.Context, shortLink string, campaign string) error
+	UpdateShortLinkInfo(ctx context.Context, shortLink string, title string, description string, tags []string) error
+	UpdateShortLinkRedirect(ctx context.Context, shortLink string, options dto.RedirectOptions) error
+	UpdateShortLinkUTM(ctx context.Context, shortLink string, utm dto.UTM) error
+	GetUserCampaignStats(ctx context.Context, email string) ([]dto.CampaignStats, error)
+	GetUserTags(ctx context.Context, email string) ([]dto.Tag, error)
+	DeleteUserTag(ctx context.Context, email string, tag string) error
+	GetShortLinksByShortUrls(ctx context.Context, shortLinks []string) ([]dto.Link, error)
+	LeaseOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]dto.OutboxEvent, error)
+	CompleteOutboxEvent(ctx context.Context, id int64) error
+	RetryOutboxEvent(ctx context.Context, id int64, retryAt time.Time, lastError string) error
+	CountOutboxEvents(ctx context.Context) (int, error)
 }
 
 type RedisStorage interface {
 	DeleteLongLinkByShortLink(ctx context.Context, shortLink string) error
 	GetShortLinkByLongLink(ctx context.Context, shortLink string) (*dto.Link, error)
 	SaveShortLinkToLongLink(ctx context.Context, link dto.Link) error
+	GetQRCode(ctx context.Context, key string) ([]byte, error)
+	SaveQRCode(ctx context.Context, key string, image []byte, ttl time.Duration) error
 }
 
+// Consumer читает сообщения из очереди и передаёт их воркерам. После ошибки StartConsuming
+// консьюмер пересоздаётся через Reconnect. Close коммитит подтверждённые сообщения и закрывает консьюмера,
+// его вызывают после остановки StartConsuming и обработки прочитанных сообщений.
 type Consumer interface {
 	StartConsuming(ctx context.Context) error
-	GetConfig() kafkaProducerConsumer.KafkaConfig
-	GetWorkerChannel() chan dto.ConsumerData
+	Reconnect() error
+	Close() error
 }
 
 type ElasticSearcher interface {
-	SearchShortLinks(ctx context.Context, word string, limit int, offset int) ([]string, error)
-	AddShortLink(ctx context.Context, link string) error
+	SearchShortLinks(ctx context.Context, query dto.SearchQuery) (dto.SearchResult, error)
+	AddShortLink(ctx context.Context, link dto.Link) error
 	DeleteShortLink(ctx context.Context, link string) error
 }
 
 type Producer interface {
-	PublishMsg(msgType string, data map[string]string, topic string) error
-	GetConfig() kafkaProducerConsumer.KafkaConfig
+	PublishEvent(ctx context.Context, key string, event dto.Event, topic string) error
+	PublishDeadLetter(ctx context.Context, topic string, letter dto.DeadLetter) error
 }
 
 var mutex = &sync.Mutex{}
@@ -66,29 +119,61 @@ type Service struct {
 	producer        Producer
 	searcher        ElasticSearcher
 	producerTopic   string
+	events          *events.Registry
+	logger          *slog.Logger
+	rules           LinkRules
+	billing         BillingRules
+	credits         CreditRules
+	domains         DomainRules
+	qr              QRRules
+	admins          AdminRules
+
+	// фоновые задачи, которые останавливает Shutdown, см. lifecycle.go
+	stopRelay     context.CancelFunc
+	stopScheduler context.CancelFunc
+	stopConsumers context.CancelFunc
+	stopWorkers   context.CancelFunc
+	workerChannel chan dto.ConsumerMessage
+	relayWG       sync.WaitGroup
+	schedulerWG   sync.WaitGroup
+	consumersWG   sync.WaitGroup
+	workersWG     sync.WaitGroup
+	viewsWG       sync.WaitGroup
 }
 
-var reservedNames = []string{
-	"register",
-	"login",
-	"logout",
-	"create_link",
-	"buy",
-	"subscriptions",
-}
-
-func New(postgresStorage PostgresStorage, redisStorage RedisStorage, producer Producer, consumers []Consumer, searcher ElasticSearcher, producerTopic string) *Service {
-	return &Service{
+// New создаёт сервис. Если logger не передан, используется slog.Default().
+func New(postgresStorage PostgresStorage, redisStorage RedisStorage, producer Producer, consumers []Consumer, searcher ElasticSearcher, producerTopic string, logger *slog.Logger, rules LinkRules) *Service {
+	s := &Service{
 		postgresStorage: postgresStorage,
 		redisStorage:    redisStorage,
 		consumers:       consumers,
 		producer:        producer,
 		searcher:        searcher,
 		producerTopic:   producerTopic,
+		events:          events.NewRegistry(),
+		logger:          logging.OrDefault(logger),
+		rules:           rules.withDefaults(),
+		billing:         BillingRules{}.withDefaults(),
+		credits:         CreditRules{}.withDefaults(),
+		domains:         DomainRules{}.withDefaults(),
+		qr:              QRRules{}.withDefaults(),
+		stopRelay:       func() {},
+		stopScheduler:   func() {},
+		stopConsumers:   func() {},
+		stopWorkers:     func() {},
 	}
+
+	events.Handle(s.events, dto.EventLinkViewed, s.handleLinkViewed)
+	events.Handle(s.events, dto.EventLinkExpired, s.handleLinkExpired)
+
+	return s
 }
 
 func (s *Service) LoginUser(ctx context.Context, email string, password string) error {
+	ctx, span := tracing.Start(ctx, "Service.LoginUser")
+
+	defer span.End()
+
 	email = strings.TrimSpace(email)
 	password = strings.TrimSpace(password)
 
@@ -156,6 +241,10 @@ func validateEmail(email string) bool {
 }
 
 func (s *Service) RegisterUser(ctx context.Context, email string, password string) error {
+	ctx, span := tracing.Start(ctx, "Service.RegisterUser")
+
+	defer span.End()
+
 	email = strings.TrimSpace(email)
 	password = strings.TrimSpace(password)
 
@@ -188,11 +277,29 @@ func (s *Service) RegisterUser(ctx context.Context, email string, password strin
 		return fmt.Errorf("RegisterUser: could not create user %w", err)
 	}
 
+	metrics.UsersRegistered.Inc()
+
+	// без подписки пользователь остаётся с квотой по умолчанию, поэтому регистрация не прерывается
+	sub, quota, err := s.startFreePlan(ctx, email, false)
+
+	if err == nil {
+		_, err = s.postgresStorage.SaveUserSubscription(ctx, sub, time.Time{}, quota)
+	}
+
+	if err != nil {
+		s.logger.WarnContext(ctx, "could not start free plan", "email", email, "error", err)
+	}
+
+	// ссылку подтверждения можно запросить повторно, поэтому регистрация не прерывается
+	if err = s.RequestEmailVerification(ctx, email); err != nil {
+		s.logger.WarnContext(ctx, "could not request email verification", "email", email, "error", err)
+	}
+
 	return nil
 }
 
-func validateLinkAlias(alias string) bool {
-	if len(alias) < 8 || len(alias) > 20 {
+func (s *Service) validateLinkAlias(alias string) bool {
+	if len(alias) < s.rules.AliasMinLength || len(alias) > s.rules.AliasMaxLength {
 		return false
 	}
 	for _, char := range alias {
@@ -209,8 +316,13 @@ func IsValidUrl(str string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
-func (s *Service) CreateShortLink(ctx context.Context, alias string, longLink string, userEmail string) (*dto.Link, error) {
-	fmt.Println()
+// CreateShortLink создаёт ссылку пользователя. Если domain не пустой, ссылка создаётся на подтверждённом
+// домене пользователя, и алиас должен быть уникален только в пределах этого домена.
+func (s *Service) CreateShortLink(ctx context.Context, alias string, longLink string, userEmail string, domain string) (*dto.Link, error) {
+	ctx, span := tracing.Start(ctx, "Service.CreateShortLink")
+
+	defer span.End()
+
 	if len(longLink) == 0 {
 		return nil, fmt.Errorf("CreateShortLink: longLink is empty")
 	}
@@ -219,10 +331,20 @@ func (s *Service) CreateShortLink(ctx context.Context, alias string, longLink st
 		return nil, fmt.Errorf("CreateShortLink: invalid longLink format")
 	}
 
+	if domain != "" {
+		var err error
+
+		domain, err = s.linkDomain(ctx, userEmail, domain)
+
+		if err != nil {
+			return nil, fmt.Errorf("CreateShortLink: %w", err)
+		}
+	}
+
 	var shortLink string
 
 	if alias != "" {
-		if !validateLinkAlias(alias) {
+		if !s.validateLinkAlias(alias) {
 			return nil, fmt.Errorf("CreateShortLink: invalid alias: %s", alias)
 		}
 		shortLink = alias
@@ -233,7 +355,7 @@ func (s *Service) CreateShortLink(ctx context.Context, alias string, longLink st
 		for i := 0; i < 10; i++ { // генерируем ссылки, пока такие существуют
 			shortLink = GenerateShortLink()
 
-			_, err = s.postgresStorage.GetShortLink(ctx, shortLink)
+			_, err = s.postgresStorage.GetShortLink(ctx, dto.DomainShortUrl(domain, shortLink))
 
 			switch {
 			case errors.Is(err, pgx.ErrNoRows):
@@ -249,10 +371,8 @@ func (s *Service) CreateShortLink(ctx context.Context, alias string, longLink st
 		}
 	}
 
-	for _, val := range reservedNames {
-		if val == shortLink {
-			return nil, fmt.Errorf("short link %s is not available", shortLink)
-		}
+	if s.rules.reserved(shortLink) {
+		return nil, fmt.Errorf("short link %s is not available", shortLink)
 	}
 
 	user, err := s.postgresStorage.GetUser(ctx, userEmail)
@@ -262,6 +382,8 @@ func (s *Service) CreateShortLink(ctx context.Context, alias string, longLink st
 	}
 
 	if user.UrlsLeft == 0 {
+		metrics.QuotaExhausted.Inc()
+
 		return nil, fmt.Errorf("CreateShortLink: user %s has no urls", userEmail)
 	}
 
@@ -271,29 +393,27 @@ func (s *Service) CreateShortLink(ctx context.Context, alias string, longLink st
 		return nil, fmt.Errorf("CreateShortLink: error while updating user links for short link %s | %w", shortLink, err)
 	}
 
-	link, err := s.postgresStorage.CreateShortLink(ctx, shortLink, longLink, userEmail)
-
-	if err != nil {
-		return nil, fmt.Errorf("CreateShortLink: error while creating a short link %s | %w", shortLink, err)
-	}
+	shortLink = dto.DomainShortUrl(domain, shortLink)
 
-	err = s.redisStorage.SaveShortLinkToLongLink(ctx, *link)
+	// Redis, индекс и Kafka обновит обработчик outbox
+	link, err := s.postgresStorage.CreateShortLink(ctx, shortLink, longLink, userEmail, domain)
 
 	if err != nil {
-		log.Println(fmt.Errorf("CreateShortLink: error while saving short link %s | %w", shortLink, err).Error())
+		return nil, fmt.Errorf("CreateShortLink: error while creating a short link %s | %w", shortLink, err)
 	}
 
-	err = s.searcher.AddShortLink(ctx, shortLink)
-
-	if err != nil {
-		log.Println(fmt.Errorf("CreateShortLink: error while adding short link %s | %v", shortLink, err).Error())
-	}
+	metrics.LinksCreated.Inc()
 
 	return link, nil
 }
 
+// GetSubscriptions возвращает включённые тарифы в порядке показа.
 func (s *Service) GetSubscriptions(ctx context.Context) ([]dto.Subscription, error) {
-	subs, err := s.postgresStorage.GetSubscriptions(ctx)
+	ctx, span := tracing.Start(ctx, "Service.GetSubscriptions")
+
+	defer span.End()
+
+	subs, err := s.postgresStorage.GetSubscriptions(ctx, true)
 
 	if err != nil {
 		return nil, fmt.Errorf("GetSubscriptions: could not get subscriptions %w", err)
@@ -303,6 +423,10 @@ func (s *Service) GetSubscriptions(ctx context.Context) ([]dto.Subscription, err
 }
 
 func (s *Service) GetUser(ctx context.Context, email string) (*dto.User, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetUser")
+
+	defer span.End()
+
 	user, err := s.postgresStorage.GetUser(ctx, email)
 
 	if err != nil {
@@ -312,18 +436,127 @@ func (s *Service) GetUser(ctx context.Context, email string) (*dto.User, error)
 	return user, nil
 }
 
-func (s *Service) GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email string, offset int, limit int) ([]dto.Link, *dto.User, error) {
+// cursorTimeLayout совпадает с текстовым представлением timestamp в Postgres и сохраняет микросекунды.
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+// linkCursor - содержимое непрозрачного курсора страницы. Ключ и направление сортировки
+// хранятся в курсоре, чтобы его нельзя было применить к выборке с другим порядком.
+type linkCursor struct {
+	SortBy    dto.LinkSortField `json:"s"`
+	Direction dto.SortDirection `json:"d"`
+	Value     string            `json:"v"`
+	ShortUrl  string            `json:"u"`
+}
+
+func encodeLinkCursor(listQuery dto.LinkListQuery, link dto.Link) (string, error) {
+	cursor := linkCursor{
+		SortBy:    listQuery.SortBy,
+		Direction: listQuery.Direction,
+		ShortUrl:  link.ShortUrl,
+	}
+
+	switch listQuery.SortBy {
+	case dto.LinkSortCreatedAt:
+		cursor.Value = link.CreatedAt.UTC().Format(cursorTimeLayout)
+	case dto.LinkSortExpiresAt:
+		cursor.Value = link.ExpiresAt.UTC().Format(cursorTimeLayout)
+	case dto.LinkSortTimesVisited:
+		cursor.Value = strconv.Itoa(link.TimesVisited)
+	case dto.LinkSortAlias:
+		cursor.Value = link.ShortUrl
+	}
+
+	data, err := json.Marshal(cursor)
+
+	if err != nil {
+		return "", fmt.Errorf("could not encode cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeLinkCursor(listQuery dto.LinkListQuery, encoded string) (*dto.LinkCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var cursor linkCursor
+
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	if cursor.SortBy != listQuery.SortBy || cursor.Direction != listQuery.Direction {
+		return nil, fmt.Errorf("cursor was issued for a different sort order")
+	}
+
+	return &dto.LinkCursor{
+		Value:    cursor.Value,
+		ShortUrl: cursor.ShortUrl,
+	}, nil
+}
+
+// GetUserShortLinks возвращает страницу ссылок пользователя в заданном порядке. Страницы
+// листаются по курсору (keyset), поэтому вставка новых ссылок не сдвигает уже выданные.
+func (s *Service) GetUserShortLinks(ctx context.Context, email string, listQuery dto.LinkListQuery, cursor string) (*dto.LinkPage, *dto.User, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetUserShortLinks")
+
+	defer span.End()
+
 	user, err := s.postgresStorage.GetUser(ctx, email)
 
 	if err != nil {
-		return nil, nil, fmt.Errorf("GetUserShortLinksWithOffsetAndLimit: error while getting user %s: %w", email, err)
+		return nil, nil, fmt.Errorf("GetUserShortLinks: error while getting user %s: %w", email, err)
+	}
+
+	if listQuery.Limit <= 0 || listQuery.Limit > s.rules.MaxPageSize {
+		listQuery.Limit = s.rules.MaxPageSize
 	}
 
-	if limit == 0 || limit > 50 {
-		limit = 50
+	if listQuery.SortBy == "" {
+		listQuery.SortBy = dto.LinkSortCreatedAt
 	}
 
-	links, err := s.postgresStorage.GetUserShortLinksWithOffsetAndLimit(ctx, email, offset, limit)
+	if listQuery.Direction == "" {
+		listQuery.Direction = dto.SortDesc
+	}
+
+	switch listQuery.SortBy {
+	case dto.LinkSortCreatedAt, dto.LinkSortExpiresAt, dto.LinkSortTimesVisited, dto.LinkSortAlias:
+
+	default:
+		return nil, nil, fmt.Errorf("GetUserShortLinks: unknown sort field %s", listQuery.SortBy)
+	}
+
+	if listQuery.Direction != dto.SortAsc && listQuery.Direction != dto.SortDesc {
+		return nil, nil, fmt.Errorf("GetUserShortLinks: unknown sort direction %s", listQuery.Direction)
+	}
+
+	switch listQuery.Filter.Status {
+	case dto.LinkStatusAll, dto.LinkStatusActive, dto.LinkStatusExpired:
+
+	default:
+		return nil, nil, fmt.Errorf("GetUserShortLinks: unknown link status %s", listQuery.Filter.Status)
+	}
+
+	listQuery.Filter.Tag = strings.ToLower(strings.TrimSpace(listQuery.Filter.Tag))
+
+	if cursor != "" {
+		listQuery.After, err = decodeLinkCursor(listQuery, cursor)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("GetUserShortLinks: %w", err)
+		}
+	}
+
+	pageSize := listQuery.Limit
+
+	// запрашиваем на одну ссылку больше, чтобы понять, есть ли следующая страница
+	listQuery.Limit++
+
+	links, err := s.postgresStorage.GetUserShortLinks(ctx, email, listQuery)
 
 	switch {
 	case err == nil:
@@ -331,15 +564,39 @@ func (s *Service) GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email
 	case errors.Is(err, pgx.ErrNoRows):
 
 	default:
-		return nil, nil, fmt.Errorf("GetUserShortLinksWithOffsetAndLimit: error while getting all user's %s shortlinks: %w", email, err)
+		return nil, nil, fmt.Errorf("GetUserShortLinks: error while getting all user's %s shortlinks: %w", email, err)
 	}
 
-	return links, user, nil
+	total, err := s.postgresStorage.GetTotalUserLinksNumber(ctx, email, listQuery.Filter)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("GetUserShortLinks: error while counting user's %s shortlinks: %w", email, err)
+	}
+
+	page := &dto.LinkPage{
+		Links: links,
+		Total: total,
+	}
+
+	if len(links) > pageSize {
+		page.Links = links[:pageSize]
+
+		page.NextCursor, err = encodeLinkCursor(listQuery, page.Links[pageSize-1])
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("GetUserShortLinks: %w", err)
+		}
+	}
 
+	return page, user, nil
 }
 
 func (s *Service) GetTotalUserLinks(ctx context.Context, email string) (int, error) {
-	totalUserLinks, err := s.postgresStorage.GetTotalUserLinksNumber(ctx, email)
+	ctx, span := tracing.Start(ctx, "Service.GetTotalUserLinks")
+
+	defer span.End()
+
+	totalUserLinks, err := s.postgresStorage.GetTotalUserLinksNumber(ctx, email, dto.LinkFilter{})
 
 	if err != nil {
 		return 0, fmt.Errorf("GetTotalUserLinks: %w", err)
@@ -348,8 +605,17 @@ func (s *Service) GetTotalUserLinks(ctx context.Context, email string) (int, err
 	return totalUserLinks, nil
 }
 
+// UpdateUserShortLinks начисляет пользователю deltaLinks ссылок от имени администратора с записью в журнал начислений.
 func (s *Service) UpdateUserShortLinks(ctx context.Context, email string, deltaLinks int) (*dto.User, error) {
-	user, err := s.postgresStorage.UpdateUserLinks(ctx, email, deltaLinks)
+	ctx, span := tracing.Start(ctx, "Service.UpdateUserShortLinks")
+
+	defer span.End()
+
+	user, err := s.creditLinks(ctx, dto.LinkCredit{
+		UserEmail: email,
+		Amount:    deltaLinks,
+		Reason:    dto.LinkCreditAdmin,
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("UpdateUserShortLinks: error while updating user's %s shortlinks: %w by %d", email, err, deltaLinks)
@@ -358,90 +624,134 @@ func (s *Service) UpdateUserShortLinks(ctx context.Context, email string, deltaL
 	return user, nil
 }
 
+// ErrLinkNotFound - ссылки нет или её срок истёк.
+var ErrLinkNotFound = errors.New("short link not found")
+
 func (s *Service) GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error) {
-	link, err := s.redisStorage.GetShortLinkByLongLink(ctx, shortLink)
+	ctx, span := tracing.Start(ctx, "Service.GetShortLink")
+
+	defer span.End()
+
+	link, err := s.cachedShortLink(ctx, shortLink)
 
 	if err != nil {
-		// TODO log
-		link, err = s.postgresStorage.GetShortLink(ctx, shortLink)
+		return nil, fmt.Errorf("GetShortLink: error while getting short link %s: %w", shortLink, err)
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("GetShortLink: error while getting short link %s: %w", shortLink, err)
-		}
+	s.countLinkView(ctx, shortLink, link.UTM.Campaign)
 
-		err = s.redisStorage.SaveShortLinkToLongLink(ctx, *link)
+	if link.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("GetShortLink: short link %s expired: %w", shortLink, ErrLinkNotFound)
+	}
 
-		if err != nil {
-			log.Println(fmt.Errorf("GetShortLink: error while saving short link  to redis %s: %v", shortLink, err).Error())
-		}
+	return link, nil
+
+}
+
+// cachedShortLink читает ссылку из кеша переходов, а при промахе - из Postgres и кладёт её в кеш.
+func (s *Service) cachedShortLink(ctx context.Context, shortLink string) (*dto.Link, error) {
+	link, err := s.redisStorage.GetShortLinkByLongLink(ctx, shortLink)
+
+	switch {
+	case err == nil:
+		metrics.RedirectCache.WithLabelValues(metrics.CacheHit).Inc()
+	case errors.Is(err, redis.Nil):
+		metrics.RedirectCache.WithLabelValues(metrics.CacheMiss).Inc()
+	default:
+		metrics.RedirectCache.WithLabelValues(metrics.CacheError).Inc()
 	}
 
-	if link.ExpiresAt.Before(time.Now()) {
-		go func() {
-			err := s.producer.PublishMsg("increment_link_view", map[string]string{
-				"short_link": shortLink,
-			}, s.producerTopic)
+	if err == nil {
+		return link, nil
+	}
 
-			if err != nil {
-				log.Println(fmt.Errorf("error while publishing to %s topic: %w", s.producerTopic, err).Error())
+	if !errors.Is(err, redis.Nil) {
+		s.logger.WarnContext(ctx, "redirect cache is unavailable, reading from postgres", "short_link", shortLink, "error", err)
+	}
 
-				s.recreateProducer(ctx)
-			}
+	link, err = s.postgresStorage.GetShortLink(ctx, shortLink)
 
-		}()
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = fmt.Errorf("%w: %w", ErrLinkNotFound, err)
+	}
 
-		return nil, fmt.Errorf("GetShortLink: short link %s expired", shortLink)
-	} else {
-		go func() {
-			err := s.producer.PublishMsg("increment_link_view", map[string]string{
-				"short_link": shortLink,
-			}, s.producerTopic)
+	if err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				log.Println(fmt.Errorf("error while publishing to %s topic: %w", s.producerTopic, err).Error())
+	err = s.redisStorage.SaveShortLinkToLongLink(ctx, *link)
 
-				s.recreateProducer(ctx)
-			} else {
-				log.Println("sent to consumer")
-			}
-		}()
+	if err != nil {
+		s.logger.WarnContext(ctx, "error while saving short link to redis", "short_link", shortLink, "error", err)
 	}
 
 	return link, nil
+}
+
+// countLinkView публикует событие о переходе в фоне, не задерживая ответ. Кампания ссылки фиксируется
+// в событии, чтобы переход попал в неё, даже если метку сменят до обработки события.
+func (s *Service) countLinkView(ctx context.Context, shortLink string, campaign string) {
+	s.viewsWG.Add(1)
+
+	go func() {
+		defer s.viewsWG.Done()
 
+		s.publishLinkView(context.WithoutCancel(ctx), shortLink, campaign)
+	}()
 }
 
-func (s *Service) recreateProducer(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Second)
+// publishLinkView отправляет событие о переходе по ссылке. Контекст запроса сюда не передаётся:
+// ответ уйдёт раньше, чем придёт отчёт о доставке.
+func (s *Service) publishLinkView(ctx context.Context, shortLink string, campaign string) {
+	event, err := events.New(dto.EventLinkViewed, dto.LinkViewed{ShortLink: shortLink, Campaign: campaign})
 
-	var err error
+	if err == nil {
+		err = s.publishEvent(ctx, shortLink, event)
+	}
 
-innerLoop:
-	for {
-		select {
-		case <-ctx.Done():
-			// TODO close consumers
-			return
-		case <-ticker.C:
-			s.producer, err = kafkaProducerConsumer.NewProducer(s.producer.GetConfig())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error while publishing link view", "topic", s.producerTopic, "short_link", shortLink, "error", err)
+	}
+}
 
-			if err == nil {
-				ticker.Stop()
+// publishEvent публикует событие в топик сервиса и учитывает его в метриках очереди.
+// Очередь передаёт контекст трассировки из ctx в заголовках сообщения.
+func (s *Service) publishEvent(ctx context.Context, key string, event dto.Event) error {
+	ctx, span := tracing.Start(ctx, "publish "+s.producerTopic, trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("event.type", string(event.Type)), attribute.String("message.key", key)))
 
-				break innerLoop
-			}
-		}
+	defer span.End()
+
+	if event.RequestId == "" {
+		event.RequestId = logging.RequestID(ctx)
 	}
+
+	err := s.producer.PublishEvent(ctx, key, event, s.producerTopic)
+
+	tracing.RecordError(span, err)
+
+	result := "ok"
+
+	if err != nil {
+		result = "error"
+	}
+
+	metrics.QueueProduced.WithLabelValues(s.producerTopic, result).Inc()
+
+	return err
 }
 
 func (s *Service) DeleteShortLink(ctx context.Context, shortLink string, email string) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteShortLink")
+
+	defer span.End()
+
 	link, err := s.postgresStorage.GetShortLink(ctx, shortLink)
 
 	if err != nil {
 		return fmt.Errorf("DeleteShortLink: error while getting short link %s: %w", shortLink, err)
 	}
 
-	fmt.Println(link.UserEmail, email)
 	if link.UserEmail != email {
 		return fmt.Errorf("DeleteShortLink: short link %s does not match email %s", shortLink, email)
 	}
@@ -452,26 +762,129 @@ func (s *Service) DeleteShortLink(ctx context.Context, shortLink string, email s
 		return fmt.Errorf("DeleteShortLink: error while deleting short link %s with email %s: %w", shortLink, email, err)
 	}
 
-	err = s.redisStorage.DeleteLongLinkByShortLink(ctx, shortLink)
+	return nil
+}
+
+const (
+	maxLinkTitleLength       = 100
+	maxLinkDescriptionLength = 1000
+	maxLinkTags              = 10
+	maxTagLength             = 30
+)
+
+// normalizeTags приводит теги к нижнему регистру, убирает дубликаты и проверяет их формат.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if tag == "" {
+			continue
+		}
+
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %s is longer than %d characters", tag, maxTagLength)
+		}
+
+		for _, char := range tag {
+			if !unicode.IsLetter(char) && !unicode.IsDigit(char) && char != '-' && char != '_' {
+				return nil, fmt.Errorf("tag %s contains invalid character %q", tag, char)
+			}
+		}
+
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxLinkTags {
+		return nil, fmt.Errorf("link cannot have more than %d tags", maxLinkTags)
+	}
+
+	return normalized, nil
+}
+
+func (s *Service) UpdateShortLinkInfo(ctx context.Context, shortLink string, email string, title string, description string, tags []string) (*dto.Link, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateShortLinkInfo")
+
+	defer span.End()
+
+	title = strings.TrimSpace(title)
+	description = strings.TrimSpace(description)
+
+	if utf8.RuneCountInString(title) > maxLinkTitleLength {
+		return nil, fmt.Errorf("UpdateShortLinkInfo: title is longer than %d characters", maxLinkTitleLength)
+	}
+
+	if utf8.RuneCountInString(description) > maxLinkDescriptionLength {
+		return nil, fmt.Errorf("UpdateShortLinkInfo: description is longer than %d characters", maxLinkDescriptionLength)
+	}
+
+	tags, err := normalizeTags(tags)
 
 	if err != nil {
-		log.Println(fmt.Errorf("DeleteShortLink: error while deleting short link from redis %s: %w", shortLink, err).Error())
+		return nil, fmt.Errorf("UpdateShortLinkInfo: %w", err)
 	}
 
-	err = s.searcher.DeleteShortLink(ctx, shortLink)
+	link, err := s.postgresStorage.GetShortLink(ctx, shortLink)
 
 	if err != nil {
-		log.Println(fmt.Errorf("DeleteShortLink: error while deleting short link from elastic %s: %w", shortLink, err).Error())
+		return nil, fmt.Errorf("UpdateShortLinkInfo: error while getting short link %s: %w", shortLink, err)
 	}
 
-	return nil
+	if link.UserEmail != email {
+		return nil, fmt.Errorf("UpdateShortLinkInfo: short link %s does not belong to user", shortLink)
+	}
+
+	err = s.postgresStorage.UpdateShortLinkInfo(ctx, shortLink, title, description, tags)
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateShortLinkInfo: error while updating short link %s: %w", shortLink, err)
+	}
+
+	link, err = s.postgresStorage.GetShortLink(ctx, shortLink)
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateShortLinkInfo: error while getting updated short link %s: %w", shortLink, err)
+	}
+
+	return link, nil
 }
 
-func (s *Service) CreateSubscriptions(ctx context.Context) error {
-	err := s.postgresStorage.CreateSubscriptions(ctx)
+func (s *Service) GetUserTags(ctx context.Context, email string) ([]dto.Tag, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetUserTags")
+
+	defer span.End()
+
+	tags, err := s.postgresStorage.GetUserTags(ctx, email)
 
 	if err != nil {
-		return fmt.Errorf("CreateSubscriptions: error while creating subscriptions %w", err)
+		return nil, fmt.Errorf("GetUserTags: could not get tags %w", err)
+	}
+
+	return tags, nil
+}
+
+func (s *Service) DeleteUserTag(ctx context.Context, email string, tag string) error {
+	ctx, span := tracing.Start(ctx, "Service.DeleteUserTag")
+
+	defer span.End()
+
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	if tag == "" {
+		return fmt.Errorf("DeleteUserTag: tag is empty")
+	}
+
+	err := s.postgresStorage.DeleteUserTag(ctx, email, tag)
+
+	if err != nil {
+		return fmt.Errorf("DeleteUserTag: error while deleting tag %s: %w", tag, err)
 	}
 
 	return nil
@@ -495,8 +908,14 @@ func GenerateShortLink() string {
 }
 
 func (s *Service) StartConsumers(ctx context.Context) {
+	ctx, s.stopConsumers = context.WithCancel(ctx)
+
 	for i := 0; i < len(s.consumers); i++ {
+		s.consumersWG.Add(1)
+
 		go func(i int) {
+			defer s.consumersWG.Done()
+
 			for {
 				err := s.consumers[i].StartConsuming(ctx)
 
@@ -504,24 +923,24 @@ func (s *Service) StartConsumers(ctx context.Context) {
 					return
 				}
 
-				log.Println("error while running kafka consumer", err)
+				s.logger.ErrorContext(ctx, "error while running consumer, recreating it", "error", err)
 
 				ticker := time.NewTicker(5 * time.Second)
 
-				log.Println("recreating kafka consumer...")
 			innerLoop:
 				for {
 					select {
 					case <-ctx.Done():
-						// TODO close consumers
+						ticker.Stop()
+
 						return
 					case <-ticker.C:
-						s.consumers[i], err = kafkaProducerConsumer.NewConsumer(s.consumers[i].GetConfig(), s.consumers[i].GetWorkerChannel())
+						err = s.consumers[i].Reconnect()
 
 						if err == nil {
 							ticker.Stop()
 
-							log.Println("recreated kafka consumer")
+							s.logger.InfoContext(ctx, "recreated consumer")
 							break innerLoop
 						}
 					}
@@ -532,107 +951,239 @@ func (s *Service) StartConsumers(ctx context.Context) {
 	}
 }
 
-func (s *Service) StartConsumingWorkers(ctx context.Context, n int, workerChannel chan dto.ConsumerData) {
-	for i := 0; i < n; i++ {
+// WorkersConfig задаёт обработку сообщений из Kafka.
+type WorkersConfig struct {
+	Number int
+	// MaxAttempts - сколько раз обрабатывать сообщение, прежде чем отправить его в DeadLetterTopic.
+	MaxAttempts     int
+	DeadLetterTopic string
+}
+
+const (
+	processMinBackoff = 100 * time.Millisecond
+	processMaxBackoff = 5 * time.Second
+)
+
+// StartConsumingWorkers запускает воркеры. Они работают, пока Shutdown не закроет workerChannel
+// и они не обработают оставшиеся в нём сообщения, или пока не отменён ctx.
+func (s *Service) StartConsumingWorkers(ctx context.Context, cfg WorkersConfig, workerChannel chan dto.ConsumerMessage) {
+	ctx, s.stopWorkers = context.WithCancel(ctx)
+
+	s.workerChannel = workerChannel
+
+	for i := 0; i < cfg.Number; i++ {
+		s.workersWG.Add(1)
+
 		go func() {
-			for {
-				err := s.startWorker(ctx, workerChannel)
+			defer s.workersWG.Done()
 
-				if err == nil {
-					return
-				}
-			}
+			s.startWorker(ctx, cfg, workerChannel)
 		}()
 	}
 }
 
-func (s *Service) startWorker(ctx context.Context, workerChannel chan dto.ConsumerData) error {
+func (s *Service) startWorker(ctx context.Context, cfg WorkersConfig, workerChannel chan dto.ConsumerMessage) {
 	for {
 		select {
 		case <-ctx.Done():
-			return nil
-		case data := <-workerChannel:
-			err := s.processData(ctx, data.TypeOfMessage, data.Data)
-
-			if err != nil {
-				log.Println(fmt.Errorf("error while processing data: %w", err))
-
-				return fmt.Errorf("startWorker: error while processing data: %w", err)
+			return
+		case msg, ok := <-workerChannel:
+			if !ok {
+				return
 			}
+
+			s.HandleMessage(ctx, cfg, msg)
 		}
 	}
-
 }
 
-func (s *Service) processData(ctx context.Context, dataType string, data map[string]string) error {
-	log.Printf("data type: %s, data: %v\n", dataType, data)
-	switch dataType {
-	case "delete_expired_link":
-		shortLink, ok := data["short_link"]
+// HandleMessage обрабатывает сообщение с повторами и подтверждает его. Сообщение, которое так и не удалось
+// обработать, подтверждается только после записи в очередь недоставленных, поэтому не теряется.
+func (s *Service) HandleMessage(ctx context.Context, cfg WorkersConfig, msg dto.ConsumerMessage) {
+	// обработка продолжает трейс, в котором сообщение было отправлено
+	ctx, span := tracing.Start(tracing.Extract(ctx, msg.Headers), "process "+msg.Topic, trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("message.key", string(msg.Key)), attribute.Int64("message.offset", msg.Offset)))
 
-		if !ok {
-			return fmt.Errorf("processData: invalid data type for delete_expired_link")
-		}
+	defer span.End()
 
-		err := s.redisStorage.DeleteLongLinkByShortLink(ctx, shortLink)
+	ctx = logging.WithRequestID(ctx, events.RequestID(msg.Value))
 
-		if err != nil {
-			return fmt.Errorf("processData: error while deleting short link from redis: %w", err)
-		}
+	attempts, err := s.processWithRetries(ctx, cfg.MaxAttempts, msg)
 
-		err = s.postgresStorage.DeleteShortLink(ctx, shortLink)
+	span.SetAttributes(attribute.Int("message.attempts", attempts))
 
-		if err != nil {
-			return fmt.Errorf("processData: error while deleting short link from postgres: %w", err)
-		}
+	tracing.RecordError(span, err)
 
-		err = s.searcher.DeleteShortLink(ctx, shortLink)
+	if err == nil {
+		msg.Ack()
 
-		if err != nil {
-			log.Println(fmt.Errorf("DeleteShortLink: error while deleting short link from searcher %s: %w", shortLink, err).Error())
+		metrics.QueueConsumed.WithLabelValues(msg.Topic, metrics.ConsumeProcessed).Inc()
+
+		return
+	}
+
+	// при остановке сервиса сообщение не подтверждается и будет прочитано заново
+	if ctx.Err() != nil {
+		metrics.QueueConsumed.WithLabelValues(msg.Topic, metrics.ConsumeInterrupted).Inc()
+
+		return
+	}
+
+	s.logger.WarnContext(ctx, "message failed, sending to dead letter topic", "topic", msg.Topic, "partition", msg.Partition,
+		"offset", msg.Offset, "attempts", attempts, "dead_letter_topic", cfg.DeadLetterTopic, "error", err)
+
+	letter := dto.DeadLetter{Message: msg, Error: err.Error(), Attempts: attempts}
+
+	for backoff := processMinBackoff; ; backoff = min(backoff*2, processMaxBackoff) {
+		err = s.producer.PublishDeadLetter(ctx, cfg.DeadLetterTopic, letter)
+
+		if err == nil {
+			msg.Ack()
+
+			metrics.QueueConsumed.WithLabelValues(msg.Topic, metrics.ConsumeDeadLetter).Inc()
+			metrics.QueueProduced.WithLabelValues(cfg.DeadLetterTopic, "ok").Inc()
+
+			return
 		}
 
-		return nil
-	case "increment_link_view":
-		shortLink, ok := data["short_link"]
+		metrics.QueueProduced.WithLabelValues(cfg.DeadLetterTopic, "error").Inc()
+
+		s.logger.ErrorContext(ctx, "error while publishing to dead letter topic", "dead_letter_topic", cfg.DeadLetterTopic, "error", err)
 
-		if !ok {
-			return fmt.Errorf("processData: invalid data type for delete_expired_link")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
 		}
+	}
+}
 
-		err := s.postgresStorage.IncrementShortLinkTimesWatchedCount(ctx, shortLink)
+func (s *Service) processWithRetries(ctx context.Context, maxAttempts int, msg dto.ConsumerMessage) (int, error) {
+	backoff := processMinBackoff
 
-		if err != nil {
-			return fmt.Errorf("processData: error while incrementing short link times: %w", err)
+	for attempt := 1; ; attempt++ {
+		err := s.events.Dispatch(ctx, msg.Value)
+
+		if err == nil || errors.Is(err, events.ErrMalformed) || attempt >= maxAttempts {
+			return attempt, err
 		}
 
-		return nil
-	default:
-		return nil
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, processMaxBackoff)
 	}
 }
 
-func (s *Service) GetShortLinksMatchingPattern(ctx context.Context, containsWord string, offset int) (dto.SearcherMatchResult, error) {
+func (s *Service) handleLinkViewed(ctx context.Context, _ dto.Event, payload dto.LinkViewed) error {
+	if payload.ShortLink == "" {
+		return fmt.Errorf("handleLinkViewed: %w, no short_link", events.ErrMalformed)
+	}
+
+	err := s.postgresStorage.IncrementShortLinkTimesWatchedCount(ctx, payload.ShortLink, payload.Campaign)
+
+	if err != nil {
+		return fmt.Errorf("handleLinkViewed: error while incrementing short link times: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) handleLinkExpired(ctx context.Context, _ dto.Event, payload dto.LinkExpired) error {
+	if payload.ShortLink == "" {
+		return fmt.Errorf("handleLinkExpired: %w, no short_link", events.ErrMalformed)
+	}
+
+	err := s.postgresStorage.DeleteShortLink(ctx, payload.ShortLink)
+
+	if err != nil {
+		return fmt.Errorf("handleLinkExpired: error while deleting short link from postgres: %w", err)
+	}
+
+	return nil
+}
+
+// GetShortLinksMatchingPattern ищет ссылки пользователя email; при global ищет по ссылкам всех пользователей.
+// Найденные ссылки дочитываются из Postgres, чтобы отдавать актуальные данные, а не копию из индекса.
+func (s *Service) GetShortLinksMatchingPattern(ctx context.Context, email string, global bool, containsWord string, offset int) (dto.SearcherMatchResult, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetShortLinksMatchingPattern")
+
+	defer span.End()
+
 	if offset < 0 {
 		return dto.SearcherMatchResult{}, nil
 	}
 
-	limit := 20
+	searchQuery := dto.SearchQuery{
+		Text:   containsWord,
+		Limit:  s.rules.SearchLimit,
+		Offset: offset,
+	}
+
+	if !global {
+		searchQuery.OwnerEmail = email
+	}
 
-	shortLinks, err := s.searcher.SearchShortLinks(ctx, containsWord, limit, offset)
+	searchResult, err := s.searcher.SearchShortLinks(ctx, searchQuery)
 
 	if err != nil {
 		return dto.SearcherMatchResult{}, fmt.Errorf("GetShortLinksMatchingPattern: error while searching short links: %w", err)
 	}
 
-	return dto.SearcherMatchResult{
-		Limit:      limit,
-		Offset:     offset,
-		ShortLinks: shortLinks,
-	}, nil
+	matchResult := dto.SearcherMatchResult{
+		Limit:  s.rules.SearchLimit,
+		Offset: offset,
+		Total:  searchResult.Total,
+		Links:  make([]dto.LinkMatch, 0, len(searchResult.Hits)),
+	}
+
+	if len(searchResult.Hits) == 0 {
+		return matchResult, nil
+	}
+
+	shortLinks := make([]string, 0, len(searchResult.Hits))
+
+	for _, hit := range searchResult.Hits {
+		shortLinks = append(shortLinks, hit.ShortUrl)
+	}
+
+	links, err := s.postgresStorage.GetShortLinksByShortUrls(ctx, shortLinks)
+
+	if err != nil {
+		return dto.SearcherMatchResult{}, fmt.Errorf("GetShortLinksMatchingPattern: error while getting found short links: %w", err)
+	}
+
+	linksByShortUrl := make(map[string]dto.Link, len(links))
+
+	for _, link := range links {
+		linksByShortUrl[link.ShortUrl] = link
+	}
+
+	// сохраняем порядок релевантности; ссылки, удалённые после индексации, и чужие ссылки пропускаем
+	for _, hit := range searchResult.Hits {
+		link, ok := linksByShortUrl[hit.ShortUrl]
+
+		if !ok || (!global && link.UserEmail != email) {
+			continue
+		}
+
+		matchResult.Links = append(matchResult.Links, dto.LinkMatch{
+			Link:       link,
+			Highlights: hit.Highlights,
+		})
+	}
+
+	return matchResult, nil
 }
 
 func (s *Service) LoginUserWithCode(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "Service.LoginUserWithCode")
+
+	defer span.End()
+
 	email = strings.TrimSpace(email)
 
 	if len(email) == 0 {
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"unicode"
+	"urleater/dto"
+	"urleater/internal/tracing"
+)
+
+// maxUTMLength - длина одной метки кампании в символах.
+const maxUTMLength = 200
+
+// ErrInvalidUTM - метки кампании не заполнены или содержат недопустимые символы.
+var ErrInvalidUTM = errors.New("invalid utm tags")
+
+// NormalizeUTM обрезает пробелы у меток и проверяет их. Пустые метки допустимы: у ссылки нет кампании.
+// Иначе обязательны источник и кампания, как в utm_source и utm_campaign.
+func NormalizeUTM(utm dto.UTM) (dto.UTM, error) {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"source", &utm.Source},
+		{"medium", &utm.Medium},
+		{"campaign", &utm.Campaign},
+		{"term", &utm.Term},
+		{"content", &utm.Content},
+	}
+
+	for _, field := range fields {
+		name, value := field.name, field.value
+
+		*value = strings.TrimSpace(*value)
+
+		if len([]rune(*value)) > maxUTMLength {
+			return utm, fmt.Errorf("%s is longer than %d characters %w", name, maxUTMLength, ErrInvalidUTM)
+		}
+
+		if strings.IndexFunc(*value, unicode.IsControl) >= 0 {
+			return utm, fmt.Errorf("%s contains control characters %w", name, ErrInvalidUTM)
+		}
+	}
+
+	if utm == (dto.UTM{}) {
+		return utm, nil
+	}
+
+	if utm.Source == "" || utm.Campaign == "" {
+		return utm, fmt.Errorf("source and campaign are required %w", ErrInvalidUTM)
+	}
+
+	return utm, nil
+}
+
+// UpdateShortLinkUTM задаёт метки кампании ссылки пользователя. Пустые метки убирают ссылку из кампании.
+func (s *Service) UpdateShortLinkUTM(ctx context.Context, shortLink string, email string, utm dto.UTM) (*dto.Link, error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateShortLinkUTM")
+
+	defer span.End()
+
+	utm, err := NormalizeUTM(utm)
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateShortLinkUTM: %w", err)
+	}
+
+	link, err := s.postgresStorage.GetShortLink(ctx, shortLink)
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), err == nil && link.UserEmail != email:
+		return nil, fmt.Errorf("UpdateShortLinkUTM: short link %s %w", shortLink, ErrLinkNotFound)
+
+	case err != nil:
+		return nil, fmt.Errorf("UpdateShortLinkUTM: error while getting short link %s: %w", shortLink, err)
+	}
+
+	if err = s.postgresStorage.UpdateShortLinkUTM(ctx, shortLink, utm); err != nil {
+		return nil, fmt.Errorf("UpdateShortLinkUTM: error while updating short link %s: %w", shortLink, err)
+	}
+
+	link, err = s.postgresStorage.GetShortLink(ctx, shortLink)
+
+	if err != nil {
+		return nil, fmt.Errorf("UpdateShortLinkUTM: error while getting updated short link %s: %w", shortLink, err)
+	}
+
+	return link, nil
+}
+
+// GetCampaignStats возвращает переходы по ссылкам пользователя, сгруппированные по кампаниям.
+func (s *Service) GetCampaignStats(ctx context.Context, email string) ([]dto.CampaignStats, error) {
+	ctx, span := tracing.Start(ctx, "Service.GetCampaignStats")
+
+	defer span.End()
+
+	stats, err := s.postgresStorage.GetUserCampaignStats(ctx, email)
+
+	if err != nil {
+		return nil, fmt.Errorf("GetCampaignStats: error while getting campaign stats for %s: %w", email, err)
+	}
+
+	return stats, nil
+}
+
+// withUTM дописывает метки кампании к запросу длинной ссылки rawQuery. Метки заменяют одноимённые параметры ссылки.
+func withUTM(rawQuery string, utm dto.UTM) string {
+	params := utm.Params()
+
+	rawQuery = withoutParams(rawQuery, params)
+
+	if rawQuery == "" {
+		return params.Encode()
+	}
+
+	return rawQuery + "&" + params.Encode()
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// EchoMiddleware начинает серверный спан на каждый запрос, продолжая трейс из заголовка traceparent.
+// Спан называется по шаблону маршрута, а ctx запроса заменяется на ctx со спаном, чтобы его продолжили сервис и хранилища.
+func EchoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		request := c.Request()
+
+		ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+
+		route := c.Path()
+
+		name := request.Method
+
+		if route != "" {
+			name += " " + route
+		}
+
+		ctx, span := Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(request.URL.Path),
+			),
+		)
+
+		defer span.End()
+
+		c.SetRequest(request.WithContext(ctx))
+
+		err := next(c)
+
+		status := c.Response().Status
+
+		if err != nil {
+			var httpErr *echo.HTTPError
+
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			} else {
+				status = http.StatusInternalServerError
+			}
+
+			span.RecordError(err)
+		}
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		return err
+	}
+}
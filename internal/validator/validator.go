@@ -12,7 +12,6 @@ type CustomValidator struct {
 }
 
 func (cv *CustomValidator) Validate(i interface{}) error {
-	fmt.Println()
 	if err := cv.validator.Struct(i); err != nil {
 		return fmt.Errorf("error while validation data | %w", err)
 	}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"urleater/internal/handlers"
 	"urleater/internal/service"
@@ -44,6 +45,22 @@ func (s *BaseSuite) MakeRequestWithBody(method string, f Handler, jsonString str
 	return rec.Body.Bytes(), rec.Code
 }
 
+func (s *BaseSuite) MakeRequestWithQuery(method string, f Handler, query url.Values) ([]byte, int) {
+	e := echo.New()
+
+	req := httptest.NewRequest(method, "http://localhost/?"+query.Encode(), nil)
+
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	err := f(c)
+
+	s.NoError(err)
+
+	return rec.Body.Bytes(), rec.Code
+}
+
 func (s *BaseSuite) RegisterUser(data *handlers.RegisterRequest) ([]byte, int) {
 	res, err := json.Marshal(data)
 	s.NoError(err)
@@ -65,6 +82,21 @@ func (s *BaseSuite) CreateShortLink(data *handlers.CreateShortLinkRequest) ([]by
 	return s.MakeRequestWithBody(http.MethodPost, s.Handlers.CreateShortLink, string(res))
 }
 
+func (s *BaseSuite) UpdateShortLinkInfo(data *handlers.UpdateShortLinkInfoRequest) ([]byte, int) {
+	res, err := json.Marshal(data)
+	s.NoError(err)
+
+	return s.MakeRequestWithBody(http.MethodPut, s.Handlers.UpdateShortLinkInfo, string(res))
+}
+
+func (s *BaseSuite) GetUserShortLinks(query url.Values) ([]byte, int) {
+	return s.MakeRequestWithQuery(http.MethodGet, s.Handlers.GetUserShortLinks, query)
+}
+
+func (s *BaseSuite) SearchLinks(query url.Values) ([]byte, int) {
+	return s.MakeRequestWithQuery(http.MethodGet, s.Handlers.GetShortLinksMatchingPattern, query)
+}
+
 func (s *BaseSuite) FinishSetupTest(
 	postgresStorage service.PostgresStorage,
 	redisStorage service.RedisStorage,
@@ -72,7 +104,7 @@ func (s *BaseSuite) FinishSetupTest(
 	consumers []service.Consumer,
 	producer service.Producer,
 	mockSessionStore handlers.SessionStore) {
-	httpSegSvc := service.New(postgresStorage, redisStorage, producer, consumers, elasticSearcher, "")
+	httpSegSvc := service.New(postgresStorage, redisStorage, producer, consumers, elasticSearcher, "", nil, service.LinkRules{})
 
 	hndls := handlers.Handlers{
 		Service: httpSegSvc,
@@ -0,0 +1,160 @@
+package config_loading
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"time"
+	"urleater/internal/config"
+)
+
+func (s *configSuite) TestPrecedence() {
+	path := s.file("config.yaml", requiredYAML+`
+http_address: ":9090"
+link_ttl: 720h
+alias_min_length: 6
+reserved_names: [admin, api]
+`)
+
+	// 1
+	cfg, err := config.Load(path)
+
+	s.Require().NoError(err)
+
+	s.Equal(":9090", cfg.HTTP.Address)
+	s.Equal(720*time.Hour, cfg.Links.TTL)
+	s.Equal(6, cfg.Links.AliasMinLength)
+	s.Equal([]string{"admin", "api"}, cfg.Links.ReservedNames)
+	s.Equal("db", cfg.DB.PostgresHost)
+	s.Equal(20, cfg.Links.AliasMaxLength)
+	s.Equal(10*time.Second, cfg.Kafka.SessionTimeout)
+
+	// 2
+	s.T().Setenv("HTTP_ADDRESS", ":7070")
+	s.T().Setenv("ALIAS_MIN_LENGTH", "10")
+
+	cfg, err = config.Load(path)
+
+	s.Require().NoError(err)
+
+	s.Equal(":7070", cfg.HTTP.Address)
+	s.Equal(10, cfg.Links.AliasMinLength)
+	s.Equal(720*time.Hour, cfg.Links.TTL)
+
+	// 3
+	_, ok := os.LookupEnv("LINK_TTL")
+
+	s.False(ok)
+}
+
+func (s *configSuite) TestTOML() {
+	path := s.file("config.toml", `
+session_secret = "0123456789abcdef0123"
+queue_backend = "memory"
+search_backend = "postgres"
+links_max_page_size = 25
+
+[postgres]
+host = "db"
+port = 5432
+user = "urleater"
+password = "pg-password"
+database = "urleater"
+
+[redis]
+host = "redis"
+port = 6379
+`)
+
+	// 1
+	cfg, err := config.Load(path)
+
+	s.Require().NoError(err)
+
+	s.Equal(25, cfg.Links.MaxPageSize)
+	s.Equal("5432", cfg.DB.PostgresPort)
+}
+
+func (s *configSuite) TestInvalidConfig() {
+	// 1
+	_, err := config.Load(s.file("typo.yaml", requiredYAML+"alias_min_lenght: 6\n"))
+
+	s.ErrorContains(err, `unknown key "alias_min_lenght"`)
+
+	// 2
+	_, err = config.Load(s.file("config.json", "{}"))
+
+	s.ErrorContains(err, "must have .yaml, .yml or .toml extension")
+
+	// 3
+	s.T().Setenv("SESSION_SECRET", "short")
+	s.T().Setenv("QUEUE_BACKEND", "kafka")
+
+	_, err = config.Load(s.file("invalid.yaml", requiredYAML+`
+http_address: "8080"
+alias_min_length: 30
+kafka_heartbeat_interval: 20s
+admin_emails: [admin@admin.com, admin]
+`))
+
+	s.Require().Error(err)
+
+	s.ErrorContains(err, "HTTP_ADDRESS")
+	s.ErrorContains(err, "SESSION_SECRET must be at least 16 characters")
+	s.ErrorContains(err, "ALIAS_MIN_LENGTH (30) must not exceed ALIAS_MAX_LENGTH (20)")
+	s.ErrorContains(err, "KAFKA_ADDRESS and KAFKA_GROUP_ID are required")
+	s.ErrorContains(err, "KAFKA_HEARTBEAT_INTERVAL")
+	s.ErrorContains(err, `ADMIN_EMAILS: "admin" is not an email`)
+	s.NotContains(err.Error(), "admin@admin.com")
+}
+
+func (s *configSuite) TestPostgresOnly() {
+	withoutRedis := strings.Replace(requiredYAML, "redis:\n  host: redis\n  port: 6379\n", "", 1)
+
+	// 1
+	_, err := config.Load(s.file("redis.yaml", withoutRedis))
+
+	s.ErrorContains(err, "REDIS_HOST and REDIS_PORT are required for the redis cache backend")
+
+	// 2
+	cfg, err := config.Load(s.file("memory.yaml", withoutRedis+"cache_backend: memory\n"))
+
+	s.Require().NoError(err)
+
+	s.Equal(config.CacheBackendMemory, cfg.Cache.Backend)
+
+	// 3
+	_, err = config.Load(s.file("unknown.yaml", withoutRedis+"cache_backend: memcached\n"))
+
+	s.ErrorContains(err, `unknown CACHE_BACKEND "memcached"`)
+}
+
+func (s *configSuite) TestDumpMasksSecrets() {
+	cfg, err := config.Load(s.file("config.yaml", requiredYAML))
+
+	s.Require().NoError(err)
+
+	var out bytes.Buffer
+
+	// 1
+	s.Require().NoError(cfg.Dump(&out))
+
+	s.Contains(out.String(), "postgres_host: db\n")
+	s.Contains(out.String(), "link_ttl: 2160h0m0s\n")
+	s.Contains(out.String(), "postgres_password: '******'\n")
+	s.Contains(out.String(), "session_secret: '******'\n")
+	s.NotContains(out.String(), "pg-password")
+	s.NotContains(out.String(), "0123456789abcdef0123")
+
+	// 2
+	dump := strings.NewReplacer(
+		"session_secret: '******'", "session_secret: 0123456789abcdef0123",
+		"postgres_password: '******'", "postgres_password: pg-password",
+	).Replace(out.String())
+
+	reloaded, err := config.Load(s.file("dump.yaml", dump))
+
+	s.Require().NoError(err)
+
+	s.Equal(cfg, reloaded)
+}
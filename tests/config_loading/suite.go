@@ -0,0 +1,51 @@
+package config_loading
+
+import (
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+)
+
+// requiredYAML содержит обязательные параметры, без которых конфигурация не загрузится.
+const requiredYAML = `
+postgres:
+  host: db
+  port: 5432
+  user: urleater
+  password: pg-password
+  database: urleater
+redis:
+  host: redis
+  port: 6379
+session_secret: 0123456789abcdef0123
+queue_backend: memory
+search_backend: postgres
+`
+
+type configSuite struct {
+	suite.Suite
+
+	dir string
+}
+
+func (s *configSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+
+	// значения из окружения разработчика не должны влиять на тесты
+	for _, name := range []string{"HTTP_ADDRESS", "LINK_TTL", "ALIAS_MIN_LENGTH", "ALIAS_MAX_LENGTH", "RESERVED_NAMES", "SESSION_SECRET", "QUEUE_BACKEND", "SEARCH_BACKEND", "CACHE_BACKEND", "REDIS_HOST", "REDIS_PORT", "ADMIN_EMAILS"} {
+		if value, ok := os.LookupEnv(name); ok {
+			s.Require().NoError(os.Unsetenv(name))
+
+			s.T().Cleanup(func() { _ = os.Setenv(name, value) })
+		}
+	}
+}
+
+// file записывает файл конфигурации name с содержимым content и возвращает путь к нему.
+func (s *configSuite) file(name string, content string) string {
+	path := filepath.Join(s.dir, name)
+
+	s.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
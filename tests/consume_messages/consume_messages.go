@@ -0,0 +1,117 @@
+package consume_messages
+
+import (
+	"context"
+	"errors"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/mock"
+	"urleater/dto"
+	kafkaProducerConsumer "urleater/internal/repository/kafka"
+)
+
+func message(offset int64, value string, acked *int) dto.ConsumerMessage {
+	return dto.ConsumerMessage{
+		Topic:     "links",
+		Partition: 0,
+		Offset:    offset,
+		Key:       []byte("link1"),
+		Value:     []byte(value),
+		Ack: func() {
+			*acked++
+		},
+	}
+}
+
+func (s *consumeMessagesSuite) TestHandleMessage() {
+	ctx := context.Background()
+
+	// 1
+	acked := 0
+
+	s.storage.On("IncrementShortLinkTimesWatchedCount", mock.Anything, "link1", "").Return(errors.New("connection reset")).Once()
+	s.storage.On("IncrementShortLinkTimesWatchedCount", mock.Anything, "link1", "").Return(nil).Once()
+
+	s.service.HandleMessage(ctx, s.config, message(1, `{"id":"e1","type":"link_viewed","version":1,"producer":"urleater","data":{"short_link":"link1"}}`, &acked))
+
+	s.Equal(1, acked)
+
+	// 2
+	acked = 0
+
+	s.storage.On("DeleteShortLink", mock.Anything, "link2").Return(errors.New("database is down")).Times(3)
+
+	s.producer.On("PublishDeadLetter", mock.Anything, "links_dlq", mock.MatchedBy(func(letter dto.DeadLetter) bool {
+		return letter.Attempts == 3 && letter.Message.Offset == 2
+	})).Return(nil).Once()
+
+	// сообщение старого формата без версии
+	s.service.HandleMessage(ctx, s.config, message(2, `{"type":"delete_expired_link","data":{"short_link":"link2"}}`, &acked))
+
+	s.Equal(1, acked)
+
+	// 3
+	acked = 0
+
+	s.producer.On("PublishDeadLetter", mock.Anything, "links_dlq", mock.MatchedBy(func(letter dto.DeadLetter) bool {
+		return letter.Attempts == 1 && letter.Message.Offset == 3
+	})).Return(errors.New("broker unavailable")).Once()
+
+	s.producer.On("PublishDeadLetter", mock.Anything, "links_dlq", mock.MatchedBy(func(letter dto.DeadLetter) bool {
+		return letter.Attempts == 1 && letter.Message.Offset == 3
+	})).Return(nil).Once()
+
+	s.service.HandleMessage(ctx, s.config, message(3, `not json`, &acked))
+
+	s.Equal(1, acked)
+
+	// 4
+	acked = 0
+
+	s.storage.On("IncrementShortLinkTimesWatchedCount", mock.Anything, "link4", "").Return(nil).Once()
+
+	s.service.HandleMessage(ctx, s.config, message(4, `{"id":"e4","type":"link_viewed","version":3,"data":{"short_link":"link4","referrer":"t.me"}}`, &acked))
+
+	s.Equal(1, acked)
+
+	// 5
+	acked = 0
+
+	s.service.HandleMessage(ctx, s.config, message(5, `{"id":"e5","type":"link_archived","version":1,"data":{"short_link":"link5"}}`, &acked))
+
+	s.Equal(1, acked)
+}
+
+func (s *consumeMessagesSuite) TestOffsetTracker() {
+	tracker := kafkaProducerConsumer.NewOffsetTracker()
+
+	ack5 := tracker.Track("links", 0, 5)
+	ack6 := tracker.Track("links", 0, 6)
+	ack7 := tracker.Track("links", 0, 7)
+
+	// 1
+	ack6()
+	ack7()
+
+	s.Empty(tracker.Committable())
+
+	// 2
+	ack5()
+
+	committable := tracker.Committable()
+
+	s.Len(committable, 1)
+	s.Equal(kafka.Offset(8), committable[0].Offset)
+
+	tracker.MarkCommitted(committable)
+
+	s.Empty(tracker.Committable())
+
+	// 3
+	ack8 := tracker.Track("links", 0, 8)
+
+	tracker.Forget(committable)
+
+	ack8()
+
+	s.Empty(tracker.Committable())
+}
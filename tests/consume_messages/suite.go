@@ -0,0 +1,29 @@
+package consume_messages
+
+import (
+	"github.com/stretchr/testify/suite"
+	"urleater/internal/service"
+	"urleater/tests/mocks"
+)
+
+type consumeMessagesSuite struct {
+	suite.Suite
+
+	storage  *mocks.PostgresStorage
+	producer *mocks.Producer
+	service  *service.Service
+	config   service.WorkersConfig
+}
+
+func (s *consumeMessagesSuite) SetupTest() {
+	s.storage = mocks.NewPostgresStorage(s.T())
+	s.producer = mocks.NewProducer(s.T())
+
+	s.service = service.New(s.storage, nil, s.producer, nil, nil, "links", nil, service.LinkRules{})
+
+	s.config = service.WorkersConfig{
+		Number:          1,
+		MaxAttempts:     3,
+		DeadLetterTopic: "links_dlq",
+	}
+}
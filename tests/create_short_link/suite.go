@@ -41,7 +41,7 @@ func (s *createShortLinkSuite) SetupTest() {
 		LongUrl:  longUrl1,
 	}
 
-	storage.On("CreateShortLink", mock.Anything, mock.Anything, longUrl1, mock.Anything).Return(&createdNewLink1, nil).Once()
+	storage.On("CreateShortLink", mock.Anything, mock.Anything, longUrl1, mock.Anything, "").Return(&createdNewLink1, nil).Once()
 
 	// 4
 	longUrl4 := "https://www.gismeteo.ru/weather-moscow-4368/weekend/#dataset"
@@ -52,7 +52,7 @@ func (s *createShortLinkSuite) SetupTest() {
 		LongUrl:  longUrl4,
 	}
 
-	storage.On("CreateShortLink", mock.Anything, alias4, longUrl4, mock.Anything).Return(&createdNewLink4, nil).Once()
+	storage.On("CreateShortLink", mock.Anything, alias4, longUrl4, mock.Anything, "").Return(&createdNewLink4, nil).Once()
 
 	// 8
 	longUrl8 := "https://www.gismeteo.ru/weather-moscow-4368/weekend/#dataset"
@@ -63,7 +63,7 @@ func (s *createShortLinkSuite) SetupTest() {
 		LongUrl:  longUrl8,
 	}
 
-	storage.On("CreateShortLink", mock.Anything, alias8, longUrl8, mock.Anything).Return(&createdNewLink8, nil).Once()
+	storage.On("CreateShortLink", mock.Anything, alias8, longUrl8, mock.Anything, "").Return(&createdNewLink8, nil).Once()
 
 	s.FinishSetupTest(storage, redisStorage, searcherStorage, nil, nil, sessionStore)
 }
@@ -0,0 +1,204 @@
+package custom_domains
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"urleater/dto"
+	"urleater/internal/handlers"
+)
+
+func (s *customDomainsSuite) TestDomainLifecycle() {
+	// 1
+	rec := s.request(http.MethodPost, s.Handlers.AddDomain, "localhost",
+		handlers.AddDomainRequest{Host: " Go.Brand.Example. ", FallbackUrl: "https://brand.example"}, "", "")
+
+	s.Require().Equal(http.StatusCreated, rec.Code, rec.Body.String())
+
+	added := s.publishRecord(rec)
+
+	s.Equal(brand, added.Domain.Host)
+	s.Nil(added.Domain.VerifiedAt)
+	s.Equal("_urleater."+brand, added.VerificationRecord.Name)
+	s.Equal("urleater-verification="+added.Domain.VerificationToken, added.VerificationRecord.Value)
+
+	// 2
+	rec = s.request(http.MethodPost, s.Handlers.CreateShortLink, "localhost",
+		handlers.CreateShortLinkRequest{ShortURL: "promo2026", LongURL: "https://example.org/promo2026", Domain: brand}, "", "")
+
+	s.Equal(http.StatusConflict, rec.Code, rec.Body.String())
+
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, brand, nil, "short_link", "promo2026")
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	// 3
+	rec = s.request(http.MethodPost, s.Handlers.VerifyDomain, "localhost", nil, "host", brand)
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	var verified handlers.DomainResponse
+
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &verified))
+	s.NotNil(verified.Domain.VerifiedAt)
+
+	// 4
+	rec = s.request(http.MethodPost, s.Handlers.CreateShortLink, "localhost",
+		handlers.CreateShortLinkRequest{ShortURL: "promo2026", LongURL: "https://example.org/promo2026", Domain: brand}, "", "")
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	var created handlers.CreateShortLinkResponse
+
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &created))
+	s.Equal(dto.DomainShortUrl(brand, "promo2026"), created.Link.ShortUrl)
+	s.Equal(brand, created.Link.Domain)
+
+	rec = s.request(http.MethodPost, s.Handlers.CreateShortLink, "localhost",
+		handlers.CreateShortLinkRequest{ShortURL: "promo2026", LongURL: "https://example.org/main"}, "", "")
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	// 5
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, brand+":443", nil, "short_link", "promo2026")
+
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://example.org/promo2026", rec.Header().Get(echo.HeaderLocation))
+
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, "localhost", nil, "short_link", "promo2026")
+
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://example.org/main", rec.Header().Get(echo.HeaderLocation))
+
+	// 6
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, brand, nil, "short_link", "missing")
+
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://brand.example", rec.Header().Get(echo.HeaderLocation))
+
+	rec = s.request(http.MethodGet, s.Handlers.GetMainPage, brand, nil, "", "")
+
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://brand.example", rec.Header().Get(echo.HeaderLocation))
+
+	// 7
+	rec = s.request(http.MethodPut, s.Handlers.UpdateDomain, "localhost", handlers.UpdateDomainRequest{}, "host", brand)
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, brand, nil, "short_link", "missing")
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	// 8
+	rec = s.request(http.MethodDelete, s.Handlers.DeleteDomain, "localhost", nil, "host", brand)
+
+	s.Require().Equal(http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = s.request(http.MethodGet, s.Handlers.GetDomains, "localhost", nil, "", "")
+
+	s.Require().Equal(http.StatusOK, rec.Code)
+	s.JSONEq(`{"domains": []}`, rec.Body.String())
+
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, "localhost", nil, "short_link", "promo2026")
+
+	s.Equal(http.StatusFound, rec.Code)
+}
+
+func (s *customDomainsSuite) TestDomainRules() {
+	// 1
+	for _, request := range []handlers.AddDomainRequest{
+		{Host: "localhost"},
+		{Host: "urleater.ru"},
+		{Host: "brand"},
+		{Host: "10.0.0.1"},
+		{Host: "bad_host.example"},
+		{Host: "ok.example", FallbackUrl: "not a url"},
+	} {
+		rec := s.request(http.MethodPost, s.Handlers.AddDomain, "localhost", request, "", "")
+
+		s.Equal(http.StatusBadRequest, rec.Code, request.Host)
+	}
+
+	// 2
+	rec := s.request(http.MethodPost, s.Handlers.AddDomain, "localhost", handlers.AddDomainRequest{Host: brand}, "", "")
+
+	s.Require().Equal(http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = s.request(http.MethodPost, s.Handlers.AddDomain, "localhost", handlers.AddDomainRequest{Host: "second.example"}, "", "")
+
+	s.Equal(http.StatusForbidden, rec.Code)
+
+	// 3
+	s.loginAs(other)
+
+	rec = s.request(http.MethodPost, s.Handlers.AddDomain, "localhost", handlers.AddDomainRequest{Host: "other.example"}, "", "")
+
+	s.Equal(http.StatusForbidden, rec.Code)
+
+	s.subscribe(other, "Gold")
+
+	// заявка email не подтверждена, поэтому домен не занят
+	rec = s.request(http.MethodPost, s.Handlers.AddDomain, "localhost", handlers.AddDomainRequest{Host: brand}, "", "")
+
+	s.Require().Equal(http.StatusCreated, rec.Code, rec.Body.String())
+
+	claim := rec
+
+	// 4
+	for _, f := range []func(c echo.Context) error{s.Handlers.VerifyDomain, s.Handlers.DeleteDomain} {
+		rec = s.request(http.MethodPost, f, "localhost", nil, "host", "unknown.example")
+
+		s.Equal(http.StatusNotFound, rec.Code)
+	}
+
+	rec = s.request(http.MethodPost, s.Handlers.CreateShortLink, "localhost",
+		handlers.CreateShortLinkRequest{LongURL: "https://example.org", Domain: brand}, "", "")
+
+	s.Equal(http.StatusConflict, rec.Code)
+
+	// 5
+	s.loginAs(email)
+
+	rec = s.request(http.MethodPost, s.Handlers.VerifyDomain, "localhost", nil, "host", brand)
+
+	s.Equal(http.StatusConflict, rec.Code)
+
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, brand, nil, "short_link", "missing")
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	// 6
+	s.publishRecord(claim)
+	s.loginAs(other)
+
+	rec = s.request(http.MethodPost, s.Handlers.VerifyDomain, "localhost", nil, "host", brand)
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = s.request(http.MethodPost, s.Handlers.CreateShortLink, "localhost",
+		handlers.CreateShortLinkRequest{ShortURL: "promo2026", LongURL: "https://example.org/other", Domain: brand}, "", "")
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = s.request(http.MethodGet, s.Handlers.GetShortLink, brand, nil, "short_link", "promo2026")
+
+	s.Equal(http.StatusFound, rec.Code)
+	s.Equal("https://example.org/other", rec.Header().Get(echo.HeaderLocation))
+
+	// 7
+	s.loginAs(email)
+
+	rec = s.request(http.MethodGet, s.Handlers.GetDomains, "localhost", nil, "", "")
+
+	s.Require().Equal(http.StatusOK, rec.Code)
+	s.JSONEq(`{"domains": []}`, rec.Body.String())
+
+	rec = s.request(http.MethodPost, s.Handlers.VerifyDomain, "localhost", nil, "host", brand)
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	rec = s.request(http.MethodPost, s.Handlers.AddDomain, "localhost", handlers.AddDomainRequest{Host: brand}, "", "")
+
+	s.Equal(http.StatusConflict, rec.Code)
+}
@@ -0,0 +1,137 @@
+package custom_domains
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"urleater/internal/handlers"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+const (
+	email = "user@mail.ru"
+	other = "other@mail.ru"
+	brand = "go.brand.example"
+)
+
+// stubResolver отвечает на TXT-запросы из records, вместо DNS.
+type stubResolver struct {
+	records map[string][]string
+}
+
+func (r *stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.records[name], nil
+}
+
+// customDomainsSuite проверяет свои домены на хранилище в памяти: TXT-записи отдаёт stubResolver,
+// а домен запроса задаётся заголовком Host.
+type customDomainsSuite struct {
+	base.BaseSuite
+
+	storage      *memstorage.Storage
+	srv          *service.Service
+	resolver     *stubResolver
+	sessionStore *mocks.SessionStore
+}
+
+func (s *customDomainsSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	ctx := context.Background()
+
+	s.storage = memstorage.NewStorage()
+	s.resolver = &stubResolver{records: make(map[string][]string)}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	s.srv = service.New(s.storage, memstorage.NewCache(), memqueue.New(100, nil), nil, memstorage.NewSearcher(), "links", logger, service.LinkRules{}).
+		WithDomains(service.DomainRules{PrimaryHosts: []string{"localhost", "urleater.ru"}, Resolver: s.resolver})
+
+	s.sessionStore = mocks.NewSessionStore(s.T())
+
+	s.Handlers = handlers.Handlers{
+		Service: s.srv,
+		Store:   s.sessionStore,
+		Logger:  logger,
+	}
+
+	s.Require().NoError(s.srv.RegisterUser(ctx, email, "password1"))
+	s.Require().NoError(s.srv.RegisterUser(ctx, other, "password1"))
+
+	s.subscribe(email, "Silver")
+
+	s.loginAs(email)
+}
+
+// loginAs задаёт пользователя, от имени которого выполняются следующие запросы.
+func (s *customDomainsSuite) loginAs(email string) {
+	s.sessionStore.ExpectedCalls = nil
+
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return(email, nil).Maybe()
+}
+
+func (s *customDomainsSuite) subscribe(email string, name string) {
+	plans, err := s.srv.GetAllSubscriptions(context.Background())
+
+	s.Require().NoError(err)
+
+	for _, plan := range plans {
+		if plan.Name == name {
+			_, _, err = s.srv.Subscribe(context.Background(), email, plan.Id, "")
+
+			s.Require().NoError(err)
+
+			return
+		}
+	}
+
+	s.FailNow("plan not found", name)
+}
+
+// request вызывает обработчик с заголовком Host, телом body и параметром пути param, если он не пустой.
+func (s *customDomainsSuite) request(method string, f base.Handler, host string, body interface{}, param string, value string) *httptest.ResponseRecorder {
+	var payload string
+
+	if body != nil {
+		res, err := json.Marshal(body)
+		s.Require().NoError(err)
+
+		payload = string(res)
+	}
+
+	req := httptest.NewRequest(method, "http://"+host, strings.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+
+	if param != "" {
+		c.SetParamNames(param)
+		c.SetParamValues(value)
+	}
+
+	s.NoError(f(c))
+
+	return rec
+}
+
+// publishRecord кладёт в stubResolver TXT-запись подтверждения домена из ответа обработчика.
+func (s *customDomainsSuite) publishRecord(rec *httptest.ResponseRecorder) handlers.DomainResponse {
+	var resp handlers.DomainResponse
+
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	s.resolver.records[resp.VerificationRecord.Name] = append(s.resolver.records[resp.VerificationRecord.Name], resp.VerificationRecord.Value)
+
+	return resp
+}
@@ -0,0 +1,10 @@
+package end_to_end
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(endToEndSuite))
+}
@@ -0,0 +1,74 @@
+package end_to_end
+
+import (
+	"context"
+	"time"
+)
+
+func (s *endToEndSuite) TestLinkLifecycle() {
+	ctx := context.Background()
+
+	email := "owner@mail.ru"
+
+	// 1
+	s.Require().NoError(s.srv.RegisterUser(ctx, email, "password1"))
+	s.Error(s.srv.RegisterUser(ctx, email, "password1"))
+	s.NoError(s.srv.LoginUser(ctx, email, "password1"))
+	s.Error(s.srv.LoginUser(ctx, email, "password2"))
+
+	// 2
+	link, err := s.srv.CreateShortLink(ctx, "weather2030", "https://www.example.com/forecast", email, "")
+
+	s.Require().NoError(err)
+	s.Equal("weather2030", link.ShortUrl)
+
+	user, err := s.srv.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(9, user.UrlsLeft)
+
+	_, err = s.srv.UpdateShortLinkInfo(ctx, "weather2030", email, "Weather forecast", "", []string{"news"})
+
+	s.Require().NoError(err)
+
+	s.relay()
+
+	// 3
+	cached, err := s.cache.GetShortLinkByLongLink(ctx, "weather2030")
+
+	s.Require().NoError(err)
+	s.Equal("https://www.example.com/forecast", cached.LongUrl)
+
+	found, err := s.srv.GetShortLinksMatchingPattern(ctx, email, false, "forecast", 0)
+
+	s.Require().NoError(err)
+	s.Require().Len(found.Links, 1)
+	s.Equal("weather2030", found.Links[0].Link.ShortUrl)
+	s.Equal([]string{"Weather <mark>forecast</mark>"}, found.Links[0].Highlights["title"])
+
+	// 4
+	redirect, err := s.srv.GetShortLink(ctx, "weather2030")
+
+	s.Require().NoError(err)
+	s.Equal("https://www.example.com/forecast", redirect.LongUrl)
+
+	s.Eventually(func() bool {
+		stored, err := s.storage.GetShortLink(ctx, "weather2030")
+
+		return err == nil && stored.TimesVisited == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// 5
+	s.Require().NoError(s.srv.DeleteShortLink(ctx, "weather2030", email))
+
+	s.relay()
+
+	_, err = s.srv.GetShortLink(ctx, "weather2030")
+
+	s.Error(err)
+
+	found, err = s.srv.GetShortLinksMatchingPattern(ctx, email, false, "forecast", 0)
+
+	s.Require().NoError(err)
+	s.Empty(found.Links)
+}
@@ -0,0 +1,54 @@
+package end_to_end
+
+import (
+	"context"
+	"time"
+	"urleater/dto"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+)
+
+func (s *endToEndSuite) TestConfiguredLinkRules() {
+	ctx := context.Background()
+
+	email := "owner@mail.ru"
+
+	storage := memstorage.NewStorage().WithLinkTTL(24 * time.Hour)
+
+	srv := service.New(storage, memstorage.NewCache(), nil, nil, memstorage.NewSearcher(), topic, nil, service.LinkRules{
+		AliasMinLength: 4,
+		AliasMaxLength: 6,
+		ReservedNames:  []string{"admin"},
+		MaxPageSize:    2,
+	})
+
+	s.Require().NoError(srv.RegisterUser(ctx, email, "password1"))
+
+	// 1
+	link, err := srv.CreateShortLink(ctx, "news", "https://www.example.com/news", email, "")
+
+	s.Require().NoError(err)
+	s.WithinDuration(time.Now().Add(24*time.Hour), link.ExpiresAt, time.Minute)
+
+	// 2
+	_, err = srv.CreateShortLink(ctx, "weather2030", "https://www.example.com/forecast", email, "")
+
+	s.Error(err)
+
+	_, err = srv.CreateShortLink(ctx, "admin", "https://www.example.com/admin", email, "")
+
+	s.ErrorContains(err, "is not available")
+
+	// 3
+	for _, alias := range []string{"sport", "games"} {
+		_, err = srv.CreateShortLink(ctx, alias, "https://www.example.com/"+alias, email, "")
+
+		s.Require().NoError(err)
+	}
+
+	page, _, err := srv.GetUserShortLinks(ctx, email, dto.LinkListQuery{Limit: 10}, "")
+
+	s.Require().NoError(err)
+	s.Len(page.Links, 2)
+	s.NotEmpty(page.NextCursor)
+}
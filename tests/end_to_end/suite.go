@@ -0,0 +1,56 @@
+package end_to_end
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"urleater/dto"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+)
+
+const topic = "links"
+
+// endToEndSuite собирает сервис целиком на хранилищах в памяти: без моков и без Docker.
+type endToEndSuite struct {
+	suite.Suite
+
+	storage *memstorage.Storage
+	cache   *memstorage.Cache
+	srv     *service.Service
+	cancel  context.CancelFunc
+}
+
+func (s *endToEndSuite) SetupTest() {
+	var ctx context.Context
+
+	ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.storage = memstorage.NewStorage()
+	s.cache = memstorage.NewCache()
+
+	queue := memqueue.New(100, nil)
+	workerChannel := make(chan dto.ConsumerMessage, 100)
+
+	s.srv = service.New(s.storage, s.cache, queue, []service.Consumer{queue.NewConsumer(topic, workerChannel)}, memstorage.NewSearcher(), topic, nil, service.LinkRules{})
+
+	s.srv.StartConsumers(ctx)
+	s.srv.StartConsumingWorkers(ctx, service.WorkersConfig{Number: 2, MaxAttempts: 3, DeadLetterTopic: topic + "_dlq"}, workerChannel)
+}
+
+func (s *endToEndSuite) TearDownTest() {
+	s.cancel()
+}
+
+// relay применяет все накопившиеся события outbox, как это делает фоновый relay.
+func (s *endToEndSuite) relay() {
+	for {
+		n, err := s.srv.RelayOutboxEvents(context.Background())
+
+		s.Require().NoError(err)
+
+		if n == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package get_user_short_links
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(getUserShortLinksSuite))
+}
@@ -0,0 +1,74 @@
+package get_user_short_links
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"urleater/internal/handlers"
+)
+
+func (s *getUserShortLinksSuite) TestGetUserShortLinks() {
+	// 1
+	body, code := s.GetUserShortLinks(url.Values{
+		"limit": {"2"},
+	})
+
+	var resp1 handlers.GetUserShortLinksResponse
+
+	err := json.Unmarshal(body, &resp1)
+
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, code)
+	s.Len(resp1.Links, 2)
+	s.Equal(3, resp1.Total)
+	s.NotEmpty(resp1.NextCursor)
+
+	// 2
+	body, code = s.GetUserShortLinks(url.Values{
+		"limit":  {"2"},
+		"cursor": {resp1.NextCursor},
+	})
+
+	var resp2 handlers.GetUserShortLinksResponse
+
+	err = json.Unmarshal(body, &resp2)
+
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, code)
+	s.Len(resp2.Links, 1)
+	s.Equal("firstlink", resp2.Links[0].ShortUrl)
+	s.Empty(resp2.NextCursor)
+
+	// 3
+	_, code = s.GetUserShortLinks(url.Values{
+		"limit": {"500"},
+		"sort":  {"times_visited"},
+		"order": {"asc"},
+	})
+
+	s.Equal(http.StatusOK, code)
+
+	// 4
+	_, code = s.GetUserShortLinks(url.Values{
+		"sort":   {"times_visited"},
+		"cursor": {resp1.NextCursor},
+	})
+
+	s.Equal(http.StatusInternalServerError, code)
+
+	// 5
+	_, code = s.GetUserShortLinks(url.Values{
+		"sort": {"long_url"},
+	})
+
+	s.Equal(http.StatusInternalServerError, code)
+
+	// 6
+	_, code = s.GetUserShortLinks(url.Values{
+		"created_from": {"01.10.2026"},
+	})
+
+	s.Equal(http.StatusBadRequest, code)
+}
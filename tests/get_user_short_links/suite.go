@@ -0,0 +1,54 @@
+package get_user_short_links
+
+import (
+	"github.com/stretchr/testify/mock"
+	"time"
+	"urleater/dto"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+type getUserShortLinksSuite struct {
+	base.BaseSuite
+}
+
+func (s *getUserShortLinksSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	storage := mocks.NewPostgresStorage(s.T())
+	sessionStore := mocks.NewSessionStore(s.T())
+
+	sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return("owner@mail.ru", nil)
+
+	storage.On("GetUser", mock.Anything, "owner@mail.ru").Return(&dto.User{
+		Email:    "owner@mail.ru",
+		UrlsLeft: 5,
+	}, nil).Maybe()
+
+	storage.On("GetTotalUserLinksNumber", mock.Anything, "owner@mail.ru", mock.Anything).Return(3, nil).Maybe()
+
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// 1
+	storage.On("GetUserShortLinks", mock.Anything, "owner@mail.ru", mock.MatchedBy(func(q dto.LinkListQuery) bool {
+		return q.After == nil && q.Limit == 3 && q.SortBy == dto.LinkSortCreatedAt && q.Direction == dto.SortDesc
+	})).Return([]dto.Link{
+		{ShortUrl: "thirdlink", CreatedAt: createdAt.Add(2 * time.Hour)},
+		{ShortUrl: "secondlink", CreatedAt: createdAt.Add(time.Hour)},
+		{ShortUrl: "firstlink", CreatedAt: createdAt},
+	}, nil).Once()
+
+	// 2
+	storage.On("GetUserShortLinks", mock.Anything, "owner@mail.ru", mock.MatchedBy(func(q dto.LinkListQuery) bool {
+		return q.After != nil && q.After.ShortUrl == "secondlink" && q.After.Value == "2026-10-01 13:00:00"
+	})).Return([]dto.Link{
+		{ShortUrl: "firstlink", CreatedAt: createdAt},
+	}, nil).Once()
+
+	// 3
+	storage.On("GetUserShortLinks", mock.Anything, "owner@mail.ru", mock.MatchedBy(func(q dto.LinkListQuery) bool {
+		return q.After == nil && q.Limit == 51 && q.SortBy == dto.LinkSortTimesVisited && q.Direction == dto.SortAsc
+	})).Return([]dto.Link{}, nil).Once()
+
+	s.FinishSetupTest(storage, nil, nil, nil, nil, sessionStore)
+}
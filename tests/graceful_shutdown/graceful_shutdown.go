@@ -0,0 +1,85 @@
+package graceful_shutdown
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"sync/atomic"
+	"time"
+	"urleater/dto"
+	"urleater/internal/service"
+)
+
+func (s *gracefulShutdownSuite) newService(workerChannel chan dto.ConsumerMessage) *service.Service {
+	srv := service.New(s.storage, nil, s.producer, []service.Consumer{s.consumer}, nil, "links", nil, service.LinkRules{})
+
+	srv.StartConsumers(context.Background())
+	srv.StartConsumingWorkers(context.Background(), service.WorkersConfig{Number: 2, MaxAttempts: 1, DeadLetterTopic: "links_dlq"}, workerChannel)
+
+	return srv
+}
+
+func (s *gracefulShutdownSuite) TestDrainQueuedMessages() {
+	// 1
+	var acked atomic.Int32
+
+	workerChannel := make(chan dto.ConsumerMessage, 10)
+
+	for _, shortLink := range []string{"link1", "link2", "link3", "link4", "link5"} {
+		workerChannel <- s.linkViewed(shortLink, &acked)
+	}
+
+	s.storage.On("IncrementShortLinkTimesWatchedCount", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		time.Sleep(20 * time.Millisecond)
+	}).Times(5)
+
+	s.consumer.On("Close").Return(nil).Run(func(mock.Arguments) {
+		s.Equal(int32(5), acked.Load(), "consumer is closed before workers drained the queue")
+	}).Once()
+
+	srv := s.newService(workerChannel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	s.NoError(srv.Shutdown(ctx))
+	s.Equal(int32(5), acked.Load())
+}
+
+func (s *gracefulShutdownSuite) TestWorkersTimeout() {
+	// 1
+	var acked atomic.Int32
+
+	workerChannel := make(chan dto.ConsumerMessage, 10)
+
+	workerChannel <- s.linkViewed("link1", &acked)
+
+	started := make(chan struct{})
+
+	// обработка зависает, пока воркер не прервут
+	s.storage.On("IncrementShortLinkTimesWatchedCount", mock.Anything, "link1", "").Return(context.Canceled).Run(func(args mock.Arguments) {
+		close(started)
+
+		<-args.Get(0).(context.Context).Done()
+	}).Once()
+
+	s.consumer.On("Close").Return(errors.New("commit failed")).Once()
+
+	srv := s.newService(workerChannel)
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.ErrorContains(err, "commit failed")
+
+	// прерванное сообщение не подтверждается и не попадает в DLQ: оно будет прочитано заново
+	s.Equal(int32(0), acked.Load())
+	s.producer.AssertNotCalled(s.T(), "PublishDeadLetter", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,51 @@
+package graceful_shutdown
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"sync/atomic"
+	"urleater/dto"
+	"urleater/internal/events"
+	"urleater/tests/mocks"
+)
+
+type gracefulShutdownSuite struct {
+	suite.Suite
+
+	storage  *mocks.PostgresStorage
+	consumer *mocks.Consumer
+	producer *mocks.Producer
+}
+
+func (s *gracefulShutdownSuite) SetupTest() {
+	s.storage = mocks.NewPostgresStorage(s.T())
+	s.consumer = mocks.NewConsumer(s.T())
+	s.producer = mocks.NewProducer(s.T())
+
+	// консьюмер читает, пока сервис не отменит контекст
+	s.consumer.On("StartConsuming", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Once()
+}
+
+// linkViewed создаёт сообщение о переходе по ссылке, которое при подтверждении увеличивает acked.
+func (s *gracefulShutdownSuite) linkViewed(shortLink string, acked *atomic.Int32) dto.ConsumerMessage {
+	event, err := events.New(dto.EventLinkViewed, dto.LinkViewed{ShortLink: shortLink})
+
+	s.Require().NoError(err)
+
+	value, err := json.Marshal(event)
+
+	s.Require().NoError(err)
+
+	return dto.ConsumerMessage{
+		Topic: "links",
+		Key:   []byte(shortLink),
+		Value: value,
+		Ack: func() {
+			acked.Add(1)
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package health_checks
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+	"urleater/dto"
+)
+
+func (s *healthChecksSuite) TestHealthz() {
+	// 1
+	body, code := s.MakeRequestWithQuery(http.MethodGet, s.Handlers.GetHealthz, url.Values{})
+
+	s.Equal(http.StatusOK, code)
+	s.JSONEq(`{"status":"ok"}`, string(body))
+}
+
+func (s *healthChecksSuite) TestReadyz() {
+	// 1
+	s.setChecks(time.Second, &dependency{}, &dependency{}, &dependency{})
+
+	report, code := s.readyz()
+
+	s.Equal(http.StatusOK, code)
+	s.Equal(dto.HealthStatusOK, report.Status)
+	s.Len(report.Dependencies, 3)
+	s.True(report.Dependencies["postgres"].Critical)
+
+	// 2
+	s.setChecks(time.Second, &dependency{}, &dependency{}, &dependency{err: errors.New("cluster is red")})
+
+	report, code = s.readyz()
+
+	s.Equal(http.StatusOK, code)
+	s.Equal(dto.HealthStatusDegraded, report.Status)
+	s.Equal(dto.HealthStatusDegraded, report.Dependencies["elastic"].Status)
+	s.Equal("cluster is red", report.Dependencies["elastic"].Error)
+	s.Equal(dto.HealthStatusOK, report.Dependencies["redis"].Status)
+
+	// 3
+	s.setChecks(time.Second, &dependency{err: errors.New("connection refused")}, &dependency{err: errors.New("connection refused")}, &dependency{})
+
+	report, code = s.readyz()
+
+	s.Equal(http.StatusServiceUnavailable, code)
+	s.Equal(dto.HealthStatusDown, report.Status)
+	s.Equal(dto.HealthStatusDown, report.Dependencies["postgres"].Status)
+	s.Equal(dto.HealthStatusDegraded, report.Dependencies["redis"].Status)
+}
+
+func (s *healthChecksSuite) TestReadyzTimeout() {
+	// 1
+	s.setChecks(50*time.Millisecond, &dependency{}, &dependency{delay: time.Minute}, &dependency{delay: time.Minute})
+
+	started := time.Now()
+
+	report, code := s.readyz()
+
+	// зависимости проверяются параллельно, каждая не дольше таймаута
+	s.Less(time.Since(started), time.Second)
+	s.Equal(http.StatusOK, code)
+	s.Equal(dto.HealthStatusDegraded, report.Status)
+	s.Equal("context deadline exceeded", report.Dependencies["redis"].Error)
+	s.Equal("context deadline exceeded", report.Dependencies["elastic"].Error)
+}
@@ -0,0 +1,49 @@
+package health_checks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+	"urleater/dto"
+	"urleater/internal/health"
+	base "urleater/tests"
+)
+
+type healthChecksSuite struct {
+	base.BaseSuite
+}
+
+// dependency - проверяемая зависимость, которая отвечает ошибкой err через delay.
+type dependency struct {
+	delay time.Duration
+	err   error
+}
+
+func (d *dependency) Ping(ctx context.Context) error {
+	select {
+	case <-time.After(d.delay):
+		return d.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *healthChecksSuite) setChecks(timeout time.Duration, postgres, redis, elastic *dependency) {
+	s.Handlers.Health = health.NewChecker(timeout,
+		health.Check{Name: "postgres", Critical: true, Ping: postgres.Ping},
+		health.Check{Name: "redis", Ping: redis.Ping},
+		health.Check{Name: "elastic", Ping: elastic.Ping},
+	)
+}
+
+func (s *healthChecksSuite) readyz() (dto.HealthReport, int) {
+	body, code := s.MakeRequestWithQuery(http.MethodGet, s.Handlers.GetReadyz, url.Values{})
+
+	var report dto.HealthReport
+
+	s.NoError(json.Unmarshal(body, &report))
+
+	return report, code
+}
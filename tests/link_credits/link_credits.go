@@ -0,0 +1,318 @@
+package link_credits
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+	"urleater/dto"
+	"urleater/internal/handlers"
+	"urleater/internal/service"
+)
+
+func (s *linkCreditsSuite) TestAdminCredits() {
+	s.loginAs(admin)
+
+	// 1
+	body, code := s.request(http.MethodPut, s.Handlers.UpdateUserShortLinks, "",
+		handlers.UpdateUserShortLinksRequest{Email: email, DeltaLinks: 5})
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	var resp handlers.UpdateUserShortLinksResponse
+
+	s.Require().NoError(json.Unmarshal(body, &resp))
+	s.Equal(15, resp.User.UrlsLeft)
+
+	credits := s.credits(email)
+
+	s.Require().Len(credits, 1)
+	s.Equal(dto.LinkCreditAdmin, credits[0].Reason)
+	s.Equal(5, credits[0].Amount)
+
+	// 2
+	_, code = s.request(http.MethodPut, s.Handlers.UpdateUserShortLinks, "",
+		handlers.UpdateUserShortLinksRequest{Email: email, DeltaLinks: -3})
+
+	s.Equal(http.StatusBadRequest, code)
+
+	// 3
+	_, code = s.request(http.MethodPut, s.Handlers.UpdateUserShortLinks, "",
+		handlers.UpdateUserShortLinksRequest{Email: "nobody@mail.ru", DeltaLinks: 5})
+
+	s.Equal(http.StatusNotFound, code)
+
+	// 4
+	s.loginAs(email)
+
+	_, code = s.request(http.MethodPut, s.Handlers.UpdateUserShortLinks, "",
+		handlers.UpdateUserShortLinksRequest{Email: email, DeltaLinks: 5})
+
+	s.Equal(http.StatusForbidden, code)
+	s.Equal(15, s.user(email).UrlsLeft)
+}
+
+func (s *linkCreditsSuite) TestPromoLinkCredits() {
+	ctx := context.Background()
+
+	s.Require().NoError(s.srv.RegisterUser(ctx, invitee, "password1"))
+
+	// 1
+	s.loginAs(admin)
+
+	body, code := s.request(http.MethodPost, s.Handlers.CreatePromoCode, "", handlers.PromoCodeRequest{
+		Code:           " welcome-25 ",
+		Kind:           dto.PromoLinkCredits,
+		LinkCredits:    25,
+		MaxRedemptions: 1,
+	})
+
+	s.Require().Equal(http.StatusCreated, code, string(body))
+
+	var created handlers.PromoCodeResponse
+
+	s.Require().NoError(json.Unmarshal(body, &created))
+	s.Equal("WELCOME-25", created.PromoCode.Code)
+
+	_, code = s.request(http.MethodPost, s.Handlers.CreatePromoCode, "", handlers.PromoCodeRequest{
+		Code: "WELCOME-25", Kind: dto.PromoLinkCredits, LinkCredits: 5,
+	})
+
+	s.Equal(http.StatusConflict, code)
+
+	_, code = s.request(http.MethodPost, s.Handlers.CreatePromoCode, "", handlers.PromoCodeRequest{
+		Code: "BROKEN", Kind: dto.PromoLinkCredits, LinkCredits: 5, PercentOff: 10,
+	})
+
+	s.Equal(http.StatusBadRequest, code)
+
+	// 2
+	s.loginAs(email)
+
+	body, code = s.request(http.MethodPost, s.Handlers.RedeemPromoCode, "", handlers.RedeemPromoCodeRequest{Code: "welcome-25"})
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+	s.Equal(35, s.user(email).UrlsLeft)
+
+	credits := s.credits(email)
+
+	s.Require().Len(credits, 1)
+	s.Equal(dto.LinkCreditPromo, credits[0].Reason)
+	s.Equal("WELCOME-25", credits[0].Reference)
+
+	// 3
+	_, code = s.request(http.MethodPost, s.Handlers.RedeemPromoCode, "", handlers.RedeemPromoCodeRequest{Code: "WELCOME-25"})
+
+	s.Equal(http.StatusConflict, code)
+
+	_, err := s.srv.CreatePromoCode(ctx, dto.PromoCode{Code: "BONUS", Kind: dto.PromoLinkCredits, LinkCredits: 5})
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.RedeemPromoCode(ctx, email, "BONUS")
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.RedeemPromoCode(ctx, email, "BONUS")
+
+	s.ErrorIs(err, service.ErrPromoCodeRedeemed)
+	s.Equal(40, s.user(email).UrlsLeft)
+
+	// 4
+	_, err = s.srv.RedeemPromoCode(ctx, invitee, "WELCOME-25")
+
+	s.ErrorIs(err, service.ErrPromoCodeUnavailable)
+	s.Equal(10, s.user(invitee).UrlsLeft)
+
+	// 5
+	expiresAt := time.Now().Add(-time.Hour)
+
+	_, err = s.storage.CreatePromoCode(ctx, dto.PromoCode{Code: "OLD", Kind: dto.PromoLinkCredits, LinkCredits: 5, ExpiresAt: &expiresAt})
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.RedeemPromoCode(ctx, email, "old")
+
+	s.ErrorIs(err, service.ErrPromoCodeUnavailable)
+
+	_, code = s.request(http.MethodPost, s.Handlers.RedeemPromoCode, "", handlers.RedeemPromoCodeRequest{Code: "MISSING"})
+
+	s.Equal(http.StatusNotFound, code)
+
+	// 6
+	s.loginAs(admin)
+
+	_, code = s.request(http.MethodDelete, s.Handlers.DeletePromoCode, "WELCOME-25", nil)
+
+	s.Equal(http.StatusNoContent, code)
+
+	body, code = s.request(http.MethodGet, s.Handlers.GetPromoCodes, "", nil)
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	var promos handlers.GetPromoCodesResponse
+
+	s.Require().NoError(json.Unmarshal(body, &promos))
+	s.Len(promos.PromoCodes, 2)
+	s.Len(s.credits(email), 2)
+}
+
+func (s *linkCreditsSuite) TestPercentOffPromo() {
+	ctx := context.Background()
+
+	silver := s.plan("Silver")
+
+	_, err := s.srv.CreatePromoCode(ctx, dto.PromoCode{Code: "SILVER20", Kind: dto.PromoPercentOff, PercentOff: 20, PlanId: &silver.Id})
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.CreatePromoCode(ctx, dto.PromoCode{Code: "ANY10", Kind: dto.PromoPercentOff, PercentOff: 10})
+
+	s.Require().NoError(err)
+
+	// 1
+	_, _, err = s.srv.Subscribe(ctx, email, s.plan("Gold").Id, "SILVER20")
+
+	s.ErrorIs(err, service.ErrPromoCodeNotApplicable)
+
+	body, code := s.request(http.MethodPost, s.Handlers.Subscribe, "", handlers.SubscribeRequest{PlanId: silver.Id, PromoCode: "silver20"})
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	var resp handlers.UserSubscriptionResponse
+
+	s.Require().NoError(json.Unmarshal(body, &resp))
+	s.Require().NotNil(resp.Charge)
+	s.Equal(silver.Price*80/100, resp.Charge.Price)
+	s.Equal("SILVER20", resp.Charge.PromoCode)
+	s.Equal("Silver", resp.Subscription.Plan.Name)
+
+	promo, err := s.storage.GetPromoCode(ctx, "SILVER20")
+
+	s.Require().NoError(err)
+	s.Equal(1, promo.Redemptions)
+
+	// 2
+	_, code = s.request(http.MethodPost, s.Handlers.RedeemPromoCode, "", handlers.RedeemPromoCodeRequest{Code: "ANY10"})
+
+	s.Equal(http.StatusBadRequest, code)
+
+	// 3
+	_, _, err = s.srv.Subscribe(ctx, email, s.plan("Free").Id, "ANY10")
+
+	s.ErrorIs(err, service.ErrPromoCodeNotApplicable)
+
+	_, charge, err := s.srv.Subscribe(ctx, email, s.plan("Gold").Id, "")
+
+	s.Require().NoError(err)
+	s.Equal(s.plan("Gold").Price, charge.Price)
+	s.Empty(charge.PromoCode)
+}
+
+func (s *linkCreditsSuite) TestReferralCredits() {
+	ctx := context.Background()
+
+	// 1
+	body, code := s.request(http.MethodGet, s.Handlers.GetReferrals, "", nil)
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	var referrals handlers.GetReferralsResponse
+
+	s.Require().NoError(json.Unmarshal(body, &referrals))
+	s.Require().NotEmpty(referrals.ReferralCode)
+	s.Equal("/register?ref="+referrals.ReferralCode, referrals.ReferralLink)
+	s.Empty(referrals.Referrals)
+
+	referralCode, _, err := s.srv.GetReferrals(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(referrals.ReferralCode, referralCode)
+
+	s.ErrorIs(s.srv.AddReferral(ctx, email, referralCode), service.ErrReferralCodeNotFound)
+
+	// 2
+	s.loginAs("")
+
+	body, code = s.RegisterUser(&handlers.RegisterRequest{Email: invitee, Password: "password1", ReferralCode: referralCode})
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	_, list, err := s.srv.GetReferrals(ctx, email)
+
+	s.Require().NoError(err)
+	s.Require().Len(list, 1)
+	s.Equal(invitee, list[0].InviteeEmail)
+	s.Nil(list[0].CreditedAt)
+	s.False(s.user(invitee).EmailVerified)
+
+	// 3
+	_, code = s.MakeRequestWithQuery(http.MethodGet, s.Handlers.VerifyEmail, url.Values{"token": {"wrong"}})
+
+	s.Equal(http.StatusBadRequest, code)
+
+	token := s.verificationToken(invitee)
+
+	_, code = s.MakeRequestWithQuery(http.MethodGet, s.Handlers.VerifyEmail, url.Values{"token": {token}})
+
+	s.Require().Equal(http.StatusSeeOther, code)
+	s.True(s.user(invitee).EmailVerified)
+	s.Equal(25, s.user(email).UrlsLeft)
+
+	credits := s.credits(email)
+
+	s.Require().Len(credits, 1)
+	s.Equal(dto.LinkCreditReferral, credits[0].Reason)
+	s.Equal(invitee, credits[0].Reference)
+	s.Equal(15, credits[0].Amount)
+
+	// 4
+	_, err = s.srv.VerifyEmail(ctx, token)
+
+	s.ErrorIs(err, service.ErrInvalidVerificationToken)
+	s.Equal(25, s.user(email).UrlsLeft)
+
+	_, list, err = s.srv.GetReferrals(ctx, email)
+
+	s.Require().NoError(err)
+	s.NotNil(list[0].CreditedAt)
+
+	// 5
+	s.loginAs(invitee)
+
+	_, code = s.request(http.MethodPost, s.Handlers.ResendEmailVerification, "", nil)
+
+	s.Equal(http.StatusConflict, code)
+
+	s.loginAs(email)
+
+	_, code = s.request(http.MethodPost, s.Handlers.ResendEmailVerification, "", nil)
+
+	s.Require().Equal(http.StatusNoContent, code)
+
+	newToken := s.verificationToken(email)
+
+	s.NotEqual(s.verificationToken(invitee), newToken)
+
+	_, err = s.srv.VerifyEmail(ctx, newToken)
+
+	s.Require().NoError(err)
+	s.True(s.user(email).EmailVerified)
+}
+
+func (s *linkCreditsSuite) TestVerificationWithoutMailer() {
+	s.srv.WithCredits(service.CreditRules{ReferralCredits: 15})
+
+	// 1
+	_, code := s.request(http.MethodPost, s.Handlers.ResendEmailVerification, "", nil)
+
+	s.Equal(http.StatusServiceUnavailable, code)
+
+	// 2
+	s.Require().NoError(s.srv.RegisterUser(context.Background(), invitee, "password1"))
+
+	s.Empty(s.mailer.letters[invitee])
+	s.NotContains(s.logs.String(), "verify_email")
+	s.False(s.user(invitee).EmailVerified)
+}
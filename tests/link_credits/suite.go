@@ -0,0 +1,196 @@
+package link_credits
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"urleater/dto"
+	"urleater/internal/handlers"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+const (
+	admin   = "admin@admin.com"
+	email   = "user@mail.ru"
+	invitee = "friend@mail.ru"
+)
+
+// stubMailer запоминает письма вместо отправки.
+type stubMailer struct {
+	mu      sync.Mutex
+	letters map[string][]string
+}
+
+func (m *stubMailer) SendMail(ctx context.Context, to string, subject string, body string) error {
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	m.letters[to] = append(m.letters[to], body)
+
+	return nil
+}
+
+// linkCreditsSuite проверяет начисления ссылок, промокоды и приглашения на хранилище в памяти.
+// Ссылки подтверждения почты тесты берут из писем stubMailer.
+type linkCreditsSuite struct {
+	base.BaseSuite
+
+	storage      *memstorage.Storage
+	srv          *service.Service
+	sessionStore *mocks.SessionStore
+	mailer       *stubMailer
+	logs         *bytes.Buffer
+}
+
+func (s *linkCreditsSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	s.storage = memstorage.NewStorage()
+	s.logs = new(bytes.Buffer)
+	s.mailer = &stubMailer{letters: make(map[string][]string)}
+
+	logger := slog.New(slog.NewJSONHandler(s.logs, nil))
+
+	s.srv = service.New(s.storage, memstorage.NewCache(), nil, nil, memstorage.NewSearcher(), "", logger, service.LinkRules{}).
+		WithCredits(service.CreditRules{ReferralCredits: 15, Mailer: s.mailer, BaseUrl: "https://urleater.example"}).
+		WithAdmins(service.AdminRules{Emails: []string{admin}})
+
+	s.sessionStore = mocks.NewSessionStore(s.T())
+
+	s.sessionStore.On("Get", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	s.sessionStore.On("Save", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	s.Handlers = handlers.Handlers{
+		Service: s.srv,
+		Store:   s.sessionStore,
+		Logger:  logger,
+	}
+
+	s.Require().NoError(s.srv.RegisterUser(context.Background(), email, "password1"))
+	s.Require().NoError(s.srv.RegisterUser(context.Background(), admin, "password1"))
+	s.Require().NoError(s.srv.RequestEmailVerification(context.Background(), admin))
+
+	_, err := s.srv.VerifyEmail(context.Background(), s.verificationToken(admin))
+
+	s.Require().NoError(err)
+
+	s.loginAs(email)
+}
+
+// loginAs задаёт пользователя, от имени которого выполняются следующие запросы. Пустой email - гость.
+func (s *linkCreditsSuite) loginAs(email string) {
+	s.sessionStore.ExpectedCalls = withoutCalls(s.sessionStore.ExpectedCalls, "RetrieveEmailFromSession")
+
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return(email, nil)
+}
+
+func withoutCalls(calls []*mock.Call, method string) []*mock.Call {
+	var kept []*mock.Call
+
+	for _, call := range calls {
+		if call.Method != method {
+			kept = append(kept, call)
+		}
+	}
+
+	return kept
+}
+
+// request вызывает обработчик с телом body и параметром пути code, если он не пустой.
+func (s *linkCreditsSuite) request(method string, f base.Handler, code string, body interface{}) ([]byte, int) {
+	var payload string
+
+	if body != nil {
+		res, err := json.Marshal(body)
+		s.Require().NoError(err)
+
+		payload = string(res)
+	}
+
+	req := httptest.NewRequest(method, "http://localhost", strings.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+
+	if code != "" {
+		c.SetParamNames("code")
+		c.SetParamValues(code)
+	}
+
+	s.NoError(f(c))
+
+	return rec.Body.Bytes(), rec.Code
+}
+
+func (s *linkCreditsSuite) user(email string) *dto.User {
+	user, err := s.storage.GetUser(context.Background(), email)
+
+	s.Require().NoError(err)
+
+	return user
+}
+
+func (s *linkCreditsSuite) credits(email string) []dto.LinkCredit {
+	credits, err := s.srv.GetLinkCredits(context.Background(), email)
+
+	s.Require().NoError(err)
+
+	return credits
+}
+
+// verificationToken возвращает токен из последнего письма подтверждения почты email и проверяет, что его нет в логе.
+func (s *linkCreditsSuite) verificationToken(email string) string {
+	s.mailer.mu.Lock()
+
+	letters := s.mailer.letters[email]
+
+	s.mailer.mu.Unlock()
+
+	s.Require().NotEmpty(letters, "no verification email for %s", email)
+
+	var token string
+
+	for _, field := range strings.Fields(letters[len(letters)-1]) {
+		if strings.HasPrefix(field, "https://urleater.example/verify_email?") {
+			link, err := url.Parse(field)
+
+			s.Require().NoError(err)
+
+			token = link.Query().Get("token")
+		}
+	}
+
+	s.Require().NotEmpty(token, "no verification link for %s", email)
+	s.NotContains(s.logs.String(), token)
+
+	return token
+}
+
+func (s *linkCreditsSuite) plan(name string) dto.Subscription {
+	plans, err := s.srv.GetAllSubscriptions(context.Background())
+
+	s.Require().NoError(err)
+
+	for _, plan := range plans {
+		if plan.Name == name {
+			return plan
+		}
+	}
+
+	s.FailNow("plan not found", name)
+
+	return dto.Subscription{}
+}
@@ -0,0 +1,10 @@
+package memory_queue
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(memoryQueueSuite))
+}
@@ -0,0 +1,56 @@
+package memory_queue
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"time"
+	"urleater/dto"
+	"urleater/internal/events"
+	"urleater/internal/service"
+)
+
+func (s *memoryQueueSuite) TestConsumeEvents() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	workerChannel := make(chan dto.ConsumerMessage, 10)
+
+	srv := service.New(s.storage, nil, s.queue, []service.Consumer{s.queue.NewConsumer("links", workerChannel)}, nil, "links", nil, service.LinkRules{})
+
+	srv.StartConsumers(ctx)
+	srv.StartConsumingWorkers(ctx, service.WorkersConfig{Number: 2, MaxAttempts: 1, DeadLetterTopic: "links_dlq"}, workerChannel)
+
+	// 1
+	viewed := make(chan struct{})
+
+	s.storage.On("IncrementShortLinkTimesWatchedCount", mock.Anything, "link1", "").Return(nil).Run(func(mock.Arguments) {
+		close(viewed)
+	}).Once()
+
+	event, err := events.New(dto.EventLinkViewed, dto.LinkViewed{ShortLink: "link1"})
+
+	s.NoError(err)
+	s.NoError(s.queue.PublishEvent(ctx, "link1", event, "links"))
+
+	select {
+	case <-viewed:
+	case <-time.After(time.Second):
+		s.Fail("link_viewed event was not processed")
+	}
+
+	// 2
+	s.storage.On("IncrementShortLinkTimesWatchedCount", mock.Anything, "link2", "").Return(errors.New("database is down")).Once()
+
+	event, err = events.New(dto.EventLinkViewed, dto.LinkViewed{ShortLink: "link2"})
+
+	s.NoError(err)
+	s.NoError(s.queue.PublishEvent(ctx, "link2", event, "links"))
+
+	s.Eventually(func() bool {
+		return len(s.queue.DeadLetters()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	s.Equal("link2", string(s.queue.DeadLetters()[0].Message.Key))
+}
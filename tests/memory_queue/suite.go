@@ -0,0 +1,19 @@
+package memory_queue
+
+import (
+	"github.com/stretchr/testify/suite"
+	"urleater/internal/repository/memqueue"
+	"urleater/tests/mocks"
+)
+
+type memoryQueueSuite struct {
+	suite.Suite
+
+	storage *mocks.PostgresStorage
+	queue   *memqueue.Queue
+}
+
+func (s *memoryQueueSuite) SetupTest() {
+	s.storage = mocks.NewPostgresStorage(s.T())
+	s.queue = memqueue.New(10, nil)
+}
@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"urleater/dto"
+	"urleater/internal/events"
+	"urleater/internal/metrics"
+	"urleater/internal/service"
+)
+
+func (s *metricsSuite) TestHTTPMetrics() {
+	e := echo.New()
+
+	e.Use(metrics.EchoMiddleware)
+
+	e.GET("/:short_link", func(c echo.Context) error {
+		return c.Redirect(http.StatusFound, "https://example.com")
+	})
+	e.GET("/broken", func(c echo.Context) error {
+		return errors.New("broken handler")
+	})
+
+	redirects := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/:short_link", "302")
+	failed := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/broken", "500")
+	unmatched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "404")
+
+	redirectsBefore := testutil.ToFloat64(redirects)
+	failedBefore := testutil.ToFloat64(failed)
+	unmatchedBefore := testutil.ToFloat64(unmatched)
+
+	// 1
+	for _, path := range []string{"/link1", "/link2"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	s.Equal(redirectsBefore+2, testutil.ToFloat64(redirects), "short links are counted by route template")
+
+	// 2
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+	s.Equal(failedBefore+1, testutil.ToFloat64(failed))
+
+	// 3
+	api := echo.New()
+
+	api.Use(metrics.EchoMiddleware)
+
+	api.GET("/broken", func(c echo.Context) error {
+		return errors.New("broken handler")
+	})
+
+	api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/path", nil))
+
+	s.Equal(unmatchedBefore+1, testutil.ToFloat64(unmatched))
+}
+
+func (s *metricsSuite) TestRedirectCache() {
+	ctx := context.Background()
+
+	hits := metrics.RedirectCache.WithLabelValues(metrics.CacheHit)
+	misses := metrics.RedirectCache.WithLabelValues(metrics.CacheMiss)
+
+	hitsBefore := testutil.ToFloat64(hits)
+	missesBefore := testutil.ToFloat64(misses)
+
+	s.Require().NoError(s.storage.CreateUser(ctx, "user@mail.ru", "password1"))
+
+	_, err := s.storage.CreateShortLink(ctx, "weather2030", "https://forecast.example.com", "user@mail.ru", "")
+
+	s.Require().NoError(err)
+
+	// 1
+	_, err = s.srv.GetShortLink(ctx, "weather2030")
+
+	s.NoError(err)
+	s.Equal(missesBefore+1, testutil.ToFloat64(misses))
+
+	// 2
+	_, err = s.srv.GetShortLink(ctx, "weather2030")
+
+	s.NoError(err)
+	s.Equal(hitsBefore+1, testutil.ToFloat64(hits))
+	s.Equal(missesBefore+1, testutil.ToFloat64(misses))
+}
+
+func (s *metricsSuite) TestBusinessCounters() {
+	ctx := context.Background()
+
+	registeredBefore := testutil.ToFloat64(metrics.UsersRegistered)
+	createdBefore := testutil.ToFloat64(metrics.LinksCreated)
+	exhaustedBefore := testutil.ToFloat64(metrics.QuotaExhausted)
+
+	// 1
+	s.Require().NoError(s.srv.RegisterUser(ctx, "user@mail.ru", "password1"))
+
+	s.Equal(registeredBefore+1, testutil.ToFloat64(metrics.UsersRegistered))
+
+	// 2
+	_, err := s.srv.CreateShortLink(ctx, "weather2030", "https://forecast.example.com", "user@mail.ru", "")
+
+	s.Require().NoError(err)
+	s.Equal(createdBefore+1, testutil.ToFloat64(metrics.LinksCreated))
+
+	// 3
+	_, err = s.storage.UpdateUserLinks(ctx, "user@mail.ru", 0)
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.CreateShortLink(ctx, "weather2031", "https://forecast.example.com", "user@mail.ru", "")
+
+	s.Error(err)
+	s.Equal(createdBefore+1, testutil.ToFloat64(metrics.LinksCreated))
+	s.Equal(exhaustedBefore+1, testutil.ToFloat64(metrics.QuotaExhausted))
+}
+
+func (s *metricsSuite) TestQueueMetrics() {
+	ctx := context.Background()
+
+	produced := metrics.QueueProduced.WithLabelValues(topic, "ok")
+	processed := metrics.QueueConsumed.WithLabelValues(topic, metrics.ConsumeProcessed)
+	deadLetters := metrics.QueueConsumed.WithLabelValues(topic, metrics.ConsumeDeadLetter)
+
+	producedBefore := testutil.ToFloat64(produced)
+	processedBefore := testutil.ToFloat64(processed)
+	deadLettersBefore := testutil.ToFloat64(deadLetters)
+
+	s.Require().NoError(s.storage.CreateUser(ctx, "user@mail.ru", "password1"))
+
+	_, err := s.storage.CreateShortLink(ctx, "weather2030", "https://forecast.example.com", "user@mail.ru", "")
+
+	s.Require().NoError(err)
+
+	// 1
+	_, err = s.srv.GetShortLink(ctx, "weather2030")
+
+	s.NoError(err)
+	s.Eventually(func() bool {
+		return testutil.ToFloat64(produced) == producedBefore+1
+	}, time.Second, 10*time.Millisecond)
+
+	// 2
+	cfg := service.WorkersConfig{Number: 1, MaxAttempts: 1, DeadLetterTopic: topic + "_dlq"}
+
+	s.srv.HandleMessage(ctx, cfg, s.linkViewed("weather2030"))
+
+	s.Equal(processedBefore+1, testutil.ToFloat64(processed))
+
+	// 3
+	// событие без короткой ссылки не обработать, оно сразу уходит в DLQ
+	s.srv.HandleMessage(ctx, cfg, s.linkViewed(""))
+
+	s.Equal(deadLettersBefore+1, testutil.ToFloat64(deadLetters))
+}
+
+func (s *metricsSuite) TestWorkerChannelDepth() {
+	workerChannel := make(chan dto.ConsumerMessage, 10)
+
+	metrics.RegisterWorkerChannel(workerChannel)
+
+	// 1
+	workerChannel <- s.linkViewed("weather2030")
+	workerChannel <- s.linkViewed("weather2031")
+
+	s.Equal(float64(2), s.gauge("urleater_worker_channel_depth"))
+	s.Equal(float64(10), s.gauge("urleater_worker_channel_capacity"))
+}
+
+func (s *metricsSuite) linkViewed(shortLink string) dto.ConsumerMessage {
+	event, err := events.New(dto.EventLinkViewed, dto.LinkViewed{ShortLink: shortLink})
+
+	s.Require().NoError(err)
+
+	value, err := json.Marshal(event)
+
+	s.Require().NoError(err)
+
+	return dto.ConsumerMessage{Topic: topic, Key: []byte(shortLink), Value: value, Ack: func() {}}
+}
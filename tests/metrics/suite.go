@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/suite"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+)
+
+const topic = "links"
+
+type metricsSuite struct {
+	suite.Suite
+
+	storage *memstorage.Storage
+	cache   *memstorage.Cache
+	srv     *service.Service
+}
+
+func (s *metricsSuite) SetupTest() {
+	s.storage = memstorage.NewStorage()
+	s.cache = memstorage.NewCache()
+
+	s.srv = service.New(s.storage, s.cache, memqueue.New(100, nil), nil, memstorage.NewSearcher(), topic, nil, service.LinkRules{})
+}
+
+// gauge возвращает значение метрики без меток из стандартного реестра.
+func (s *metricsSuite) gauge(name string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+
+	s.Require().NoError(err)
+
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+
+	s.Failf("metric not found", "metric %s is not registered", name)
+
+	return 0
+}
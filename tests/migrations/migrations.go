@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"context"
+	"testing/fstest"
+	"urleater/build/migrations"
+	"urleater/internal/migrate"
+)
+
+func (s *migrationsSuite) TestEmbeddedMigrations() {
+	// 1
+	loaded, err := migrate.Load(migrations.FS)
+
+	s.Require().NoError(err)
+	s.Require().NotEmpty(loaded)
+
+	for i := 1; i < len(loaded); i++ {
+		s.Less(loaded[i-1].Version, loaded[i].Version)
+	}
+
+	s.Equal(int64(20240907112417), loaded[0].Version)
+	s.Equal("create_dbs", loaded[0].Name)
+
+	// 2
+	var seed *migrate.Migration
+
+	for i := range loaded {
+		if loaded[i].Name == "seed_subscriptions" {
+			seed = &loaded[i]
+		}
+	}
+
+	s.Require().NotNil(seed)
+	s.Contains(seed.Up, "ON CONFLICT (name) DO NOTHING")
+}
+
+func (s *migrationsSuite) TestLoadErrors() {
+	// 1
+	_, err := migrate.Load(fstest.MapFS{
+		"1_create.up.sql": {Data: []byte("CREATE TABLE t (id int);")},
+	})
+
+	s.ErrorContains(err, "must have both up and down files")
+
+	// 2
+	_, err = migrate.Load(fstest.MapFS{
+		"1_create.up.sql":   {Data: []byte("CREATE TABLE t (id int);")},
+		"1_create.down.sql": {Data: []byte("DROP TABLE t;")},
+		"1_other.up.sql":    {Data: []byte("SELECT 1;")},
+	})
+
+	s.ErrorContains(err, "have the same version")
+
+	// 3
+	loaded, err := migrate.Load(fstest.MapFS{
+		"2_second.up.sql":   {Data: []byte("SELECT 2;")},
+		"2_second.down.sql": {Data: []byte("SELECT 2;")},
+		"1_first.up.sql":    {Data: []byte("SELECT 1;")},
+		"1_first.down.sql":  {Data: []byte("SELECT 1;")},
+		"README.md":         {Data: []byte("not a migration")},
+	})
+
+	s.Require().NoError(err)
+	s.Require().Len(loaded, 2)
+	s.Equal("first", loaded[0].Name)
+	s.Equal("second", loaded[1].Name)
+}
+
+func (s *postgresSuite) TestUpDownStatus() {
+	ctx := context.Background()
+
+	migrator, err := migrate.New(s.pool, migrations.FS, nil)
+
+	s.Require().NoError(err)
+
+	// 1
+	_, err = migrator.Up(ctx)
+
+	s.Require().NoError(err)
+
+	applied, err := migrator.Up(ctx)
+
+	s.Require().NoError(err)
+	s.Zero(applied)
+
+	status, err := migrator.Status(ctx)
+
+	s.Require().NoError(err)
+	s.Zero(status.Pending())
+	s.False(status.Dirty)
+
+	// 2
+	reverted, err := migrator.Down(ctx, 1)
+
+	s.Require().NoError(err)
+	s.Equal(1, reverted)
+
+	status, err = migrator.Status(ctx)
+
+	s.Require().NoError(err)
+	s.Equal(1, status.Pending())
+
+	applied, err = migrator.Up(ctx)
+
+	s.Require().NoError(err)
+	s.Equal(1, applied)
+
+	// 3
+	var plans int
+
+	s.Require().NoError(s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM subscriptions WHERE name = 'Gold'").Scan(&plans))
+	s.Equal(1, plans)
+}
@@ -0,0 +1,16 @@
+package migrations
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+type migrationsSuite struct {
+	suite.Suite
+}
+
+type postgresSuite struct {
+	suite.Suite
+
+	pool *pgxpool.Pool
+}
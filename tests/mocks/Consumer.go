@@ -1,12 +1,9 @@
-// Code generated by mockery v2.46.3. DO NOT EDIT.
+// Code generated by mockery v2.53.5. DO NOT EDIT.
 
 package mocks
 
 import (
 	context "context"
-	dto "urleater/dto"
-
-	kafkaProducerConsumer "urleater/internal/repository/kafka"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -16,39 +13,37 @@ type Consumer struct {
 	mock.Mock
 }
 
-// GetConfig provides a mock function with given fields:
-func (_m *Consumer) GetConfig() kafkaProducerConsumer.KafkaConfig {
+// Close provides a mock function with no fields
+func (_m *Consumer) Close() error {
 	ret := _m.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetConfig")
+		panic("no return value specified for Close")
 	}
 
-	var r0 kafkaProducerConsumer.KafkaConfig
-	if rf, ok := ret.Get(0).(func() kafkaProducerConsumer.KafkaConfig); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
 		r0 = rf()
 	} else {
-		r0 = ret.Get(0).(kafkaProducerConsumer.KafkaConfig)
+		r0 = ret.Error(0)
 	}
 
 	return r0
 }
 
-// GetWorkerChannel provides a mock function with given fields:
-func (_m *Consumer) GetWorkerChannel() chan dto.ConsumerData {
+// Reconnect provides a mock function with no fields
+func (_m *Consumer) Reconnect() error {
 	ret := _m.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetWorkerChannel")
+		panic("no return value specified for Reconnect")
 	}
 
-	var r0 chan dto.ConsumerData
-	if rf, ok := ret.Get(0).(func() chan dto.ConsumerData); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
 		r0 = rf()
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(chan dto.ConsumerData)
-		}
+		r0 = ret.Error(0)
 	}
 
 	return r0
@@ -1,9 +1,10 @@
-// Code generated by mockery v2.46.3. DO NOT EDIT.
+// Code generated by mockery v2.53.5. DO NOT EDIT.
 
 package mocks
 
 import (
 	context "context"
+	dto "urleater/dto"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,7 +15,7 @@ type ElasticSearcher struct {
 }
 
 // AddShortLink provides a mock function with given fields: ctx, link
-func (_m *ElasticSearcher) AddShortLink(ctx context.Context, link string) error {
+func (_m *ElasticSearcher) AddShortLink(ctx context.Context, link dto.Link) error {
 	ret := _m.Called(ctx, link)
 
 	if len(ret) == 0 {
@@ -22,7 +23,7 @@ func (_m *ElasticSearcher) AddShortLink(ctx context.Context, link string) error
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Link) error); ok {
 		r0 = rf(ctx, link)
 	} else {
 		r0 = ret.Error(0)
@@ -49,29 +50,27 @@ func (_m *ElasticSearcher) DeleteShortLink(ctx context.Context, link string) err
 	return r0
 }
 
-// SearchShortLinks provides a mock function with given fields: ctx, word, offset, limit
-func (_m *ElasticSearcher) SearchShortLinks(ctx context.Context, word string, offset int, limit int) ([]string, error) {
-	ret := _m.Called(ctx, word, offset, limit)
+// SearchShortLinks provides a mock function with given fields: ctx, query
+func (_m *ElasticSearcher) SearchShortLinks(ctx context.Context, query dto.SearchQuery) (dto.SearchResult, error) {
+	ret := _m.Called(ctx, query)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SearchShortLinks")
 	}
 
-	var r0 []string
+	var r0 dto.SearchResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) ([]string, error)); ok {
-		return rf(ctx, word, offset, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, dto.SearchQuery) (dto.SearchResult, error)); ok {
+		return rf(ctx, query)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []string); ok {
-		r0 = rf(ctx, word, offset, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, dto.SearchQuery) dto.SearchResult); ok {
+		r0 = rf(ctx, query)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]string)
-		}
+		r0 = ret.Get(0).(dto.SearchResult)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
-		r1 = rf(ctx, word, offset, limit)
+	if rf, ok := ret.Get(1).(func(context.Context, dto.SearchQuery) error); ok {
+		r1 = rf(ctx, query)
 	} else {
 		r1 = ret.Error(1)
 	}
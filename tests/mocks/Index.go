@@ -0,0 +1,196 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	dto "urleater/dto"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Index is an autogenerated mock type for the Index type
+type Index struct {
+	mock.Mock
+}
+
+// Alias provides a mock function with no fields
+func (_m *Index) Alias() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Alias")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// BulkDelete provides a mock function with given fields: ctx, index, shortLinks
+func (_m *Index) BulkDelete(ctx context.Context, index string, shortLinks []string) error {
+	ret := _m.Called(ctx, index, shortLinks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BulkDelete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(ctx, index, shortLinks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BulkIndex provides a mock function with given fields: ctx, index, links
+func (_m *Index) BulkIndex(ctx context.Context, index string, links []dto.Link) error {
+	ret := _m.Called(ctx, index, links)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BulkIndex")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []dto.Link) error); ok {
+		r0 = rf(ctx, index, links)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateVersionedIndex provides a mock function with given fields: ctx
+func (_m *Index) CreateVersionedIndex(ctx context.Context) (string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateVersionedIndex")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) string); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteIndices provides a mock function with given fields: ctx, indices
+func (_m *Index) DeleteIndices(ctx context.Context, indices ...string) error {
+	_va := make([]interface{}, len(indices))
+	for _i := range indices {
+		_va[_i] = indices[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteIndices")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) error); ok {
+		r0 = rf(ctx, indices...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IndexedShortLinks provides a mock function with given fields: ctx, index
+func (_m *Index) IndexedShortLinks(ctx context.Context, index string) (map[string]dto.Link, error) {
+	ret := _m.Called(ctx, index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IndexedShortLinks")
+	}
+
+	var r0 map[string]dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (map[string]dto.Link, error)); ok {
+		return rf(ctx, index)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) map[string]dto.Link); ok {
+		r0 = rf(ctx, index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SwitchAlias provides a mock function with given fields: ctx, index
+func (_m *Index) SwitchAlias(ctx context.Context, index string) ([]string, error) {
+	ret := _m.Called(ctx, index)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SwitchAlias")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, index)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, index)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, index)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewIndex creates a new instance of Index. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIndex(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Index {
+	mock := &Index{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
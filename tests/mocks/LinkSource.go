@@ -0,0 +1,87 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	dto "urleater/dto"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// LinkSource is an autogenerated mock type for the LinkSource type
+type LinkSource struct {
+	mock.Mock
+}
+
+// CountShortLinks provides a mock function with given fields: ctx
+func (_m *LinkSource) CountShortLinks(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountShortLinks")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetShortLinksBatch provides a mock function with given fields: ctx, afterShortUrl, limit
+func (_m *LinkSource) GetShortLinksBatch(ctx context.Context, afterShortUrl string, limit int) ([]dto.Link, error) {
+	ret := _m.Called(ctx, afterShortUrl, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetShortLinksBatch")
+	}
+
+	var r0 []dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]dto.Link, error)); ok {
+		return rf(ctx, afterShortUrl, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []dto.Link); ok {
+		r0 = rf(ctx, afterShortUrl, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, afterShortUrl, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewLinkSource creates a new instance of LinkSource. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLinkSource(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LinkSource {
+	mock := &LinkSource{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
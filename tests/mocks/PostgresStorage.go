@@ -1,4 +1,4 @@
-// Code generated by mockery v2.46.3. DO NOT EDIT.
+// Code generated by mockery v2.53.5. DO NOT EDIT.
 
 package mocks
 
@@ -16,6 +16,66 @@ type PostgresStorage struct {
 	mock.Mock
 }
 
+// AddLinkCredit provides a mock function with given fields: ctx, credit
+func (_m *PostgresStorage) AddLinkCredit(ctx context.Context, credit dto.LinkCredit) (*dto.User, error) {
+	ret := _m.Called(ctx, credit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddLinkCredit")
+	}
+
+	var r0 *dto.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.LinkCredit) (*dto.User, error)); ok {
+		return rf(ctx, credit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.LinkCredit) *dto.User); ok {
+		r0 = rf(ctx, credit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.LinkCredit) error); ok {
+		r1 = rf(ctx, credit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddPrepaidPeriods provides a mock function with given fields: ctx, email, periods
+func (_m *PostgresStorage) AddPrepaidPeriods(ctx context.Context, email string, periods int) (*dto.UserSubscription, error) {
+	ret := _m.Called(ctx, email, periods)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddPrepaidPeriods")
+	}
+
+	var r0 *dto.UserSubscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (*dto.UserSubscription, error)); ok {
+		return rf(ctx, email, periods)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *dto.UserSubscription); ok {
+		r0 = rf(ctx, email, periods)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.UserSubscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, email, periods)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ChangePassword provides a mock function with given fields: ctx, email, password
 func (_m *PostgresStorage) ChangePassword(ctx context.Context, email string, password string) error {
 	ret := _m.Called(ctx, email, password)
@@ -24,39 +84,807 @@ func (_m *PostgresStorage) ChangePassword(ctx context.Context, email string, pas
 		panic("no return value specified for ChangePassword")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, email, password)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CompleteOutboxEvent provides a mock function with given fields: ctx, id
+func (_m *PostgresStorage) CompleteOutboxEvent(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompleteOutboxEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CountOutboxEvents provides a mock function with given fields: ctx
+func (_m *PostgresStorage) CountOutboxEvents(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountOutboxEvents")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateDomain provides a mock function with given fields: ctx, domain
+func (_m *PostgresStorage) CreateDomain(ctx context.Context, domain dto.Domain) (*dto.Domain, error) {
+	ret := _m.Called(ctx, domain)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateDomain")
+	}
+
+	var r0 *dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Domain) (*dto.Domain, error)); ok {
+		return rf(ctx, domain)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Domain) *dto.Domain); ok {
+		r0 = rf(ctx, domain)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.Domain) error); ok {
+		r1 = rf(ctx, domain)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateEmailVerification provides a mock function with given fields: ctx, email, tokenHash, expiresAt
+func (_m *PostgresStorage) CreateEmailVerification(ctx context.Context, email string, tokenHash string, expiresAt time.Time) error {
+	ret := _m.Called(ctx, email, tokenHash, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEmailVerification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) error); ok {
+		r0 = rf(ctx, email, tokenHash, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePromoCode provides a mock function with given fields: ctx, promo
+func (_m *PostgresStorage) CreatePromoCode(ctx context.Context, promo dto.PromoCode) (*dto.PromoCode, error) {
+	ret := _m.Called(ctx, promo)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePromoCode")
+	}
+
+	var r0 *dto.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.PromoCode) (*dto.PromoCode, error)); ok {
+		return rf(ctx, promo)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.PromoCode) *dto.PromoCode); ok {
+		r0 = rf(ctx, promo)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.PromoCode) error); ok {
+		r1 = rf(ctx, promo)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateReferral provides a mock function with given fields: ctx, inviteeEmail, referralCode
+func (_m *PostgresStorage) CreateReferral(ctx context.Context, inviteeEmail string, referralCode string) (*dto.Referral, error) {
+	ret := _m.Called(ctx, inviteeEmail, referralCode)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateReferral")
+	}
+
+	var r0 *dto.Referral
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dto.Referral, error)); ok {
+		return rf(ctx, inviteeEmail, referralCode)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dto.Referral); ok {
+		r0 = rf(ctx, inviteeEmail, referralCode)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Referral)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, inviteeEmail, referralCode)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateShortLink provides a mock function with given fields: ctx, shortLink, longLink, userID, domain
+func (_m *PostgresStorage) CreateShortLink(ctx context.Context, shortLink string, longLink string, userID string, domain string) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink, longLink, userID, domain)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateShortLink")
+	}
+
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink, longLink, userID, domain)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *dto.Link); ok {
+		r0 = rf(ctx, shortLink, longLink, userID, domain)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, shortLink, longLink, userID, domain)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSubscription provides a mock function with given fields: ctx, sub
+func (_m *PostgresStorage) CreateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error) {
+	ret := _m.Called(ctx, sub)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSubscription")
+	}
+
+	var r0 *dto.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) (*dto.Subscription, error)); ok {
+		return rf(ctx, sub)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) *dto.Subscription); ok {
+		r0 = rf(ctx, sub)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.Subscription) error); ok {
+		r1 = rf(ctx, sub)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: ctx, email, password
+func (_m *PostgresStorage) CreateUser(ctx context.Context, email string, password string) error {
+	ret := _m.Called(ctx, email, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteDomain provides a mock function with given fields: ctx, email, host
+func (_m *PostgresStorage) DeleteDomain(ctx context.Context, email string, host string) error {
+	ret := _m.Called(ctx, email, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, host)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePromoCode provides a mock function with given fields: ctx, code
+func (_m *PostgresStorage) DeletePromoCode(ctx context.Context, code string) error {
+	ret := _m.Called(ctx, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePromoCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteShortLink provides a mock function with given fields: ctx, shortLink
+func (_m *PostgresStorage) DeleteShortLink(ctx context.Context, shortLink string) error {
+	ret := _m.Called(ctx, shortLink)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteShortLink")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, shortLink)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteSubscription provides a mock function with given fields: ctx, id
+func (_m *PostgresStorage) DeleteSubscription(ctx context.Context, id int) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteSubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUserTag provides a mock function with given fields: ctx, email, tag
+func (_m *PostgresStorage) DeleteUserTag(ctx context.Context, email string, tag string) error {
+	ret := _m.Called(ctx, email, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUserTag")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, tag)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnsureReferralCode provides a mock function with given fields: ctx, email, code
+func (_m *PostgresStorage) EnsureReferralCode(ctx context.Context, email string, code string) (string, error) {
+	ret := _m.Called(ctx, email, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnsureReferralCode")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, email, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, email, code)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, email, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExtendShortLink provides a mock function with given fields: ctx, shortLink, expiresAt
+func (_m *PostgresStorage) ExtendShortLink(ctx context.Context, shortLink string, expiresAt time.Time) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExtendShortLink")
+	}
+
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) *dto.Link); ok {
+		r0 = rf(ctx, shortLink, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, shortLink, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDomain provides a mock function with given fields: ctx, host
+func (_m *PostgresStorage) GetDomain(ctx context.Context, host string) (*dto.Domain, error) {
+	ret := _m.Called(ctx, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDomain")
+	}
+
+	var r0 *dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.Domain, error)); ok {
+		return rf(ctx, host)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.Domain); ok {
+		r0 = rf(ctx, host)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, host)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDueUserSubscriptions provides a mock function with given fields: ctx, now, limit
+func (_m *PostgresStorage) GetDueUserSubscriptions(ctx context.Context, now time.Time, limit int) ([]dto.UserSubscription, error) {
+	ret := _m.Called(ctx, now, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDueUserSubscriptions")
+	}
+
+	var r0 []dto.UserSubscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) ([]dto.UserSubscription, error)); ok {
+		return rf(ctx, now, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []dto.UserSubscription); ok {
+		r0 = rf(ctx, now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.UserSubscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLinkCredits provides a mock function with given fields: ctx, email, limit
+func (_m *PostgresStorage) GetLinkCredits(ctx context.Context, email string, limit int) ([]dto.LinkCredit, error) {
+	ret := _m.Called(ctx, email, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinkCredits")
+	}
+
+	var r0 []dto.LinkCredit
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]dto.LinkCredit, error)); ok {
+		return rf(ctx, email, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []dto.LinkCredit); ok {
+		r0 = rf(ctx, email, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.LinkCredit)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, email, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPromoCode provides a mock function with given fields: ctx, code
+func (_m *PostgresStorage) GetPromoCode(ctx context.Context, code string) (*dto.PromoCode, error) {
+	ret := _m.Called(ctx, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPromoCode")
+	}
+
+	var r0 *dto.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.PromoCode, error)); ok {
+		return rf(ctx, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.PromoCode); ok {
+		r0 = rf(ctx, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPromoCodes provides a mock function with given fields: ctx
+func (_m *PostgresStorage) GetPromoCodes(ctx context.Context) ([]dto.PromoCode, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPromoCodes")
+	}
+
+	var r0 []dto.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]dto.PromoCode, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []dto.PromoCode); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReferrals provides a mock function with given fields: ctx, referrerEmail
+func (_m *PostgresStorage) GetReferrals(ctx context.Context, referrerEmail string) ([]dto.Referral, error) {
+	ret := _m.Called(ctx, referrerEmail)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReferrals")
+	}
+
+	var r0 []dto.Referral
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.Referral, error)); ok {
+		return rf(ctx, referrerEmail)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.Referral); ok {
+		r0 = rf(ctx, referrerEmail)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Referral)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, referrerEmail)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetShortLink provides a mock function with given fields: ctx, shortLink
+func (_m *PostgresStorage) GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetShortLink")
+	}
+
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.Link); ok {
+		r0 = rf(ctx, shortLink)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, shortLink)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetShortLinksByShortUrls provides a mock function with given fields: ctx, shortLinks
+func (_m *PostgresStorage) GetShortLinksByShortUrls(ctx context.Context, shortLinks []string) ([]dto.Link, error) {
+	ret := _m.Called(ctx, shortLinks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetShortLinksByShortUrls")
+	}
+
+	var r0 []dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]dto.Link, error)); ok {
+		return rf(ctx, shortLinks)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []dto.Link); ok {
+		r0 = rf(ctx, shortLinks)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, shortLinks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSubscription provides a mock function with given fields: ctx, id
+func (_m *PostgresStorage) GetSubscription(ctx context.Context, id int) (*dto.Subscription, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubscription")
+	}
+
+	var r0 *dto.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*dto.Subscription, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *dto.Subscription); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSubscriptions provides a mock function with given fields: ctx, activeOnly
+func (_m *PostgresStorage) GetSubscriptions(ctx context.Context, activeOnly bool) ([]dto.Subscription, error) {
+	ret := _m.Called(ctx, activeOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubscriptions")
+	}
+
+	var r0 []dto.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool) ([]dto.Subscription, error)); ok {
+		return rf(ctx, activeOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, bool) []dto.Subscription); ok {
+		r0 = rf(ctx, activeOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, bool) error); ok {
+		r1 = rf(ctx, activeOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTotalUserLinksNumber provides a mock function with given fields: ctx, email, filter
+func (_m *PostgresStorage) GetTotalUserLinksNumber(ctx context.Context, email string, filter dto.LinkFilter) (int, error) {
+	ret := _m.Called(ctx, email, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTotalUserLinksNumber")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.LinkFilter) (int, error)); ok {
+		return rf(ctx, email, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.LinkFilter) int); ok {
+		r0 = rf(ctx, email, filter)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, dto.LinkFilter) error); ok {
+		r1 = rf(ctx, email, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, email
+func (_m *PostgresStorage) GetUser(ctx context.Context, email string) (*dto.User, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 *dto.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.User, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.User); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserCampaignStats provides a mock function with given fields: ctx, email
+func (_m *PostgresStorage) GetUserCampaignStats(ctx context.Context, email string) ([]dto.CampaignStats, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserCampaignStats")
+	}
+
+	var r0 []dto.CampaignStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.CampaignStats, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.CampaignStats); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.CampaignStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
 	} else {
-		r0 = ret.Error(0)
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
-// CreateShortLink provides a mock function with given fields: ctx, shortLink, longLink, userID
-func (_m *PostgresStorage) CreateShortLink(ctx context.Context, shortLink string, longLink string, userID string) (*dto.Link, error) {
-	ret := _m.Called(ctx, shortLink, longLink, userID)
+// GetUserDomain provides a mock function with given fields: ctx, email, host
+func (_m *PostgresStorage) GetUserDomain(ctx context.Context, email string, host string) (*dto.Domain, error) {
+	ret := _m.Called(ctx, email, host)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateShortLink")
+		panic("no return value specified for GetUserDomain")
 	}
 
-	var r0 *dto.Link
+	var r0 *dto.Domain
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*dto.Link, error)); ok {
-		return rf(ctx, shortLink, longLink, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dto.Domain, error)); ok {
+		return rf(ctx, email, host)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *dto.Link); ok {
-		r0 = rf(ctx, shortLink, longLink, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dto.Domain); ok {
+		r0 = rf(ctx, email, host)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*dto.Link)
+			r0 = ret.Get(0).(*dto.Domain)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
-		r1 = rf(ctx, shortLink, longLink, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, email, host)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -64,83 +892,119 @@ func (_m *PostgresStorage) CreateShortLink(ctx context.Context, shortLink string
 	return r0, r1
 }
 
-// CreateSubscriptions provides a mock function with given fields: ctx
-func (_m *PostgresStorage) CreateSubscriptions(ctx context.Context) error {
-	ret := _m.Called(ctx)
+// GetUserDomains provides a mock function with given fields: ctx, email
+func (_m *PostgresStorage) GetUserDomains(ctx context.Context, email string) ([]dto.Domain, error) {
+	ret := _m.Called(ctx, email)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateSubscriptions")
+		panic("no return value specified for GetUserDomains")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
-		r0 = rf(ctx)
+	var r0 []dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.Domain, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.Domain); ok {
+		r0 = rf(ctx, email)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Domain)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// CreateUser provides a mock function with given fields: ctx, email, password
-func (_m *PostgresStorage) CreateUser(ctx context.Context, email string, password string) error {
-	ret := _m.Called(ctx, email, password)
+// GetUserShortLinks provides a mock function with given fields: ctx, email, listQuery
+func (_m *PostgresStorage) GetUserShortLinks(ctx context.Context, email string, listQuery dto.LinkListQuery) ([]dto.Link, error) {
+	ret := _m.Called(ctx, email, listQuery)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateUser")
+		panic("no return value specified for GetUserShortLinks")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, email, password)
+	var r0 []dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.LinkListQuery) ([]dto.Link, error)); ok {
+		return rf(ctx, email, listQuery)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.LinkListQuery) []dto.Link); ok {
+		r0 = rf(ctx, email, listQuery)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Link)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, dto.LinkListQuery) error); ok {
+		r1 = rf(ctx, email, listQuery)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// DeleteShortLink provides a mock function with given fields: ctx, shortLink
-func (_m *PostgresStorage) DeleteShortLink(ctx context.Context, shortLink string) error {
-	ret := _m.Called(ctx, shortLink)
+// GetUserSubscription provides a mock function with given fields: ctx, email
+func (_m *PostgresStorage) GetUserSubscription(ctx context.Context, email string) (*dto.UserSubscription, error) {
+	ret := _m.Called(ctx, email)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteShortLink")
+		panic("no return value specified for GetUserSubscription")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, shortLink)
+	var r0 *dto.UserSubscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.UserSubscription, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.UserSubscription); ok {
+		r0 = rf(ctx, email)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.UserSubscription)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// ExtendShortLink provides a mock function with given fields: ctx, shortLink, expiresAt
-func (_m *PostgresStorage) ExtendShortLink(ctx context.Context, shortLink string, expiresAt time.Time) (*dto.Link, error) {
-	ret := _m.Called(ctx, shortLink, expiresAt)
+// GetUserTags provides a mock function with given fields: ctx, email
+func (_m *PostgresStorage) GetUserTags(ctx context.Context, email string) ([]dto.Tag, error) {
+	ret := _m.Called(ctx, email)
 
 	if len(ret) == 0 {
-		panic("no return value specified for ExtendShortLink")
+		panic("no return value specified for GetUserTags")
 	}
 
-	var r0 *dto.Link
+	var r0 []dto.Tag
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (*dto.Link, error)); ok {
-		return rf(ctx, shortLink, expiresAt)
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.Tag, error)); ok {
+		return rf(ctx, email)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) *dto.Link); ok {
-		r0 = rf(ctx, shortLink, expiresAt)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.Tag); ok {
+		r0 = rf(ctx, email)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*dto.Link)
+			r0 = ret.Get(0).([]dto.Tag)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
-		r1 = rf(ctx, shortLink, expiresAt)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -148,29 +1012,47 @@ func (_m *PostgresStorage) ExtendShortLink(ctx context.Context, shortLink string
 	return r0, r1
 }
 
-// GetShortLink provides a mock function with given fields: ctx, shortLink
-func (_m *PostgresStorage) GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error) {
-	ret := _m.Called(ctx, shortLink)
+// IncrementShortLinkTimesWatchedCount provides a mock function with given fields: ctx, shortLink, campaign
+func (_m *PostgresStorage) IncrementShortLinkTimesWatchedCount(ctx context.Context, shortLink string, campaign string) error {
+	ret := _m.Called(ctx, shortLink, campaign)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetShortLink")
+		panic("no return value specified for IncrementShortLinkTimesWatchedCount")
 	}
 
-	var r0 *dto.Link
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, shortLink, campaign)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LeaseOutboxEvents provides a mock function with given fields: ctx, limit, lease
+func (_m *PostgresStorage) LeaseOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]dto.OutboxEvent, error) {
+	ret := _m.Called(ctx, limit, lease)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LeaseOutboxEvents")
+	}
+
+	var r0 []dto.OutboxEvent
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.Link, error)); ok {
-		return rf(ctx, shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) ([]dto.OutboxEvent, error)); ok {
+		return rf(ctx, limit, lease)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.Link); ok {
-		r0 = rf(ctx, shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) []dto.OutboxEvent); ok {
+		r0 = rf(ctx, limit, lease)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*dto.Link)
+			r0 = ret.Get(0).([]dto.OutboxEvent)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, shortLink)
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Duration) error); ok {
+		r1 = rf(ctx, limit, lease)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -178,29 +1060,29 @@ func (_m *PostgresStorage) GetShortLink(ctx context.Context, shortLink string) (
 	return r0, r1
 }
 
-// GetSubscriptions provides a mock function with given fields: ctx
-func (_m *PostgresStorage) GetSubscriptions(ctx context.Context) ([]dto.Subscription, error) {
-	ret := _m.Called(ctx)
+// RedeemPromoCode provides a mock function with given fields: ctx, code, email, now
+func (_m *PostgresStorage) RedeemPromoCode(ctx context.Context, code string, email string, now time.Time) (*dto.PromoCode, error) {
+	ret := _m.Called(ctx, code, email, now)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetSubscriptions")
+		panic("no return value specified for RedeemPromoCode")
 	}
 
-	var r0 []dto.Subscription
+	var r0 *dto.PromoCode
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]dto.Subscription, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*dto.PromoCode, error)); ok {
+		return rf(ctx, code, email, now)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []dto.Subscription); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *dto.PromoCode); ok {
+		r0 = rf(ctx, code, email, now)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]dto.Subscription)
+			r0 = ret.Get(0).(*dto.PromoCode)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, code, email, now)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -208,27 +1090,45 @@ func (_m *PostgresStorage) GetSubscriptions(ctx context.Context) ([]dto.Subscrip
 	return r0, r1
 }
 
-// GetTotalUserLinksNumber provides a mock function with given fields: ctx, email
-func (_m *PostgresStorage) GetTotalUserLinksNumber(ctx context.Context, email string) (int, error) {
-	ret := _m.Called(ctx, email)
+// RetryOutboxEvent provides a mock function with given fields: ctx, id, retryAt, lastError
+func (_m *PostgresStorage) RetryOutboxEvent(ctx context.Context, id int64, retryAt time.Time, lastError string) error {
+	ret := _m.Called(ctx, id, retryAt, lastError)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetTotalUserLinksNumber")
+		panic("no return value specified for RetryOutboxEvent")
 	}
 
-	var r0 int
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time, string) error); ok {
+		r0 = rf(ctx, id, retryAt, lastError)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveUserSubscription provides a mock function with given fields: ctx, sub, expectedPeriodEnd, quota
+func (_m *PostgresStorage) SaveUserSubscription(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange) (bool, error) {
+	ret := _m.Called(ctx, sub, expectedPeriodEnd, quota)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveUserSubscription")
+	}
+
+	var r0 bool
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
-		return rf(ctx, email)
+	if rf, ok := ret.Get(0).(func(context.Context, dto.UserSubscription, time.Time, dto.QuotaChange) (bool, error)); ok {
+		return rf(ctx, sub, expectedPeriodEnd, quota)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
-		r0 = rf(ctx, email)
+	if rf, ok := ret.Get(0).(func(context.Context, dto.UserSubscription, time.Time, dto.QuotaChange) bool); ok {
+		r0 = rf(ctx, sub, expectedPeriodEnd, quota)
 	} else {
-		r0 = ret.Get(0).(int)
+		r0 = ret.Get(0).(bool)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, email)
+	if rf, ok := ret.Get(1).(func(context.Context, dto.UserSubscription, time.Time, dto.QuotaChange) error); ok {
+		r1 = rf(ctx, sub, expectedPeriodEnd, quota)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -236,59 +1136,66 @@ func (_m *PostgresStorage) GetTotalUserLinksNumber(ctx context.Context, email st
 	return r0, r1
 }
 
-// GetUser provides a mock function with given fields: ctx, email
-func (_m *PostgresStorage) GetUser(ctx context.Context, email string) (*dto.User, error) {
-	ret := _m.Called(ctx, email)
+// SaveUserSubscriptionWithPromoCode provides a mock function with given fields: ctx, sub, expectedPeriodEnd, quota, code, now
+func (_m *PostgresStorage) SaveUserSubscriptionWithPromoCode(ctx context.Context, sub dto.UserSubscription, expectedPeriodEnd time.Time, quota dto.QuotaChange, code string, now time.Time) (*dto.PromoCode, bool, error) {
+	ret := _m.Called(ctx, sub, expectedPeriodEnd, quota, code, now)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUser")
+		panic("no return value specified for SaveUserSubscriptionWithPromoCode")
 	}
 
-	var r0 *dto.User
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.User, error)); ok {
-		return rf(ctx, email)
+	var r0 *dto.PromoCode
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.UserSubscription, time.Time, dto.QuotaChange, string, time.Time) (*dto.PromoCode, bool, error)); ok {
+		return rf(ctx, sub, expectedPeriodEnd, quota, code, now)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.User); ok {
-		r0 = rf(ctx, email)
+	if rf, ok := ret.Get(0).(func(context.Context, dto.UserSubscription, time.Time, dto.QuotaChange, string, time.Time) *dto.PromoCode); ok {
+		r0 = rf(ctx, sub, expectedPeriodEnd, quota, code, now)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*dto.User)
+			r0 = ret.Get(0).(*dto.PromoCode)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, email)
+	if rf, ok := ret.Get(1).(func(context.Context, dto.UserSubscription, time.Time, dto.QuotaChange, string, time.Time) bool); ok {
+		r1 = rf(ctx, sub, expectedPeriodEnd, quota, code, now)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(bool)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, dto.UserSubscription, time.Time, dto.QuotaChange, string, time.Time) error); ok {
+		r2 = rf(ctx, sub, expectedPeriodEnd, quota, code, now)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
-// GetUserShortLinksWithOffsetAndLimit provides a mock function with given fields: ctx, email, offset, limit
-func (_m *PostgresStorage) GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email string, offset int, limit int) ([]dto.Link, error) {
-	ret := _m.Called(ctx, email, offset, limit)
+// UpdateDomainFallback provides a mock function with given fields: ctx, email, host, fallbackUrl
+func (_m *PostgresStorage) UpdateDomainFallback(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error) {
+	ret := _m.Called(ctx, email, host, fallbackUrl)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUserShortLinksWithOffsetAndLimit")
+		panic("no return value specified for UpdateDomainFallback")
 	}
 
-	var r0 []dto.Link
+	var r0 *dto.Domain
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) ([]dto.Link, error)); ok {
-		return rf(ctx, email, offset, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*dto.Domain, error)); ok {
+		return rf(ctx, email, host, fallbackUrl)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []dto.Link); ok {
-		r0 = rf(ctx, email, offset, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *dto.Domain); ok {
+		r0 = rf(ctx, email, host, fallbackUrl)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]dto.Link)
+			r0 = ret.Get(0).(*dto.Domain)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) error); ok {
-		r1 = rf(ctx, email, offset, limit)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, email, host, fallbackUrl)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -296,17 +1203,53 @@ func (_m *PostgresStorage) GetUserShortLinksWithOffsetAndLimit(ctx context.Conte
 	return r0, r1
 }
 
-// IncrementShortLinkTimesWatchedCount provides a mock function with given fields: ctx, shortLink
-func (_m *PostgresStorage) IncrementShortLinkTimesWatchedCount(ctx context.Context, shortLink string) error {
-	ret := _m.Called(ctx, shortLink)
+// UpdateShortLinkInfo provides a mock function with given fields: ctx, shortLink, title, description, tags
+func (_m *PostgresStorage) UpdateShortLinkInfo(ctx context.Context, shortLink string, title string, description string, tags []string) error {
+	ret := _m.Called(ctx, shortLink, title, description, tags)
 
 	if len(ret) == 0 {
-		panic("no return value specified for IncrementShortLinkTimesWatchedCount")
+		panic("no return value specified for UpdateShortLinkInfo")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, []string) error); ok {
+		r0 = rf(ctx, shortLink, title, description, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateShortLinkRedirect provides a mock function with given fields: ctx, shortLink, options
+func (_m *PostgresStorage) UpdateShortLinkRedirect(ctx context.Context, shortLink string, options dto.RedirectOptions) error {
+	ret := _m.Called(ctx, shortLink, options)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkRedirect")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.RedirectOptions) error); ok {
+		r0 = rf(ctx, shortLink, options)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateShortLinkUTM provides a mock function with given fields: ctx, shortLink, utm
+func (_m *PostgresStorage) UpdateShortLinkUTM(ctx context.Context, shortLink string, utm dto.UTM) error {
+	ret := _m.Called(ctx, shortLink, utm)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkUTM")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.UTM) error); ok {
+		r0 = rf(ctx, shortLink, utm)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -314,6 +1257,36 @@ func (_m *PostgresStorage) IncrementShortLinkTimesWatchedCount(ctx context.Conte
 	return r0
 }
 
+// UpdateSubscription provides a mock function with given fields: ctx, sub
+func (_m *PostgresStorage) UpdateSubscription(ctx context.Context, sub dto.Subscription) (*dto.Subscription, error) {
+	ret := _m.Called(ctx, sub)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSubscription")
+	}
+
+	var r0 *dto.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) (*dto.Subscription, error)); ok {
+		return rf(ctx, sub)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) *dto.Subscription); ok {
+		r0 = rf(ctx, sub)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.Subscription) error); ok {
+		r1 = rf(ctx, sub)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateUserLinks provides a mock function with given fields: ctx, email, newUrlsNumber
 func (_m *PostgresStorage) UpdateUserLinks(ctx context.Context, email string, newUrlsNumber int) (*dto.User, error) {
 	ret := _m.Called(ctx, email, newUrlsNumber)
@@ -344,6 +1317,73 @@ func (_m *PostgresStorage) UpdateUserLinks(ctx context.Context, email string, ne
 	return r0, r1
 }
 
+// VerifyDomain provides a mock function with given fields: ctx, email, host, verifiedAt
+func (_m *PostgresStorage) VerifyDomain(ctx context.Context, email string, host string, verifiedAt time.Time) (*dto.Domain, error) {
+	ret := _m.Called(ctx, email, host, verifiedAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyDomain")
+	}
+
+	var r0 *dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*dto.Domain, error)); ok {
+		return rf(ctx, email, host, verifiedAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *dto.Domain); ok {
+		r0 = rf(ctx, email, host, verifiedAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, email, host, verifiedAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, tokenHash, now, referralCredits
+func (_m *PostgresStorage) VerifyEmail(ctx context.Context, tokenHash string, now time.Time, referralCredits int) (string, *dto.Referral, error) {
+	ret := _m.Called(ctx, tokenHash, now, referralCredits)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 string
+	var r1 *dto.Referral
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, int) (string, *dto.Referral, error)); ok {
+		return rf(ctx, tokenHash, now, referralCredits)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, int) string); ok {
+		r0 = rf(ctx, tokenHash, now, referralCredits)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, int) *dto.Referral); ok {
+		r1 = rf(ctx, tokenHash, now, referralCredits)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*dto.Referral)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, time.Time, int) error); ok {
+		r2 = rf(ctx, tokenHash, now, referralCredits)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // VerifyUserPassword provides a mock function with given fields: ctx, email, password
 func (_m *PostgresStorage) VerifyUserPassword(ctx context.Context, email string, password string) error {
 	ret := _m.Called(ctx, email, password)
@@ -1,25 +1,48 @@
-// Code generated by mockery v2.46.3. DO NOT EDIT.
+// Code generated by mockery v2.53.5. DO NOT EDIT.
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+	dto "urleater/dto"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Producer is an autogenerated mock type for the Producer type
 type Producer struct {
 	mock.Mock
 }
 
-// PublishMsg provides a mock function with given fields: msgType, data, topic
-func (_m *Producer) PublishMsg(msgType string, data any, topic string) error {
-	ret := _m.Called(msgType, data, topic)
+// PublishDeadLetter provides a mock function with given fields: ctx, topic, letter
+func (_m *Producer) PublishDeadLetter(ctx context.Context, topic string, letter dto.DeadLetter) error {
+	ret := _m.Called(ctx, topic, letter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PublishDeadLetter")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.DeadLetter) error); ok {
+		r0 = rf(ctx, topic, letter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PublishEvent provides a mock function with given fields: ctx, key, event, topic
+func (_m *Producer) PublishEvent(ctx context.Context, key string, event dto.Event, topic string) error {
+	ret := _m.Called(ctx, key, event, topic)
 
 	if len(ret) == 0 {
-		panic("no return value specified for PublishMsg")
+		panic("no return value specified for PublishEvent")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, any, string) error); ok {
-		r0 = rf(msgType, data, topic)
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.Event, string) error); ok {
+		r0 = rf(ctx, key, event, topic)
 	} else {
 		r0 = ret.Error(0)
 	}
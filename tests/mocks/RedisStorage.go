@@ -1,4 +1,4 @@
-// Code generated by mockery v2.46.3. DO NOT EDIT.
+// Code generated by mockery v2.53.5. DO NOT EDIT.
 
 package mocks
 
@@ -7,6 +7,8 @@ import (
 	dto "urleater/dto"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // RedisStorage is an autogenerated mock type for the RedisStorage type
@@ -32,6 +34,36 @@ func (_m *RedisStorage) DeleteLongLinkByShortLink(ctx context.Context, shortLink
 	return r0
 }
 
+// GetQRCode provides a mock function with given fields: ctx, key
+func (_m *RedisStorage) GetQRCode(ctx context.Context, key string) ([]byte, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetQRCode")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetShortLinkByLongLink provides a mock function with given fields: ctx, shortLink
 func (_m *RedisStorage) GetShortLinkByLongLink(ctx context.Context, shortLink string) (*dto.Link, error) {
 	ret := _m.Called(ctx, shortLink)
@@ -62,6 +94,24 @@ func (_m *RedisStorage) GetShortLinkByLongLink(ctx context.Context, shortLink st
 	return r0, r1
 }
 
+// SaveQRCode provides a mock function with given fields: ctx, key, image, ttl
+func (_m *RedisStorage) SaveQRCode(ctx context.Context, key string, image []byte, ttl time.Duration) error {
+	ret := _m.Called(ctx, key, image, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveQRCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Duration) error); ok {
+		r0 = rf(ctx, key, image, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveShortLinkToLongLink provides a mock function with given fields: ctx, link
 func (_m *RedisStorage) SaveShortLinkToLongLink(ctx context.Context, link dto.Link) error {
 	ret := _m.Called(ctx, link)
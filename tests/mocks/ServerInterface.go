@@ -1,4 +1,4 @@
-// Code generated by mockery v2.45.0. DO NOT EDIT.
+// Code generated by mockery v2.53.5. DO NOT EDIT.
 
 package mocks
 
@@ -13,6 +13,60 @@ type ServerInterface struct {
 	mock.Mock
 }
 
+// AddDomain provides a mock function with given fields: c
+func (_m *ServerInterface) AddDomain(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CancelSubscription provides a mock function with given fields: c
+func (_m *ServerInterface) CancelSubscription(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CancelSubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePromoCode provides a mock function with given fields: c
+func (_m *ServerInterface) CreatePromoCode(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePromoCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateShortLink provides a mock function with given fields: c
 func (_m *ServerInterface) CreateShortLink(c echo.Context) error {
 	ret := _m.Called(c)
@@ -31,6 +85,60 @@ func (_m *ServerInterface) CreateShortLink(c echo.Context) error {
 	return r0
 }
 
+// CreateSubscription provides a mock function with given fields: c
+func (_m *ServerInterface) CreateSubscription(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteDomain provides a mock function with given fields: c
+func (_m *ServerInterface) DeleteDomain(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePromoCode provides a mock function with given fields: c
+func (_m *ServerInterface) DeletePromoCode(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePromoCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteShortLink provides a mock function with given fields: c
 func (_m *ServerInterface) DeleteShortLink(c echo.Context) error {
 	ret := _m.Called(c)
@@ -49,6 +157,78 @@ func (_m *ServerInterface) DeleteShortLink(c echo.Context) error {
 	return r0
 }
 
+// DeleteSubscription provides a mock function with given fields: c
+func (_m *ServerInterface) DeleteSubscription(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteSubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUserTag provides a mock function with given fields: c
+func (_m *ServerInterface) DeleteUserTag(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUserTag")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAllSubscriptions provides a mock function with given fields: c
+func (_m *ServerInterface) GetAllSubscriptions(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllSubscriptions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetCampaignStats provides a mock function with given fields: c
+func (_m *ServerInterface) GetCampaignStats(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCampaignStats")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetCreateShortLink provides a mock function with given fields: c
 func (_m *ServerInterface) GetCreateShortLink(c echo.Context) error {
 	ret := _m.Called(c)
@@ -67,6 +247,78 @@ func (_m *ServerInterface) GetCreateShortLink(c echo.Context) error {
 	return r0
 }
 
+// GetDomains provides a mock function with given fields: c
+func (_m *ServerInterface) GetDomains(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDomains")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetHealthz provides a mock function with given fields: c
+func (_m *ServerInterface) GetHealthz(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHealthz")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetLinkCredits provides a mock function with given fields: c
+func (_m *ServerInterface) GetLinkCredits(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinkCredits")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetLinksPage provides a mock function with given fields: c
+func (_m *ServerInterface) GetLinksPage(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinksPage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetLoginPage provides a mock function with given fields: c
 func (_m *ServerInterface) GetLoginPage(c echo.Context) error {
 	ret := _m.Called(c)
@@ -121,6 +373,60 @@ func (_m *ServerInterface) GetMainPage(_a0 echo.Context) error {
 	return r0
 }
 
+// GetPromoCodes provides a mock function with given fields: c
+func (_m *ServerInterface) GetPromoCodes(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPromoCodes")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetReadyz provides a mock function with given fields: c
+func (_m *ServerInterface) GetReadyz(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReadyz")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetReferrals provides a mock function with given fields: c
+func (_m *ServerInterface) GetReferrals(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReferrals")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetRegisterPage provides a mock function with given fields: c
 func (_m *ServerInterface) GetRegisterPage(c echo.Context) error {
 	ret := _m.Called(c)
@@ -139,6 +445,24 @@ func (_m *ServerInterface) GetRegisterPage(c echo.Context) error {
 	return r0
 }
 
+// GetSearchLinksPage provides a mock function with given fields: c
+func (_m *ServerInterface) GetSearchLinksPage(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSearchLinksPage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetShortLink provides a mock function with given fields: c
 func (_m *ServerInterface) GetShortLink(c echo.Context) error {
 	ret := _m.Called(c)
@@ -157,6 +481,42 @@ func (_m *ServerInterface) GetShortLink(c echo.Context) error {
 	return r0
 }
 
+// GetShortLinkQR provides a mock function with given fields: c
+func (_m *ServerInterface) GetShortLinkQR(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetShortLinkQR")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetShortLinksMatchingPattern provides a mock function with given fields: c
+func (_m *ServerInterface) GetShortLinksMatchingPattern(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetShortLinksMatchingPattern")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetSubscriptions provides a mock function with given fields: c
 func (_m *ServerInterface) GetSubscriptions(c echo.Context) error {
 	ret := _m.Called(c)
@@ -229,6 +589,42 @@ func (_m *ServerInterface) GetUserShortLinks(c echo.Context) error {
 	return r0
 }
 
+// GetUserShortLinksNumber provides a mock function with given fields: c
+func (_m *ServerInterface) GetUserShortLinksNumber(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserShortLinksNumber")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetUserTags provides a mock function with given fields: c
+func (_m *ServerInterface) GetUserTags(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserTags")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // PostLogin provides a mock function with given fields: c
 func (_m *ServerInterface) PostLogin(c echo.Context) error {
 	ret := _m.Called(c)
@@ -265,6 +661,168 @@ func (_m *ServerInterface) PostRegister(c echo.Context) error {
 	return r0
 }
 
+// RecordPayment provides a mock function with given fields: c
+func (_m *ServerInterface) RecordPayment(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordPayment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RedeemPromoCode provides a mock function with given fields: c
+func (_m *ServerInterface) RedeemPromoCode(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RedeemPromoCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResendEmailVerification provides a mock function with given fields: c
+func (_m *ServerInterface) ResendEmailVerification(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResendEmailVerification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Subscribe provides a mock function with given fields: c
+func (_m *ServerInterface) Subscribe(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscribe")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateDomain provides a mock function with given fields: c
+func (_m *ServerInterface) UpdateDomain(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateShortLinkInfo provides a mock function with given fields: c
+func (_m *ServerInterface) UpdateShortLinkInfo(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkInfo")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateShortLinkRedirect provides a mock function with given fields: c
+func (_m *ServerInterface) UpdateShortLinkRedirect(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkRedirect")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateShortLinkUTM provides a mock function with given fields: c
+func (_m *ServerInterface) UpdateShortLinkUTM(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkUTM")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateSubscription provides a mock function with given fields: c
+func (_m *ServerInterface) UpdateSubscription(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateUserShortLinks provides a mock function with given fields: c
 func (_m *ServerInterface) UpdateUserShortLinks(c echo.Context) error {
 	ret := _m.Called(c)
@@ -283,6 +841,42 @@ func (_m *ServerInterface) UpdateUserShortLinks(c echo.Context) error {
 	return r0
 }
 
+// VerifyDomain provides a mock function with given fields: c
+func (_m *ServerInterface) VerifyDomain(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyEmail provides a mock function with given fields: c
+func (_m *ServerInterface) VerifyEmail(c echo.Context) error {
+	ret := _m.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(echo.Context) error); ok {
+		r0 = rf(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewServerInterface creates a new instance of ServerInterface. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewServerInterface(t interface {
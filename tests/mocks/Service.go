@@ -1,12 +1,16 @@
-// Code generated by mockery v2.45.0. DO NOT EDIT.
+// Code generated by mockery v2.53.5. DO NOT EDIT.
 
 package mocks
 
 import (
 	context "context"
-	postgresDB "urleater/dto"
+	dto "urleater/dto"
 
 	mock "github.com/stretchr/testify/mock"
+
+	service "urleater/internal/service"
+
+	url "net/url"
 )
 
 // Service is an autogenerated mock type for the Service type
@@ -14,29 +18,29 @@ type Service struct {
 	mock.Mock
 }
 
-// CreateShortLink provides a mock function with given fields: ctx, shortLink, longLink, userEmail
-func (_m *Service) CreateShortLink(ctx context.Context, shortLink string, longLink string, userEmail string) (*postgresDB.Link, error) {
-	ret := _m.Called(ctx, shortLink, longLink, userEmail)
+// AddDomain provides a mock function with given fields: ctx, email, host, fallbackUrl
+func (_m *Service) AddDomain(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error) {
+	ret := _m.Called(ctx, email, host, fallbackUrl)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateShortLink")
+		panic("no return value specified for AddDomain")
 	}
 
-	var r0 *postgresDB.Link
+	var r0 *dto.Domain
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*postgresDB.Link, error)); ok {
-		return rf(ctx, shortLink, longLink, userEmail)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*dto.Domain, error)); ok {
+		return rf(ctx, email, host, fallbackUrl)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *postgresDB.Link); ok {
-		r0 = rf(ctx, shortLink, longLink, userEmail)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *dto.Domain); ok {
+		r0 = rf(ctx, email, host, fallbackUrl)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*postgresDB.Link)
+			r0 = ret.Get(0).(*dto.Domain)
 		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
-		r1 = rf(ctx, shortLink, longLink, userEmail)
+		r1 = rf(ctx, email, host, fallbackUrl)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -44,17 +48,17 @@ func (_m *Service) CreateShortLink(ctx context.Context, shortLink string, longLi
 	return r0, r1
 }
 
-// DeleteShortLink provides a mock function with given fields: ctx, shortLink, email
-func (_m *Service) DeleteShortLink(ctx context.Context, shortLink string, email string) error {
-	ret := _m.Called(ctx, shortLink, email)
+// AddReferral provides a mock function with given fields: ctx, inviteeEmail, referralCode
+func (_m *Service) AddReferral(ctx context.Context, inviteeEmail string, referralCode string) error {
+	ret := _m.Called(ctx, inviteeEmail, referralCode)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteShortLink")
+		panic("no return value specified for AddReferral")
 	}
 
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, shortLink, email)
+		r0 = rf(ctx, inviteeEmail, referralCode)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -62,29 +66,29 @@ func (_m *Service) DeleteShortLink(ctx context.Context, shortLink string, email
 	return r0
 }
 
-// GetShortLink provides a mock function with given fields: ctx, shortLink
-func (_m *Service) GetShortLink(ctx context.Context, shortLink string) (*postgresDB.Link, error) {
-	ret := _m.Called(ctx, shortLink)
+// CancelSubscription provides a mock function with given fields: ctx, email
+func (_m *Service) CancelSubscription(ctx context.Context, email string) (*dto.UserSubscription, error) {
+	ret := _m.Called(ctx, email)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetShortLink")
+		panic("no return value specified for CancelSubscription")
 	}
 
-	var r0 *postgresDB.Link
+	var r0 *dto.UserSubscription
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*postgresDB.Link, error)); ok {
-		return rf(ctx, shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.UserSubscription, error)); ok {
+		return rf(ctx, email)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *postgresDB.Link); ok {
-		r0 = rf(ctx, shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.UserSubscription); ok {
+		r0 = rf(ctx, email)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*postgresDB.Link)
+			r0 = ret.Get(0).(*dto.UserSubscription)
 		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, shortLink)
+		r1 = rf(ctx, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -92,24 +96,204 @@ func (_m *Service) GetShortLink(ctx context.Context, shortLink string) (*postgre
 	return r0, r1
 }
 
-// GetSubscriptions provides a mock function with given fields: ctx
-func (_m *Service) GetSubscriptions(ctx context.Context) ([]postgresDB.Subscription, error) {
+// CreatePromoCode provides a mock function with given fields: ctx, promo
+func (_m *Service) CreatePromoCode(ctx context.Context, promo dto.PromoCode) (*dto.PromoCode, error) {
+	ret := _m.Called(ctx, promo)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePromoCode")
+	}
+
+	var r0 *dto.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.PromoCode) (*dto.PromoCode, error)); ok {
+		return rf(ctx, promo)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.PromoCode) *dto.PromoCode); ok {
+		r0 = rf(ctx, promo)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.PromoCode) error); ok {
+		r1 = rf(ctx, promo)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateShortLink provides a mock function with given fields: ctx, shortLink, longLink, userEmail, domain
+func (_m *Service) CreateShortLink(ctx context.Context, shortLink string, longLink string, userEmail string, domain string) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink, longLink, userEmail, domain)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateShortLink")
+	}
+
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink, longLink, userEmail, domain)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *dto.Link); ok {
+		r0 = rf(ctx, shortLink, longLink, userEmail, domain)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, shortLink, longLink, userEmail, domain)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSubscription provides a mock function with given fields: ctx, plan
+func (_m *Service) CreateSubscription(ctx context.Context, plan dto.Subscription) (*dto.Subscription, error) {
+	ret := _m.Called(ctx, plan)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSubscription")
+	}
+
+	var r0 *dto.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) (*dto.Subscription, error)); ok {
+		return rf(ctx, plan)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) *dto.Subscription); ok {
+		r0 = rf(ctx, plan)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.Subscription) error); ok {
+		r1 = rf(ctx, plan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteDomain provides a mock function with given fields: ctx, email, host
+func (_m *Service) DeleteDomain(ctx context.Context, email string, host string) error {
+	ret := _m.Called(ctx, email, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteDomain")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, host)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePromoCode provides a mock function with given fields: ctx, code
+func (_m *Service) DeletePromoCode(ctx context.Context, code string) error {
+	ret := _m.Called(ctx, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePromoCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteShortLink provides a mock function with given fields: ctx, shortLink, email
+func (_m *Service) DeleteShortLink(ctx context.Context, shortLink string, email string) error {
+	ret := _m.Called(ctx, shortLink, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteShortLink")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, shortLink, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteSubscription provides a mock function with given fields: ctx, id
+func (_m *Service) DeleteSubscription(ctx context.Context, id int) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteSubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUserTag provides a mock function with given fields: ctx, email, tag
+func (_m *Service) DeleteUserTag(ctx context.Context, email string, tag string) error {
+	ret := _m.Called(ctx, email, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUserTag")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, tag)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAllSubscriptions provides a mock function with given fields: ctx
+func (_m *Service) GetAllSubscriptions(ctx context.Context) ([]dto.Subscription, error) {
 	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetSubscriptions")
+		panic("no return value specified for GetAllSubscriptions")
 	}
 
-	var r0 []postgresDB.Subscription
+	var r0 []dto.Subscription
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]postgresDB.Subscription, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context) ([]dto.Subscription, error)); ok {
 		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []postgresDB.Subscription); ok {
+	if rf, ok := ret.Get(0).(func(context.Context) []dto.Subscription); ok {
 		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]postgresDB.Subscription)
+			r0 = ret.Get(0).([]dto.Subscription)
 		}
 	}
 
@@ -122,24 +306,24 @@ func (_m *Service) GetSubscriptions(ctx context.Context) ([]postgresDB.Subscript
 	return r0, r1
 }
 
-// GetUser provides a mock function with given fields: ctx, email
-func (_m *Service) GetUser(ctx context.Context, email string) (*postgresDB.User, error) {
+// GetCampaignStats provides a mock function with given fields: ctx, email
+func (_m *Service) GetCampaignStats(ctx context.Context, email string) ([]dto.CampaignStats, error) {
 	ret := _m.Called(ctx, email)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUser")
+		panic("no return value specified for GetCampaignStats")
 	}
 
-	var r0 *postgresDB.User
+	var r0 []dto.CampaignStats
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*postgresDB.User, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.CampaignStats, error)); ok {
 		return rf(ctx, email)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *postgresDB.User); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.CampaignStats); ok {
 		r0 = rf(ctx, email)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*postgresDB.User)
+			r0 = ret.Get(0).([]dto.CampaignStats)
 		}
 	}
 
@@ -152,38 +336,96 @@ func (_m *Service) GetUser(ctx context.Context, email string) (*postgresDB.User,
 	return r0, r1
 }
 
-// GetUserShortLinksWithOffsetAndLimit provides a mock function with given fields: ctx, email, offset, limit
-func (_m *Service) GetUserShortLinksWithOffsetAndLimit(ctx context.Context, email string, offset int, limit int) ([]postgresDB.Link, *postgresDB.User, error) {
-	ret := _m.Called(ctx, email, offset, limit)
+// GetLinkCredits provides a mock function with given fields: ctx, email
+func (_m *Service) GetLinkCredits(ctx context.Context, email string) ([]dto.LinkCredit, error) {
+	ret := _m.Called(ctx, email)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUserShortLinksWithOffsetAndLimit")
+		panic("no return value specified for GetLinkCredits")
 	}
 
-	var r0 []postgresDB.Link
-	var r1 *postgresDB.User
-	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) ([]postgresDB.Link, *postgresDB.User, error)); ok {
-		return rf(ctx, email, offset, limit)
+	var r0 []dto.LinkCredit
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.LinkCredit, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.LinkCredit); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.LinkCredit)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) []postgresDB.Link); ok {
-		r0 = rf(ctx, email, offset, limit)
+
+	return r0, r1
+}
+
+// GetPromoCodes provides a mock function with given fields: ctx
+func (_m *Service) GetPromoCodes(ctx context.Context) ([]dto.PromoCode, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPromoCodes")
+	}
+
+	var r0 []dto.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]dto.PromoCode, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []dto.PromoCode); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]postgresDB.Link)
+			r0 = ret.Get(0).([]dto.PromoCode)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, int, int) *postgresDB.User); ok {
-		r1 = rf(ctx, email, offset, limit)
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReferrals provides a mock function with given fields: ctx, email
+func (_m *Service) GetReferrals(ctx context.Context, email string) (string, []dto.Referral, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReferrals")
+	}
+
+	var r0 string
+	var r1 []dto.Referral
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, []dto.Referral, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) []dto.Referral); ok {
+		r1 = rf(ctx, email)
 	} else {
 		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(*postgresDB.User)
+			r1 = ret.Get(1).([]dto.Referral)
 		}
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, string, int, int) error); ok {
-		r2 = rf(ctx, email, offset, limit)
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, email)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -191,65 +433,781 @@ func (_m *Service) GetUserShortLinksWithOffsetAndLimit(ctx context.Context, emai
 	return r0, r1, r2
 }
 
-// LoginUser provides a mock function with given fields: ctx, email, password
-func (_m *Service) LoginUser(ctx context.Context, email string, password string) error {
-	ret := _m.Called(ctx, email, password)
+// GetShortLink provides a mock function with given fields: ctx, shortLink
+func (_m *Service) GetShortLink(ctx context.Context, shortLink string) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink)
 
 	if len(ret) == 0 {
-		panic("no return value specified for LoginUser")
+		panic("no return value specified for GetShortLink")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, email, password)
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.Link); ok {
+		r0 = rf(ctx, shortLink)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, shortLink)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// RegisterUser provides a mock function with given fields: ctx, email, password
-func (_m *Service) RegisterUser(ctx context.Context, email string, password string) error {
-	ret := _m.Called(ctx, email, password)
+// GetShortLinkQR provides a mock function with given fields: ctx, shortLink, baseUrl, options
+func (_m *Service) GetShortLinkQR(ctx context.Context, shortLink string, baseUrl string, options service.QROptions) (*service.QRImage, error) {
+	ret := _m.Called(ctx, shortLink, baseUrl, options)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RegisterUser")
+		panic("no return value specified for GetShortLinkQR")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, email, password)
+	var r0 *service.QRImage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, service.QROptions) (*service.QRImage, error)); ok {
+		return rf(ctx, shortLink, baseUrl, options)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, service.QROptions) *service.QRImage); ok {
+		r0 = rf(ctx, shortLink, baseUrl, options)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*service.QRImage)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, service.QROptions) error); ok {
+		r1 = rf(ctx, shortLink, baseUrl, options)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// UpdateUserShortLinks provides a mock function with given fields: ctx, email, deltaLinks
-func (_m *Service) UpdateUserShortLinks(ctx context.Context, email string, deltaLinks int) (*postgresDB.User, error) {
-	ret := _m.Called(ctx, email, deltaLinks)
+// GetShortLinksMatchingPattern provides a mock function with given fields: ctx, email, global, containsWord, offset
+func (_m *Service) GetShortLinksMatchingPattern(ctx context.Context, email string, global bool, containsWord string, offset int) (dto.SearcherMatchResult, error) {
+	ret := _m.Called(ctx, email, global, containsWord, offset)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateUserShortLinks")
+		panic("no return value specified for GetShortLinksMatchingPattern")
 	}
 
-	var r0 *postgresDB.User
+	var r0 dto.SearcherMatchResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int) (*postgresDB.User, error)); ok {
-		return rf(ctx, email, deltaLinks)
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, string, int) (dto.SearcherMatchResult, error)); ok {
+		return rf(ctx, email, global, containsWord, offset)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, int) *postgresDB.User); ok {
-		r0 = rf(ctx, email, deltaLinks)
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, string, int) dto.SearcherMatchResult); ok {
+		r0 = rf(ctx, email, global, containsWord, offset)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*postgresDB.User)
-		}
+		r0 = ret.Get(0).(dto.SearcherMatchResult)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
-		r1 = rf(ctx, email, deltaLinks)
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool, string, int) error); ok {
+		r1 = rf(ctx, email, global, containsWord, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSubscriptions provides a mock function with given fields: ctx
+func (_m *Service) GetSubscriptions(ctx context.Context) ([]dto.Subscription, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubscriptions")
+	}
+
+	var r0 []dto.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]dto.Subscription, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []dto.Subscription); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTotalUserLinks provides a mock function with given fields: ctx, email
+func (_m *Service) GetTotalUserLinks(ctx context.Context, email string) (int, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTotalUserLinks")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, email
+func (_m *Service) GetUser(ctx context.Context, email string) (*dto.User, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUser")
+	}
+
+	var r0 *dto.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.User, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.User); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserDomains provides a mock function with given fields: ctx, email
+func (_m *Service) GetUserDomains(ctx context.Context, email string) ([]dto.Domain, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserDomains")
+	}
+
+	var r0 []dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.Domain, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.Domain); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserShortLinks provides a mock function with given fields: ctx, email, listQuery, cursor
+func (_m *Service) GetUserShortLinks(ctx context.Context, email string, listQuery dto.LinkListQuery, cursor string) (*dto.LinkPage, *dto.User, error) {
+	ret := _m.Called(ctx, email, listQuery, cursor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserShortLinks")
+	}
+
+	var r0 *dto.LinkPage
+	var r1 *dto.User
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.LinkListQuery, string) (*dto.LinkPage, *dto.User, error)); ok {
+		return rf(ctx, email, listQuery, cursor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, dto.LinkListQuery, string) *dto.LinkPage); ok {
+		r0 = rf(ctx, email, listQuery, cursor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.LinkPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, dto.LinkListQuery, string) *dto.User); ok {
+		r1 = rf(ctx, email, listQuery, cursor)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*dto.User)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, dto.LinkListQuery, string) error); ok {
+		r2 = rf(ctx, email, listQuery, cursor)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetUserSubscription provides a mock function with given fields: ctx, email
+func (_m *Service) GetUserSubscription(ctx context.Context, email string) (*dto.UserSubscription, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserSubscription")
+	}
+
+	var r0 *dto.UserSubscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.UserSubscription, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.UserSubscription); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.UserSubscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserTags provides a mock function with given fields: ctx, email
+func (_m *Service) GetUserTags(ctx context.Context, email string) ([]dto.Tag, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserTags")
+	}
+
+	var r0 []dto.Tag
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dto.Tag, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dto.Tag); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dto.Tag)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsAdmin provides a mock function with given fields: ctx, email
+func (_m *Service) IsAdmin(ctx context.Context, email string) (bool, error) {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsAdmin")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginUser provides a mock function with given fields: ctx, email, password
+func (_m *Service) LoginUser(ctx context.Context, email string, password string) error {
+	ret := _m.Called(ctx, email, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LoginUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordPayment provides a mock function with given fields: ctx, email, periods
+func (_m *Service) RecordPayment(ctx context.Context, email string, periods int) (*dto.UserSubscription, error) {
+	ret := _m.Called(ctx, email, periods)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordPayment")
+	}
+
+	var r0 *dto.UserSubscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (*dto.UserSubscription, error)); ok {
+		return rf(ctx, email, periods)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *dto.UserSubscription); ok {
+		r0 = rf(ctx, email, periods)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.UserSubscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, email, periods)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RedeemPromoCode provides a mock function with given fields: ctx, email, code
+func (_m *Service) RedeemPromoCode(ctx context.Context, email string, code string) (*dto.PromoCode, error) {
+	ret := _m.Called(ctx, email, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RedeemPromoCode")
+	}
+
+	var r0 *dto.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dto.PromoCode, error)); ok {
+		return rf(ctx, email, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dto.PromoCode); ok {
+		r0 = rf(ctx, email, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, email, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Redirect provides a mock function with given fields: ctx, shortLink, path, query
+func (_m *Service) Redirect(ctx context.Context, shortLink string, path string, query url.Values) (string, error) {
+	ret := _m.Called(ctx, shortLink, path, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Redirect")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, url.Values) (string, error)); ok {
+		return rf(ctx, shortLink, path, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, url.Values) string); ok {
+		r0 = rf(ctx, shortLink, path, query)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, url.Values) error); ok {
+		r1 = rf(ctx, shortLink, path, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RegisterUser provides a mock function with given fields: ctx, email, password
+func (_m *Service) RegisterUser(ctx context.Context, email string, password string) error {
+	ret := _m.Called(ctx, email, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, email, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RequestEmailVerification provides a mock function with given fields: ctx, email
+func (_m *Service) RequestEmailVerification(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestEmailVerification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResolveHost provides a mock function with given fields: ctx, host
+func (_m *Service) ResolveHost(ctx context.Context, host string) (*dto.Domain, error) {
+	ret := _m.Called(ctx, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResolveHost")
+	}
+
+	var r0 *dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*dto.Domain, error)); ok {
+		return rf(ctx, host)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *dto.Domain); ok {
+		r0 = rf(ctx, host)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, host)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Subscribe provides a mock function with given fields: ctx, email, planId, promoCode
+func (_m *Service) Subscribe(ctx context.Context, email string, planId int, promoCode string) (*dto.UserSubscription, *dto.Charge, error) {
+	ret := _m.Called(ctx, email, planId, promoCode)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscribe")
+	}
+
+	var r0 *dto.UserSubscription
+	var r1 *dto.Charge
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) (*dto.UserSubscription, *dto.Charge, error)); ok {
+		return rf(ctx, email, planId, promoCode)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, string) *dto.UserSubscription); ok {
+		r0 = rf(ctx, email, planId, promoCode)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.UserSubscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, string) *dto.Charge); ok {
+		r1 = rf(ctx, email, planId, promoCode)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*dto.Charge)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, int, string) error); ok {
+		r2 = rf(ctx, email, planId, promoCode)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdateDomainFallback provides a mock function with given fields: ctx, email, host, fallbackUrl
+func (_m *Service) UpdateDomainFallback(ctx context.Context, email string, host string, fallbackUrl string) (*dto.Domain, error) {
+	ret := _m.Called(ctx, email, host, fallbackUrl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateDomainFallback")
+	}
+
+	var r0 *dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*dto.Domain, error)); ok {
+		return rf(ctx, email, host, fallbackUrl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *dto.Domain); ok {
+		r0 = rf(ctx, email, host, fallbackUrl)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, email, host, fallbackUrl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateShortLinkInfo provides a mock function with given fields: ctx, shortLink, email, title, description, tags
+func (_m *Service) UpdateShortLinkInfo(ctx context.Context, shortLink string, email string, title string, description string, tags []string) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink, email, title, description, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkInfo")
+	}
+
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, []string) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink, email, title, description, tags)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, []string) *dto.Link); ok {
+		r0 = rf(ctx, shortLink, email, title, description, tags)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string, []string) error); ok {
+		r1 = rf(ctx, shortLink, email, title, description, tags)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateShortLinkRedirect provides a mock function with given fields: ctx, shortLink, email, options
+func (_m *Service) UpdateShortLinkRedirect(ctx context.Context, shortLink string, email string, options dto.RedirectOptions) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink, email, options)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkRedirect")
+	}
+
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dto.RedirectOptions) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink, email, options)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dto.RedirectOptions) *dto.Link); ok {
+		r0 = rf(ctx, shortLink, email, options)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, dto.RedirectOptions) error); ok {
+		r1 = rf(ctx, shortLink, email, options)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateShortLinkUTM provides a mock function with given fields: ctx, shortLink, email, utm
+func (_m *Service) UpdateShortLinkUTM(ctx context.Context, shortLink string, email string, utm dto.UTM) (*dto.Link, error) {
+	ret := _m.Called(ctx, shortLink, email, utm)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateShortLinkUTM")
+	}
+
+	var r0 *dto.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dto.UTM) (*dto.Link, error)); ok {
+		return rf(ctx, shortLink, email, utm)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dto.UTM) *dto.Link); ok {
+		r0 = rf(ctx, shortLink, email, utm)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Link)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, dto.UTM) error); ok {
+		r1 = rf(ctx, shortLink, email, utm)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateSubscription provides a mock function with given fields: ctx, plan
+func (_m *Service) UpdateSubscription(ctx context.Context, plan dto.Subscription) (*dto.Subscription, error) {
+	ret := _m.Called(ctx, plan)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateSubscription")
+	}
+
+	var r0 *dto.Subscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) (*dto.Subscription, error)); ok {
+		return rf(ctx, plan)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dto.Subscription) *dto.Subscription); ok {
+		r0 = rf(ctx, plan)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Subscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dto.Subscription) error); ok {
+		r1 = rf(ctx, plan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateUserShortLinks provides a mock function with given fields: ctx, email, deltaLinks
+func (_m *Service) UpdateUserShortLinks(ctx context.Context, email string, deltaLinks int) (*dto.User, error) {
+	ret := _m.Called(ctx, email, deltaLinks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUserShortLinks")
+	}
+
+	var r0 *dto.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (*dto.User, error)); ok {
+		return rf(ctx, email, deltaLinks)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *dto.User); ok {
+		r0 = rf(ctx, email, deltaLinks)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, email, deltaLinks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyDomain provides a mock function with given fields: ctx, email, host
+func (_m *Service) VerifyDomain(ctx context.Context, email string, host string) (*dto.Domain, error) {
+	ret := _m.Called(ctx, email, host)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyDomain")
+	}
+
+	var r0 *dto.Domain
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dto.Domain, error)); ok {
+		return rf(ctx, email, host)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dto.Domain); ok {
+		r0 = rf(ctx, email, host)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dto.Domain)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, email, host)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *Service) VerifyEmail(ctx context.Context, token string) (string, error) {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,70 @@
+package outbox_relay
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/mock"
+	"time"
+	"urleater/dto"
+)
+
+func (s *outboxRelaySuite) TestRelayOutboxEvents() {
+	ctx := context.Background()
+
+	link := dto.Link{ShortUrl: "created1", LongUrl: "https://a.ru", UserEmail: "owner@mail.ru"}
+
+	created := dto.OutboxEvent{Id: 1, EventId: "e1", Type: dto.EventLinkCreated, Version: 1, Key: "created1", Payload: []byte(`{"short_link":"created1"}`), Attempts: 1}
+	deleted := dto.OutboxEvent{Id: 2, EventId: "e2", Type: dto.EventLinkDeleted, Version: 1, Key: "deleted1", Payload: []byte(`{"short_link":"deleted1"}`), Attempts: 1}
+	gone := dto.OutboxEvent{Id: 3, EventId: "e3", Type: dto.EventLinkUpdated, Version: 1, Key: "gone1", Payload: []byte(`{"short_link":"gone1"}`), Attempts: 3}
+	registered := dto.OutboxEvent{Id: 4, EventId: "e4", Type: dto.EventUserRegistered, Version: 1, Key: "owner@mail.ru", Payload: []byte(`{"email":"owner@mail.ru"}`), Attempts: 1}
+
+	// 1
+	s.storage.On("CountOutboxEvents", mock.Anything).Return(4, nil).Once()
+	s.storage.On("LeaseOutboxEvents", mock.Anything, 100, 30*time.Second).Return([]dto.OutboxEvent{created, deleted, gone, registered}, nil).Once()
+
+	s.storage.On("GetShortLink", mock.Anything, "created1").Return(&link, nil).Once()
+	s.redis.On("SaveShortLinkToLongLink", mock.Anything, link).Return(nil).Once()
+	s.searcher.On("AddShortLink", mock.Anything, link).Return(nil).Once()
+	s.producer.On("PublishEvent", mock.Anything, "created1", mock.MatchedBy(func(event dto.Event) bool {
+		return event.Id == "e1" && event.Type == dto.EventLinkCreated && string(event.Data) == `{"short_link":"created1"}`
+	}), "links").Return(nil).Once()
+	s.storage.On("CompleteOutboxEvent", mock.Anything, int64(1)).Return(nil).Once()
+
+	s.storage.On("GetShortLink", mock.Anything, "deleted1").Return(nil, pgx.ErrNoRows).Once()
+	s.redis.On("DeleteLongLinkByShortLink", mock.Anything, "deleted1").Return(nil).Once()
+	s.searcher.On("DeleteShortLink", mock.Anything, "deleted1").Return(nil).Once()
+	s.producer.On("PublishEvent", mock.Anything, "deleted1", mock.MatchedBy(func(event dto.Event) bool {
+		return event.Id == "e2" && event.Type == dto.EventLinkDeleted
+	}), "links").Return(nil).Once()
+	s.storage.On("CompleteOutboxEvent", mock.Anything, int64(2)).Return(nil).Once()
+
+	// ссылка удалена раньше, чем обработано её изменение: из кеша и индекса она должна пропасть
+	s.storage.On("GetShortLink", mock.Anything, "gone1").Return(nil, pgx.ErrNoRows).Once()
+	s.redis.On("DeleteLongLinkByShortLink", mock.Anything, "gone1").Return(nil).Once()
+	s.searcher.On("DeleteShortLink", mock.Anything, "gone1").Return(errors.New("elastic is down")).Once()
+	s.storage.On("RetryOutboxEvent", mock.Anything, int64(3), mock.MatchedBy(func(retryAt time.Time) bool {
+		delay := time.Until(retryAt)
+
+		return delay > 3*time.Second && delay <= 4*time.Second
+	}), "elastic is down").Return(nil).Once()
+
+	// события пользователей только публикуются
+	s.producer.On("PublishEvent", mock.Anything, "owner@mail.ru", mock.MatchedBy(func(event dto.Event) bool {
+		return event.Id == "e4" && event.Type == dto.EventUserRegistered
+	}), "links").Return(nil).Once()
+	s.storage.On("CompleteOutboxEvent", mock.Anything, int64(4)).Return(nil).Once()
+
+	processed, err := s.service.RelayOutboxEvents(ctx)
+
+	s.NoError(err)
+	s.Equal(4, processed)
+
+	// 2
+	s.storage.On("CountOutboxEvents", mock.Anything).Return(0, nil).Once()
+
+	processed, err = s.service.RelayOutboxEvents(ctx)
+
+	s.NoError(err)
+	s.Equal(0, processed)
+}
@@ -0,0 +1,26 @@
+package outbox_relay
+
+import (
+	"github.com/stretchr/testify/suite"
+	"urleater/internal/service"
+	"urleater/tests/mocks"
+)
+
+type outboxRelaySuite struct {
+	suite.Suite
+
+	storage  *mocks.PostgresStorage
+	redis    *mocks.RedisStorage
+	searcher *mocks.ElasticSearcher
+	producer *mocks.Producer
+	service  *service.Service
+}
+
+func (s *outboxRelaySuite) SetupTest() {
+	s.storage = mocks.NewPostgresStorage(s.T())
+	s.redis = mocks.NewRedisStorage(s.T())
+	s.searcher = mocks.NewElasticSearcher(s.T())
+	s.producer = mocks.NewProducer(s.T())
+
+	s.service = service.New(s.storage, s.redis, s.producer, nil, s.searcher, "links", nil, service.LinkRules{})
+}
@@ -0,0 +1,10 @@
+package qr_codes
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(qrCodesSuite))
+}
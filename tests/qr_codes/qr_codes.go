@@ -0,0 +1,201 @@
+package qr_codes
+
+import (
+	"bytes"
+	"context"
+	"github.com/labstack/echo/v4"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+	"urleater/dto"
+	"urleater/internal/service"
+)
+
+func (s *qrCodesSuite) decodePNG(body []byte) image.Image {
+	img, err := png.Decode(bytes.NewReader(body))
+
+	s.Require().NoError(err)
+
+	return img
+}
+
+// colors возвращает все цвета изображения.
+func colors(img image.Image) map[color.RGBA]bool {
+	found := make(map[color.RGBA]bool)
+
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			found[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)] = true
+		}
+	}
+
+	return found
+}
+
+func (s *qrCodesSuite) TestPNG() {
+	// 1
+	rec := s.request(alias, nil)
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+	s.Equal("image/png", rec.Header().Get(echo.HeaderContentType))
+	s.Empty(rec.Header().Get(echo.HeaderContentDisposition))
+
+	img := s.decodePNG(rec.Body.Bytes())
+
+	s.Equal(image.Rect(0, 0, 256, 256), img.Bounds())
+	s.Equal(map[color.RGBA]bool{{A: 0xff}: true, {R: 0xff, G: 0xff, B: 0xff, A: 0xff}: true}, colors(img))
+
+	// 2
+	rec = s.request(alias, url.Values{"size": {"512"}, "fg": {"#123"}, "bg": {"fafafa"}, "margin": {"0"}, "level": {"h"}})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	img = s.decodePNG(rec.Body.Bytes())
+
+	s.Equal(image.Rect(0, 0, 512, 512), img.Bounds())
+	s.Equal(map[color.RGBA]bool{{R: 0x11, G: 0x22, B: 0x33, A: 0xff}: true, {R: 0xfa, G: 0xfa, B: 0xfa, A: 0xff}: true}, colors(img))
+
+	// 3
+	rec = s.request(alias, url.Values{"download": {"true"}})
+
+	s.Require().Equal(http.StatusOK, rec.Code)
+	s.Equal(`attachment; filename="`+alias+`.png"`, rec.Header().Get(echo.HeaderContentDisposition))
+}
+
+func (s *qrCodesSuite) TestSVG() {
+	// 1
+	rec := s.request(alias, url.Values{"format": {"svg"}, "size": {"300"}, "fg": {"#112233"}, "margin": {"2"}, "download": {"1"}})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+	s.Equal("image/svg+xml", rec.Header().Get(echo.HeaderContentType))
+	s.Equal(`attachment; filename="`+alias+`.svg"`, rec.Header().Get(echo.HeaderContentDisposition))
+
+	body := rec.Body.String()
+
+	s.True(strings.HasPrefix(body, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300"`), body)
+	s.Contains(body, `fill="#112233"`)
+	s.Contains(body, `fill="#ffffff"`)
+	s.Contains(body, `d="M2 2h7v1h-7z`, "top-left finder pattern starts at the margin")
+	s.NotContains(body, "<image")
+
+	// 2
+	rec = s.request(alias, url.Values{"format": {"svg"}, "logo": {"true"}})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+	s.Contains(rec.Body.String(), `href="data:image/png;base64,`)
+}
+
+func (s *qrCodesSuite) TestLogo() {
+	red := color.RGBA{R: 0xff, A: 0xff}
+
+	// 1
+	rec := s.request(alias, nil)
+
+	s.Require().Equal(http.StatusOK, rec.Code)
+	s.False(colors(s.decodePNG(rec.Body.Bytes()))[red])
+
+	// 2
+	rec = s.request(alias, url.Values{"logo": {"true"}, "level": {"L"}})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	img := s.decodePNG(rec.Body.Bytes())
+
+	s.Equal(red, color.RGBAModel.Convert(img.At(128, 128)))
+}
+
+func (s *qrCodesSuite) TestInvalidOptions() {
+	// 1
+	for _, query := range []url.Values{
+		{"format": {"gif"}},
+		{"size": {"10"}},
+		{"size": {"2048"}},
+		{"size": {"big"}},
+		{"level": {"X"}},
+		{"margin": {"-1"}},
+		{"margin": {"17"}},
+		{"fg": {"black"}},
+		{"fg": {"#fff"}},
+		{"logo": {"maybe"}},
+	} {
+		rec := s.request(alias, query)
+
+		s.Equal(http.StatusBadRequest, rec.Code, query.Encode())
+	}
+
+	// 2
+	s.srv.WithQR(service.QRRules{MaxSize: 1024})
+
+	rec := s.request(alias, url.Values{"logo": {"true"}})
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	s.Contains(rec.Body.String(), "logo is not configured")
+}
+
+func (s *qrCodesSuite) TestNotFound() {
+	ctx := context.Background()
+
+	// 1
+	rec := s.request("missing2026", nil)
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	// 2
+	_, err := s.storage.CreateShortLink(ctx, "expired2026", "https://example.org", email, "")
+
+	s.Require().NoError(err)
+
+	_, err = s.storage.ExtendShortLink(ctx, "expired2026", time.Now().AddDate(0, -6, 0))
+
+	s.Require().NoError(err)
+
+	rec = s.request("expired2026", nil)
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	// 3
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/links/"+alias+"/qr?domain=go.brand.example", nil)
+	rec = httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+
+	c.SetParamNames("short")
+	c.SetParamValues(alias)
+
+	s.NoError(s.Handlers.GetShortLinkQR(c))
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Contains(rec.Body.String(), dto.DomainShortUrl("go.brand.example", alias))
+}
+
+func (s *qrCodesSuite) TestCache() {
+	// 1
+	first := s.request(alias, url.Values{"size": {"128"}})
+
+	s.Require().Equal(http.StatusOK, first.Code)
+	s.Equal(0, s.cache.hits)
+
+	// 2
+	second := s.request(alias, url.Values{"size": {"128"}})
+
+	s.Require().Equal(http.StatusOK, second.Code)
+	s.Equal(1, s.cache.hits)
+	s.Equal(first.Body.Bytes(), second.Body.Bytes())
+
+	// 3
+	s.request(alias, url.Values{"size": {"128"}, "format": {"svg"}})
+	s.request(alias, url.Values{"size": {"128"}, "fg": {"#111111"}})
+
+	s.Equal(1, s.cache.hits)
+
+	// 4
+	s.Require().NoError(s.srv.DeleteShortLink(context.Background(), alias, email))
+
+	rec := s.request(alias, url.Values{"size": {"128"}})
+
+	s.Equal(http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,93 @@
+package qr_codes
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"urleater/internal/handlers"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	base "urleater/tests"
+)
+
+const (
+	email = "user@mail.ru"
+	alias = "qrcode2026"
+)
+
+// countingCache считает изображения, отданные из кеша.
+type countingCache struct {
+	*memstorage.Cache
+
+	hits int
+}
+
+func (c *countingCache) GetQRCode(ctx context.Context, key string) ([]byte, error) {
+	image, err := c.Cache.GetQRCode(ctx, key)
+
+	if err == nil {
+		c.hits++
+	}
+
+	return image, err
+}
+
+// qrCodesSuite проверяет QR-коды ссылок на хранилище в памяти. Логотип - красный квадрат.
+type qrCodesSuite struct {
+	base.BaseSuite
+
+	storage *memstorage.Storage
+	cache   *countingCache
+	srv     *service.Service
+}
+
+func (s *qrCodesSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	ctx := context.Background()
+
+	s.storage = memstorage.NewStorage()
+	s.cache = &countingCache{Cache: memstorage.NewCache()}
+
+	logo := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	draw.Draw(logo, logo.Bounds(), image.NewUniform(color.RGBA{R: 0xff, A: 0xff}), image.Point{}, draw.Src)
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	s.srv = service.New(s.storage, s.cache, nil, nil, memstorage.NewSearcher(), "", logger, service.LinkRules{}).
+		WithQR(service.QRRules{MaxSize: 1024, Logo: logo})
+
+	s.Handlers = handlers.Handlers{
+		Service: s.srv,
+		Logger:  logger,
+	}
+
+	s.Require().NoError(s.srv.RegisterUser(ctx, email, "password1"))
+
+	_, err := s.srv.CreateShortLink(ctx, alias, "https://example.org", email, "")
+
+	s.Require().NoError(err)
+}
+
+// request запрашивает QR-код ссылки shortLink с параметрами query.
+func (s *qrCodesSuite) request(shortLink string, query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/links/"+shortLink+"/qr?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+
+	c.SetParamNames("short")
+	c.SetParamValues(shortLink)
+
+	s.NoError(s.Handlers.GetShortLinkQR(c))
+
+	return rec
+}
@@ -0,0 +1,145 @@
+package redirect_passthrough
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+	"urleater/dto"
+	"urleater/internal/handlers"
+)
+
+func (s *redirectPassthroughSuite) TestQueryConflicts() {
+	longUrl := "https://example.org/landing?utm_source=site&ref=a"
+
+	s.createLink("plainlnk", longUrl, "", handlers.UpdateShortLinkRedirectRequest{})
+	s.createLink("keeplink", longUrl, "", handlers.UpdateShortLinkRedirectRequest{ForwardQuery: true})
+	s.createLink("overlink", longUrl, "", handlers.UpdateShortLinkRedirectRequest{ForwardQuery: true, QueryConflict: dto.QueryConflictOverride})
+	s.createLink("appendln", longUrl, "", handlers.UpdateShortLinkRedirectRequest{ForwardQuery: true, QueryConflict: dto.QueryConflictAppend})
+
+	// 1
+	s.Equal(longUrl, s.location("localhost", "/plainlnk?ref=b&x=1"))
+	s.Equal(longUrl, s.location("localhost", "/keeplink"))
+
+	// 2
+	s.Equal("https://example.org/landing?utm_source=site&ref=a&x=1", s.location("localhost", "/keeplink?ref=b&x=1"))
+
+	// 3
+	s.Equal("https://example.org/landing?utm_source=site&ref=b&x=1", s.location("localhost", "/overlink?ref=b&x=1"))
+
+	// 4
+	s.Equal("https://example.org/landing?utm_source=site&ref=a&ref=b&x=1", s.location("localhost", "/appendln?ref=b&x=1"))
+
+	// 5
+	s.createLink("bareurl1", "https://example.org", "", handlers.UpdateShortLinkRedirectRequest{ForwardQuery: true})
+
+	s.Equal("https://example.org?q=go+lang", s.location("localhost", "/bareurl1?q=go%20lang"))
+}
+
+func (s *redirectPassthroughSuite) TestPathForwarding() {
+	s.createLink("docslink", "https://example.org/docs/?lang=en", "", handlers.UpdateShortLinkRedirectRequest{ForwardPath: true, ForwardQuery: true})
+	s.createLink("fixedlnk", "https://example.org/fixed", "", handlers.UpdateShortLinkRedirectRequest{ForwardQuery: true})
+
+	// 1
+	s.Equal("https://example.org/docs/?lang=en", s.location("localhost", "/docslink"))
+	s.Equal("https://example.org/docs/guide/intro?lang=en", s.location("localhost", "/docslink/guide/intro"))
+	s.Equal("https://example.org/docs/guide/?lang=en&page=2", s.location("localhost", "/docslink/guide/?page=2"))
+
+	// 2
+	s.Equal("https://example.org/docs/a%2Fb/c%20d?lang=en", s.location("localhost", "/docslink/a%2Fb/c%20d"))
+
+	// 3
+	s.Equal(http.StatusNotFound, s.visit("localhost", "/fixedlnk/guide").Code)
+	s.Equal("https://example.org/fixed?page=2", s.location("localhost", "/fixedlnk?page=2"))
+
+	// 4
+	rec := s.visit("localhost", "/links/docslink/qr")
+
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("image/png", rec.Header().Get("Content-Type"))
+}
+
+func (s *redirectPassthroughSuite) TestCustomDomain() {
+	s.createLink("promo2026", "https://example.org/promo", brand, handlers.UpdateShortLinkRedirectRequest{ForwardPath: true})
+	s.createLink("fixed2026", "https://example.org/fixed", brand, handlers.UpdateShortLinkRedirectRequest{})
+
+	// 1
+	s.Equal("https://example.org/promo/spring", s.location(brand, "/promo2026/spring"))
+
+	// 2
+	s.Equal("https://brand.example", s.location(brand, "/fixed2026/spring"))
+	s.Equal("https://brand.example", s.location(brand, "/missing1/spring"))
+
+	// 3
+	s.Equal(http.StatusNotFound, s.visit("localhost", "/promo2026/spring").Code)
+}
+
+func (s *redirectPassthroughSuite) TestExpiredLink() {
+	s.storage.WithLinkTTL(-time.Hour)
+
+	s.createLink("oldlink1", "https://example.org/old", "", handlers.UpdateShortLinkRedirectRequest{ForwardPath: true, ForwardQuery: true})
+
+	// 1
+	s.Equal(http.StatusNotFound, s.visit("localhost", "/oldlink1/page?x=1").Code)
+	s.Equal(http.StatusNotFound, s.visit("localhost", "/oldlink1").Code)
+}
+
+func (s *redirectPassthroughSuite) TestUpdateOptions() {
+	ctx := context.Background()
+
+	s.createLink("optslink", "https://example.org/opts", "", handlers.UpdateShortLinkRedirectRequest{})
+
+	// 1
+	s.Equal("https://example.org/opts", s.location("localhost", "/optslink?x=1"))
+
+	rec := s.updateRedirect(handlers.UpdateShortLinkRedirectRequest{ShortLink: "optslink", ForwardQuery: true, ForwardPath: true})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	var updated handlers.UpdateShortLinkInfoResponse
+
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &updated))
+	s.Equal(dto.RedirectOptions{ForwardQuery: true, QueryConflict: dto.QueryConflictKeep, ForwardPath: true}, updated.Link.RedirectOptions)
+
+	body, code := s.GetUserShortLinks(url.Values{})
+
+	s.Require().Equal(http.StatusOK, code)
+
+	var list handlers.GetUserShortLinksResponse
+
+	s.Require().NoError(json.Unmarshal(body, &list))
+	s.Require().Len(list.Links, 1)
+	s.Equal(updated.Link.RedirectOptions, list.Links[0].RedirectOptions)
+
+	// 2
+	_, err := s.srv.RelayOutboxEvents(ctx)
+
+	s.Require().NoError(err)
+
+	s.Equal("https://example.org/opts/page?x=1", s.location("localhost", "/optslink/page?x=1"))
+
+	// 3
+	rec = s.updateRedirect(handlers.UpdateShortLinkRedirectRequest{ShortLink: "optslink", QueryConflict: "replace"})
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+
+	// 4
+	s.loginAs(other)
+
+	rec = s.updateRedirect(handlers.UpdateShortLinkRedirectRequest{ShortLink: "optslink", ForwardQuery: true})
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	rec = s.updateRedirect(handlers.UpdateShortLinkRedirectRequest{ShortLink: "missing1"})
+
+	s.Equal(http.StatusNotFound, rec.Code)
+
+	// 5
+	s.loginAs("")
+
+	rec = s.updateRedirect(handlers.UpdateShortLinkRedirectRequest{ShortLink: "optslink"})
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+	s.JSONEq(`{"redirectTo": "/login"}`, rec.Body.String())
+}
@@ -0,0 +1,159 @@
+package redirect_passthrough
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"urleater/dto"
+	"urleater/internal/handlers"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+const (
+	email = "user@mail.ru"
+	other = "other@mail.ru"
+	brand = "go.brand.example"
+)
+
+// stubResolver отвечает на TXT-запросы из records, вместо DNS.
+type stubResolver struct {
+	records map[string][]string
+}
+
+func (r *stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.records[name], nil
+}
+
+// redirectPassthroughSuite проверяет перенос параметров и пути в длинную ссылку на хранилище в памяти.
+// Переходы идут через маршрутизатор echo, чтобы проверить и маршрут алиаса-префикса.
+type redirectPassthroughSuite struct {
+	base.BaseSuite
+
+	storage      *memstorage.Storage
+	srv          *service.Service
+	router       *echo.Echo
+	sessionStore *mocks.SessionStore
+}
+
+func (s *redirectPassthroughSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	ctx := context.Background()
+
+	s.storage = memstorage.NewStorage()
+
+	resolver := &stubResolver{records: make(map[string][]string)}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	s.srv = service.New(s.storage, memstorage.NewCache(), memqueue.New(100, nil), nil, memstorage.NewSearcher(), "links", logger, service.LinkRules{}).
+		WithDomains(service.DomainRules{PrimaryHosts: []string{"localhost"}, Resolver: resolver})
+
+	s.sessionStore = mocks.NewSessionStore(s.T())
+
+	s.Handlers = handlers.Handlers{
+		Service: s.srv,
+		Store:   s.sessionStore,
+		Logger:  logger,
+	}
+
+	s.router = echo.New()
+	s.router.GET("/:short_link", s.Handlers.GetShortLink)
+	s.router.GET("/:short_link/*", s.Handlers.GetShortLink)
+	s.router.GET("/links/:short/qr", s.Handlers.GetShortLinkQR)
+
+	s.Require().NoError(s.srv.RegisterUser(ctx, email, "password1"))
+	s.Require().NoError(s.srv.RegisterUser(ctx, other, "password1"))
+
+	s.loginAs(email)
+
+	// свой домен для проверки запасного адреса
+	plans, err := s.srv.GetAllSubscriptions(ctx)
+
+	s.Require().NoError(err)
+
+	for _, plan := range plans {
+		if plan.Name == "Silver" {
+			_, _, err = s.srv.Subscribe(ctx, email, plan.Id, "")
+
+			s.Require().NoError(err)
+		}
+	}
+
+	domain, err := s.srv.AddDomain(ctx, email, brand, "https://brand.example")
+
+	s.Require().NoError(err)
+
+	name, value := service.DomainVerificationRecord(*domain)
+	resolver.records[name] = []string{value}
+
+	_, err = s.srv.VerifyDomain(ctx, email, brand)
+
+	s.Require().NoError(err)
+}
+
+// loginAs задаёт пользователя, от имени которого выполняются следующие запросы.
+func (s *redirectPassthroughSuite) loginAs(email string) {
+	s.sessionStore.ExpectedCalls = nil
+
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return(email, nil).Maybe()
+}
+
+// createLink создаёт ссылку alias на longUrl на домене domain и задаёт ей параметры перехода options.
+func (s *redirectPassthroughSuite) createLink(alias string, longUrl string, domain string, options handlers.UpdateShortLinkRedirectRequest) {
+	_, code := s.CreateShortLink(&handlers.CreateShortLinkRequest{ShortURL: alias, LongURL: longUrl, Domain: domain})
+
+	s.Require().Equal(http.StatusOK, code)
+
+	options.ShortLink = dto.DomainShortUrl(domain, alias)
+
+	rec := s.updateRedirect(options)
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func (s *redirectPassthroughSuite) updateRedirect(options handlers.UpdateShortLinkRedirectRequest) *httptest.ResponseRecorder {
+	body, err := json.Marshal(options)
+
+	s.Require().NoError(err)
+
+	req := httptest.NewRequest(http.MethodPut, "/update_link_redirect", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+
+	s.NoError(s.Handlers.UpdateShortLinkRedirect(echo.New().NewContext(req, rec)))
+
+	return rec
+}
+
+// visit переходит по target на хосте host через маршрутизатор.
+func (s *redirectPassthroughSuite) visit(host string, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = host
+
+	rec := httptest.NewRecorder()
+
+	s.router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// location переходит по target и возвращает адрес перенаправления.
+func (s *redirectPassthroughSuite) location(host string, target string) string {
+	rec := s.visit(host, target)
+
+	s.Require().Equal(http.StatusFound, rec.Code, target+" "+rec.Body.String())
+
+	return rec.Header().Get(echo.HeaderLocation)
+}
@@ -3,6 +3,7 @@ package register_user
 import (
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/mock"
+	"time"
 	"urleater/dto"
 	base "urleater/tests"
 	"urleater/tests/mocks"
@@ -26,6 +27,13 @@ func (s *registerUserSuite) SetupTest() {
 		Return(nil, pgx.ErrNoRows).Once()
 	storage.On("CreateUser", mock.Anything, "test_name1@mail.ru", "qwertyui").Return(nil).Once()
 
+	storage.On("GetSubscriptions", mock.Anything, false).
+		Return([]dto.Subscription{{Id: 4, Name: "Free", TotalUrls: 10, BillingPeriod: dto.BillingPeriodMonth}}, nil).Once()
+	storage.On("SaveUserSubscription", mock.Anything, mock.MatchedBy(func(sub dto.UserSubscription) bool {
+		return sub.UserEmail == "test_name1@mail.ru" && sub.Plan.Name == "Free" && sub.Status == dto.UserSubscriptionActive
+	}), time.Time{}, dto.QuotaChange{Policy: dto.QuotaReset, Urls: 10}).Return(true, nil).Once()
+	// почта не настроена, поэтому CreateEmailVerification не вызывается: неожиданный вызов мока завершит тест
+
 	// 2
 	user2 := dto.User{
 		Email:        "test_name1@mail.ru",
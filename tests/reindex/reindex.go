@@ -0,0 +1,151 @@
+package reindex
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"urleater/dto"
+)
+
+var (
+	link1 = dto.Link{ShortUrl: "aaa", LongUrl: "https://a.ru", UserEmail: "owner@mail.ru", Tags: []string{"news"}}
+	link2 = dto.Link{ShortUrl: "bbb", LongUrl: "https://b.ru", UserEmail: "owner@mail.ru"}
+	link3 = dto.Link{ShortUrl: "ccc", LongUrl: "https://c.ru", UserEmail: "other@mail.ru", Title: "c"}
+)
+
+func (s *reindexSuite) expectBatches() {
+	s.source.On("CountShortLinks", mock.Anything).Return(3, nil).Once()
+	s.source.On("GetShortLinksBatch", mock.Anything, "", 2).Return([]dto.Link{link1, link2}, nil).Once()
+	s.source.On("GetShortLinksBatch", mock.Anything, "bbb", 2).Return([]dto.Link{link3}, nil).Once()
+}
+
+func (s *reindexSuite) TestRebuild() {
+	// 1
+	s.expectBatches()
+
+	s.index.On("CreateVersionedIndex", mock.Anything).Return("short_links_v1_20261019120000", nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links_v1_20261019120000", []dto.Link{link1, link2}).Return(nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links_v1_20261019120000", []dto.Link{link3}).Return(nil).Once()
+	s.index.On("SwitchAlias", mock.Anything, "short_links_v1_20261019120000").Return([]string{"short_links_v1_20261001000000"}, nil).Once()
+	s.index.On("DeleteIndices", mock.Anything, "short_links_v1_20261001000000").Return(nil).Once()
+
+	// за время копирования ничего не изменилось
+	s.source.On("GetShortLinksBatch", mock.Anything, "", 2).Return([]dto.Link{link1, link2}, nil).Once()
+	s.source.On("GetShortLinksBatch", mock.Anything, "bbb", 2).Return([]dto.Link{link3}, nil).Once()
+	s.index.On("IndexedShortLinks", mock.Anything, "short_links").Return(map[string]dto.Link{
+		"aaa": link1,
+		"bbb": link2,
+		"ccc": link3,
+	}, nil).Once()
+
+	total, err := s.reindexer.Rebuild(context.Background())
+
+	s.NoError(err)
+	s.Equal(3, total)
+	s.Contains(s.out.String(), "indexed 2/3")
+	s.Contains(s.out.String(), "indexed 3/3")
+	s.Contains(s.out.String(), "caught up 0 documents")
+
+	// 2
+	s.source.On("CountShortLinks", mock.Anything).Return(3, nil).Once()
+	s.source.On("GetShortLinksBatch", mock.Anything, "", 2).Return([]dto.Link{link1, link2}, nil).Once()
+
+	s.index.On("CreateVersionedIndex", mock.Anything).Return("short_links_v1_20261019130000", nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links_v1_20261019130000", []dto.Link{link1, link2}).Return(errors.New("bulk failed")).Once()
+	s.index.On("DeleteIndices", mock.Anything, "short_links_v1_20261019130000").Return(nil).Once()
+
+	_, err = s.reindexer.Rebuild(context.Background())
+
+	s.Error(err)
+}
+
+func (s *reindexSuite) TestRebuildCatchUp() {
+	// 1
+	s.expectBatches()
+
+	s.index.On("CreateVersionedIndex", mock.Anything).Return("short_links_v1_20261019120000", nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links_v1_20261019120000", mock.Anything).Return(nil).Twice()
+	s.index.On("SwitchAlias", mock.Anything, "short_links_v1_20261019120000").Return([]string{"short_links_v1_20261001000000"}, nil).Once()
+	s.index.On("DeleteIndices", mock.Anything, "short_links_v1_20261001000000").Return(nil).Once()
+
+	// 2
+	changedLink2 := link2
+	changedLink2.LongUrl = "https://b-changed.ru"
+
+	link4 := dto.Link{ShortUrl: "ddd", LongUrl: "https://d.ru", UserEmail: "owner@mail.ru"}
+
+	// пока копировались пачки, bbb изменили, ccc удалили, а ddd создали
+	s.source.On("GetShortLinksBatch", mock.Anything, "", 2).Return([]dto.Link{link1, changedLink2}, nil).Once()
+	s.source.On("GetShortLinksBatch", mock.Anything, "bbb", 2).Return([]dto.Link{link4}, nil).Once()
+	s.index.On("IndexedShortLinks", mock.Anything, "short_links").Return(map[string]dto.Link{
+		"aaa": link1,
+		"bbb": link2,
+		"ccc": link3,
+	}, nil).Once()
+
+	s.index.On("BulkIndex", mock.Anything, "short_links", []dto.Link{changedLink2}).Return(nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links", []dto.Link{link4}).Return(nil).Once()
+	s.index.On("BulkDelete", mock.Anything, "short_links", []string{"ccc"}).Return(nil).Once()
+
+	total, err := s.reindexer.Rebuild(context.Background())
+
+	s.NoError(err)
+	s.Equal(3, total)
+	s.Contains(s.out.String(), "caught up 3 documents")
+
+	// 3
+	s.expectBatches()
+
+	s.index.On("CreateVersionedIndex", mock.Anything).Return("short_links_v1_20261019130000", nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links_v1_20261019130000", mock.Anything).Return(nil).Twice()
+	s.index.On("SwitchAlias", mock.Anything, "short_links_v1_20261019130000").Return([]string{"short_links_v1_20261019120000"}, nil).Once()
+	s.index.On("IndexedShortLinks", mock.Anything, "short_links").Return(nil, errors.New("search failed")).Once()
+
+	// прежний индекс остаётся, DeleteIndices не ожидается
+	_, err = s.reindexer.Rebuild(context.Background())
+
+	s.ErrorContains(err, "run backfill")
+}
+
+func (s *reindexSuite) TestBackfill() {
+	// 1
+	s.expectBatches()
+
+	s.index.On("IndexedShortLinks", mock.Anything, "short_links").Return(map[string]dto.Link{
+		"aaa":     link1,
+		"deleted": {ShortUrl: "deleted"},
+	}, nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links", []dto.Link{link1, link2}).Return(nil).Once()
+	s.index.On("BulkIndex", mock.Anything, "short_links", []dto.Link{link3}).Return(nil).Once()
+	s.index.On("BulkDelete", mock.Anything, "short_links", []string{"deleted"}).Return(nil).Once()
+
+	total, err := s.reindexer.Backfill(context.Background())
+
+	s.NoError(err)
+	s.Equal(3, total)
+}
+
+func (s *reindexSuite) TestDiff() {
+	// 1
+	s.source.On("GetShortLinksBatch", mock.Anything, "", 2).Return([]dto.Link{link1, link2}, nil).Once()
+	s.source.On("GetShortLinksBatch", mock.Anything, "bbb", 2).Return([]dto.Link{link3}, nil).Once()
+
+	staleLink3 := link3
+	staleLink3.Title = "old title"
+
+	s.index.On("IndexedShortLinks", mock.Anything, "short_links").Return(map[string]dto.Link{
+		"aaa":     link1,
+		"ccc":     staleLink3,
+		"deleted": {ShortUrl: "deleted"},
+	}, nil).Once()
+
+	diff, err := s.reindexer.Diff(context.Background())
+
+	s.NoError(err)
+	s.False(diff.Consistent())
+	s.Equal(3, diff.Total)
+	s.Equal(3, diff.Indexed)
+	s.Equal([]string{"bbb"}, diff.Missing)
+	s.Equal([]string{"ccc"}, diff.Stale)
+	s.Equal([]string{"deleted"}, diff.Orphaned)
+}
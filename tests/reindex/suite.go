@@ -0,0 +1,27 @@
+package reindex
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"urleater/internal/reindex"
+	"urleater/tests/mocks"
+)
+
+type reindexSuite struct {
+	suite.Suite
+
+	source    *mocks.LinkSource
+	index     *mocks.Index
+	out       *bytes.Buffer
+	reindexer *reindex.Reindexer
+}
+
+func (s *reindexSuite) SetupTest() {
+	s.source = mocks.NewLinkSource(s.T())
+	s.index = mocks.NewIndex(s.T())
+	s.out = &bytes.Buffer{}
+
+	s.index.On("Alias").Return("short_links").Maybe()
+
+	s.reindexer = reindex.New(s.source, s.index, 2, s.out)
+}
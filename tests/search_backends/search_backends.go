@@ -0,0 +1,102 @@
+package search_backends
+
+import (
+	"context"
+	"urleater/dto"
+)
+
+func (s *searcherSuite) addWeatherLinks() {
+	s.add(dto.Link{ShortUrl: s.token + "one", LongUrl: "https://www.weather.com/today", Title: "Weather in " + s.token})
+	s.add(dto.Link{ShortUrl: s.token + "two", LongUrl: "https://news.example.org", Title: "News for " + s.token})
+	s.add(dto.Link{ShortUrl: s.token + "three", LongUrl: "https://example.org/recipes", Title: "Recipes", Tags: []string{s.token + "tag"}})
+}
+
+func (s *searcherSuite) TestSearch() {
+	s.addWeatherLinks()
+
+	// 1
+	result := s.search(dto.SearchQuery{Text: s.token})
+
+	s.Equal(3, result.Total)
+	s.ElementsMatch([]string{s.token + "one", s.token + "two", s.token + "three"}, shortUrls(result))
+
+	// 2
+	result = s.search(dto.SearchQuery{Text: "Weather " + s.token})
+
+	s.Equal(1, result.Total)
+	s.Equal([]string{s.token + "one"}, shortUrls(result))
+	s.Contains(result.Hits[0].Highlights["title"][0], "<mark>")
+
+	// 3
+	result = s.search(dto.SearchQuery{Text: s.token + "tag"})
+
+	s.Equal([]string{s.token + "three"}, shortUrls(result))
+	s.Contains(result.Hits[0].Highlights["tags"][0], "<mark>")
+
+	// 4
+	result = s.search(dto.SearchQuery{Text: s.token + " missing"})
+
+	s.Equal(0, result.Total)
+	s.Empty(result.Hits)
+}
+
+func (s *searcherSuite) TestOwnerFilter() {
+	s.addWeatherLinks()
+	s.add(dto.Link{ShortUrl: s.token + "foreign", LongUrl: "https://example.org", Title: s.token, UserEmail: "other" + s.owner})
+
+	// 1
+	result := s.search(dto.SearchQuery{Text: s.token, OwnerEmail: s.owner})
+
+	s.Equal(3, result.Total)
+	s.NotContains(shortUrls(result), s.token+"foreign")
+
+	// 2
+	result = s.search(dto.SearchQuery{Text: s.token})
+
+	s.Equal(4, result.Total)
+}
+
+func (s *searcherSuite) TestShortQueryMatchesShortUrlPrefix() {
+	s.addWeatherLinks()
+
+	// 1
+	result := s.search(dto.SearchQuery{Text: s.token[:2], OwnerEmail: s.owner})
+
+	s.Equal(3, result.Total)
+
+	// 2
+	result = s.search(dto.SearchQuery{Text: s.token[1:3], OwnerEmail: s.owner})
+
+	s.Equal(0, result.Total)
+}
+
+func (s *searcherSuite) TestPagination() {
+	s.addWeatherLinks()
+
+	first := s.search(dto.SearchQuery{Text: s.token, Limit: 2})
+	second := s.search(dto.SearchQuery{Text: s.token, Limit: 2, Offset: 2})
+
+	s.Equal(3, first.Total)
+	s.Equal(3, second.Total)
+	s.Len(first.Hits, 2)
+	s.Len(second.Hits, 1)
+	s.ElementsMatch([]string{s.token + "one", s.token + "two", s.token + "three"}, append(shortUrls(first), shortUrls(second)...))
+}
+
+func (s *searcherSuite) TestUpdateAndDelete() {
+	s.addWeatherLinks()
+
+	// 1
+	s.add(dto.Link{ShortUrl: s.token + "one", LongUrl: "https://forecast.example.com", Title: "Forecast " + s.token})
+
+	s.Equal(0, s.search(dto.SearchQuery{Text: "Weather " + s.token}).Total)
+	s.Equal([]string{s.token + "one"}, shortUrls(s.search(dto.SearchQuery{Text: "Forecast " + s.token})))
+
+	// 2
+	s.NoError(s.searcher.DeleteShortLink(context.Background(), s.token+"two"))
+
+	s.Equal(2, s.search(dto.SearchQuery{Text: s.token}).Total)
+
+	// 3
+	s.NoError(s.searcher.DeleteShortLink(context.Background(), s.token+"two"))
+}
@@ -0,0 +1,76 @@
+package search_backends
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"math/rand"
+	"time"
+	"urleater/dto"
+	"urleater/internal/service"
+)
+
+type searcherSuite struct {
+	suite.Suite
+
+	searcher service.ElasticSearcher
+
+	// token - случайное слово, которое есть только в ссылках текущего теста.
+	token string
+	owner string
+	added []string
+}
+
+func (s *searcherSuite) SetupTest() {
+	letters := []rune("abcdefghijklmnopqrstuvwxyz")
+	token := make([]rune, 10)
+
+	for i := range token {
+		token[i] = letters[rand.Intn(len(letters))]
+	}
+
+	s.token = string(token)
+	s.owner = s.token + "@mail.ru"
+	s.added = nil
+}
+
+func (s *searcherSuite) TearDownTest() {
+	for _, shortUrl := range s.added {
+		s.NoError(s.searcher.DeleteShortLink(context.Background(), shortUrl))
+	}
+}
+
+func (s *searcherSuite) add(link dto.Link) {
+	if link.UserEmail == "" {
+		link.UserEmail = s.owner
+	}
+
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	}
+
+	s.Require().NoError(s.searcher.AddShortLink(context.Background(), link))
+
+	s.added = append(s.added, link.ShortUrl)
+}
+
+func (s *searcherSuite) search(query dto.SearchQuery) dto.SearchResult {
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+
+	result, err := s.searcher.SearchShortLinks(context.Background(), query)
+
+	s.Require().NoError(err)
+
+	return result
+}
+
+func shortUrls(result dto.SearchResult) []string {
+	urls := make([]string, 0, len(result.Hits))
+
+	for _, hit := range result.Hits {
+		urls = append(urls, hit.ShortUrl)
+	}
+
+	return urls
+}
@@ -0,0 +1,54 @@
+package search_links
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/url"
+	"urleater/internal/handlers"
+)
+
+func (s *searchLinksSuite) TestSearchLinks() {
+	// 1
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return("owner@mail.ru", nil).Once()
+
+	body, code := s.SearchLinks(url.Values{
+		"contains_word": {"weather"},
+	})
+
+	var resp1 handlers.GetShortLinksWithMatchingPatternResponse
+
+	err := json.Unmarshal(body, &resp1)
+
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, code)
+	s.Equal(3, resp1.Total)
+	s.Len(resp1.ShortLinks, 2)
+	s.Equal("weather2", resp1.ShortLinks[0].ShortUrl)
+	s.Equal("weather1", resp1.ShortLinks[1].ShortUrl)
+	s.Equal([]string{"<mark>wea</mark>ther"}, resp1.Highlights[0]["title"])
+
+	// 2
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return("admin@admin.com", nil).Once()
+
+	body, code = s.SearchLinks(url.Values{
+		"contains_word": {"weather"},
+	})
+
+	var resp2 handlers.GetShortLinksWithMatchingPatternResponse
+
+	err = json.Unmarshal(body, &resp2)
+
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, code)
+	s.Len(resp2.ShortLinks, 1)
+
+	// 3
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return("owner@mail.ru", nil).Once()
+
+	_, code = s.SearchLinks(url.Values{})
+
+	s.Equal(http.StatusInternalServerError, code)
+}
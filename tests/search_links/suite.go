@@ -0,0 +1,64 @@
+package search_links
+
+import (
+	"github.com/stretchr/testify/mock"
+	"urleater/dto"
+	"urleater/internal/handlers"
+	"urleater/internal/service"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+type searchLinksSuite struct {
+	base.BaseSuite
+
+	sessionStore *mocks.SessionStore
+}
+
+func (s *searchLinksSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	storage := mocks.NewPostgresStorage(s.T())
+	searcher := mocks.NewElasticSearcher(s.T())
+	s.sessionStore = mocks.NewSessionStore(s.T())
+
+	// 1
+	searcher.On("SearchShortLinks", mock.Anything, dto.SearchQuery{
+		Text:       "weather",
+		OwnerEmail: "owner@mail.ru",
+		Limit:      20,
+	}).Return(dto.SearchResult{
+		Total: 3,
+		Hits: []dto.SearchHit{
+			{ShortUrl: "weather2", Highlights: map[string][]string{"title": {"<mark>wea</mark>ther"}}},
+			{ShortUrl: "deletedlink"},
+			{ShortUrl: "weather1"},
+		},
+	}, nil).Once()
+
+	storage.On("GetShortLinksByShortUrls", mock.Anything, []string{"weather2", "deletedlink", "weather1"}).Return([]dto.Link{
+		{ShortUrl: "weather1", UserEmail: "owner@mail.ru"},
+		{ShortUrl: "weather2", UserEmail: "owner@mail.ru"},
+	}, nil).Once()
+
+	// 2
+	searcher.On("SearchShortLinks", mock.Anything, dto.SearchQuery{
+		Text:  "weather",
+		Limit: 20,
+	}).Return(dto.SearchResult{
+		Total: 1,
+		Hits:  []dto.SearchHit{{ShortUrl: "weather1"}},
+	}, nil).Once()
+
+	storage.On("GetShortLinksByShortUrls", mock.Anything, []string{"weather1"}).Return([]dto.Link{
+		{ShortUrl: "weather1", UserEmail: "owner@mail.ru"},
+	}, nil).Once()
+
+	storage.On("GetUser", mock.Anything, "admin@admin.com").Return(&dto.User{Email: "admin@admin.com", EmailVerified: true}, nil).Once()
+
+	s.Handlers = handlers.Handlers{
+		Service: service.New(storage, nil, nil, nil, searcher, "", nil, service.LinkRules{}).
+			WithAdmins(service.AdminRules{Emails: []string{"admin@admin.com"}}),
+		Store: s.sessionStore,
+	}
+}
@@ -0,0 +1,103 @@
+package storage_contract
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+	"time"
+	"urleater/dto"
+	"urleater/internal/service"
+)
+
+type cacheSuite struct {
+	suite.Suite
+
+	cache  service.RedisStorage
+	suffix string
+}
+
+func (s *cacheSuite) SetupTest() {
+	s.suffix = randomSuffix()
+}
+
+func (s *cacheSuite) TestCache() {
+	ctx := context.Background()
+
+	shortUrl := "cached" + s.suffix
+
+	// 1
+	_, err := s.cache.GetShortLinkByLongLink(ctx, shortUrl)
+
+	s.ErrorIs(err, redis.Nil)
+
+	// 2
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 600, time.UTC)
+
+	s.NoError(s.cache.SaveShortLinkToLongLink(ctx, dto.Link{
+		ShortUrl:     shortUrl,
+		LongUrl:      "https://example.org",
+		UserEmail:    "owner@mail.ru",
+		ExpiresAt:    expiresAt,
+		Title:        "not cached",
+		TimesVisited: 5,
+		RedirectOptions: dto.RedirectOptions{
+			ForwardQuery:  true,
+			QueryConflict: dto.QueryConflictAppend,
+			ForwardPath:   true,
+		},
+		UTM: dto.UTM{Source: "news:letter", Campaign: "spring & sale", Content: "a=b"},
+	}))
+
+	link, err := s.cache.GetShortLinkByLongLink(ctx, shortUrl)
+
+	s.Require().NoError(err)
+	s.Equal(&dto.Link{
+		ShortUrl:  shortUrl,
+		LongUrl:   "https://example.org",
+		UserEmail: "owner@mail.ru",
+		ExpiresAt: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		RedirectOptions: dto.RedirectOptions{
+			ForwardQuery:  true,
+			QueryConflict: dto.QueryConflictAppend,
+			ForwardPath:   true,
+		},
+		UTM: dto.UTM{Source: "news:letter", Campaign: "spring & sale", Content: "a=b"},
+	}, link)
+
+	// 3
+	s.NoError(s.cache.DeleteLongLinkByShortLink(ctx, shortUrl))
+	s.NoError(s.cache.DeleteLongLinkByShortLink(ctx, shortUrl))
+
+	_, err = s.cache.GetShortLinkByLongLink(ctx, shortUrl)
+
+	s.ErrorIs(err, redis.Nil)
+
+	// 4
+	_, err = s.cache.GetQRCode(ctx, shortUrl)
+
+	s.ErrorIs(err, redis.Nil)
+
+	s.NoError(s.cache.SaveShortLinkToLongLink(ctx, dto.Link{ShortUrl: shortUrl, LongUrl: "https://example.org"}))
+	s.NoError(s.cache.SaveQRCode(ctx, shortUrl, []byte("qr image"), time.Minute))
+
+	image, err := s.cache.GetQRCode(ctx, shortUrl)
+
+	s.Require().NoError(err)
+	s.Equal([]byte("qr image"), image)
+
+	link, err = s.cache.GetShortLinkByLongLink(ctx, shortUrl)
+
+	s.Require().NoError(err)
+	s.Equal("https://example.org", link.LongUrl)
+
+	s.NoError(s.cache.DeleteLongLinkByShortLink(ctx, shortUrl))
+
+	// 5
+	s.NoError(s.cache.SaveQRCode(ctx, shortUrl, []byte("qr image"), time.Millisecond))
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = s.cache.GetQRCode(ctx, shortUrl)
+
+	s.ErrorIs(err, redis.Nil)
+}
@@ -0,0 +1,86 @@
+package storage_contract
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"time"
+	"urleater/dto"
+	"urleater/internal/events"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/pgqueue"
+	"urleater/internal/service"
+)
+
+// contractQueue - продюсер вместе с возможностью создать консьюмера топика.
+type contractQueue interface {
+	service.Producer
+	NewConsumer(topic string, workerChannel chan dto.ConsumerMessage) service.Consumer
+}
+
+type memoryQueue struct {
+	*memqueue.Queue
+}
+
+func (q memoryQueue) NewConsumer(topic string, workerChannel chan dto.ConsumerMessage) service.Consumer {
+	return q.Queue.NewConsumer(topic, workerChannel)
+}
+
+type postgresQueue struct {
+	*pgqueue.Queue
+}
+
+func (q postgresQueue) NewConsumer(topic string, workerChannel chan dto.ConsumerMessage) service.Consumer {
+	return q.Queue.NewConsumer(topic, workerChannel)
+}
+
+type queueSuite struct {
+	suite.Suite
+
+	newQueue func() contractQueue
+}
+
+func (s *queueSuite) TestPublishAndConsume() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	queue := s.newQueue()
+	topic := "topic_" + randomSuffix()
+	workerChannel := make(chan dto.ConsumerMessage, 10)
+
+	go queue.NewConsumer(topic, workerChannel).StartConsuming(ctx)
+
+	published := make(map[string]dto.Event)
+
+	for _, shortLink := range []string{"link1", "link2"} {
+		event, err := events.New(dto.EventLinkViewed, dto.LinkViewed{ShortLink: shortLink})
+
+		s.Require().NoError(err)
+		s.Require().NoError(queue.PublishEvent(ctx, shortLink, event, topic))
+
+		published[shortLink] = event
+	}
+
+	for range published {
+		select {
+		case msg := <-workerChannel:
+			event, ok := published[string(msg.Key)]
+
+			s.Require().True(ok, "unexpected key %q", msg.Key)
+			s.Equal(topic, msg.Topic)
+
+			var received dto.Event
+
+			s.NoError(json.Unmarshal(msg.Value, &received))
+			s.Equal(event.Id, received.Id)
+			s.JSONEq(string(event.Data), string(received.Data))
+
+			msg.Ack()
+
+			delete(published, string(msg.Key))
+		case <-time.After(5 * time.Second):
+			s.FailNow("message was not consumed")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package storage_contract
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"urleater/internal/handlers"
+)
+
+type sessionSuite struct {
+	suite.Suite
+
+	store handlers.SessionStore
+}
+
+// request выполняет запрос с cookie предыдущего ответа и возвращает контекст и ответ.
+func (s *sessionSuite) request(cookies []*http.Cookie) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+
+	return echo.New().NewContext(req, rec), rec
+}
+
+func (s *sessionSuite) TestSessions() {
+	email := "user" + randomSuffix() + "@mail.ru"
+
+	// 1
+	c, _ := s.request(nil)
+
+	retrieved, err := s.store.RetrieveEmailFromSession(c)
+
+	s.NoError(err)
+	s.Equal("", retrieved)
+
+	// 2
+	c, rec := s.request(nil)
+
+	session, err := s.store.Get(c.Request(), "session_key")
+
+	s.Require().NoError(err)
+	s.Require().NoError(s.store.Save(c, email, session))
+
+	cookies := rec.Result().Cookies()
+
+	s.Require().NotEmpty(cookies)
+
+	c, _ = s.request(cookies)
+
+	retrieved, err = s.store.RetrieveEmailFromSession(c)
+
+	s.NoError(err)
+	s.Equal(email, retrieved)
+
+	// 3
+	c, _ = s.request(cookies)
+
+	session, err = s.store.Get(c.Request(), "session_key")
+
+	s.Require().NoError(err)
+
+	session.Options.MaxAge = -1
+
+	s.Require().NoError(session.Save(c.Request(), c.Response()))
+
+	c, _ = s.request(cookies)
+
+	retrieved, err = s.store.RetrieveEmailFromSession(c)
+
+	s.NoError(err)
+	s.Equal("", retrieved)
+}
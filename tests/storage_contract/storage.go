@@ -0,0 +1,1175 @@
+package storage_contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/suite"
+	"slices"
+	"strconv"
+	"time"
+	"urleater/dto"
+)
+
+type storageSuite struct {
+	suite.Suite
+
+	newStorage func() contractStorage
+	storage    contractStorage
+	suffix     string
+}
+
+func (s *storageSuite) SetupTest() {
+	s.storage = s.newStorage()
+	s.suffix = randomSuffix()
+}
+
+func (s *storageSuite) email(name string) string {
+	return name + s.suffix + "@mail.ru"
+}
+
+func (s *storageSuite) alias(name string) string {
+	return name + s.suffix
+}
+
+func (s *storageSuite) createUser(name string) string {
+	email := s.email(name)
+
+	s.Require().NoError(s.storage.CreateUser(context.Background(), email, "password1"))
+
+	return email
+}
+
+func (s *storageSuite) createLink(name string, email string) *dto.Link {
+	link, err := s.storage.CreateShortLink(context.Background(), s.alias(name), "https://example.org/"+name, email, "")
+
+	s.Require().NoError(err)
+
+	return link
+}
+
+func (s *storageSuite) requirePgError(err error, code string) {
+	var pgErr *pgconn.PgError
+
+	s.Require().True(errors.As(err, &pgErr), "expected a postgres error, got %v", err)
+	s.Equal(code, pgErr.Code)
+}
+
+func (s *storageSuite) TestUsers() {
+	ctx := context.Background()
+
+	// 1
+	_, err := s.storage.GetUser(ctx, s.email("missing"))
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	// 2
+	email := s.createUser("user")
+
+	user, err := s.storage.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(email, user.Email)
+	s.Equal(10, user.UrlsLeft)
+
+	// 3
+	s.requirePgError(s.storage.CreateUser(ctx, email, "password2"), "23505")
+
+	// 4
+	s.NoError(s.storage.VerifyUserPassword(ctx, email, "password1"))
+
+	err = s.storage.VerifyUserPassword(ctx, email, "password2")
+
+	s.Error(err)
+	s.NotErrorIs(err, pgx.ErrNoRows)
+
+	s.ErrorIs(s.storage.VerifyUserPassword(ctx, s.email("missing"), "password1"), pgx.ErrNoRows)
+
+	// 5
+	s.NoError(s.storage.ChangePassword(ctx, email, "password2"))
+	s.NoError(s.storage.VerifyUserPassword(ctx, email, "password2"))
+	s.NoError(s.storage.ChangePassword(ctx, s.email("missing"), "password2"))
+
+	// 6
+	user, err = s.storage.UpdateUserLinks(ctx, email, 3)
+
+	s.Require().NoError(err)
+	s.Equal(3, user.UrlsLeft)
+
+	_, err = s.storage.UpdateUserLinks(ctx, s.email("missing"), 3)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestShortLinks() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+
+	// 1
+	link := s.createLink("first", email)
+
+	s.Equal(s.alias("first"), link.ShortUrl)
+	s.Equal("https://example.org/first", link.LongUrl)
+	s.Equal(email, link.UserEmail)
+	s.WithinDuration(time.Now(), link.CreatedAt, time.Minute)
+	s.WithinDuration(link.CreatedAt.Add(90*24*time.Hour), link.ExpiresAt, time.Second)
+
+	// 2
+	_, err := s.storage.CreateShortLink(ctx, s.alias("first"), "https://example.org", email, "")
+
+	s.requirePgError(err, "23505")
+
+	_, err = s.storage.CreateShortLink(ctx, s.alias("orphan"), "https://example.org", s.email("missing"), "")
+
+	s.requirePgError(err, "23503")
+
+	// 3
+	stored, err := s.storage.GetShortLink(ctx, s.alias("first"))
+
+	s.Require().NoError(err)
+	s.Equal(link.ShortUrl, stored.ShortUrl)
+	s.Equal(link.ExpiresAt, stored.ExpiresAt)
+	s.Equal(link.CreatedAt, stored.CreatedAt)
+	s.Equal("", stored.Title)
+	s.Empty(stored.Tags)
+
+	_, err = s.storage.GetShortLink(ctx, s.alias("missing"))
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	// 4
+	s.NoError(s.storage.IncrementShortLinkTimesWatchedCount(ctx, s.alias("first"), ""))
+	s.NoError(s.storage.IncrementShortLinkTimesWatchedCount(ctx, s.alias("missing"), ""))
+	s.NoError(s.storage.IncrementShortLinkTimesWatchedCount(ctx, s.alias("missing"), "spring"))
+
+	stored, err = s.storage.GetShortLink(ctx, s.alias("first"))
+
+	s.Require().NoError(err)
+	s.Equal(1, stored.TimesVisited)
+
+	// 5
+	extendFrom := time.Date(2030, 1, 2, 3, 4, 5, 600, time.UTC)
+
+	extended, err := s.storage.ExtendShortLink(ctx, s.alias("first"), extendFrom)
+
+	s.Require().NoError(err)
+	s.Equal(time.Date(2030, 4, 2, 3, 4, 5, 0, time.UTC), extended.ExpiresAt)
+
+	_, err = s.storage.ExtendShortLink(ctx, s.alias("missing"), extendFrom)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	// 6
+	s.createLink("second", email)
+
+	links, err := s.storage.GetShortLinksByShortUrls(ctx, []string{s.alias("first"), s.alias("second"), s.alias("missing")})
+
+	s.Require().NoError(err)
+	s.Len(links, 2)
+
+	// 7
+	s.NoError(s.storage.DeleteShortLink(ctx, s.alias("first")))
+	s.NoError(s.storage.DeleteShortLink(ctx, s.alias("first")))
+
+	_, err = s.storage.GetShortLink(ctx, s.alias("first"))
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestLinkInfoAndTags() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+
+	s.createLink("first", email)
+	s.createLink("second", email)
+
+	// 1
+	s.NoError(s.storage.UpdateShortLinkInfo(ctx, s.alias("first"), "Title", "Description", []string{"work", "news"}))
+	s.NoError(s.storage.UpdateShortLinkInfo(ctx, s.alias("second"), "", "", []string{"news"}))
+
+	link, err := s.storage.GetShortLink(ctx, s.alias("first"))
+
+	s.Require().NoError(err)
+	s.Equal("Title", link.Title)
+	s.Equal("Description", link.Description)
+	s.Equal([]string{"news", "work"}, link.Tags)
+
+	// 2
+	s.NoError(s.storage.UpdateShortLinkInfo(ctx, s.alias("first"), "Title", "Description", []string{"news"}))
+
+	tags, err := s.storage.GetUserTags(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal([]dto.Tag{{Name: "news", LinksNumber: 2}, {Name: "work", LinksNumber: 0}}, tags)
+
+	// 3
+	s.NoError(s.storage.DeleteUserTag(ctx, email, "news"))
+
+	link, err = s.storage.GetShortLink(ctx, s.alias("second"))
+
+	s.Require().NoError(err)
+	s.Empty(link.Tags)
+
+	// 4
+	s.ErrorIs(s.storage.UpdateShortLinkInfo(ctx, s.alias("missing"), "", "", nil), pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestDeleteUserTagOutbox() {
+	ctx := context.Background()
+
+	email := s.createUser("tagger")
+
+	s.createLink("tagged", email)
+	s.createLink("untagged", email)
+
+	s.NoError(s.storage.UpdateShortLinkInfo(ctx, s.alias("tagged"), "", "", []string{"news"}))
+
+	// события одной ссылки выдаются по очереди: сначала создание, затем изменение
+	for _, event := range s.leaseEvents(s.alias("tagged"), s.alias("untagged")) {
+		s.NoError(s.storage.CompleteOutboxEvent(ctx, event.Id))
+	}
+
+	for _, event := range s.leaseEvents(s.alias("tagged"), s.alias("untagged")) {
+		s.NoError(s.storage.CompleteOutboxEvent(ctx, event.Id))
+	}
+
+	// 1
+	s.NoError(s.storage.DeleteUserTag(ctx, email, "news"))
+
+	events := s.leaseEvents(s.alias("tagged"), s.alias("untagged"))
+
+	s.Require().Len(events, 1)
+	s.Equal(dto.EventLinkUpdated, events[0].Type)
+	s.Equal(s.alias("tagged"), events[0].Key)
+
+	var updated dto.LinkUpdated
+
+	s.NoError(json.Unmarshal(events[0].Payload, &updated))
+	s.Equal(dto.LinkUpdated{ShortLink: s.alias("tagged"), UserEmail: email}, updated)
+
+	// 2
+	s.NoError(s.storage.CompleteOutboxEvent(ctx, events[0].Id))
+	s.NoError(s.storage.DeleteUserTag(ctx, email, "news"))
+	s.Empty(s.leaseEvents(s.alias("tagged"), s.alias("untagged")))
+}
+
+func (s *storageSuite) TestLinkRedirectOptions() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+
+	created := s.createLink("redirect", email)
+
+	s.Equal(dto.RedirectOptions{QueryConflict: dto.QueryConflictKeep}, created.RedirectOptions)
+
+	// 1
+	options := dto.RedirectOptions{ForwardQuery: true, QueryConflict: dto.QueryConflictOverride, ForwardPath: true}
+
+	s.NoError(s.storage.UpdateShortLinkRedirect(ctx, s.alias("redirect"), options))
+
+	link, err := s.storage.GetShortLink(ctx, s.alias("redirect"))
+
+	s.Require().NoError(err)
+	s.Equal(options, link.RedirectOptions)
+
+	links, err := s.storage.GetShortLinksByShortUrls(ctx, []string{s.alias("redirect")})
+
+	s.Require().NoError(err)
+	s.Require().Len(links, 1)
+	s.Equal(options, links[0].RedirectOptions)
+
+	// 2
+	s.ErrorIs(s.storage.UpdateShortLinkRedirect(ctx, s.alias("missing"), options), pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestLinkUTM() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+	other := s.createUser("other")
+
+	s.Equal(dto.UTM{}, s.createLink("spring1", email).UTM)
+
+	s.createLink("spring2", email)
+	s.createLink("winter1", email)
+	s.createLink("untagged", email)
+	s.createLink("foreign", other)
+
+	// 1
+	utm := dto.UTM{Source: "newsletter", Medium: "email", Campaign: "spring" + s.suffix, Term: "shoes", Content: "banner"}
+
+	s.NoError(s.storage.UpdateShortLinkUTM(ctx, s.alias("spring1"), utm))
+
+	link, err := s.storage.GetShortLink(ctx, s.alias("spring1"))
+
+	s.Require().NoError(err)
+	s.Equal(utm, link.UTM)
+
+	links, err := s.storage.GetShortLinksByShortUrls(ctx, []string{s.alias("spring1")})
+
+	s.Require().NoError(err)
+	s.Require().Len(links, 1)
+	s.Equal(utm, links[0].UTM)
+
+	// 2
+	s.NoError(s.storage.UpdateShortLinkUTM(ctx, s.alias("spring2"), dto.UTM{Source: "ads", Campaign: "spring" + s.suffix}))
+	s.NoError(s.storage.UpdateShortLinkUTM(ctx, s.alias("winter1"), dto.UTM{Source: "ads", Campaign: "winter" + s.suffix}))
+	s.NoError(s.storage.UpdateShortLinkUTM(ctx, s.alias("foreign"), dto.UTM{Source: "ads", Campaign: "spring" + s.suffix}))
+
+	// кампанию в момент перехода передаёт событие перехода
+	for _, visit := range [][2]string{{"spring2", "spring"}, {"winter1", "winter"}, {"winter1", "winter"}, {"winter1", "winter"}, {"untagged", ""}, {"foreign", "spring"}} {
+		campaign := visit[1]
+
+		if campaign != "" {
+			campaign += s.suffix
+		}
+
+		s.NoError(s.storage.IncrementShortLinkTimesWatchedCount(ctx, s.alias(visit[0]), campaign))
+	}
+
+	stats, err := s.storage.GetUserCampaignStats(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal([]dto.CampaignStats{
+		{Campaign: "winter" + s.suffix, Links: 1, Clicks: 3},
+		{Campaign: "spring" + s.suffix, Links: 2, Clicks: 1},
+	}, stats)
+
+	// 3
+	s.NoError(s.storage.UpdateShortLinkUTM(ctx, s.alias("winter1"), dto.UTM{}))
+	s.NoError(s.storage.IncrementShortLinkTimesWatchedCount(ctx, s.alias("winter1"), ""))
+
+	stats, err = s.storage.GetUserCampaignStats(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal([]dto.CampaignStats{
+		{Campaign: "winter" + s.suffix, Links: 0, Clicks: 3},
+		{Campaign: "spring" + s.suffix, Links: 2, Clicks: 1},
+	}, stats)
+
+	link, err = s.storage.GetShortLink(ctx, s.alias("winter1"))
+
+	s.Require().NoError(err)
+	s.Equal(4, link.TimesVisited)
+
+	// 4
+	s.ErrorIs(s.storage.UpdateShortLinkUTM(ctx, s.alias("missing"), utm), pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestListUserShortLinks() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+
+	for i, name := range []string{"a", "b", "c", "d"} {
+		s.createLink(name, email)
+
+		for j := 0; j < i%2; j++ {
+			s.NoError(s.storage.IncrementShortLinkTimesWatchedCount(ctx, s.alias(name), ""))
+		}
+	}
+
+	s.NoError(s.storage.UpdateShortLinkInfo(ctx, s.alias("c"), "", "", []string{"news"}))
+
+	_, err := s.storage.ExtendShortLink(ctx, s.alias("d"), time.Now().Add(-100*24*time.Hour))
+
+	s.Require().NoError(err)
+
+	listQuery := dto.LinkListQuery{SortBy: dto.LinkSortTimesVisited, Direction: dto.SortDesc, Limit: 2}
+
+	// 1
+	links, err := s.storage.GetUserShortLinks(ctx, email, listQuery)
+
+	s.Require().NoError(err)
+	s.Equal([]string{s.alias("d"), s.alias("b")}, shortUrls(links))
+
+	// 2
+	listQuery.After = &dto.LinkCursor{Value: strconv.Itoa(links[1].TimesVisited), ShortUrl: links[1].ShortUrl}
+
+	links, err = s.storage.GetUserShortLinks(ctx, email, listQuery)
+
+	s.Require().NoError(err)
+	s.Equal([]string{s.alias("c"), s.alias("a")}, shortUrls(links))
+
+	// 3
+	links, err = s.storage.GetUserShortLinks(ctx, email, dto.LinkListQuery{SortBy: dto.LinkSortAlias, Direction: dto.SortAsc, Limit: 10,
+		After: &dto.LinkCursor{Value: s.alias("b"), ShortUrl: s.alias("b")}})
+
+	s.Require().NoError(err)
+	s.Equal([]string{s.alias("c"), s.alias("d")}, shortUrls(links))
+
+	// 4
+	filters := map[dto.LinkFilter][]string{
+		{Tag: "news"}:                           {s.alias("c")},
+		{Status: dto.LinkStatusExpired}:         {s.alias("d")},
+		{Status: dto.LinkStatusActive}:          {s.alias("a"), s.alias("b"), s.alias("c")},
+		{CreatedTo: time.Now().Add(-time.Hour)}: {},
+	}
+
+	for filter, expected := range filters {
+		links, err = s.storage.GetUserShortLinks(ctx, email, dto.LinkListQuery{Filter: filter, SortBy: dto.LinkSortAlias, Direction: dto.SortAsc, Limit: 10})
+
+		s.Require().NoError(err)
+		s.Equal(expected, shortUrls(links), "filter %+v", filter)
+
+		total, err := s.storage.GetTotalUserLinksNumber(ctx, email, filter)
+
+		s.Require().NoError(err)
+		s.Equal(len(expected), total, "filter %+v", filter)
+	}
+
+	// 5
+	_, err = s.storage.GetUserShortLinks(ctx, email, dto.LinkListQuery{SortBy: "unknown", Limit: 10})
+
+	s.Error(err)
+}
+
+func (s *storageSuite) TestSubscriptions() {
+	ctx := context.Background()
+
+	plan := dto.Subscription{
+		Name:          s.alias("plan"),
+		TotalUrls:     100,
+		Price:         9900,
+		Currency:      "RUB",
+		BillingPeriod: dto.BillingPeriodMonth,
+		Features:      dto.PlanFeatures{MaxExpiryDays: 30, APIRateLimit: 10},
+		DisplayOrder:  -1,
+	}
+
+	// 1
+	created, err := s.storage.CreateSubscription(ctx, plan)
+
+	s.Require().NoError(err)
+
+	plan.Id = created.Id
+
+	s.Equal(plan, *created)
+
+	_, err = s.storage.CreateSubscription(ctx, plan)
+
+	s.requirePgError(err, "23505")
+
+	// 2
+	all, err := s.storage.GetSubscriptions(ctx, false)
+
+	s.Require().NoError(err)
+	s.Require().NotEmpty(all)
+	s.Equal(plan, all[0])
+
+	active, err := s.storage.GetSubscriptions(ctx, true)
+
+	s.Require().NoError(err)
+
+	for _, sub := range active {
+		s.True(sub.Active)
+		s.NotEqual(plan.Id, sub.Id)
+	}
+
+	// 3
+	plan.Active = true
+	plan.Price = 19900
+
+	updated, err := s.storage.UpdateSubscription(ctx, plan)
+
+	s.Require().NoError(err)
+	s.Equal(plan, *updated)
+
+	found, err := s.storage.GetSubscription(ctx, plan.Id)
+
+	s.Require().NoError(err)
+	s.Equal(plan, *found)
+
+	// 4
+	s.Require().NoError(s.storage.DeleteSubscription(ctx, plan.Id))
+
+	s.ErrorIs(s.storage.DeleteSubscription(ctx, plan.Id), pgx.ErrNoRows)
+
+	_, err = s.storage.GetSubscription(ctx, plan.Id)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	_, err = s.storage.UpdateSubscription(ctx, plan)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestUserSubscriptions() {
+	ctx := context.Background()
+
+	email := s.createUser("subscriber")
+
+	plans, err := s.storage.GetSubscriptions(ctx, false)
+
+	s.Require().NoError(err)
+	s.Require().NotEmpty(plans)
+
+	start := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	sub := dto.UserSubscription{
+		UserEmail:   email,
+		Plan:        plans[0],
+		Status:      dto.UserSubscriptionTrial,
+		PeriodStart: start,
+		PeriodEnd:   start.AddDate(0, 0, 7),
+		TrialUsed:   true,
+	}
+
+	// 1
+	_, err = s.storage.GetUserSubscription(ctx, email)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	saved, err := s.storage.SaveUserSubscription(ctx, sub, time.Time{}, dto.QuotaChange{Policy: dto.QuotaReset, Urls: 50})
+
+	s.Require().NoError(err)
+	s.True(saved)
+
+	found, err := s.storage.GetUserSubscription(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(sub, *found)
+
+	user, err := s.storage.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(50, user.UrlsLeft)
+
+	// 2
+	due, err := s.storage.GetDueUserSubscriptions(ctx, start.AddDate(0, 0, 7), 1000)
+
+	s.Require().NoError(err)
+	s.Contains(due, sub)
+
+	due, err = s.storage.GetDueUserSubscriptions(ctx, start.AddDate(0, 0, 6), 1000)
+
+	s.Require().NoError(err)
+	s.NotContains(due, sub)
+
+	// 3
+	next := sub
+	next.Status = dto.UserSubscriptionActive
+	next.PeriodStart = sub.PeriodEnd
+	next.PeriodEnd = sub.PeriodEnd.AddDate(0, 1, 0)
+
+	saved, err = s.storage.SaveUserSubscription(ctx, next, sub.PeriodEnd, dto.QuotaChange{Policy: dto.QuotaTopUp, Urls: 5})
+
+	s.Require().NoError(err)
+	s.True(saved)
+
+	saved, err = s.storage.SaveUserSubscription(ctx, next, sub.PeriodEnd, dto.QuotaChange{Policy: dto.QuotaTopUp, Urls: 5})
+
+	s.Require().NoError(err)
+	s.False(saved)
+
+	user, err = s.storage.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(55, user.UrlsLeft)
+
+	// квота записана в журнал: 10 ссылок при регистрации, 40 до квоты 50 и 5 сверху
+	credits, err := s.storage.GetLinkCredits(ctx, email, 10)
+
+	s.Require().NoError(err)
+	s.Require().Len(credits, 2)
+	s.Equal(dto.LinkCreditSubscription, credits[0].Reason)
+	s.Equal(plans[0].Name, credits[0].Reference)
+	s.Equal(5, credits[0].Amount)
+	s.Equal(dto.LinkCreditSubscription, credits[1].Reason)
+	s.Equal(40, credits[1].Amount)
+
+	// 4
+	found, err = s.storage.AddPrepaidPeriods(ctx, email, 2)
+
+	s.Require().NoError(err)
+	s.Equal(2, found.PrepaidPeriods)
+
+	_, err = s.storage.AddPrepaidPeriods(ctx, s.email("missing"), 1)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	// 5
+	s.requirePgError(s.storage.DeleteSubscription(ctx, plans[0].Id), "23503")
+
+	_, err = s.storage.SaveUserSubscription(ctx, dto.UserSubscription{UserEmail: s.email("missing"), Plan: plans[0],
+		Status: dto.UserSubscriptionActive, PeriodStart: start, PeriodEnd: start}, time.Time{}, dto.QuotaChange{})
+
+	s.requirePgError(err, "23503")
+}
+
+func (s *storageSuite) TestLinkCredits() {
+	ctx := context.Background()
+
+	email := s.createUser("credited")
+
+	// 1
+	user, err := s.storage.AddLinkCredit(ctx, dto.LinkCredit{UserEmail: email, Amount: 5, Reason: dto.LinkCreditAdmin})
+
+	s.Require().NoError(err)
+	s.Equal(15, user.UrlsLeft)
+
+	_, err = s.storage.AddLinkCredit(ctx, dto.LinkCredit{UserEmail: email, Amount: 7, Reason: dto.LinkCreditReferral, Reference: "friend"})
+
+	s.Require().NoError(err)
+
+	found, err := s.storage.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(22, found.UrlsLeft)
+
+	// 2
+	credits, err := s.storage.GetLinkCredits(ctx, email, 10)
+
+	s.Require().NoError(err)
+	s.Require().Len(credits, 2)
+	s.Equal(dto.LinkCreditReferral, credits[0].Reason)
+	s.Equal("friend", credits[0].Reference)
+	s.Equal(7, credits[0].Amount)
+	s.Equal(dto.LinkCreditAdmin, credits[1].Reason)
+	s.Greater(credits[0].Id, credits[1].Id)
+
+	credits, err = s.storage.GetLinkCredits(ctx, email, 1)
+
+	s.Require().NoError(err)
+	s.Len(credits, 1)
+
+	// 3
+	_, err = s.storage.AddLinkCredit(ctx, dto.LinkCredit{UserEmail: s.email("missing"), Amount: 5, Reason: dto.LinkCreditAdmin})
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestPromoCodes() {
+	ctx := context.Background()
+
+	email := s.createUser("promo")
+	other := s.createUser("promo_other")
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Hour).Truncate(time.Second)
+	code := "LINKS" + s.suffix
+
+	// 1
+	created, err := s.storage.CreatePromoCode(ctx, dto.PromoCode{Code: code, Kind: dto.PromoLinkCredits, LinkCredits: 3,
+		MaxRedemptions: 1, ExpiresAt: &expiresAt})
+
+	s.Require().NoError(err)
+	s.Equal(code, created.Code)
+	s.Equal(0, created.Redemptions)
+	s.Nil(created.PlanId)
+	s.Require().NotNil(created.ExpiresAt)
+	s.True(expiresAt.Equal(*created.ExpiresAt))
+
+	_, err = s.storage.CreatePromoCode(ctx, dto.PromoCode{Code: code, Kind: dto.PromoLinkCredits, LinkCredits: 3})
+
+	s.requirePgError(err, "23505")
+
+	missingPlan := -1
+
+	_, err = s.storage.CreatePromoCode(ctx, dto.PromoCode{Code: "PLAN" + s.suffix, Kind: dto.PromoPercentOff, PercentOff: 5, PlanId: &missingPlan})
+
+	s.requirePgError(err, "23503")
+
+	found, err := s.storage.GetPromoCode(ctx, code)
+
+	s.Require().NoError(err)
+	s.Equal(*created, *found)
+
+	// 2
+	redeemed, err := s.storage.RedeemPromoCode(ctx, code, email, now)
+
+	s.Require().NoError(err)
+	s.Equal(1, redeemed.Redemptions)
+
+	user, err := s.storage.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(13, user.UrlsLeft)
+
+	credits, err := s.storage.GetLinkCredits(ctx, email, 10)
+
+	s.Require().NoError(err)
+	s.Require().Len(credits, 1)
+	s.Equal(dto.LinkCreditPromo, credits[0].Reason)
+	s.Equal(code, credits[0].Reference)
+
+	// 3
+	_, err = s.storage.RedeemPromoCode(ctx, code, other, now)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	unlimited := "ANY" + s.suffix
+
+	_, err = s.storage.CreatePromoCode(ctx, dto.PromoCode{Code: unlimited, Kind: dto.PromoPercentOff, PercentOff: 10, ExpiresAt: &expiresAt})
+
+	s.Require().NoError(err)
+
+	_, err = s.storage.RedeemPromoCode(ctx, unlimited, email, now)
+
+	s.Require().NoError(err)
+
+	_, err = s.storage.RedeemPromoCode(ctx, unlimited, email, now)
+
+	s.requirePgError(err, "23505")
+
+	_, err = s.storage.RedeemPromoCode(ctx, unlimited, other, expiresAt)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	credits, err = s.storage.GetLinkCredits(ctx, email, 10)
+
+	s.Require().NoError(err)
+	s.Len(credits, 1)
+
+	// 4
+	promos, err := s.storage.GetPromoCodes(ctx)
+
+	s.Require().NoError(err)
+
+	codes := make([]string, 0, len(promos))
+
+	for _, promo := range promos {
+		codes = append(codes, promo.Code)
+	}
+
+	s.Contains(codes, code)
+	s.Contains(codes, unlimited)
+
+	s.Require().NoError(s.storage.DeletePromoCode(ctx, code))
+
+	_, err = s.storage.GetPromoCode(ctx, code)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+	s.ErrorIs(s.storage.DeletePromoCode(ctx, code), pgx.ErrNoRows)
+	s.NoError(s.storage.DeletePromoCode(ctx, unlimited))
+}
+
+func (s *storageSuite) TestSubscriptionPromoCode() {
+	ctx := context.Background()
+
+	email := s.createUser("promo_subscriber")
+
+	plans, err := s.storage.GetSubscriptions(ctx, false)
+
+	s.Require().NoError(err)
+	s.Require().NotEmpty(plans)
+
+	now := time.Now().UTC()
+	start := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	code := "SUB" + s.suffix
+
+	sub := dto.UserSubscription{
+		UserEmail:   email,
+		Plan:        plans[0],
+		Status:      dto.UserSubscriptionActive,
+		PeriodStart: start,
+		PeriodEnd:   start.AddDate(0, 1, 0),
+	}
+
+	_, err = s.storage.CreatePromoCode(ctx, dto.PromoCode{Code: code, Kind: dto.PromoPercentOff, PercentOff: 10, MaxRedemptions: 1})
+
+	s.Require().NoError(err)
+
+	// 1
+	_, saved, err := s.storage.SaveUserSubscriptionWithPromoCode(ctx, sub, start, dto.QuotaChange{}, code, now)
+
+	s.Require().NoError(err)
+	s.False(saved)
+
+	promo, err := s.storage.GetPromoCode(ctx, code)
+
+	s.Require().NoError(err)
+	s.Equal(0, promo.Redemptions)
+
+	_, err = s.storage.GetUserSubscription(ctx, email)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	// 2
+	promo, saved, err = s.storage.SaveUserSubscriptionWithPromoCode(ctx, sub, time.Time{}, dto.QuotaChange{}, code, now)
+
+	s.Require().NoError(err)
+	s.True(saved)
+	s.Equal(1, promo.Redemptions)
+
+	found, err := s.storage.GetUserSubscription(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(sub, *found)
+
+	// 3
+	next := sub
+	next.PeriodStart = sub.PeriodEnd
+	next.PeriodEnd = sub.PeriodEnd.AddDate(0, 1, 0)
+
+	_, _, err = s.storage.SaveUserSubscriptionWithPromoCode(ctx, next, sub.PeriodEnd, dto.QuotaChange{}, code, now)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	found, err = s.storage.GetUserSubscription(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(sub, *found)
+
+	s.NoError(s.storage.DeletePromoCode(ctx, code))
+}
+
+func (s *storageSuite) TestReferrals() {
+	ctx := context.Background()
+
+	referrer := s.createUser("referrer")
+	invitee := s.createUser("invitee")
+
+	now := time.Now().UTC()
+
+	// 1
+	code, err := s.storage.EnsureReferralCode(ctx, referrer, "ref"+s.suffix)
+
+	s.Require().NoError(err)
+	s.Equal("ref"+s.suffix, code)
+
+	code, err = s.storage.EnsureReferralCode(ctx, referrer, "other"+s.suffix)
+
+	s.Require().NoError(err)
+	s.Equal("ref"+s.suffix, code)
+
+	_, err = s.storage.CreateReferral(ctx, referrer, code)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	_, err = s.storage.CreateReferral(ctx, invitee, "missing"+s.suffix)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	// 2
+	referral, err := s.storage.CreateReferral(ctx, invitee, code)
+
+	s.Require().NoError(err)
+	s.Equal(invitee, referral.InviteeEmail)
+	s.Equal(referrer, referral.ReferrerEmail)
+	s.Nil(referral.CreditedAt)
+
+	_, err = s.storage.CreateReferral(ctx, invitee, code)
+
+	s.requirePgError(err, "23505")
+
+	referrals, err := s.storage.GetReferrals(ctx, referrer)
+
+	s.Require().NoError(err)
+	s.Equal([]dto.Referral{*referral}, referrals)
+
+	// 3
+	s.Require().NoError(s.storage.CreateEmailVerification(ctx, invitee, "expired"+s.suffix, now.Add(-time.Minute)))
+
+	_, _, err = s.storage.VerifyEmail(ctx, "expired"+s.suffix, now, 4)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	s.Require().NoError(s.storage.CreateEmailVerification(ctx, invitee, "token"+s.suffix, now.Add(time.Hour)))
+
+	_, _, err = s.storage.VerifyEmail(ctx, "expired"+s.suffix, now.Add(-time.Hour), 4)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	email, credited, err := s.storage.VerifyEmail(ctx, "token"+s.suffix, now, 4)
+
+	s.Require().NoError(err)
+	s.Equal(invitee, email)
+	s.Require().NotNil(credited)
+	s.Equal(referrer, credited.ReferrerEmail)
+	s.NotNil(credited.CreditedAt)
+
+	user, err := s.storage.GetUser(ctx, invitee)
+
+	s.Require().NoError(err)
+	s.True(user.EmailVerified)
+
+	user, err = s.storage.GetUser(ctx, referrer)
+
+	s.Require().NoError(err)
+	s.False(user.EmailVerified)
+	s.Equal(14, user.UrlsLeft)
+
+	credits, err := s.storage.GetLinkCredits(ctx, referrer, 10)
+
+	s.Require().NoError(err)
+	s.Require().Len(credits, 1)
+	s.Equal(dto.LinkCreditReferral, credits[0].Reason)
+	s.Equal(invitee, credits[0].Reference)
+
+	// 4
+	_, _, err = s.storage.VerifyEmail(ctx, "token"+s.suffix, now, 4)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	s.Require().NoError(s.storage.CreateEmailVerification(ctx, invitee, "again"+s.suffix, now.Add(time.Hour)))
+
+	_, credited, err = s.storage.VerifyEmail(ctx, "again"+s.suffix, now, 4)
+
+	s.Require().NoError(err)
+	s.Nil(credited)
+
+	user, err = s.storage.GetUser(ctx, referrer)
+
+	s.Require().NoError(err)
+	s.Equal(14, user.UrlsLeft)
+}
+
+func (s *storageSuite) TestDomains() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+	host := s.alias("brand") + ".example"
+
+	// 1
+	domain, err := s.storage.CreateDomain(ctx, dto.Domain{
+		Host:              host,
+		UserEmail:         email,
+		VerificationToken: "token",
+		FallbackUrl:       "https://example.org",
+	})
+
+	s.Require().NoError(err)
+	s.Equal(host, domain.Host)
+	s.Equal(email, domain.UserEmail)
+	s.Equal("token", domain.VerificationToken)
+	s.Nil(domain.VerifiedAt)
+	s.WithinDuration(time.Now(), domain.CreatedAt, time.Minute)
+
+	_, err = s.storage.CreateDomain(ctx, dto.Domain{Host: host, UserEmail: email})
+
+	s.requirePgError(err, "23505")
+
+	_, err = s.storage.CreateDomain(ctx, dto.Domain{Host: s.alias("orphan") + ".example", UserEmail: s.email("missing")})
+
+	s.requirePgError(err, "23503")
+
+	_, err = s.storage.GetDomain(ctx, host)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	// 2
+	_, err = s.storage.CreateShortLink(ctx, dto.DomainShortUrl(s.alias("other")+".example", "a"), "https://example.org", email, s.alias("other")+".example")
+
+	s.requirePgError(err, "23503")
+
+	link, err := s.storage.CreateShortLink(ctx, dto.DomainShortUrl(host, "a"), "https://example.org/a", email, host)
+
+	s.Require().NoError(err)
+	s.Equal(host, link.Domain)
+	s.Equal("a", link.Alias())
+
+	stored, err := s.storage.GetShortLink(ctx, dto.DomainShortUrl(host, "a"))
+
+	s.Require().NoError(err)
+	s.Equal(host, stored.Domain)
+
+	// 3
+	verifiedAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	domain, err = s.storage.VerifyDomain(ctx, email, host, verifiedAt)
+
+	s.Require().NoError(err)
+	s.Require().NotNil(domain.VerifiedAt)
+	s.True(verifiedAt.Equal(*domain.VerifiedAt))
+
+	domain, err = s.storage.VerifyDomain(ctx, email, host, verifiedAt.Add(time.Hour))
+
+	s.Require().NoError(err)
+	s.True(verifiedAt.Equal(*domain.VerifiedAt))
+
+	domain, err = s.storage.GetDomain(ctx, host)
+
+	s.Require().NoError(err)
+	s.Equal(email, domain.UserEmail)
+
+	// 4
+	domain, err = s.storage.UpdateDomainFallback(ctx, email, host, "")
+
+	s.Require().NoError(err)
+	s.Equal("", domain.FallbackUrl)
+
+	domains, err := s.storage.GetUserDomains(ctx, email)
+
+	s.Require().NoError(err)
+	s.Require().Len(domains, 1)
+	s.Equal(host, domains[0].Host)
+
+	// 5
+	s.NoError(s.storage.DeleteDomain(ctx, email, host))
+
+	_, err = s.storage.GetShortLink(ctx, dto.DomainShortUrl(host, "a"))
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	_, err = s.storage.GetDomain(ctx, host)
+
+	s.ErrorIs(err, pgx.ErrNoRows)
+
+	s.ErrorIs(s.storage.DeleteDomain(ctx, email, host), pgx.ErrNoRows)
+}
+
+func (s *storageSuite) TestDomainClaims() {
+	ctx := context.Background()
+
+	first := s.createUser("first")
+	second := s.createUser("second")
+	third := s.createUser("third")
+	host := s.alias("claimed") + ".example"
+
+	// 1
+	for _, email := range []string{first, second, third} {
+		_, err := s.storage.CreateDomain(ctx, dto.Domain{Host: host, UserEmail: email, VerificationToken: email})
+
+		s.Require().NoError(err)
+	}
+
+	claim, err := s.storage.GetUserDomain(ctx, second, host)
+
+	s.Require().NoError(err)
+	s.Equal(second, claim.VerificationToken)
+
+	// 2
+	_, err = s.storage.VerifyDomain(ctx, second, host, time.Now())
+
+	s.Require().NoError(err)
+
+	for _, email := range []string{first, third} {
+		_, err = s.storage.GetUserDomain(ctx, email, host)
+
+		s.ErrorIs(err, pgx.ErrNoRows)
+	}
+
+	domain, err := s.storage.GetDomain(ctx, host)
+
+	s.Require().NoError(err)
+	s.Equal(second, domain.UserEmail)
+
+	// 3
+	_, err = s.storage.CreateDomain(ctx, dto.Domain{Host: host, UserEmail: first, VerificationToken: first})
+
+	s.Require().NoError(err)
+
+	_, err = s.storage.VerifyDomain(ctx, first, host, time.Now())
+
+	s.requirePgError(err, "23505")
+
+	_, err = s.storage.CreateShortLink(ctx, dto.DomainShortUrl(host, "a"), "https://example.org", third, host)
+
+	s.requirePgError(err, "23503")
+}
+
+func (s *storageSuite) TestShortLinksBatch() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+
+	s.createLink("a", email)
+	s.createLink("b", email)
+
+	// 1
+	links, err := s.storage.GetShortLinksBatch(ctx, s.alias("a"), 1000)
+
+	s.Require().NoError(err)
+	s.Contains(shortUrls(links), s.alias("b"))
+	s.NotContains(shortUrls(links), s.alias("a"))
+
+	// 2
+	count, err := s.storage.CountShortLinks(ctx)
+
+	s.Require().NoError(err)
+	s.GreaterOrEqual(count, 2)
+}
+
+func (s *storageSuite) TestOutbox() {
+	ctx := context.Background()
+
+	email := s.createUser("owner")
+
+	s.createLink("first", email)
+
+	s.NoError(s.storage.UpdateShortLinkInfo(ctx, s.alias("first"), "Title", "", nil))
+
+	// 1
+	events := s.leaseOwnEvents()
+
+	s.Require().Len(events, 2)
+	s.Equal(dto.EventUserRegistered, events[0].Type)
+	s.Equal(email, events[0].Key)
+	s.Equal(dto.EventLinkCreated, events[1].Type)
+	s.Equal(1, events[1].Attempts)
+	s.Equal(dto.EventVersions[dto.EventLinkCreated], events[1].Version)
+	s.NotEmpty(events[1].EventId)
+
+	var created dto.LinkCreated
+
+	s.NoError(json.Unmarshal(events[1].Payload, &created))
+	s.Equal(dto.LinkCreated{ShortLink: s.alias("first"), LongLink: "https://example.org/first", UserEmail: email}, created)
+
+	// 2
+	s.Empty(s.leaseOwnEvents())
+
+	// 3
+	s.NoError(s.storage.CompleteOutboxEvent(ctx, events[1].Id))
+
+	updated := s.leaseOwnEvents()
+
+	s.Require().Len(updated, 1)
+	s.Equal(dto.EventLinkUpdated, updated[0].Type)
+
+	// 4
+	s.NoError(s.storage.CompleteOutboxEvent(ctx, events[0].Id))
+	s.NoError(s.storage.RetryOutboxEvent(ctx, updated[0].Id, time.Now().Add(-time.Second), "elastic is down"))
+
+	retried := s.leaseOwnEvents()
+
+	s.Require().Len(retried, 1)
+	s.Equal(updated[0].Id, retried[0].Id)
+	s.Equal(2, retried[0].Attempts)
+
+	s.NoError(s.storage.CompleteOutboxEvent(ctx, retried[0].Id))
+
+	// 5
+	s.NoError(s.storage.DeleteShortLink(ctx, s.alias("first")))
+
+	deleted := s.leaseOwnEvents()
+
+	s.Require().Len(deleted, 1)
+	s.Equal(dto.EventLinkDeleted, deleted[0].Type)
+}
+
+// leaseOwnEvents арендует события и оставляет только события текущего теста: в общей базе могут быть чужие.
+func (s *storageSuite) leaseOwnEvents() []dto.OutboxEvent {
+	return s.leaseEvents(s.alias("first"), s.email("owner"))
+}
+
+// leaseEvents арендует события и оставляет только события с ключами keys.
+func (s *storageSuite) leaseEvents(keys ...string) []dto.OutboxEvent {
+	events, err := s.storage.LeaseOutboxEvents(context.Background(), 1000, time.Minute)
+
+	s.Require().NoError(err)
+
+	own := make([]dto.OutboxEvent, 0)
+
+	for _, event := range events {
+		if slices.Contains(keys, event.Key) {
+			own = append(own, event)
+		}
+	}
+
+	return own
+}
+
+func shortUrls(links []dto.Link) []string {
+	urls := make([]string, 0, len(links))
+
+	for _, link := range links {
+		urls = append(urls, link.ShortUrl)
+	}
+
+	return urls
+}
@@ -0,0 +1,25 @@
+package storage_contract
+
+import (
+	"math/rand"
+	"urleater/internal/reindex"
+	"urleater/internal/service"
+)
+
+// contractStorage - всё, что сервис и переиндексация требуют от основного хранилища.
+type contractStorage interface {
+	service.PostgresStorage
+	reindex.LinkSource
+}
+
+// randomSuffix делает имена пользователей и ссылок уникальными, чтобы тесты на общей базе не пересекались.
+func randomSuffix() string {
+	letters := []rune("abcdefghijklmnopqrstuvwxyz0123456789")
+	suffix := make([]rune, 10)
+
+	for i := range suffix {
+		suffix[i] = letters[rand.Intn(len(letters))]
+	}
+
+	return string(suffix)
+}
@@ -0,0 +1,10 @@
+package structured_logging
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(loggingSuite))
+}
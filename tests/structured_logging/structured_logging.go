@@ -0,0 +1,206 @@
+package structured_logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+	"urleater/dto"
+	"urleater/internal/events"
+	"urleater/internal/logging"
+	"urleater/internal/service"
+)
+
+func (s *loggingSuite) TestRedaction() {
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	// 1
+	s.logger.InfoContext(ctx, "login failed for user@mail.ru",
+		"email", "user@mail.ru",
+		"remote_ip", "203.0.113.7",
+		"error", errors.New("wrong password for user@mail.ru"))
+
+	records := s.records("login failed for u***@mail.ru")
+
+	s.Require().Len(records, 1)
+	s.Equal("u***@mail.ru", records[0]["email"])
+	s.Equal("[redacted]", records[0]["remote_ip"])
+	s.Equal("wrong password for u***@mail.ru", records[0]["error"])
+	s.Equal("req-1", records[0][logging.RequestIDKey])
+	s.NotContains(s.output.String(), "user@mail.ru")
+	s.NotContains(s.output.String(), "203.0.113.7")
+
+	// 2
+	plain, err := logging.New(logging.Config{Level: "info", Format: logging.FormatText}, s.output)
+
+	s.Require().NoError(err)
+
+	plain.Info("visible", "email", "user@mail.ru")
+
+	s.Contains(s.output.String(), "email=user@mail.ru")
+
+	// 3
+	_, err = logging.New(logging.Config{Level: "verbose", Format: logging.FormatJSON}, s.output)
+
+	s.Error(err)
+
+	_, err = logging.New(logging.Config{Level: "info", Format: "xml"}, s.output)
+
+	s.Error(err)
+}
+
+func (s *loggingSuite) TestRequestIDMiddleware() {
+	var seen string
+
+	e := echo.New()
+
+	e.Use(logging.EchoMiddleware(s.logger))
+
+	e.GET("/ping", func(c echo.Context) error {
+		seen = logging.RequestID(c.Request().Context())
+
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.GET("/fail", func(c echo.Context) error {
+		return errors.New("boom")
+	})
+
+	// 1
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(echo.HeaderXRequestID, "client-id")
+
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	s.Equal("client-id", rec.Header().Get(echo.HeaderXRequestID))
+	s.Equal("client-id", seen)
+
+	// 2
+	rec = httptest.NewRecorder()
+
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	generated := rec.Header().Get(echo.HeaderXRequestID)
+
+	s.NotEmpty(generated)
+	s.Equal(generated, seen)
+
+	// 3
+	req = httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(echo.HeaderXRequestID, strings.Repeat("x", 500))
+
+	rec = httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	s.Len(rec.Header().Get(echo.HeaderXRequestID), 36)
+
+	// 4
+	req = httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(echo.HeaderXRequestID, "failing-id")
+
+	rec = httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusInternalServerError, rec.Code)
+
+	var failed map[string]any
+
+	for _, record := range s.records("request") {
+		if record[logging.RequestIDKey] == "failing-id" {
+			failed = record
+		}
+	}
+
+	s.Require().NotNil(failed)
+	s.Equal("ERROR", failed["level"])
+	s.Equal("/fail", failed["route"])
+	s.EqualValues(http.StatusInternalServerError, failed["status"])
+	s.Equal("[redacted]", failed["remote_ip"])
+}
+
+func (s *loggingSuite) TestRequestIDReachesEvents() {
+	ctx := context.Background()
+
+	s.Require().NoError(s.storage.CreateUser(ctx, "user@mail.ru", "password1"))
+
+	// 1
+	_, err := s.srv.RelayOutboxEvents(ctx)
+
+	s.Require().NoError(err)
+
+	registered := s.nextEvent()
+
+	s.Equal(dto.EventUserRegistered, registered.Type)
+	s.Empty(registered.RequestId)
+
+	// 2
+	_, err = s.srv.CreateShortLink(logging.WithRequestID(ctx, "create-id"), "weather2030", "https://forecast.example.com", "user@mail.ru", "")
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.RelayOutboxEvents(ctx)
+
+	s.Require().NoError(err)
+
+	created := s.nextEvent()
+
+	s.Equal(dto.EventLinkCreated, created.Type)
+	s.Equal("create-id", created.RequestId)
+
+	// 3
+	_, err = s.srv.GetShortLink(logging.WithRequestID(ctx, "redirect-id"), "weather2030")
+
+	s.Require().NoError(err)
+
+	viewed := s.nextEvent()
+
+	s.Equal(dto.EventLinkViewed, viewed.Type)
+	s.Equal("redirect-id", viewed.RequestId)
+}
+
+func (s *loggingSuite) TestWorkerLogsRequestID() {
+	event, err := events.New(dto.EventLinkViewed, dto.LinkViewed{})
+
+	s.Require().NoError(err)
+
+	event.RequestId = "worker-id"
+
+	value, err := json.Marshal(event)
+
+	s.Require().NoError(err)
+
+	// 1
+	s.srv.HandleMessage(context.Background(), service.WorkersConfig{MaxAttempts: 1, DeadLetterTopic: topic + "_dlq"},
+		dto.ConsumerMessage{Topic: topic, Value: value, Ack: func() {}})
+
+	records := s.records("message failed, sending to dead letter topic")
+
+	s.Require().Len(records, 1)
+	s.Equal("worker-id", records[0][logging.RequestIDKey])
+
+	s.Require().Len(s.records("dead letter"), 1)
+	s.Equal("worker-id", s.records("dead letter")[0][logging.RequestIDKey])
+}
+
+func (s *loggingSuite) nextEvent() dto.Event {
+	select {
+	case msg := <-s.messages:
+		event, err := events.Decode(msg.Value)
+
+		s.Require().NoError(err)
+
+		return event
+	case <-time.After(time.Second):
+		s.FailNow("no message in the queue")
+
+		return dto.Event{}
+	}
+}
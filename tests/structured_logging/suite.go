@@ -0,0 +1,102 @@
+package structured_logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"log/slog"
+	"strings"
+	"sync"
+	"urleater/dto"
+	"urleater/internal/logging"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+)
+
+const topic = "links"
+
+type loggingSuite struct {
+	suite.Suite
+
+	output   *syncBuffer
+	logger   *slog.Logger
+	storage  *memstorage.Storage
+	queue    *memqueue.Queue
+	srv      *service.Service
+	messages chan dto.ConsumerMessage
+	cancel   context.CancelFunc
+}
+
+func (s *loggingSuite) SetupTest() {
+	var err error
+
+	s.output = &syncBuffer{}
+
+	s.logger, err = logging.New(logging.Config{Level: "debug", Format: logging.FormatJSON, RedactPII: true}, s.output)
+
+	s.Require().NoError(err)
+
+	var ctx context.Context
+
+	ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.storage = memstorage.NewStorage()
+	s.queue = memqueue.New(10, s.logger)
+	s.messages = make(chan dto.ConsumerMessage, 10)
+
+	s.srv = service.New(s.storage, memstorage.NewCache(), s.queue, nil, memstorage.NewSearcher(), topic, s.logger, service.LinkRules{})
+
+	// сообщения читаются напрямую, чтобы проверить, что именно ушло в очередь
+	go func() {
+		_ = s.queue.NewConsumer(topic, s.messages).StartConsuming(ctx)
+	}()
+}
+
+func (s *loggingSuite) TearDownTest() {
+	s.cancel()
+}
+
+// records возвращает записи лога с сообщением msg.
+func (s *loggingSuite) records(msg string) []map[string]any {
+	var records []map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(s.output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		record := map[string]any{}
+
+		s.Require().NoError(json.Unmarshal([]byte(line), &record), line)
+
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// syncBuffer - буфер для записи лога из нескольких горутин.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
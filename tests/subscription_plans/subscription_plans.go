@@ -0,0 +1,139 @@
+package subscription_plans
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"urleater/dto"
+	"urleater/internal/handlers"
+)
+
+func (s *subscriptionPlansSuite) TestManagePlans() {
+	s.loginAs(admin)
+
+	// 1
+	s.Equal([]string{"Free", "Bronze", "Silver", "Gold"}, s.planNames(s.Handlers.GetSubscriptions))
+
+	// 2
+	inactive := false
+
+	body, code := s.request(http.MethodPost, s.Handlers.CreateSubscription, "", handlers.SubscriptionPlanRequest{
+		Name:          " Platinum ",
+		TotalUrls:     50000,
+		Price:         249900,
+		Currency:      "rub",
+		BillingPeriod: dto.BillingPeriodYear,
+		CustomDomains: 20,
+		APIRateLimit:  5000,
+		Active:        &inactive,
+		DisplayOrder:  4,
+	})
+
+	s.Require().Equal(http.StatusCreated, code, string(body))
+
+	var created handlers.SubscriptionPlanResponse
+
+	s.Require().NoError(json.Unmarshal(body, &created))
+	s.Equal("Platinum", created.Subscription.Name)
+	s.Equal("RUB", created.Subscription.Currency)
+	s.Equal(20, created.Subscription.Features.CustomDomains)
+	s.False(created.Subscription.Active)
+
+	s.Equal([]string{"Free", "Bronze", "Silver", "Gold"}, s.planNames(s.Handlers.GetSubscriptions))
+	s.Equal([]string{"Free", "Bronze", "Silver", "Gold", "Platinum"}, s.planNames(s.Handlers.GetAllSubscriptions))
+
+	// 3
+	_, code = s.request(http.MethodPost, s.Handlers.CreateSubscription, "", handlers.SubscriptionPlanRequest{
+		Name:          "Weekly",
+		TotalUrls:     100,
+		Price:         -1,
+		Currency:      "rubles",
+		BillingPeriod: "week",
+	})
+
+	s.Equal(http.StatusBadRequest, code)
+
+	// 4
+	_, code = s.request(http.MethodPost, s.Handlers.CreateSubscription, "", handlers.SubscriptionPlanRequest{
+		Name:          "Gold",
+		TotalUrls:     100,
+		Currency:      "RUB",
+		BillingPeriod: dto.BillingPeriodMonth,
+	})
+
+	s.Equal(http.StatusConflict, code)
+
+	// 5
+	id := strconv.Itoa(created.Subscription.Id)
+
+	body, code = s.request(http.MethodPut, s.Handlers.UpdateSubscription, id, handlers.SubscriptionPlanRequest{
+		Name:          "Platinum",
+		TotalUrls:     50000,
+		Price:         199900,
+		Currency:      "RUB",
+		BillingPeriod: dto.BillingPeriodYear,
+	})
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+	s.Equal([]string{"Free", "Platinum", "Bronze", "Silver", "Gold"}, s.planNames(s.Handlers.GetSubscriptions))
+
+	_, code = s.request(http.MethodPut, s.Handlers.UpdateSubscription, id, handlers.SubscriptionPlanRequest{
+		Name:          "Silver",
+		TotalUrls:     50000,
+		Currency:      "RUB",
+		BillingPeriod: dto.BillingPeriodYear,
+	})
+
+	s.Equal(http.StatusConflict, code)
+
+	// 6
+	_, code = s.request(http.MethodPut, s.Handlers.UpdateSubscription, "1000", handlers.SubscriptionPlanRequest{
+		Name:          "Missing",
+		TotalUrls:     1,
+		Currency:      "RUB",
+		BillingPeriod: dto.BillingPeriodMonth,
+	})
+
+	s.Equal(http.StatusNotFound, code)
+
+	_, code = s.request(http.MethodDelete, s.Handlers.DeleteSubscription, id, nil)
+
+	s.Equal(http.StatusNoContent, code)
+
+	_, code = s.request(http.MethodDelete, s.Handlers.DeleteSubscription, id, nil)
+
+	s.Equal(http.StatusNotFound, code)
+	s.Equal([]string{"Free", "Bronze", "Silver", "Gold"}, s.planNames(s.Handlers.GetAllSubscriptions))
+}
+
+func (s *subscriptionPlansSuite) TestOnlyAdminManagesPlans() {
+	s.loginAs("user@mail.ru")
+
+	// 1
+	s.Len(s.planNames(s.Handlers.GetSubscriptions), 4)
+
+	// 2
+	_, code := s.request(http.MethodGet, s.Handlers.GetAllSubscriptions, "", nil)
+
+	s.Equal(http.StatusForbidden, code)
+
+	_, code = s.request(http.MethodPost, s.Handlers.CreateSubscription, "", handlers.SubscriptionPlanRequest{
+		Name:          "Free",
+		TotalUrls:     100,
+		Currency:      "RUB",
+		BillingPeriod: dto.BillingPeriodMonth,
+	})
+
+	s.Equal(http.StatusForbidden, code)
+
+	_, code = s.request(http.MethodDelete, s.Handlers.DeleteSubscription, "1", nil)
+
+	s.Equal(http.StatusForbidden, code)
+
+	// 3
+	s.loginAs(unverifiedAdmin)
+
+	_, code = s.request(http.MethodGet, s.Handlers.GetAllSubscriptions, "", nil)
+
+	s.Equal(http.StatusForbidden, code)
+}
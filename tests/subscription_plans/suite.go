@@ -0,0 +1,109 @@
+package subscription_plans
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+	"urleater/internal/handlers"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+const (
+	admin           = "admin@admin.com"
+	unverifiedAdmin = "boss@admin.com"
+)
+
+// subscriptionPlansSuite проверяет управление тарифами через обработчики на хранилище в памяти,
+// которое, как и база после миграций, начинается с тарифов Free, Bronze, Silver и Gold.
+// Из двух администраторов почту подтвердил только admin.
+type subscriptionPlansSuite struct {
+	base.BaseSuite
+
+	sessionStore *mocks.SessionStore
+}
+
+func (s *subscriptionPlansSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	s.sessionStore = mocks.NewSessionStore(s.T())
+
+	ctx := context.Background()
+	storage := memstorage.NewStorage()
+
+	srv := service.New(storage, nil, nil, nil, nil, "", nil, service.LinkRules{}).
+		WithAdmins(service.AdminRules{Emails: []string{admin, unverifiedAdmin}})
+
+	s.Require().NoError(srv.RegisterUser(ctx, admin, "password1"))
+	s.Require().NoError(srv.RegisterUser(ctx, unverifiedAdmin, "password1"))
+	s.Require().NoError(storage.CreateEmailVerification(ctx, admin, "token-hash", time.Now().Add(time.Hour)))
+
+	_, _, err := storage.VerifyEmail(ctx, "token-hash", time.Now(), 0)
+
+	s.Require().NoError(err)
+
+	s.Handlers = handlers.Handlers{
+		Service: srv,
+		Store:   s.sessionStore,
+	}
+}
+
+// loginAs задаёт пользователя, от имени которого выполняются следующие запросы.
+func (s *subscriptionPlansSuite) loginAs(email string) {
+	s.sessionStore.ExpectedCalls = nil
+
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return(email, nil)
+}
+
+// request вызывает обработчик с телом body и параметром пути id, если он не пустой.
+func (s *subscriptionPlansSuite) request(method string, f base.Handler, id string, body interface{}) ([]byte, int) {
+	var payload string
+
+	if body != nil {
+		res, err := json.Marshal(body)
+		s.Require().NoError(err)
+
+		payload = string(res)
+	}
+
+	req := httptest.NewRequest(method, "http://localhost", strings.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+
+	if id != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+
+	s.NoError(f(c))
+
+	return rec.Body.Bytes(), rec.Code
+}
+
+func (s *subscriptionPlansSuite) planNames(f base.Handler) []string {
+	body, code := s.request(http.MethodGet, f, "", nil)
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	var resp handlers.GetSubscriptionsResponse
+
+	s.Require().NoError(json.Unmarshal(body, &resp))
+
+	names := make([]string, 0, len(resp.Subscriptions))
+
+	for _, sub := range resp.Subscriptions {
+		names = append(names, sub.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,303 @@
+package subscription_renewals
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+	"urleater/dto"
+	"urleater/internal/handlers"
+	"urleater/internal/service"
+)
+
+func (s *subscriptionRenewalsSuite) TestPaidSubscriptionLifecycle() {
+	ctx := context.Background()
+
+	// 1
+	sub := s.subscription()
+
+	s.Equal("Free", sub.Plan.Name)
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.WithinDuration(time.Now().AddDate(0, 1, 0), sub.PeriodEnd, time.Minute)
+	s.Equal(10, s.urlsLeft())
+
+	// 2
+	silver := s.plan("Silver")
+
+	sub, _, err := s.srv.Subscribe(ctx, email, silver.Id, "")
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionPending, sub.Status)
+	s.WithinDuration(time.Now().Add(48*time.Hour), sub.PeriodEnd, time.Minute)
+	s.Equal(10, s.urlsLeft())
+
+	_, _, err = s.srv.Subscribe(ctx, email, silver.Id, "")
+
+	s.ErrorIs(err, service.ErrSubscriptionState)
+
+	// 3
+	sub, err = s.srv.RecordPayment(ctx, email, 1)
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.Equal(0, sub.PrepaidPeriods)
+	s.WithinDuration(time.Now().AddDate(0, 3, 0), sub.PeriodEnd, time.Minute)
+	s.Equal(5000, s.urlsLeft())
+
+	// 4
+	s.renew(0)
+
+	periodEnd := s.endPeriod(dto.UserSubscriptionActive)
+
+	s.renew(1)
+
+	sub = s.subscription()
+
+	s.Equal(dto.UserSubscriptionPastDue, sub.Status)
+	s.Equal(periodEnd.Add(48*time.Hour), sub.PeriodEnd)
+	s.Equal(5000, s.urlsLeft())
+
+	// 5
+	sub, err = s.srv.RecordPayment(ctx, email, 2)
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.Equal(1, sub.PrepaidPeriods)
+	s.WithinDuration(time.Now().AddDate(0, 3, 0), sub.PeriodEnd, time.Minute)
+
+	// 6
+	periodEnd = s.endPeriod(dto.UserSubscriptionActive)
+
+	s.renew(1)
+
+	sub = s.subscription()
+
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.Equal(0, sub.PrepaidPeriods)
+	s.Equal(periodEnd, sub.PeriodStart)
+	s.Equal(periodEnd.AddDate(0, 3, 0), sub.PeriodEnd)
+
+	// 7
+	s.endPeriod(dto.UserSubscriptionPastDue)
+
+	s.renew(1)
+
+	sub = s.subscription()
+
+	s.Equal("Free", sub.Plan.Name)
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.Equal(10, s.urlsLeft())
+
+	// 8
+	_, err = s.srv.RecordPayment(ctx, "missing@mail.ru", 1)
+
+	s.ErrorIs(err, service.ErrNoSubscription)
+}
+
+func (s *subscriptionRenewalsSuite) TestCancelAndResume() {
+	ctx := context.Background()
+
+	// 1
+	_, err := s.srv.CancelSubscription(ctx, email)
+
+	s.ErrorIs(err, service.ErrSubscriptionState)
+
+	// 2
+	gold := s.plan("Gold")
+
+	_, _, err = s.srv.Subscribe(ctx, email, gold.Id, "")
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.CancelSubscription(ctx, email)
+
+	s.ErrorIs(err, service.ErrSubscriptionState)
+
+	_, err = s.srv.RecordPayment(ctx, email, 1)
+
+	s.Require().NoError(err)
+
+	sub, err := s.srv.CancelSubscription(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionCancelled, sub.Status)
+
+	// 3
+	resumed, _, err := s.srv.Subscribe(ctx, email, gold.Id, "")
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionActive, resumed.Status)
+	s.Equal(sub.PeriodEnd, resumed.PeriodEnd)
+
+	// 4
+	_, err = s.srv.CancelSubscription(ctx, email)
+
+	s.Require().NoError(err)
+
+	s.endPeriod(dto.UserSubscriptionCancelled)
+
+	s.renew(1)
+
+	s.Equal("Free", s.subscription().Plan.Name)
+
+	// 5
+	s.ErrorIs(s.srv.DeleteSubscription(ctx, s.plan("Free").Id), service.ErrPlanInUse)
+}
+
+func (s *subscriptionRenewalsSuite) TestTrialAndQuotaPolicies() {
+	ctx := context.Background()
+
+	// 1
+	pro, err := s.srv.CreateSubscription(ctx, dto.Subscription{
+		Name:          "Pro",
+		TotalUrls:     100,
+		Price:         9900,
+		Currency:      "RUB",
+		BillingPeriod: dto.BillingPeriodMonth,
+		TrialDays:     7,
+		QuotaPolicy:   dto.QuotaTopUp,
+		Active:        true,
+	})
+
+	s.Require().NoError(err)
+
+	sub, _, err := s.srv.Subscribe(ctx, email, pro.Id, "")
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionTrial, sub.Status)
+	s.True(sub.TrialUsed)
+	s.WithinDuration(time.Now().AddDate(0, 0, 7), sub.PeriodEnd, time.Minute)
+	s.Equal(110, s.urlsLeft())
+
+	// 2
+	_, err = s.srv.RecordPayment(ctx, email, 1)
+
+	s.Require().NoError(err)
+
+	s.endPeriod(dto.UserSubscriptionTrial)
+
+	s.renew(1)
+
+	sub = s.subscription()
+
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.Equal(210, s.urlsLeft())
+
+	// 3
+	_, _, err = s.srv.Subscribe(ctx, email, s.plan("Free").Id, "")
+
+	s.Require().NoError(err)
+
+	sub, _, err = s.srv.Subscribe(ctx, email, pro.Id, "")
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionPending, sub.Status)
+	s.Equal(210, s.urlsLeft())
+
+	// 4
+	inactive := s.plan("Bronze")
+	inactive.Active = false
+
+	_, err = s.srv.UpdateSubscription(ctx, inactive)
+
+	s.Require().NoError(err)
+
+	_, _, err = s.srv.Subscribe(ctx, email, inactive.Id, "")
+
+	s.ErrorIs(err, service.ErrPlanNotFound)
+}
+
+func (s *subscriptionRenewalsSuite) TestPlanHopping() {
+	ctx := context.Background()
+
+	gold, silver := s.plan("Gold"), s.plan("Silver")
+
+	// 1
+	_, _, err := s.srv.Subscribe(ctx, email, gold.Id, "")
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.RecordPayment(ctx, email, 1)
+
+	s.Require().NoError(err)
+	s.Equal(10000, s.urlsLeft())
+
+	_, err = s.storage.UpdateUserLinks(ctx, email, 9990)
+
+	s.Require().NoError(err)
+
+	// 2
+	for _, plan := range []dto.Subscription{silver, gold, silver, gold} {
+		sub, _, err := s.srv.Subscribe(ctx, email, plan.Id, "")
+
+		s.Require().NoError(err)
+		s.Equal(dto.UserSubscriptionPending, sub.Status)
+		s.Equal(9990, s.urlsLeft())
+	}
+
+	// 3
+	s.endPeriod(dto.UserSubscriptionPending)
+
+	s.renew(1)
+
+	sub := s.subscription()
+
+	s.Equal("Free", sub.Plan.Name)
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.Equal(9990, s.urlsLeft())
+
+	// 4
+	_, _, err = s.srv.Subscribe(ctx, email, silver.Id, "")
+
+	s.Require().NoError(err)
+
+	sub, err = s.srv.RecordPayment(ctx, email, 1)
+
+	s.Require().NoError(err)
+	s.Equal(dto.UserSubscriptionActive, sub.Status)
+	s.Equal(5000, s.urlsLeft())
+}
+
+func (s *subscriptionRenewalsSuite) TestUserSeesPlan() {
+	// 1
+	body, code := s.MakeRequestWithBody(http.MethodPost, s.Handlers.Subscribe, `{"plan_id": 2}`)
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	// 2
+	body, code = s.MakeRequestWithBody(http.MethodGet, s.Handlers.GetUser, "")
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	var resp handlers.GetUserResponse
+
+	s.Require().NoError(json.Unmarshal(body, &resp))
+	s.Require().NotNil(resp.Subscription)
+	s.Equal("Silver", resp.Subscription.Plan.Name)
+	s.Equal(dto.UserSubscriptionPending, resp.Subscription.Status)
+	s.Equal(10, resp.User.UrlsLeft)
+	s.WithinDuration(time.Now().Add(48*time.Hour), resp.Subscription.PeriodEnd, time.Minute)
+
+	// 3
+	_, code = s.MakeRequestWithBody(http.MethodPost, s.Handlers.RecordPayment, `{"email": "user@mail.ru", "periods": 1}`)
+
+	s.Equal(http.StatusForbidden, code)
+}
+
+func (s *subscriptionRenewalsSuite) TestScheduler() {
+	s.endPeriod(dto.UserSubscriptionActive)
+
+	// 1
+	s.srv.StartSubscriptionScheduler(context.Background())
+
+	s.Eventually(func() bool {
+		return s.subscription().PeriodEnd.After(time.Now())
+	}, time.Second, 10*time.Millisecond)
+
+	// 2
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+	defer cancel()
+
+	s.NoError(s.srv.Shutdown(ctx))
+}
@@ -0,0 +1,99 @@
+package subscription_renewals
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"time"
+	"urleater/dto"
+	"urleater/internal/handlers"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+const email = "user@mail.ru"
+
+// subscriptionRenewalsSuite проверяет переходы подписок на хранилище в памяти. Чтобы не ждать конца периода,
+// тесты сдвигают его в прошлое через хранилище и вызывают RenewSubscriptions, как это делает планировщик.
+type subscriptionRenewalsSuite struct {
+	base.BaseSuite
+
+	storage *memstorage.Storage
+	srv     *service.Service
+}
+
+func (s *subscriptionRenewalsSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	s.storage = memstorage.NewStorage()
+
+	s.srv = service.New(s.storage, memstorage.NewCache(), nil, nil, memstorage.NewSearcher(), "", nil, service.LinkRules{}).
+		WithBilling(service.BillingRules{GracePeriod: 48 * time.Hour, CheckInterval: 10 * time.Millisecond})
+
+	sessionStore := mocks.NewSessionStore(s.T())
+
+	sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return(email, nil).Maybe()
+
+	s.Handlers = handlers.Handlers{
+		Service: s.srv,
+		Store:   sessionStore,
+	}
+
+	s.Require().NoError(s.srv.RegisterUser(context.Background(), email, "password1"))
+}
+
+func (s *subscriptionRenewalsSuite) subscription() *dto.UserSubscription {
+	sub, err := s.srv.GetUserSubscription(context.Background(), email)
+
+	s.Require().NoError(err)
+	s.Require().NotNil(sub)
+
+	return sub
+}
+
+func (s *subscriptionRenewalsSuite) urlsLeft() int {
+	user, err := s.storage.GetUser(context.Background(), email)
+
+	s.Require().NoError(err)
+
+	return user.UrlsLeft
+}
+
+// endPeriod переводит подписку в состояние status с периодом, закончившимся час назад.
+func (s *subscriptionRenewalsSuite) endPeriod(status dto.UserSubscriptionStatus) time.Time {
+	sub := s.subscription()
+
+	sub.Status = status
+	sub.PeriodEnd = time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+
+	saved, err := s.storage.SaveUserSubscription(context.Background(), *sub, time.Time{}, dto.QuotaChange{})
+
+	s.Require().NoError(err)
+	s.Require().True(saved)
+
+	return sub.PeriodEnd
+}
+
+func (s *subscriptionRenewalsSuite) renew(expected int) {
+	advanced, err := s.srv.RenewSubscriptions(context.Background())
+
+	s.Require().NoError(err)
+	s.Equal(expected, advanced)
+}
+
+func (s *subscriptionRenewalsSuite) plan(name string) dto.Subscription {
+	plans, err := s.srv.GetAllSubscriptions(context.Background())
+
+	s.Require().NoError(err)
+
+	for _, plan := range plans {
+		if plan.Name == name {
+			return plan
+		}
+	}
+
+	s.FailNow("plan not found", name)
+
+	return dto.Subscription{}
+}
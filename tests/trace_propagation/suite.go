@@ -0,0 +1,64 @@
+package trace_propagation
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"urleater/dto"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	"urleater/internal/tracing"
+)
+
+const topic = "links"
+
+type tracingSuite struct {
+	suite.Suite
+
+	recorder *tracetest.SpanRecorder
+	storage  *memstorage.Storage
+	srv      *service.Service
+	cancel   context.CancelFunc
+}
+
+func (s *tracingSuite) SetupTest() {
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+
+	s.Require().NoError(err)
+
+	s.recorder = tracetest.NewSpanRecorder()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder)))
+
+	var ctx context.Context
+
+	ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.storage = memstorage.NewStorage()
+
+	queue := memqueue.New(10, nil)
+	workerChannel := make(chan dto.ConsumerMessage, 10)
+
+	s.srv = service.New(s.storage, memstorage.NewCache(), queue, []service.Consumer{queue.NewConsumer(topic, workerChannel)}, nil, topic, nil, service.LinkRules{})
+
+	s.srv.StartConsumers(ctx)
+	s.srv.StartConsumingWorkers(ctx, service.WorkersConfig{Number: 1, MaxAttempts: 1, DeadLetterTopic: topic + "_dlq"}, workerChannel)
+}
+
+func (s *tracingSuite) TearDownTest() {
+	s.cancel()
+}
+
+// span возвращает завершённый спан с именем name.
+func (s *tracingSuite) span(name string) sdktrace.ReadOnlySpan {
+	for _, span := range s.recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package trace_propagation
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"urleater/internal/tracing"
+)
+
+func (s *tracingSuite) TestRedirectTraceReachesWorker() {
+	ctx := context.Background()
+
+	s.Require().NoError(s.storage.CreateUser(ctx, "user@mail.ru", "password1"))
+
+	_, err := s.storage.CreateShortLink(ctx, "weather2030", "https://forecast.example.com", "user@mail.ru", "")
+
+	s.Require().NoError(err)
+
+	e := echo.New()
+
+	e.Use(tracing.EchoMiddleware)
+
+	e.GET("/:short_link", func(c echo.Context) error {
+		link, err := s.srv.GetShortLink(c.Request().Context(), c.Param("short_link"))
+
+		if err != nil {
+			return c.JSON(http.StatusNotFound, err.Error())
+		}
+
+		return c.Redirect(http.StatusFound, link.LongUrl)
+	})
+
+	// 1
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	req := httptest.NewRequest(http.MethodGet, "/weather2030", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusFound, rec.Code)
+
+	s.Eventually(func() bool {
+		return s.span("process "+topic) != nil
+	}, time.Second, 10*time.Millisecond)
+
+	server := s.span("GET /:short_link")
+	getShortLink := s.span("Service.GetShortLink")
+	publish := s.span("publish " + topic)
+	process := s.span("process " + topic)
+
+	s.Require().NotNil(server)
+	s.Require().NotNil(getShortLink)
+	s.Require().NotNil(publish)
+
+	// запрос продолжает трейс клиента, событие о переходе и его обработка воркером - тот же трейс
+	for _, span := range []sdktrace.ReadOnlySpan{server, getShortLink, publish, process} {
+		s.Equal(traceID, span.SpanContext().TraceID().String(), span.Name())
+	}
+
+	s.Equal(server.SpanContext().SpanID(), getShortLink.Parent().SpanID())
+	s.Equal(getShortLink.SpanContext().SpanID(), publish.Parent().SpanID())
+	s.Equal(publish.SpanContext().SpanID(), process.Parent().SpanID())
+	s.Equal(trace.SpanKindConsumer, process.SpanKind())
+}
@@ -0,0 +1,54 @@
+package update_short_link_info
+
+import (
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/mock"
+	"urleater/dto"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+type updateShortLinkInfoSuite struct {
+	base.BaseSuite
+}
+
+func (s *updateShortLinkInfoSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	storage := mocks.NewPostgresStorage(s.T())
+	sessionStore := mocks.NewSessionStore(s.T())
+
+	sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return("owner@mail.ru", nil)
+
+	ownLink := dto.Link{
+		ShortUrl:  "ownlink1",
+		LongUrl:   "https://www.gismeteo.ru/weather-moscow-4368/weekend/#dataset",
+		UserEmail: "owner@mail.ru",
+	}
+
+	// 1
+	storage.On("GetShortLink", mock.Anything, "ownlink1").Return(&ownLink, nil).Once()
+
+	storage.On("UpdateShortLinkInfo", mock.Anything, "ownlink1", "Weather", "Moscow weekend", []string{"weather", "moscow"}).
+		Return(nil).Once()
+
+	storage.On("GetShortLink", mock.Anything, "ownlink1").Return(&dto.Link{
+		ShortUrl:    ownLink.ShortUrl,
+		LongUrl:     ownLink.LongUrl,
+		UserEmail:   ownLink.UserEmail,
+		Title:       "Weather",
+		Description: "Moscow weekend",
+		Tags:        []string{"moscow", "weather"},
+	}, nil).Once()
+
+	// 2
+	storage.On("GetShortLink", mock.Anything, "otherlink").Return(&dto.Link{
+		ShortUrl:  "otherlink",
+		UserEmail: "other@mail.ru",
+	}, nil).Once()
+
+	// 3
+	storage.On("GetShortLink", mock.Anything, "missinglink").Return(nil, pgx.ErrNoRows).Once()
+
+	s.FinishSetupTest(storage, nil, nil, nil, nil, sessionStore)
+}
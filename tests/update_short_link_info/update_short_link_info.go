@@ -0,0 +1,68 @@
+package update_short_link_info
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"urleater/internal/handlers"
+)
+
+func (s *updateShortLinkInfoSuite) TestUpdateShortLinkInfo() {
+	// 1
+	body, code := s.UpdateShortLinkInfo(&handlers.UpdateShortLinkInfoRequest{
+		ShortLink:   "ownlink1",
+		Title:       "  Weather ",
+		Description: "Moscow weekend",
+		Tags:        []string{"Weather", " moscow", "weather", ""},
+	})
+
+	var resp1 handlers.UpdateShortLinkInfoResponse
+
+	err := json.Unmarshal(body, &resp1)
+
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, code)
+	s.Equal("Weather", resp1.Link.Title)
+	s.Equal([]string{"moscow", "weather"}, resp1.Link.Tags)
+
+	// 2
+	_, code = s.UpdateShortLinkInfo(&handlers.UpdateShortLinkInfoRequest{
+		ShortLink: "otherlink",
+		Title:     "Not mine",
+	})
+
+	s.Equal(http.StatusInternalServerError, code)
+
+	// 3
+	_, code = s.UpdateShortLinkInfo(&handlers.UpdateShortLinkInfoRequest{
+		ShortLink: "missinglink",
+		Title:     "Missing",
+	})
+
+	s.Equal(http.StatusInternalServerError, code)
+
+	// 4
+	_, code = s.UpdateShortLinkInfo(&handlers.UpdateShortLinkInfoRequest{
+		ShortLink: "ownlink1",
+		Title:     strings.Repeat("a", 101),
+	})
+
+	s.Equal(http.StatusInternalServerError, code)
+
+	// 5
+	_, code = s.UpdateShortLinkInfo(&handlers.UpdateShortLinkInfoRequest{
+		ShortLink: "ownlink1",
+		Tags:      []string{"bad tag"},
+	})
+
+	s.Equal(http.StatusInternalServerError, code)
+
+	// 6
+	_, code = s.UpdateShortLinkInfo(&handlers.UpdateShortLinkInfoRequest{
+		ShortLink: "ownlink1",
+		Tags:      []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"},
+	})
+
+	s.Equal(http.StatusInternalServerError, code)
+}
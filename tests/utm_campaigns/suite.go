@@ -0,0 +1,162 @@
+package utm_campaigns
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+	"urleater/dto"
+	"urleater/internal/handlers"
+	"urleater/internal/repository/memqueue"
+	"urleater/internal/repository/memstorage"
+	"urleater/internal/service"
+	base "urleater/tests"
+	"urleater/tests/mocks"
+)
+
+const (
+	email = "user@mail.ru"
+	other = "other@mail.ru"
+)
+
+// utmCampaignsSuite проверяет метки кампании ссылок на хранилище в памяти: переходы идут через маршрутизатор echo.
+type utmCampaignsSuite struct {
+	base.BaseSuite
+
+	storage      *memstorage.Storage
+	srv          *service.Service
+	router       *echo.Echo
+	sessionStore *mocks.SessionStore
+	cancel       context.CancelFunc
+}
+
+func (s *utmCampaignsSuite) SetupTest() {
+	s.BaseSetupTest()
+
+	var ctx context.Context
+
+	ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.storage = memstorage.NewStorage()
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	// переходы считаются воркерами, как в сервисе: кампания попадает в отчёт из события перехода
+	queue := memqueue.New(100, nil)
+	workerChannel := make(chan dto.ConsumerMessage, 100)
+
+	s.srv = service.New(s.storage, memstorage.NewCache(), queue, []service.Consumer{queue.NewConsumer("links", workerChannel)}, memstorage.NewSearcher(), "links", logger, service.LinkRules{})
+
+	s.srv.StartConsumers(ctx)
+	s.srv.StartConsumingWorkers(ctx, service.WorkersConfig{Number: 2, MaxAttempts: 3, DeadLetterTopic: "links_dlq"}, workerChannel)
+
+	s.sessionStore = mocks.NewSessionStore(s.T())
+
+	s.Handlers = handlers.Handlers{
+		Service: s.srv,
+		Store:   s.sessionStore,
+		Logger:  logger,
+	}
+
+	s.router = echo.New()
+	s.router.GET("/:short_link", s.Handlers.GetShortLink)
+	s.router.GET("/:short_link/*", s.Handlers.GetShortLink)
+
+	s.Require().NoError(s.srv.RegisterUser(ctx, email, "password1"))
+	s.Require().NoError(s.srv.RegisterUser(ctx, other, "password1"))
+
+	s.loginAs(email)
+}
+
+func (s *utmCampaignsSuite) TearDownTest() {
+	s.cancel()
+}
+
+// loginAs задаёт пользователя, от имени которого выполняются следующие запросы.
+func (s *utmCampaignsSuite) loginAs(email string) {
+	s.sessionStore.ExpectedCalls = nil
+
+	s.sessionStore.On("RetrieveEmailFromSession", mock.Anything).Return(email, nil).Maybe()
+}
+
+// createLink создаёт ссылку alias на longUrl с метками utm и возвращает ответ обработчика.
+func (s *utmCampaignsSuite) createLink(alias string, longUrl string, utm handlers.UTMRequest) ([]byte, int) {
+	return s.CreateShortLink(&handlers.CreateShortLinkRequest{ShortURL: alias, LongURL: longUrl, UTM: utm})
+}
+
+// requireLink создаёт ссылку и возвращает её.
+func (s *utmCampaignsSuite) requireLink(alias string, longUrl string, utm handlers.UTMRequest) dto.Link {
+	body, code := s.createLink(alias, longUrl, utm)
+
+	s.Require().Equal(http.StatusOK, code, string(body))
+
+	var resp handlers.CreateShortLinkResponse
+
+	s.Require().NoError(json.Unmarshal(body, &resp))
+
+	return resp.Link
+}
+
+func (s *utmCampaignsSuite) updateUTM(request handlers.UpdateShortLinkUTMRequest) *httptest.ResponseRecorder {
+	body, err := json.Marshal(request)
+
+	s.Require().NoError(err)
+
+	req := httptest.NewRequest(http.MethodPut, "/update_link_utm", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+
+	s.NoError(s.Handlers.UpdateShortLinkUTM(echo.New().NewContext(req, rec)))
+
+	return rec
+}
+
+func (s *utmCampaignsSuite) requestCampaignStats() *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+
+	s.NoError(s.Handlers.GetCampaignStats(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/campaign_stats", nil), rec)))
+
+	return rec
+}
+
+// campaignStats возвращает отчёт по кампаниям текущего пользователя.
+func (s *utmCampaignsSuite) campaignStats() []dto.CampaignStats {
+	rec := s.requestCampaignStats()
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp handlers.CampaignStatsResponse
+
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return resp.Campaigns
+}
+
+// waitCampaignStats ждёт, пока воркеры учтут переходы и отчёт по кампаниям пользователя станет равен want.
+func (s *utmCampaignsSuite) waitCampaignStats(email string, want []dto.CampaignStats) {
+	s.Eventually(func() bool {
+		stats, err := s.storage.GetUserCampaignStats(context.Background(), email)
+
+		return err == nil && assert.ObjectsAreEqual(want, stats)
+	}, time.Second, 10*time.Millisecond)
+}
+
+// location переходит по target и возвращает адрес перенаправления.
+func (s *utmCampaignsSuite) location(target string) string {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+
+	s.router.ServeHTTP(rec, req)
+
+	s.Require().Equal(http.StatusFound, rec.Code, target+" "+rec.Body.String())
+
+	return rec.Header().Get(echo.HeaderLocation)
+}
@@ -0,0 +1,183 @@
+package utm_campaigns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"urleater/dto"
+	"urleater/internal/handlers"
+)
+
+func (s *utmCampaignsSuite) TestCreateWithUTM() {
+	ctx := context.Background()
+
+	// 1
+	link := s.requireLink("spring01", "https://shop.example/sale?utm_source=old&ref=home",
+		handlers.UTMRequest{Source: " newsletter ", Medium: "email", Campaign: "spring sale"})
+
+	s.Equal(dto.UTM{Source: "newsletter", Medium: "email", Campaign: "spring sale"}, link.UTM)
+
+	// 2
+	s.Equal("https://shop.example/sale?ref=home&utm_campaign=spring+sale&utm_medium=email&utm_source=newsletter", s.location("/spring01"))
+
+	// 3
+	s.requireLink("plain001", "https://shop.example/plain?utm_source=old", handlers.UTMRequest{})
+
+	s.Equal("https://shop.example/plain?utm_source=old", s.location("/plain001"))
+
+	// 4
+	user, err := s.srv.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+
+	for _, utm := range []handlers.UTMRequest{
+		{Medium: "email"},
+		{Source: "newsletter", Term: "shoes"},
+		{Source: "newsletter", Campaign: strings.Repeat("a", 201)},
+		{Source: "news\nletter", Campaign: "spring"},
+	} {
+		body, code := s.createLink("invalid1", "https://shop.example", utm)
+
+		s.Equal(http.StatusBadRequest, code, string(body))
+	}
+
+	after, err := s.srv.GetUser(ctx, email)
+
+	s.Require().NoError(err)
+	s.Equal(user.UrlsLeft, after.UrlsLeft)
+}
+
+func (s *utmCampaignsSuite) TestVisitorQuery() {
+	ctx := context.Background()
+
+	utm := handlers.UTMRequest{Source: "newsletter", Campaign: "spring"}
+
+	s.requireLink("keeplink", "https://shop.example/sale", utm)
+	s.requireLink("overlink", "https://shop.example/sale", utm)
+
+	_, err := s.srv.UpdateShortLinkRedirect(ctx, "keeplink", email, dto.RedirectOptions{ForwardQuery: true})
+
+	s.Require().NoError(err)
+
+	_, err = s.srv.UpdateShortLinkRedirect(ctx, "overlink", email, dto.RedirectOptions{ForwardQuery: true, QueryConflict: dto.QueryConflictOverride})
+
+	s.Require().NoError(err)
+
+	// 1
+	s.Equal("https://shop.example/sale?utm_campaign=spring&utm_source=newsletter&page=2",
+		s.location("/keeplink?utm_source=twitter&page=2"))
+
+	// 2
+	s.Equal("https://shop.example/sale?utm_campaign=spring&page=2&utm_source=twitter",
+		s.location("/overlink?utm_source=twitter&page=2"))
+}
+
+func (s *utmCampaignsSuite) TestUpdateUTM() {
+	ctx := context.Background()
+
+	s.requireLink("tagme001", "https://shop.example/item", handlers.UTMRequest{})
+
+	// 1
+	s.Equal("https://shop.example/item", s.location("/tagme001"))
+
+	rec := s.updateUTM(handlers.UpdateShortLinkUTMRequest{ShortLink: "tagme001", UTM: handlers.UTMRequest{Source: "ads", Campaign: "black friday", Content: "banner"}})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	var updated handlers.UpdateShortLinkInfoResponse
+
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &updated))
+	s.Equal(dto.UTM{Source: "ads", Campaign: "black friday", Content: "banner"}, updated.Link.UTM)
+
+	// 2
+	_, err := s.srv.RelayOutboxEvents(ctx)
+
+	s.Require().NoError(err)
+
+	s.Equal("https://shop.example/item?utm_campaign=black+friday&utm_content=banner&utm_source=ads", s.location("/tagme001"))
+
+	// 3
+	rec = s.updateUTM(handlers.UpdateShortLinkUTMRequest{ShortLink: "tagme001"})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	_, err = s.srv.RelayOutboxEvents(ctx)
+
+	s.Require().NoError(err)
+
+	s.Equal("https://shop.example/item", s.location("/tagme001"))
+
+	// 4
+	rec = s.updateUTM(handlers.UpdateShortLinkUTMRequest{ShortLink: "tagme001", UTM: handlers.UTMRequest{Source: "ads"}})
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+
+	// 5
+	s.loginAs(other)
+
+	rec = s.updateUTM(handlers.UpdateShortLinkUTMRequest{ShortLink: "tagme001", UTM: handlers.UTMRequest{Source: "ads", Campaign: "spring"}})
+
+	s.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *utmCampaignsSuite) TestCampaignStats() {
+	ctx := context.Background()
+
+	// 1
+	s.Empty(s.campaignStats())
+
+	// 2
+	s.requireLink("spring01", "https://shop.example/a", handlers.UTMRequest{Source: "newsletter", Campaign: "spring"})
+	s.requireLink("spring02", "https://shop.example/b", handlers.UTMRequest{Source: "ads", Medium: "cpc", Campaign: "spring"})
+	s.requireLink("winter01", "https://shop.example/c", handlers.UTMRequest{Source: "ads", Campaign: "winter"})
+	s.requireLink("plain001", "https://shop.example/d", handlers.UTMRequest{})
+
+	s.loginAs(other)
+	s.requireLink("foreign1", "https://shop.example/e", handlers.UTMRequest{Source: "ads", Campaign: "spring"})
+	s.loginAs(email)
+
+	for _, alias := range []string{"spring01", "winter01", "winter01", "winter01", "spring02", "plain001", "foreign1"} {
+		s.location("/" + alias)
+	}
+
+	stats := []dto.CampaignStats{
+		{Campaign: "winter", Links: 1, Clicks: 3},
+		{Campaign: "spring", Links: 2, Clicks: 2},
+	}
+
+	s.waitCampaignStats(email, stats)
+	s.Equal(stats, s.campaignStats())
+
+	// 3
+	rec := s.updateUTM(handlers.UpdateShortLinkUTMRequest{ShortLink: "winter01", UTM: handlers.UTMRequest{Source: "ads", Campaign: "spring"}})
+
+	s.Require().Equal(http.StatusOK, rec.Code, rec.Body.String())
+
+	_, err := s.srv.RelayOutboxEvents(ctx)
+
+	s.Require().NoError(err)
+
+	// прежние переходы остаются в winter, новый попадает в spring
+	s.location("/winter01")
+
+	stats = []dto.CampaignStats{
+		{Campaign: "spring", Links: 3, Clicks: 3},
+		{Campaign: "winter", Links: 0, Clicks: 3},
+	}
+
+	s.waitCampaignStats(email, stats)
+	s.Equal(stats, s.campaignStats())
+
+	// 4
+	s.loginAs(other)
+
+	s.Equal([]dto.CampaignStats{{Campaign: "spring", Links: 1, Clicks: 1}}, s.campaignStats())
+
+	// 5
+	s.loginAs("")
+
+	rec = s.requestCampaignStats()
+
+	s.Equal(http.StatusBadRequest, rec.Code)
+}